@@ -45,6 +45,55 @@ type Config struct {
 	MailerUsername     string
 	MailerPassword string
 	MailerSender   string
+
+	// maximum number of operations accepted by POST /todos/bulk
+	BulkMaxBatchSize int
+	// maximum number of rows accepted by POST /todos/import
+	ImportMaxRows int
+	// reject todo writes that do not send If-Match with 428 Precondition Required
+	RequireIfMatch bool
+
+	// change stream: events kept for Last-Event-ID resume, and the keep-alive interval
+	EventBufferSize int
+	EventHeartbeat  time.Duration
+
+	// outgoing webhooks: a failed delivery is retried after WebhookRetryBase,
+	// doubling each time, until WebhookMaxAttempts is reached
+	WebhookMaxAttempts  int
+	WebhookRetryBase    time.Duration
+	WebhookTimeout      time.Duration
+	WebhookPollInterval time.Duration
+	WebhookAllowPrivate bool
+
+	// soft-deleted todos are purged once they have been in the trash for
+	// TrashRetention (0 keeps them forever); the purge runs every TrashPurgeInterval
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	// public base URL of the API, used for links back to it (e.g. local file downloads)
+	AppURL string
+
+	// file storage: STORAGE_DRIVER is "local" (files under StorageLocalPath) or "s3"
+	StorageDriver     string
+	StorageLocalPath  string
+	StorageSigningKey string
+	StorageURLTTL     time.Duration
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKey       string
+	S3SecretKey       string
+	S3UsePathStyle    bool
+
+	// attachment upload limits
+	AttachmentMaxSize      int64
+	AttachmentAllowedTypes []string
+
+	// largest avatar upload accepted, in bytes
+	AvatarMaxSize int64
+
+	// how errors are written, "envelope" or "problem" (RFC 9457); clients can ask for problem details either way
+	ErrorFormat string
 	
 }
 
@@ -154,6 +203,151 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	cfg.BulkMaxBatchSize = 100
+	if val := os.Getenv("BULK_MAX_BATCH_SIZE"); val != "" {
+		i, err := strconv.Atoi(val)
+		if err != nil || i < 1 {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid BULK_MAX_BATCH_SIZE value: %s", val), err)
+		}
+		cfg.BulkMaxBatchSize = i
+	}
+
+	cfg.RequireIfMatch = os.Getenv("REQUIRE_IF_MATCH") == "true"
+
+	cfg.ImportMaxRows = 1000
+	if val := os.Getenv("IMPORT_MAX_ROWS"); val != "" {
+		i, err := strconv.Atoi(val)
+		if err != nil || i < 1 {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid IMPORT_MAX_ROWS value: %s", val), err)
+		}
+		cfg.ImportMaxRows = i
+	}
+
+	cfg.EventBufferSize = 1000
+	if val := os.Getenv("EVENT_BUFFER_SIZE"); val != "" {
+		i, err := strconv.Atoi(val)
+		if err != nil || i < 1 {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid EVENT_BUFFER_SIZE value: %s", val), err)
+		}
+		cfg.EventBufferSize = i
+	}
+	cfg.EventHeartbeat = 25 * time.Second
+	if val := os.Getenv("EVENT_HEARTBEAT"); val != "" {
+		interval, err := time.ParseDuration(val)
+		if err != nil || interval <= 0 {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid EVENT_HEARTBEAT value: %s", val), err)
+		}
+		cfg.EventHeartbeat = interval
+	}
+
+	cfg.WebhookMaxAttempts = 8
+	if val := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); val != "" {
+		i, err := strconv.Atoi(val)
+		if err != nil || i < 1 {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid WEBHOOK_MAX_ATTEMPTS value: %s", val), err)
+		}
+		cfg.WebhookMaxAttempts = i
+	}
+	webhookDurations := []struct {
+		env    string
+		target *time.Duration
+		value  time.Duration
+	}{
+		{"WEBHOOK_RETRY_BASE", &cfg.WebhookRetryBase, 30 * time.Second},
+		{"WEBHOOK_TIMEOUT", &cfg.WebhookTimeout, 10 * time.Second},
+		{"WEBHOOK_POLL_INTERVAL", &cfg.WebhookPollInterval, 5 * time.Second},
+	}
+	for _, d := range webhookDurations {
+		*d.target = d.value
+		if val := os.Getenv(d.env); val != "" {
+			parsed, err := time.ParseDuration(val)
+			if err != nil || parsed <= 0 {
+				return nil, errors.ConfigError(fmt.Sprintf("Invalid %s value: %s", d.env, val), err)
+			}
+			*d.target = parsed
+		}
+	}
+	// lets webhooks reach loopback and private network addresses, e.g. in development
+	cfg.WebhookAllowPrivate = os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+
+	cfg.TrashRetention = 30 * 24 * time.Hour
+	if val := os.Getenv("TRASH_RETENTION"); val != "" {
+		retention, err := time.ParseDuration(val)
+		if err != nil || retention < 0 {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid TRASH_RETENTION value: %s", val), err)
+		}
+		cfg.TrashRetention = retention
+	}
+	cfg.TrashPurgeInterval = time.Hour
+	if val := os.Getenv("TRASH_PURGE_INTERVAL"); val != "" {
+		interval, err := time.ParseDuration(val)
+		if err != nil || interval <= 0 {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid TRASH_PURGE_INTERVAL value: %s", val), err)
+		}
+		cfg.TrashPurgeInterval = interval
+	}
+
+	cfg.AppURL = os.Getenv("APP_URL")
+	if cfg.AppURL == "" {
+		cfg.AppURL = fmt.Sprintf("http://localhost:%d", cfg.ServerPort)
+	}
+
+	//file storage
+	cfg.StorageDriver = os.Getenv("STORAGE_DRIVER")
+	if cfg.StorageDriver == "" {
+		cfg.StorageDriver = "local"
+	}
+	cfg.StorageLocalPath = os.Getenv("STORAGE_LOCAL_PATH")
+	if cfg.StorageLocalPath == "" {
+		cfg.StorageLocalPath = "storage"
+	}
+	cfg.StorageSigningKey = os.Getenv("STORAGE_SIGNING_KEY")
+	if cfg.StorageSigningKey == "" {
+		cfg.StorageSigningKey = cfg.JWTSecret
+	}
+	cfg.StorageURLTTL = 15 * time.Minute
+	if val := os.Getenv("STORAGE_URL_TTL"); val != "" {
+		ttl, err := time.ParseDuration(val)
+		if err != nil || ttl <= 0 {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid STORAGE_URL_TTL value: %s", val), err)
+		}
+		cfg.StorageURLTTL = ttl
+	}
+	cfg.S3Endpoint = os.Getenv("S3_ENDPOINT")
+	cfg.S3Region = os.Getenv("S3_REGION")
+	cfg.S3Bucket = os.Getenv("S3_BUCKET")
+	cfg.S3AccessKey = os.Getenv("S3_ACCESS_KEY")
+	cfg.S3SecretKey = os.Getenv("S3_SECRET_KEY")
+	cfg.S3UsePathStyle = os.Getenv("S3_USE_PATH_STYLE") == "true"
+
+	cfg.AttachmentMaxSize = 10 << 20 // 10 MiB
+	if val := os.Getenv("ATTACHMENT_MAX_SIZE"); val != "" {
+		size, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || size < 1 {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid ATTACHMENT_MAX_SIZE value: %s", val), err)
+		}
+		cfg.AttachmentMaxSize = size
+	}
+	cfg.AttachmentAllowedTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf", "text/plain"}
+	if val := os.Getenv("ATTACHMENT_ALLOWED_TYPES"); val != "" {
+		cfg.AttachmentAllowedTypes = strings.Split(val, ",")
+	}
+	cfg.AvatarMaxSize = 5 << 20 // 5 MiB
+	if val := os.Getenv("AVATAR_MAX_SIZE"); val != "" {
+		size, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || size < 1 {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid AVATAR_MAX_SIZE value: %s", val), err)
+		}
+		cfg.AvatarMaxSize = size
+	}
+	cfg.ErrorFormat = "envelope"
+	if val := os.Getenv("ERROR_FORMAT"); val != "" {
+		if val != "envelope" && val != "problem" {
+			return nil, errors.ConfigError(fmt.Sprintf("Invalid ERROR_FORMAT value: %s, must be envelope or problem", val), nil)
+		}
+		cfg.ErrorFormat = val
+	}
+
 	corsOriginStr := os.Getenv("ALLOWED_ORIGINS")
 	if corsOriginStr != "" {
 		cfg.CORSOrigins = strings.Split(corsOriginStr, ",")
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"gorm.io/gorm"
+)
+
+// Addsubtaskstotodos struct implements migration interface
+type Addsubtaskstotodos struct{}
+
+func (m *Addsubtaskstotodos) Version() string {
+	return "20261019090000"
+}
+func (m *Addsubtaskstotodos) Name() string {
+	return "add_subtasks_to_todos"
+}
+
+// up migration method
+func (m *Addsubtaskstotodos) Up(tx *gorm.DB) error {
+	log.Printf("Running Up migration: %s", m.Name())
+	// adds parent_id, position and completed_at to the todos table
+	if err := tx.AutoMigrate(&models.Todo{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Up migration: %s", m.Name())
+	return nil
+}
+
+// down migration method
+func (m *Addsubtaskstotodos) Down(tx *gorm.DB) error {
+	log.Printf("Running Down migration: %s", m.Name())
+	if tx.Migrator().HasConstraint(&models.Todo{}, "Subtasks") {
+		if err := tx.Migrator().DropConstraint(&models.Todo{}, "Subtasks"); err != nil {
+			return err
+		}
+	}
+	for _, column := range []string{"ParentID", "Position", "CompletedAt"} {
+		if tx.Migrator().HasColumn(&models.Todo{}, column) {
+			if err := tx.Migrator().DropColumn(&models.Todo{}, column); err != nil {
+				return err
+			}
+		}
+	}
+	log.Printf("Successfully applied Down migration: %s", m.Name())
+	return nil
+}
+
+func init() {
+	// Register the migration
+	RegisteredMigrations = append(RegisteredMigrations, &Addsubtaskstotodos{})
+}
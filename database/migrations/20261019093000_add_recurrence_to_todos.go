@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"gorm.io/gorm"
+)
+
+// Addrecurrencetotodos struct implements migration interface
+type Addrecurrencetotodos struct{}
+
+func (m *Addrecurrencetotodos) Version() string {
+	return "20261019093000"
+}
+func (m *Addrecurrencetotodos) Name() string {
+	return "add_recurrence_to_todos"
+}
+
+// up migration method
+func (m *Addrecurrencetotodos) Up(tx *gorm.DB) error {
+	log.Printf("Running Up migration: %s", m.Name())
+	// adds due_at, rrule, timezone, recurrence_start and next_occurrence_id
+	if err := tx.AutoMigrate(&models.Todo{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Up migration: %s", m.Name())
+	return nil
+}
+
+// down migration method
+func (m *Addrecurrencetotodos) Down(tx *gorm.DB) error {
+	log.Printf("Running Down migration: %s", m.Name())
+	for _, column := range []string{"DueAt", "RRule", "Timezone", "RecurrenceStart", "NextOccurrenceID"} {
+		if tx.Migrator().HasColumn(&models.Todo{}, column) {
+			if err := tx.Migrator().DropColumn(&models.Todo{}, column); err != nil {
+				return err
+			}
+		}
+	}
+	log.Printf("Successfully applied Down migration: %s", m.Name())
+	return nil
+}
+
+func init() {
+	// Register the migration
+	RegisteredMigrations = append(RegisteredMigrations, &Addrecurrencetotodos{})
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"gorm.io/gorm"
+)
+
+// Addownerandtagstotodos struct implements migration interface
+type Addownerandtagstotodos struct{}
+
+func (m *Addownerandtagstotodos) Version() string {
+	return "20261019100000"
+}
+func (m *Addownerandtagstotodos) Name() string {
+	return "add_owner_and_tags_to_todos"
+}
+
+// up migration method
+func (m *Addownerandtagstotodos) Up(tx *gorm.DB) error {
+	log.Printf("Running Up migration: %s", m.Name())
+	// adds todos.user_id plus the tags and todo_tags tables;
+	// todos created before ownership existed keep user_id 0 and belong to nobody
+	if err := tx.AutoMigrate(&models.Tag{}, &models.Todo{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Up migration: %s", m.Name())
+	return nil
+}
+
+// down migration method
+func (m *Addownerandtagstotodos) Down(tx *gorm.DB) error {
+	log.Printf("Running Down migration: %s", m.Name())
+	if err := tx.Migrator().DropTable("todo_tags", &models.Tag{}); err != nil {
+		return err
+	}
+	if tx.Migrator().HasColumn(&models.Todo{}, "UserID") {
+		if err := tx.Migrator().DropColumn(&models.Todo{}, "UserID"); err != nil {
+			return err
+		}
+	}
+	log.Printf("Successfully applied Down migration: %s", m.Name())
+	return nil
+}
+
+func init() {
+	// Register the migration
+	RegisteredMigrations = append(RegisteredMigrations, &Addownerandtagstotodos{})
+}
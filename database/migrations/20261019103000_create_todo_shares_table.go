@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"gorm.io/gorm"
+)
+
+// Createtodosharestable struct implements migration interface
+type Createtodosharestable struct{}
+
+func (m *Createtodosharestable) Version() string {
+	return "20261019103000"
+}
+func (m *Createtodosharestable) Name() string {
+	return "create_todo_shares_table"
+}
+
+// up migration method
+func (m *Createtodosharestable) Up(tx *gorm.DB) error {
+	log.Printf("Running Up migration: %s", m.Name())
+	if err := tx.AutoMigrate(&models.TodoShare{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Up migration: %s", m.Name())
+	return nil
+}
+
+// down migration method
+func (m *Createtodosharestable) Down(tx *gorm.DB) error {
+	log.Printf("Running Down migration: %s", m.Name())
+	if err := tx.Migrator().DropTable(&models.TodoShare{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Down migration: %s", m.Name())
+	return nil
+}
+
+func init() {
+	// Register the migration
+	RegisteredMigrations = append(RegisteredMigrations, &Createtodosharestable{})
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"gorm.io/gorm"
+)
+
+// Createcommentsandactivitiestables struct implements migration interface
+type Createcommentsandactivitiestables struct{}
+
+func (m *Createcommentsandactivitiestables) Version() string {
+	return "20261019110000"
+}
+func (m *Createcommentsandactivitiestables) Name() string {
+	return "create_comments_and_activities_tables"
+}
+
+// up migration method
+func (m *Createcommentsandactivitiestables) Up(tx *gorm.DB) error {
+	log.Printf("Running Up migration: %s", m.Name())
+	if err := tx.AutoMigrate(&models.Comment{}, &models.Activity{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Up migration: %s", m.Name())
+	return nil
+}
+
+// down migration method
+func (m *Createcommentsandactivitiestables) Down(tx *gorm.DB) error {
+	log.Printf("Running Down migration: %s", m.Name())
+	if err := tx.Migrator().DropTable(&models.Activity{}, &models.Comment{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Down migration: %s", m.Name())
+	return nil
+}
+
+func init() {
+	// Register the migration
+	RegisteredMigrations = append(RegisteredMigrations, &Createcommentsandactivitiestables{})
+}
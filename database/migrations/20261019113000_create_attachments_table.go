@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"gorm.io/gorm"
+)
+
+// Createattachmentstable struct implements migration interface
+type Createattachmentstable struct{}
+
+func (m *Createattachmentstable) Version() string {
+	return "20261019113000"
+}
+func (m *Createattachmentstable) Name() string {
+	return "create_attachments_table"
+}
+
+// up migration method
+func (m *Createattachmentstable) Up(tx *gorm.DB) error {
+	log.Printf("Running Up migration: %s", m.Name())
+	if err := tx.AutoMigrate(&models.Attachment{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Up migration: %s", m.Name())
+	return nil
+}
+
+// down migration method
+func (m *Createattachmentstable) Down(tx *gorm.DB) error {
+	log.Printf("Running Down migration: %s", m.Name())
+	if err := tx.Migrator().DropTable(&models.Attachment{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Down migration: %s", m.Name())
+	return nil
+}
+
+func init() {
+	// Register the migration
+	RegisteredMigrations = append(RegisteredMigrations, &Createattachmentstable{})
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"gorm.io/gorm"
+)
+
+// Addversiontotodos struct implements migration interface
+type Addversiontotodos struct{}
+
+func (m *Addversiontotodos) Version() string {
+	return "20261019120000"
+}
+func (m *Addversiontotodos) Name() string {
+	return "add_version_to_todos"
+}
+
+// up migration method
+func (m *Addversiontotodos) Up(tx *gorm.DB) error {
+	log.Printf("Running Up migration: %s", m.Name())
+	if err := tx.AutoMigrate(&models.Todo{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Up migration: %s", m.Name())
+	return nil
+}
+
+// down migration method
+func (m *Addversiontotodos) Down(tx *gorm.DB) error {
+	log.Printf("Running Down migration: %s", m.Name())
+	if err := tx.Migrator().DropColumn(&models.Todo{}, "version"); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Down migration: %s", m.Name())
+	return nil
+}
+
+func init() {
+	// Register the migration
+	RegisteredMigrations = append(RegisteredMigrations, &Addversiontotodos{})
+}
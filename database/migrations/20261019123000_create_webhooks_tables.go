@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"gorm.io/gorm"
+)
+
+// Createwebhookstables struct implements migration interface
+type Createwebhookstables struct{}
+
+func (m *Createwebhookstables) Version() string {
+	return "20261019123000"
+}
+func (m *Createwebhookstables) Name() string {
+	return "create_webhooks_tables"
+}
+
+// up migration method
+func (m *Createwebhookstables) Up(tx *gorm.DB) error {
+	log.Printf("Running Up migration: %s", m.Name())
+	if err := tx.AutoMigrate(&models.Webhook{}, &models.WebhookDelivery{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Up migration: %s", m.Name())
+	return nil
+}
+
+// down migration method
+func (m *Createwebhookstables) Down(tx *gorm.DB) error {
+	log.Printf("Running Down migration: %s", m.Name())
+	if err := tx.Migrator().DropTable(&models.WebhookDelivery{}, &models.Webhook{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Down migration: %s", m.Name())
+	return nil
+}
+
+func init() {
+	// Register the migration
+	RegisteredMigrations = append(RegisteredMigrations, &Createwebhookstables{})
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/codetheuri/todolist/internal/app/profile/models"
+	"gorm.io/gorm"
+)
+
+// Createprofilestable struct implements migration interface
+type Createprofilestable struct{}
+
+func (m *Createprofilestable) Version() string {
+	return "20261019130000"
+}
+func (m *Createprofilestable) Name() string {
+	return "create_profiles_table"
+}
+
+// up migration method
+func (m *Createprofilestable) Up(tx *gorm.DB) error {
+	log.Printf("Running Up migration: %s", m.Name())
+	if err := tx.AutoMigrate(&models.Profile{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Up migration: %s", m.Name())
+	return nil
+}
+
+// down migration method
+func (m *Createprofilestable) Down(tx *gorm.DB) error {
+	log.Printf("Running Down migration: %s", m.Name())
+	if err := tx.Migrator().DropTable(&models.Profile{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Down migration: %s", m.Name())
+	return nil
+}
+
+func init() {
+	// Register the migration
+	RegisteredMigrations = append(RegisteredMigrations, &Createprofilestable{})
+}
@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/codetheuri/todolist/internal/platform/identity"
+	"gorm.io/gorm"
+)
+
+// Reconcileuserstable struct implements migration interface. The users table used
+// to be described by two models: auth's (email, password, role) and todo's, which
+// also had a required unique username. Whichever created it, it now matches
+// identity.User, where username is an optional, lowercased handle.
+type Reconcileuserstable struct{}
+
+func (m *Reconcileuserstable) Version() string {
+	return "20261019133000"
+}
+func (m *Reconcileuserstable) Name() string {
+	return "reconcile_users_table"
+}
+
+// up migration method
+func (m *Reconcileuserstable) Up(tx *gorm.DB) error {
+	log.Printf("Running Up migration: %s", m.Name())
+	migrator := tx.Migrator()
+	if migrator.HasColumn(&identity.User{}, "username") {
+		// todo's model made it NOT NULL, leaving users without a handle with ''
+		if err := migrator.AlterColumn(&identity.User{}, "Username"); err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE users SET username = NULL WHERE TRIM(username) = ''").Error; err != nil {
+			return err
+		}
+		// usernames are compared lowercased; of any that now collide the oldest
+		// account keeps it. The derived table lets MySQL read the table it updates.
+		if err := tx.Exec(`UPDATE users SET username = NULL WHERE id IN (
+			SELECT id FROM (
+				SELECT u1.id FROM users u1 JOIN users u2
+				ON LOWER(TRIM(u1.username)) = LOWER(TRIM(u2.username)) AND u2.id < u1.id
+			) AS duplicates
+		)`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE users SET username = LOWER(TRIM(username)) WHERE username IS NOT NULL").Error; err != nil {
+			return err
+		}
+	}
+	// adds the column and its unique index where they are missing
+	if err := tx.AutoMigrate(&identity.User{}); err != nil {
+		return err
+	}
+	log.Printf("Successfully applied Up migration: %s", m.Name())
+	return nil
+}
+
+// down migration method; usernames are dropped along with the column
+func (m *Reconcileuserstable) Down(tx *gorm.DB) error {
+	log.Printf("Running Down migration: %s", m.Name())
+	migrator := tx.Migrator()
+	if migrator.HasIndex(&identity.User{}, "Username") {
+		if err := migrator.DropIndex(&identity.User{}, "Username"); err != nil {
+			return err
+		}
+	}
+	if migrator.HasColumn(&identity.User{}, "username") {
+		if err := migrator.DropColumn(&identity.User{}, "username"); err != nil {
+			return err
+		}
+	}
+	log.Printf("Successfully applied Down migration: %s", m.Name())
+	return nil
+}
+
+func init() {
+	// Register the migration
+	RegisteredMigrations = append(RegisteredMigrations, &Reconcileuserstable{})
+}
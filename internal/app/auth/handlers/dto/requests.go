@@ -1,9 +1,12 @@
 package dto
 
 type RegisterRequest struct {
-	Email    string `json:"email" validate:"required,email" unique:"users,email"`
+	// the unique index on users.email also covers deleted accounts
+	Email    string `json:"email" validate:"required,email,unique=users.email trashed"`
 	Password string `json:"password" validate:"required,min=8"`
 	Role     string `json:"role" validate:"required,oneof=user admin"`
+	// optional public handle
+	Username string `json:"username" validate:"omitempty,max=31"`
 }
 
 type LoginRequest struct {
@@ -16,6 +19,10 @@ type ChangePasswordRequest struct {
     NewPassword string `json:"new_password" validate:"required,min=8"`
 }
 type GetUsersRequest struct {
-    Page  int `json:"-" query:"page" validate:"omitempty,min=1"`
-    Limit int `json:"-" query:"limit" validate:"omitempty,min=1,max=100"`
-}
\ No newline at end of file
+    Page  int `json:"-" query:"page" default:"1" validate:"omitempty,min=1"`
+    Limit int `json:"-" query:"limit" default:"10" validate:"omitempty,min=1,max=100"`
+}
+// a null or empty username removes the user's handle
+type ChangeUsernameRequest struct {
+	Username *string `json:"username" validate:"omitempty,max=31"`
+}
@@ -1,21 +1,40 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	profileServices "github.com/codetheuri/todolist/internal/app/profile/services"
+)
 
 type AuthResponse struct {
 	UserID    uint   `json:"user_id"`
 	Email     string `json:"email"`
+	Username  string `json:"username,omitempty"`
 	Role      string `json:"role"`
 	Token     string `json:"token"` 
 	// CreatedAt string `json:"created_at"`     
 	ExpiresAt int64  `json:"expires_at"`
+	// set on login; left out if the profile could not be loaded
+	Profile *profileServices.ProfileResponse `json:"profile,omitempty"`
 }
 type GetUserProfileResponse struct {
     UserID uint   `json:"user_id"`
     Email  string `json:"email"`
+    Username string `json:"username,omitempty"`
 	CreatedAt *time.Time `json:"created_at"`
     // Role   string `json:"role"`
 }
 type SuccessResponse struct {
     Message string `json:"message"`
-}
\ No newline at end of file
+}
+type UsernameResponse struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username,omitempty"`
+}
+
+// Reason says why an unavailable username cannot be used
+type UsernameAvailabilityResponse struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
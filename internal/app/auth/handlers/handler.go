@@ -11,11 +11,11 @@ import (
 
 	"github.com/codetheuri/todolist/internal/app/auth/handlers/dto"
 	"github.com/codetheuri/todolist/internal/app/auth/services"
+	profileServices "github.com/codetheuri/todolist/internal/app/profile/services"
 	tokenPkg "github.com/codetheuri/todolist/pkg/auth/token"
 	appErrors "github.com/codetheuri/todolist/pkg/errors"
 	"github.com/codetheuri/todolist/pkg/logger"
 	"github.com/codetheuri/todolist/pkg/pagination"
-	"github.com/codetheuri/todolist/pkg/tonic"
 	"github.com/codetheuri/todolist/pkg/web"
 	"github.com/go-chi/chi"
 	"golang.org/x/crypto/bcrypt"
@@ -27,25 +27,29 @@ import (
 
 type AuthHandler interface {
 	// Register(w http.ResponseWriter, r *http.Request)
-	Register(ctx context.Context, req *dto.RegisterRequest) (*tonic.Response, error)
-	Login(w http.ResponseWriter, r *http.Request)
+	Register(ctx context.Context, req *dto.RegisterRequest) (*dto.AuthResponse, error)
+	Login(ctx context.Context, req *dto.LoginRequest) (*dto.AuthResponse, error)
 	GetUserProfile(w http.ResponseWriter, r *http.Request)
-	GetUsers(w http.ResponseWriter, r *http.Request)
+	GetUsers(ctx context.Context, req *dto.GetUsersRequest) (*pagination.PaginationResponse, error)
 	ChangePassword(w http.ResponseWriter, r *http.Request)
 	DeleteUser(w http.ResponseWriter, r *http.Request)
 	RestoreUser(w http.ResponseWriter, r *http.Request)
 	Logout(w http.ResponseWriter, r *http.Request)
+	ChangeUsername(ctx context.Context, req *dto.ChangeUsernameRequest) (*dto.UsernameResponse, error)
+	UsernameAvailability(w http.ResponseWriter, r *http.Request)
 }
 type AuthHandlers struct {
 	authServices *services.AuthService
+	profiles     profileServices.ProfileService
 	log          logger.Logger
 	validator    *validators.Validator
 }
 
 // constructor for AuthHandler
-func NewAuthHandler(authServices *services.AuthService, log logger.Logger, validator *validators.Validator) *AuthHandlers {
+func NewAuthHandler(authServices *services.AuthService, profiles profileServices.ProfileService, log logger.Logger, validator *validators.Validator) *AuthHandlers {
 	return &AuthHandlers{
 		authServices: authServices,
+		profiles:     profiles,
 		log:          log,
 		validator:    validator,
 	}
@@ -96,11 +100,11 @@ func NewAuthHandler(authServices *services.AuthService, log logger.Logger, valid
 // 	web.RespondData(w, http.StatusCreated, resp, "User registered successfully", web.WithSuccessType("toast"))
 
 // }
-func (h *AuthHandlers) Register(ctx context.Context, req *dto.RegisterRequest) (*tonic.Response, error) {
+func (h *AuthHandlers) Register(ctx context.Context, req *dto.RegisterRequest) (*dto.AuthResponse, error) {
 	h.log.Info("Handler: Processing registration request")
 
-	// 1. Service Logic (Validation already done by Adapter)
-	user, err := h.authServices.UserService.RegisterUser(ctx, req.Email, req.Password, req.Role)
+	// 1. Service Logic (Validation already done by tonic.Handle)
+	user, err := h.authServices.UserService.RegisterUser(ctx, req.Email, req.Password, req.Role, req.Username)
 	if err != nil {
 		return nil, err // Propagate service error
 	}
@@ -112,96 +116,66 @@ func (h *AuthHandlers) Register(ctx context.Context, req *dto.RegisterRequest) (
 	}
 
 	// 3. Map to DTO
-	resp := dto.AuthResponse{
+	resp := &dto.AuthResponse{
 		UserID: user.ID,
 		Email:  user.Email,
+		Username: user.GetUsername(),
 		Role:   user.Role,
 		Token:  tokenString,
 		ExpiresAt: h.authServices.TokenService.GetTokenTTL().Unix(),
 	}
 
 	h.log.Info("Handler: User registered and token generated", "userID", user.ID)
-	// 4. Return Data with explicit 201 status
-	return tonic.NewCreatedResponse(resp), nil
+	// 4. Return Data; the route responds with 201
+	return resp, nil
 }
 
-func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
+// Login answers an unknown email and a wrong password alike
+func (h *AuthHandlers) Login(ctx context.Context, req *dto.LoginRequest) (*dto.AuthResponse, error) {
 	h.log.Info("Handler: Received login request")
 
-	var req dto.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Handler: Failed to decode login request", err)
-		web.RespondError(w, appErrors.ValidationError("invalid request payload", err, nil), http.StatusBadRequest)
-		return
-	}
-
-	validationErrors := h.validator.Struct(req)
-	if validationErrors != nil {
-		h.log.Warn("Handler: Validation failed for login request", "errors", validationErrors)
-		web.RespondError(w, appErrors.ValidationError("validation failed", nil, validationErrors), http.StatusBadRequest)
-		return
-	}
-
-	ctx := r.Context()
-
 	// 1. Get user by email
 	user, err := h.authServices.UserService.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		h.log.Error("Handler: Failed to get user by email during login", err, "email", req.Email)
-
 		var authErr appErrors.AppError
-		if errors.As(err, &authErr) && authErr.Code() == "AUTH_ERROR" {
-			web.RespondError(w, authErr, http.StatusUnauthorized,
-				web.WithAlertifyType("toast"),
-				web.WithAlertifyTheme("danger"),
-				web.WithAlertifyMessage("Invalid email or password"),
-			)
-		} else {
-			h.handleAppError(w, err, "user login")
+		if errors.As(err, &authErr) && (authErr.Code() == appErrors.CodeAuth || authErr.Code() == appErrors.CodeNotFound) {
+			return nil, appErrors.AuthError("Invalid email or password", err)
 		}
-		return
+		return nil, err
 	}
 
 	// 2. Compare passwords
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		h.log.Warn("Handler: Invalid password attempt for user", "email", req.Email, "error", err)
-		// web.RespondError(w, appErrors.AuthError("invalid credentials", nil), http.StatusUnauthorized)
-		web.RespondError(w, appErrors.AuthError("Invalid credentials", nil), http.StatusUnauthorized,
-			web.WithAlertifyType("toast"),
-			web.WithAlertifyTheme("danger"),
-			web.WithAlertifyMessage("Invalid email or password"),
-		)
-
-		return
+		return nil, appErrors.AuthError("Invalid email or password", nil)
 	}
 
 	// 3. Generate Auth Token
 	tokenString, err := h.authServices.TokenService.GenerateToken(fmt.Sprintf("%d", user.ID), user.Role)
 	if err != nil {
 		h.log.Error("Handler: Failed to generate auth token after successful login", err, "userID", user.ID)
-		web.RespondError(w, appErrors.InternalServerError("failed to generate authentication token", err), http.StatusInternalServerError)
-		return
+		return nil, appErrors.InternalServerError("failed to generate authentication token", err)
 	}
 
-	resp := dto.AuthResponse{
+	resp := &dto.AuthResponse{
 		UserID:    user.ID,
 		Email:     user.Email,
+		Username:  user.GetUsername(),
 		Role:      user.Role,
 		Token:     tokenString,
 		ExpiresAt: h.authServices.TokenService.GetTokenTTL().Unix(), // Access token TTL from TokenService
 	}
+	// the login still succeeds without the profile
+	if profile, err := h.profiles.GetProfile(ctx, user.ID); err != nil {
+		h.log.Warn("Handler: Failed to load profile for login response", "userID", user.ID, "error", err)
+	} else {
+		resp.Profile = profile
+	}
 
 	h.log.Info("Handler: User logged in successfully", "userID", user.ID)
-	// extraSlice := map[string]string{"message":"access granted", "theme":"primary", "type":"toast"}
-
-	web.RespondData(w, http.StatusOK, resp, "access granted",
-		// web.WithMetadata(extraSlice),
-		web.WithSuccessType("toast"),
-
-		// web.WithSuccessMessage("Login successful"),
-		// web.WithSuccessTheme("primary"),
-	)
-
+	// the route shows the "access granted" toast
+	return resp, nil
 }
 
 func (h *AuthHandlers) GetUserProfile(w http.ResponseWriter, r *http.Request) {
@@ -239,6 +213,7 @@ func (h *AuthHandlers) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	resp := dto.GetUserProfileResponse{
 		UserID:    user.ID,
 		Email:     user.Email,
+		Username:  user.GetUsername(),
 		CreatedAt: &user.CreatedAt, // Ensure CreatedAt is included
 
 		// Role:   user.Role,
@@ -273,7 +248,7 @@ func (h *AuthHandlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	validationErrors := h.validator.Struct(req)
+	validationErrors := h.validator.StructCtx(r.Context(), req)
 	if validationErrors != nil {
 		h.log.Warn("Handler: Validation failed for change password request", "errors", validationErrors)
 		web.RespondError(w, appErrors.ValidationError("validation failed", nil, validationErrors), http.StatusBadRequest)
@@ -314,11 +289,12 @@ func (h *AuthHandlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.log.Error("Handler: Failed to delete user through service", err, "userID", userID)
 		h.handleAppError(w, err, "delete user")
+		return
 	}
 
 	h.log.Info("Handler: User soft-deleted successfully", "userID", userID)
-	web.RespondMessage(w, http.StatusNoContent, "User soft-deleted successfully", "success", "toast")
-	// web.RespondJSON(w, http.StatusNoContent, dto.SuccessResponse{Message: "user deleted successfully"}) // 204 No Content for successful deletion
+	// a 204 would drop the message; answered like RestoreUser
+	web.RespondMessage(w, http.StatusOK, "User soft-deleted successfully", "success", "toast")
 
 }
 
@@ -374,65 +350,73 @@ func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
 	web.RespondMessage(w, http.StatusOK, "Logged out successfully", "success", "toast")
 
 }
-func (h *AuthHandlers) GetUsers(w http.ResponseWriter, r *http.Request) {
-	h.log.Debug("Handler: Received Get users request")
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-	page, err := strconv.Atoi(pageStr)
+
+// set or clear the current user's username
+func (h *AuthHandlers) ChangeUsername(ctx context.Context, req *dto.ChangeUsernameRequest) (*dto.UsernameResponse, error) {
+	h.log.Info("Handler: Processing change username request")
+	userID, ok := tokenPkg.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, appErrors.AuthError("authentication context missing", nil)
+	}
+	username := ""
+	if req.Username != nil {
+		username = *req.Username
+	}
+	user, err := h.authServices.UserService.ChangeUsername(ctx, userID, username)
 	if err != nil {
-		page = pagination.DefaultPage
+		return nil, err
 	}
-	limit, err := strconv.Atoi(limitStr)
+	h.log.Info("Handler: Username changed", "userID", userID)
+	return &dto.UsernameResponse{
+		UserID:   user.ID,
+		Username: user.GetUsername(),
+	}, nil
+}
+
+// whether ?username= is well formed and free
+func (h *AuthHandlers) UsernameAvailability(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug("Handler: Received UsernameAvailability request")
+	username, reason, err := h.authServices.UserService.CheckUsername(r.Context(), r.URL.Query().Get("username"))
 	if err != nil {
-		limit = pagination.DefaultLimit
+		h.handleAppError(w, err, "check username")
+		return
 	}
-	ctx := r.Context()
+	web.RespondData(w, http.StatusOK, dto.UsernameAvailabilityResponse{
+		Username:  username,
+		Available: reason == "",
+		Reason:    reason,
+	}, "", web.WithoutSuccess())
+}
 
-	pParams := pagination.NewPaginationParams(page, limit)
+func (h *AuthHandlers) GetUsers(ctx context.Context, req *dto.GetUsersRequest) (*pagination.PaginationResponse, error) {
+	h.log.Debug("Handler: Received Get users request")
+	pParams := pagination.NewPaginationParams(req.Page, req.Limit)
 	users, totalCount, err := h.authServices.UserService.GetUsers(ctx, pParams.Offset(), pParams.Limit)
 	if err != nil {
 		h.log.Error("Handler: Service call failed for GetUsers", err)
-		h.handleAppError(w, err, "get users")
-		return
+		return nil, err
 	}
 	userResponses := make([]dto.GetUserProfileResponse, len(users))
 	for i, user := range users {
 		userResponses[i] = dto.GetUserProfileResponse{
-			UserID: user.ID,
-			Email:  user.Email,
+			UserID:   user.ID,
+			Email:    user.Email,
+			Username: user.GetUsername(),
 			// Role:      user.Role,
 			CreatedAt: &user.CreatedAt,
 		}
 	}
-	metadata := pagination.NewPaginationmetadata(pParams.Page, pParams.Limit, totalCount)
-
-	web.RespondListData(w, http.StatusOK, userResponses, metadata)
+	return &pagination.PaginationResponse{
+		Data:     userResponses,
+		Metadata: pagination.NewPaginationmetadata(pParams.Page, pParams.Limit, totalCount),
+	}, nil
 }
 func (h *AuthHandlers) handleAppError(w http.ResponseWriter, err error, action string) {
 	var appErr appErrors.AppError
 	if errors.As(err, &appErr) {
 		h.log.Error(fmt.Sprintf("Handler: Application error during %s", action), err, "code", appErr.Code())
-		switch appErr.Code() {
-		case "AUTH_ERROR":
-			web.RespondError(w, appErr, http.StatusUnauthorized)
-		case "NOT_FOUND":
-			web.RespondError(w, appErr, http.StatusNotFound)
-		case "VALIDATION_ERROR": // Keep this, it handles generic validation failures
-			web.RespondError(w, appErr, http.StatusBadRequest) // Often 400 or 422 for validation
-		case "DATABASE_ERROR":
-			web.RespondError(w, appErr, http.StatusInternalServerError)
-		case "INTERNAL_SERVER_ERROR":
-			web.RespondError(w, appErr, http.StatusInternalServerError)
-		case "CONFLICT_ERROR": // <--- ADD THIS CASE
-			web.RespondError(w, appErr, http.StatusConflict) // HTTP 409 Conflict
-		case "FORBIDDEN": // Ensure this is also handled
-			web.RespondError(w, appErr, http.StatusForbidden)
-		case "UNAUTHORIZED": // Differentiate from AUTH_ERROR if needed, though often similar
-			web.RespondError(w, appErr, http.StatusUnauthorized)
-		default:
-			web.RespondError(w, appErrors.InternalServerError(
-				fmt.Sprintf("an unexpected application error occurred with code %s", appErr.Code()), appErr), http.StatusInternalServerError)
-		}
+		// the status comes from the code's registration in pkg/errors
+		web.RespondError(w, appErr, http.StatusInternalServerError)
 	} else {
 		h.log.Error(fmt.Sprintf("Handler: Unknown error during %s", action), err)
 		web.RespondError(w, appErrors.InternalServerError("an unknown error occurred", err), http.StatusInternalServerError)
@@ -2,6 +2,9 @@ package models
 
 import "gorm.io/gorm"
 
+// User is the users table as the create_auth_tables migration made it. It is
+// kept for that migration only; accounts are identity.User, and later changes
+// to the table belong in their own migrations.
 type User struct {
 	gorm.Model
 	Email    string `gorm:"unique;not null" json:"email" validate:"required,email"`
@@ -1,17 +1,18 @@
 package auth
 
 import (
-	"context"
+	"net/http"
 
 	"github.com/codetheuri/todolist/config"
 	authHandlers "github.com/codetheuri/todolist/internal/app/auth/handlers"
-	"github.com/codetheuri/todolist/internal/app/auth/handlers/dto"
 	authRepositories "github.com/codetheuri/todolist/internal/app/auth/repositories"
 	authServices "github.com/codetheuri/todolist/internal/app/auth/services"
+	profileServices "github.com/codetheuri/todolist/internal/app/profile/services"
 	router "github.com/codetheuri/todolist/internal/app/routers"
 	tokenPkg "github.com/codetheuri/todolist/pkg/auth/token"
-	appErrors "github.com/codetheuri/todolist/pkg/errors"
 	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/middleware"
+	"github.com/codetheuri/todolist/pkg/openapi"
 	"github.com/codetheuri/todolist/pkg/tonic"
 	"github.com/codetheuri/todolist/pkg/validators"
 	"gorm.io/gorm"
@@ -38,8 +39,8 @@ type Module struct {
 	validator    *validators.Validator
 }
 
-// NewModule initializes  Auth module.
-func NewModule(db *gorm.DB, log logger.Logger, validator *validators.Validator, cfg *config.Config) *Module {
+// NewModule initializes  Auth module. profiles supplies the profile returned on login.
+func NewModule(db *gorm.DB, log logger.Logger, validator *validators.Validator, cfg *config.Config, profiles profileServices.ProfileService) *Module {
 	repos := authRepositories.NewAuthRepository(db, log)
 
 	jwtSecret := cfg.JWTSecret
@@ -47,7 +48,7 @@ func NewModule(db *gorm.DB, log logger.Logger, validator *validators.Validator,
 
 	TokenService := authServices.NewJWTService(repos.RevokedTokenRepo, jwtSecret, tokenTTL, log)
 	services := authServices.NewAuthService(repos, validator, jwtSecret, tokenTTL, log)
-	handler := authHandlers.NewAuthHandler(services, log, validator)
+	handler := authHandlers.NewAuthHandler(services, profiles, log, validator)
 
 	return &Module{
 		Handler:      handler,
@@ -57,24 +58,7 @@ func NewModule(db *gorm.DB, log logger.Logger, validator *validators.Validator,
 	}
 }
 
-type localHandlerAdapterFunc func(ctx context.Context, input interface{}) (interface{}, error)
-
-func createTonicAdapterBridge[T any](
-	handlerMethod func(ctx context.Context, req *T) (*tonic.Response, error),
-) tonic.HandlerFunc {
-	// Returns an explicitly typed tonic.HandlerFunc
-	return func(ctx context.Context, input interface{}) (*tonic.Response, error) {
-		// 1. Assert the input type safely back to the specific DTO pointer
-		req, ok := input.(*T)
-		if !ok {
-			// Safety fallback: This should not happen if Adapter is called correctly
-			return nil, appErrors.InternalServerError("internal type assertion failure", nil)
-		}
-
-		// 2. Call the original, strongly-typed handler method
-		return handlerMethod(ctx, req)
-	}
-}
+func (m *Module) Name() string { return "auth" }
 
 // RegisterRoutes registers the routes for the Auth module.
 func (m *Module) RegisterRoutes(r router.Router) {
@@ -82,12 +66,13 @@ func (m *Module) RegisterRoutes(r router.Router) {
 	v := m.validator
 	h := m.Handler
 	r.Group(func(r router.Router) {
-		registerHandlerFunc := createTonicAdapterBridge(h.Register)
-		r.Post("/auth/register", tonic.Adapter(registerHandlerFunc, dto.RegisterRequest{}, v))
-		// registerHandler := buildAdapterFunction[*dto.RegisterRequest](h, h.Register)
-		// r.Post("/auth/register", tonic.Adapter(registerHandler, dto.RegisterRequest{}, v))
-		// r.Post("/auth/register", tonic.Adapter(registerAdapterFunc, dto.RegisterRequest{}, v))
-		// r.Post("/auth/login", m.Handler.Login)
+		r.Method(http.MethodPost, "/auth/register", tonic.Handle(h.Register, tonic.WithValidator(v), tonic.WithStatus(http.StatusCreated)))
+		r.Method(http.MethodPost, "/auth/login", tonic.Handle(h.Login, tonic.WithValidator(v), tonic.WithToast("access granted")))
+		r.Get("/auth/username-availability", m.Handler.UsernameAvailability)
+	})
+	r.Group(func(r router.Router) {
+		r.Use(middleware.Authenticator(m.TokenService, m.log))
+		r.Method(http.MethodPut, "/auth/username", tonic.Handle(h.ChangeUsername, tonic.WithValidator(v), tonic.WithSecurity(openapi.BearerAuth)))
 	})
 
 	// Authenticated routes (will need middleware later)
@@ -99,7 +84,7 @@ func (m *Module) RegisterRoutes(r router.Router) {
 	// 	r.Delete("/auth/users/{id}", m.Handler.DeleteUser)
 	// 	r.Put("/auth/users/{id}/restore", m.Handler.RestoreUser)
 	// 	r.Post("/auth/logout", m.Handler.Logout)
-	// 	r.Get("/auth/users", m.Handler.GetUsers)
+	// 	r.Method(http.MethodGet, "/auth/users", tonic.Handle(h.GetUsers, tonic.WithValidator(v), tonic.WithSecurity(openapi.BearerAuth), tonic.ListOf[dto.GetUserProfileResponse]()))
 	// })
 
 	m.log.Info("Auth module routes registered.")
@@ -2,19 +2,22 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 
-	"github.com/codetheuri/todolist/internal/app/auth/models"
+	"github.com/codetheuri/todolist/internal/platform/identity"
+	dberrors "github.com/codetheuri/todolist/pkg/dberrors"
 	"github.com/codetheuri/todolist/pkg/logger"
 	"gorm.io/gorm"
 )
 
 // user interface
 type UserRepository interface {
-	CreateUser(ctx context.Context, user *models.User) error
-	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
-	GetUserByID(ctx context.Context, id uint) (*models.User, error)
-	GetUsers(ctx context.Context, offset, limit int) ([]*models.User, int64, error)
-	UpdateUser(ctx context.Context, user *models.User) error
+	CreateUser(ctx context.Context, user *identity.User) error
+	GetUserByEmail(ctx context.Context, email string) (*identity.User, error)
+	GetUserByID(ctx context.Context, id uint) (*identity.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*identity.User, error)
+	GetUsers(ctx context.Context, offset, limit int) ([]*identity.User, int64, error)
+	UpdateUser(ctx context.Context, user *identity.User) error
 	DeleteUser(ctx context.Context, id uint) error
 	RestoreUser(ctx context.Context, id uint) error
 }
@@ -31,14 +34,15 @@ func NewUserRepository(db *gorm.DB, log logger.Logger) UserRepository {
 	}
 }
 
-func (r *userRepository) CreateUser(ctx context.Context, user *models.User) error {
+// an email or username taken since it was checked is gorm.ErrDuplicatedKey
+func (r *userRepository) CreateUser(ctx context.Context, user *identity.User) error {
 	r.log.Info("Repository: Creating user in DB", "email", user.Email)
-	return r.db.WithContext(ctx).Create(user).Error
+	return r.duplicated(r.db.WithContext(ctx).Create(user).Error)
 }
 
-func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*identity.User, error) {
 	r.log.Info("GetUserByEmail repository")
-	var user models.User
+	var user identity.User
 	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 		r.log.Error("Failed to get user by email", err)
 		return nil, err
@@ -47,28 +51,44 @@ func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 
 }
 
-func (r *userRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+func (r *userRepository) GetUserByID(ctx context.Context, id uint) (*identity.User, error) {
 	r.log.Info("GetUserByID repository")
-	var user models.User
+	var user identity.User
 	err := r.db.WithContext(ctx).First(&user, id).Error
 	return &user, err
 }
-func (r *userRepository) UpdateUser(ctx context.Context, user *models.User) error {
+// deleted users keep their username, so they are found too
+func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (*identity.User, error) {
+	r.log.Info("GetUserByUsername repository")
+	var user identity.User
+	err := r.db.WithContext(ctx).Unscoped().Where("username = ?", username).First(&user).Error
+	return &user, err
+}
+// a username taken since it was checked is gorm.ErrDuplicatedKey
+func (r *userRepository) UpdateUser(ctx context.Context, user *identity.User) error {
 	r.log.Info("UpdateUser repository")
-	return r.db.WithContext(ctx).Save(user).Error
+	return r.duplicated(r.db.WithContext(ctx).Save(user).Error)
+}
+
+// err, marked as gorm.ErrDuplicatedKey when it broke a unique constraint
+func (r *userRepository) duplicated(err error) error {
+	if dberrors.IsUniqueViolation(r.db, err) {
+		return fmt.Errorf("%w: %w", gorm.ErrDuplicatedKey, err)
+	}
+	return err
 }
 func (r *userRepository) DeleteUser(ctx context.Context, id uint) error {
 	r.log.Info("DeleteUser repository")
-	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
+	return r.db.WithContext(ctx).Delete(&identity.User{}, id).Error
 }
 func (r *userRepository) RestoreUser(ctx context.Context, id uint) error {
 	r.log.Info("RestoreUser repository")
-	return r.db.WithContext(ctx).Unscoped().Model(&models.User{}).Where("id = ?", id).Update("deleted_at", nil).Error
+	return r.db.WithContext(ctx).Unscoped().Model(&identity.User{}).Where("id = ?", id).Update("deleted_at", nil).Error
 }
-func (r *userRepository) GetUsers(ctx context.Context, offset, limit int) ([]*models.User, int64, error) {
-	var users []*models.User
+func (r *userRepository) GetUsers(ctx context.Context, offset, limit int) ([]*identity.User, int64, error) {
+	var users []*identity.User
 	var total int64
-	if err := r.db.WithContext(ctx).Model(&models.User{}).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&identity.User{}).Count(&total).Error; err != nil {
 		r.log.Error("Repository: Failed to count todos", err)
 		return nil, 0, err
 	}
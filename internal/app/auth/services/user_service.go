@@ -2,9 +2,10 @@ package services
 
 import (
 	"errors"
+	"strings"
 
-	"github.com/codetheuri/todolist/internal/app/auth/models"
 	"github.com/codetheuri/todolist/internal/app/auth/repositories"
+	"github.com/codetheuri/todolist/internal/platform/identity"
 	appErrors "github.com/codetheuri/todolist/pkg/errors"
 	"github.com/codetheuri/todolist/pkg/logger"
 	"github.com/codetheuri/todolist/pkg/validators"
@@ -14,14 +15,19 @@ import (
 )
 
 type UserService interface {
-	RegisterUser(ctx context.Context, email, password, role string) (*models.User, error)
-	GetUserByID(ctx context.Context, id uint) (*models.User, error)
-	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
-	GetUsers(ctx context.Context, offset, limit int) ([]*models.User, int64, error)
-	UpdateUser(ctx context.Context, user *models.User) error
+	// username is optional; pass "" for none
+	RegisterUser(ctx context.Context, email, password, role, username string) (*identity.User, error)
+	GetUserByID(ctx context.Context, id uint) (*identity.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*identity.User, error)
+	GetUsers(ctx context.Context, offset, limit int) ([]*identity.User, int64, error)
+	UpdateUser(ctx context.Context, user *identity.User) error
 	ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword string) error
 	DeleteUser(ctx context.Context, id uint) error
 	RestoreUser(ctx context.Context, id uint) error
+	// set the user's username, or remove it when username is ""
+	ChangeUsername(ctx context.Context, userID uint, username string) (*identity.User, error)
+	// the normalized username and, when it cannot be taken, why not
+	CheckUsername(ctx context.Context, username string) (string, string, error)
 }
 
 type userService struct {
@@ -38,30 +44,27 @@ func NewUserService(userRepo repositories.UserRepository, validator *validators.
 	}
 }
 
-func (s *userService) RegisterUser(ctx context.Context, email, password, role string) (*models.User, error) {
+func (s *userService) RegisterUser(ctx context.Context, email, password, role, username string) (*identity.User, error) {
 	s.log.Info("Registering new user", "email", email)
 
-	newUser := models.User{
+	newUser := identity.User{
 		Email:    email,
 		Password: password,
 		Role:     role,
 	}
+	if username != "" {
+		normalized, err := s.availableUsername(ctx, 0, username)
+		if err != nil {
+			return nil, err
+		}
+		newUser.Username = &normalized
+	}
 	// validationErros := s.validator.Struct(newUser)
 	// if validationErros != nil {
 	// 	s.log.Warn("Validation failed for user registration", "err", validationErros)
 	// 	return nil, appErrors.ValidationError("validation failed for user registration", nil, validationErros)
 	// }
-	existingUser, err := s.userRepo.GetUserByEmail(ctx, email)
-
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		s.log.Error("Failed to check for existing user", err, "email", email)
-		return nil, appErrors.DatabaseError("failed to check for existing user", err)
-	}
-	if existingUser != nil {
-		s.log.Warn("User with this email already exists", "email", email)
-		// return nil, appErrors.AuthError("User with this email already exists", nil)
-		return nil, appErrors.ConflictError("user with this email already exists", nil)
-	}
+	// the unique rule of dto.RegisterRequest has checked that the email is free
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -70,6 +73,16 @@ func (s *userService) RegisterUser(ctx context.Context, email, password, role st
 	}
 	newUser.Password = string(hashedPassword)
 	if err := s.userRepo.CreateUser(ctx, &newUser); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// another registration took the email or username after they were checked
+			s.log.Warn("User registration conflicted with another", "email", email)
+			if newUser.Username != nil {
+				if _, err := s.availableUsername(ctx, 0, *newUser.Username); err != nil {
+					return nil, err
+				}
+			}
+			return nil, appErrors.ConflictError("email is already registered", err)
+		}
 		s.log.Error("Failed to create user in database", err, "email", email)
 		return nil, appErrors.DatabaseError("failed to create user in database", err)
 	}
@@ -77,7 +90,7 @@ func (s *userService) RegisterUser(ctx context.Context, email, password, role st
 	return &newUser, nil
 }
 
-func (s *userService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+func (s *userService) GetUserByID(ctx context.Context, id uint) (*identity.User, error) {
 	s.log.Debug("Getting user by ID in service", "id", id)
 	user, err := s.userRepo.GetUserByID(ctx, id)
 	if err != nil {
@@ -90,7 +103,7 @@ func (s *userService) GetUserByID(ctx context.Context, id uint) (*models.User, e
 	}
 	return user, nil
 }
-func (s *userService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) { // <--- Added error return
+func (s *userService) GetUserByEmail(ctx context.Context, email string) (*identity.User, error) { // <--- Added error return
 	s.log.Debug("Getting user by email in service", "email", email)
 	user, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
@@ -104,10 +117,10 @@ func (s *userService) GetUserByEmail(ctx context.Context, email string) (*models
 	return user, nil
 }
 
-func (s *userService) UpdateUser(ctx context.Context, user *models.User) error {
+func (s *userService) UpdateUser(ctx context.Context, user *identity.User) error {
 	s.log.Info("Updating user in service", "id", user.ID, "email", user.Email)
 	//  Add validation for the user struct before updating
-	validationErrors := s.validator.Struct(user)
+	validationErrors := s.validator.StructCtx(ctx, user)
 	if validationErrors != nil {
 		s.log.Warn("Validation failed for user update", "err", validationErrors)
 		return appErrors.ValidationError("validation failed for user update", nil, validationErrors)
@@ -196,7 +209,7 @@ func (s *userService) RestoreUser(ctx context.Context, id uint) error {
 
 	return nil
 }
-func (s *userService) GetUsers(ctx context.Context, offset, limit int) ([]*models.User, int64, error) {
+func (s *userService) GetUsers(ctx context.Context, offset, limit int) ([]*identity.User, int64, error) {
 	s.log.Info("Service: Getting users with pagination params", "offset", offset, "limit", limit)
 	users, totalCount, err := s.userRepo.GetUsers(ctx, offset, limit)
 	if err != nil {
@@ -206,3 +219,58 @@ func (s *userService) GetUsers(ctx context.Context, offset, limit int) ([]*model
 	s.log.Info("Service: Successfully retrieved paginated users (models)", "count", len(users), "total", totalCount)
 	return users, totalCount, nil
 }
+
+func (s *userService) ChangeUsername(ctx context.Context, userID uint, username string) (*identity.User, error) {
+	s.log.Info("Changing username", "userID", userID)
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Username = nil
+	if strings.TrimSpace(username) != "" {
+		normalized, err := s.availableUsername(ctx, userID, username)
+		if err != nil {
+			return nil, err
+		}
+		user.Username = &normalized
+	}
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, appErrors.ConflictError("username is already taken", err)
+		}
+		s.log.Error("Failed to update username in database", err, "userID", userID)
+		return nil, appErrors.DatabaseError("failed to update username", err)
+	}
+	return user, nil
+}
+
+func (s *userService) CheckUsername(ctx context.Context, username string) (string, string, error) {
+	normalized, err := s.availableUsername(ctx, 0, username)
+	if err == nil {
+		return normalized, "", nil
+	}
+	var appErr appErrors.AppError
+	if errors.As(err, &appErr) && (appErr.Code() == appErrors.CodeValidation || appErr.Code() == appErrors.CodeConflict) {
+		return normalized, appErr.Message(), nil
+	}
+	return normalized, "", err
+}
+
+// the normalized username if it is well formed and not held by anyone other
+// than userID
+func (s *userService) availableUsername(ctx context.Context, userID uint, username string) (string, error) {
+	normalized := identity.NormalizeUsername(username)
+	if !identity.ValidUsername(normalized) {
+		return normalized, appErrors.ValidationError("usernames are 3 to 30 letters, digits or underscores and start with a letter", nil,
+			map[string]string{"username": "Invalid username"})
+	}
+	existing, err := s.userRepo.GetUserByUsername(ctx, normalized)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.log.Error("Failed to check for existing username", err, "username", normalized)
+		return normalized, appErrors.DatabaseError("failed to check for existing username", err)
+	}
+	if err == nil && existing.ID != userID {
+		return normalized, appErrors.ConflictError("username is already taken", nil)
+	}
+	return normalized, nil
+}
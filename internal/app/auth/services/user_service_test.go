@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/codetheuri/todolist/internal/app/auth/repositories"
+	"github.com/codetheuri/todolist/internal/platform/identity"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/validators"
+	"golang.org/x/net/context"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// a user service over a private in-memory SQLite database
+func newTestUserService(t *testing.T) (UserService, *gorm.DB) {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: gormLogger.Default.LogMode(gormLogger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&identity.User{}); err != nil {
+		t.Fatal(err)
+	}
+	log := logger.NewConsoleLogger()
+	return NewUserService(repositories.NewUserRepository(db, log), validators.NewValidator(), log), db
+}
+
+func wantCode(t *testing.T, err error, code appErrors.Code) {
+	t.Helper()
+	var appErr appErrors.AppError
+	if !errors.As(err, &appErr) || appErr.Code() != code {
+		t.Fatalf("error %v, want %s", err, code)
+	}
+}
+
+// the unique rule of the register request checks the email before RegisterUser runs; a
+// registration that takes it in between is a conflict, not a database failure
+func TestRegisterUserLosingTheRaceIsAConflict(t *testing.T) {
+	s, db := newTestUserService(t)
+	ctx := context.Background()
+	if _, err := s.RegisterUser(ctx, "ada@example.com", "password1", "user", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.RegisterUser(ctx, "ada@example.com", "password2", "user", "")
+	wantCode(t, err, appErrors.CodeConflict)
+
+	var count int64
+	db.Model(&identity.User{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("%d users, want 1", count)
+	}
+}
+
+func TestChangeUsernameToATakenOneIsAConflict(t *testing.T) {
+	s, _ := newTestUserService(t)
+	ctx := context.Background()
+	ada, err := s.RegisterUser(ctx, "ada@example.com", "password1", "user", "ada")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := s.RegisterUser(ctx, "bob@example.com", "password2", "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ChangeUsername(ctx, bob.ID, "ADA")
+	wantCode(t, err, appErrors.CodeConflict)
+	if _, err := s.ChangeUsername(ctx, ada.ID, "ada"); err != nil {
+		t.Fatalf("keeping one's own username: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	router "github.com/codetheuri/todolist/internal/app/routers"
 	"github.com/codetheuri/todolist/pkg/docs"
 	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/openapi"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
@@ -12,31 +13,26 @@ type Module struct {
 	log        logger.Logger
 }
 
-func NewModule(log logger.Logger, handlerMap map[string]interface{}) *Module {
-	docService := docs.NewDocService(log, handlerMap)
+// NewModule serves the OpenAPI documents of the routes collected in registry
+func NewModule(log logger.Logger, registry *openapi.Registry) *Module {
+	docService := docs.NewDocService(log, registry)
 	return &Module{
 		DocService: docService,
 		log:        log,
 	}
 }
 
+func (m *Module) Name() string { return "docs" }
+
 func (m *Module) RegisterRoutes(r router.Router) {
 	m.log.Info("Mounting Docs API and Swagger UI...")
+	r.Get("/docs/doc.json", m.DocService.ServeAPIDocJSON)
 	r.Get("/docs/{module}/doc.json", m.DocService.ServeDocJSON)
-	r.Group(func(r router.Router) {
-		// Set a default doc URL (e.g., Auth module) for initial load.
-		r.Mount("/docs", httpSwagger.WrapHandler(
-			httpSwagger.URL("/docs/auth/doc.json"),
-			httpSwagger.InstanceName("docs"), // Unique instance name
-		))
-	})
-	swaggerHandler := httpSwagger.Handler(
-		httpSwagger.URL("/swagger/doc.json"), // URL pointing to API definition
-	)
-
-	// Mount the swagger handler
-	r.Handle("/swagger/*", swaggerHandler)
+	// the UI loads the whole API; a module's document can be opened from its explore bar
+	r.Get("/docs/*", httpSwagger.Handler(
+		httpSwagger.URL("doc.json"),
+		httpSwagger.InstanceName("docs"),
+	))
 
 	m.log.Info("Docs module registered successfully.")
-
 }
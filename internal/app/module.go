@@ -5,5 +5,7 @@ import "github.com/codetheuri/todolist/internal/app/routers"
 
 
 type Module interface {
+	// Name identifies the module, and its routes in the API documentation
+	Name() string
 	RegisterRoutes(r router.Router)
 }
\ No newline at end of file
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/codetheuri/todolist/config"
+	"github.com/codetheuri/todolist/internal/app/profile/services"
+	tokenPkg "github.com/codetheuri/todolist/pkg/auth/token"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/tonic"
+	"github.com/codetheuri/todolist/pkg/web"
+	"github.com/go-chi/chi/v5"
+)
+
+type ProfileHandler struct {
+	profileService services.ProfileService
+	cfg            *config.Config
+	log            logger.Logger
+}
+
+func NewProfileHandler(svc services.ProfileService, cfg *config.Config, log logger.Logger) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: svc,
+		cfg:            cfg,
+		log:            log,
+	}
+}
+
+// the current user's profile
+func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug("Handler: Received GetProfile request")
+	userID, ok := tokenPkg.GetUserIDFromContext(r.Context())
+	if !ok {
+		web.RespondError(w, appErrors.AuthError("authentication context missing", nil), http.StatusUnauthorized)
+		return
+	}
+	res, err := h.profileService.GetProfile(r.Context(), userID)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetProfile", err, "userID", userID)
+		web.RespondError(w, err, http.StatusInternalServerError)
+		return
+	}
+	web.RespondData(w, http.StatusOK, res, "Profile retrieved successfully", web.WithoutSuccess())
+}
+
+func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug("Handler: Received UpdateProfile request")
+	userID, ok := tokenPkg.GetUserIDFromContext(r.Context())
+	if !ok {
+		web.RespondError(w, appErrors.AuthError("authentication context missing", nil), http.StatusUnauthorized)
+		return
+	}
+	var req services.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("Handler: Failed to decode update profile request body", "error", err)
+		web.RespondError(w, appErrors.InvalidInputError("Invalid request body format", err), http.StatusBadRequest)
+		return
+	}
+	res, err := h.profileService.UpdateProfile(r.Context(), userID, &req)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for UpdateProfile", err, "userID", userID)
+		web.RespondError(w, err, http.StatusInternalServerError)
+		return
+	}
+	web.RespondData(w, http.StatusOK, res, "Profile updated successfully")
+}
+
+// look a user up by their username
+func (h *ProfileHandler) GetPublicProfile(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug("Handler: Received GetPublicProfile request")
+	username := chi.URLParam(r, "username")
+	res, err := h.profileService.GetPublicProfile(r.Context(), username)
+	if err != nil {
+		h.log.Warn("Handler: Service call failed for GetPublicProfile", "username", username, "error", err)
+		web.RespondError(w, err, http.StatusInternalServerError)
+		return
+	}
+	web.RespondData(w, http.StatusOK, res, "User retrieved successfully", web.WithoutSuccess())
+}
+
+// AvatarUpload is the image of a multipart/form-data avatar upload
+type AvatarUpload struct {
+	File *tonic.File `json:"-" form:"file" validate:"required" accept:"image/png,image/jpeg,image/gif"`
+}
+
+// replace the current user's avatar with the image in the multipart "file" field
+func (h *ProfileHandler) UploadAvatar(ctx context.Context, req *AvatarUpload) (*services.ProfileResponse, error) {
+	h.log.Debug("Handler: Received UploadAvatar request")
+	userID, ok := tokenPkg.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, appErrors.AuthError("authentication context missing", nil)
+	}
+	body, err := req.File.Open()
+	if err != nil {
+		return nil, appErrors.InternalServerError("could not read the uploaded file", err)
+	}
+	defer body.Close()
+	res, err := h.profileService.UploadAvatar(ctx, userID, &services.UploadAvatarRequest{
+		Size: req.File.Size,
+		Body: body,
+	})
+	if err != nil {
+		h.log.Error("Handler: Service call failed for UploadAvatar", err, "userID", userID)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (h *ProfileHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug("Handler: Received DeleteAvatar request")
+	userID, ok := tokenPkg.GetUserIDFromContext(r.Context())
+	if !ok {
+		web.RespondError(w, appErrors.AuthError("authentication context missing", nil), http.StatusUnauthorized)
+		return
+	}
+	res, err := h.profileService.DeleteAvatar(r.Context(), userID)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for DeleteAvatar", err, "userID", userID)
+		web.RespondError(w, err, http.StatusInternalServerError)
+		return
+	}
+	web.RespondData(w, http.StatusOK, res, "Avatar removed successfully")
+}
+
+// stream a stored avatar. A file never changes once written, so it may be cached for good.
+func (h *ProfileHandler) ServeAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseUint(chi.URLParam(r, "userID"), 10, 32)
+	if err != nil {
+		web.RespondError(w, appErrors.NotFoundError("avatar not found", err), http.StatusNotFound)
+		return
+	}
+	body, contentType, err := h.profileService.OpenAvatar(r.Context(), uint(userID), chi.URLParam(r, "name"))
+	if err != nil {
+		web.RespondError(w, err, http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil {
+		h.log.Warn("Handler: Failed to stream avatar", "userID", userID, "error", err)
+	}
+}
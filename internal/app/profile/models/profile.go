@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/codetheuri/todolist/internal/platform/identity"
+	"gorm.io/gorm"
+)
+
+// Profile holds what a user shows about themselves and how they want dates and
+// text presented. Users without a row get a default profile until they save one.
+type Profile struct {
+	gorm.Model
+	UserID             uint   `json:"user_id" gorm:"not null;uniqueIndex"`
+	DisplayName        string `json:"display_name" gorm:"not null"`
+	Bio                string `json:"bio"`
+	AvatarURL          string `json:"avatar_url"`
+	AvatarThumbnailURL string `json:"avatar_thumbnail_url"`
+	// storage keys of the resized avatar and its thumbnail
+	AvatarKey          string `json:"-" gorm:"size:255"`
+	AvatarThumbnailKey string `json:"-" gorm:"size:255"`
+	// IANA time zone name, e.g. Africa/Nairobi
+	Timezone string `json:"timezone" gorm:"size:64;not null;default:'UTC'"`
+	// BCP 47 language tag, e.g. en or fr-CA
+	Locale string        `json:"locale" gorm:"size:35;not null;default:'en'"`
+	User   identity.User `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
@@ -0,0 +1,69 @@
+package profile
+
+import (
+	"net/http"
+
+	"github.com/codetheuri/todolist/config"
+	profileHandlers "github.com/codetheuri/todolist/internal/app/profile/handlers"
+	profileRepositories "github.com/codetheuri/todolist/internal/app/profile/repositories"
+	profileServices "github.com/codetheuri/todolist/internal/app/profile/services"
+	router "github.com/codetheuri/todolist/internal/app/routers"
+	"github.com/codetheuri/todolist/internal/platform/identity"
+	tokenPkg "github.com/codetheuri/todolist/pkg/auth/token"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/middleware"
+	"github.com/codetheuri/todolist/pkg/openapi"
+	"github.com/codetheuri/todolist/pkg/storage"
+	"github.com/codetheuri/todolist/pkg/tonic"
+	"github.com/codetheuri/todolist/pkg/validators"
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	Handlers  *profileHandlers.ProfileHandler
+	log       logger.Logger
+	validator *validators.Validator
+	// the upload limit of avatars, from the configuration
+	avatarMaxSize int64
+	// issued by the auth module, which is built after this one; set it before
+	// the routes are registered
+	TokenService tokenPkg.TokenService
+	// shared with the auth module, which returns the profile on login
+	Service profileServices.ProfileService
+}
+
+func NewModule(db *gorm.DB, log logger.Logger, validator *validators.Validator, cfg *config.Config, users identity.UserDirectory, fileStorage storage.Storage) *Module {
+	profileRepo := profileRepositories.NewGormProfileRepository(db, log)
+	profileService := profileServices.NewProfileService(profileRepo, users, validator, cfg, fileStorage, log)
+
+	return &Module{
+		Handlers:      profileHandlers.NewProfileHandler(profileService, cfg, log),
+		log:           log,
+		validator:     validator,
+		avatarMaxSize: cfg.AvatarMaxSize,
+		Service:       profileService,
+	}
+}
+
+func (m *Module) Name() string { return "profile" }
+
+func (m *Module) RegisterRoutes(r router.Router) {
+	r.Route("/me", func(r router.Router) {
+		r.Use(middleware.Authenticator(m.TokenService, m.log))
+		r.Get("/profile", m.Handlers.GetProfile)
+		r.Put("/profile", m.Handlers.UpdateProfile)
+		r.Method(http.MethodPut, "/profile/avatar", tonic.Handle(m.Handlers.UploadAvatar,
+			tonic.WithValidator(m.validator),
+			tonic.WithSecurity(openapi.BearerAuth),
+			tonic.WithFileLimit("file", m.avatarMaxSize),
+			tonic.WithMessage("Avatar updated successfully"),
+		))
+		r.Delete("/profile/avatar", m.Handlers.DeleteAvatar)
+	})
+	r.Route("/users", func(r router.Router) {
+		r.Use(middleware.Authenticator(m.TokenService, m.log))
+		r.Get("/by-username/{username}", m.Handlers.GetPublicProfile)
+	})
+	// avatar links are public so that they work in <img> tags
+	r.Get("/avatars/{userID}/{name}", m.Handlers.ServeAvatar)
+}
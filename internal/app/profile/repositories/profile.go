@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/codetheuri/todolist/internal/app/profile/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProfileRepository interface {
+	// the user's saved profile; a 404 until one is saved
+	GetProfile(ctx context.Context, userID uint) (*models.Profile, error)
+	// create or update the profile; the user it belongs to is never written
+	SaveProfile(ctx context.Context, profile *models.Profile) error
+}
+
+type gormProfileRepository struct {
+	db  *gorm.DB
+	log logger.Logger
+}
+
+func NewGormProfileRepository(db *gorm.DB, log logger.Logger) ProfileRepository {
+	return &gormProfileRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *gormProfileRepository) GetProfile(ctx context.Context, userID uint) (*models.Profile, error) {
+	var profile models.Profile
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.NotFoundError(fmt.Sprintf("profile of user %d not found", userID), err)
+		}
+		r.log.Error("Repository: Failed to get profile", err, "userID", userID)
+		return nil, appErrors.DatabaseError("failed to get profile", err)
+	}
+	return &profile, nil
+}
+
+func (r *gormProfileRepository) SaveProfile(ctx context.Context, profile *models.Profile) error {
+	if err := r.db.WithContext(ctx).Omit(clause.Associations).Save(profile).Error; err != nil {
+		r.log.Error("Repository: Failed to save profile", err, "userID", profile.UserID)
+		return appErrors.DatabaseError("failed to save profile", err)
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/codetheuri/todolist/config"
+	"github.com/codetheuri/todolist/internal/app/profile/models"
+	"github.com/codetheuri/todolist/internal/app/profile/repositories"
+	"github.com/codetheuri/todolist/internal/platform/identity"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/imaging"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/storage"
+	"github.com/codetheuri/todolist/pkg/validators"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+)
+
+const (
+	// edge lengths, in pixels, of the square avatar and its thumbnail
+	avatarSize          = 512
+	avatarThumbnailSize = 128
+	// fallback when AVATAR_MAX_SIZE is not configured
+	defaultAvatarMaxSize = 5 << 20
+
+	defaultTimezone = "UTC"
+	defaultLocale   = "en"
+)
+
+var avatarTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// names of stored avatar files: a random ID, _thumb for the thumbnail, and the format
+var avatarNamePattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}(_thumb)?\.(png|jpg)$`)
+
+type ProfileService interface {
+	// the user's profile, or the defaults when they have not saved one
+	GetProfile(ctx context.Context, userID uint) (*ProfileResponse, error)
+	UpdateProfile(ctx context.Context, userID uint, updateReq *UpdateProfileRequest) (*ProfileResponse, error)
+	UploadAvatar(ctx context.Context, userID uint, uploadReq *UploadAvatarRequest) (*ProfileResponse, error)
+	DeleteAvatar(ctx context.Context, userID uint) (*ProfileResponse, error)
+	// what anyone signed in can see about the user with a username
+	GetPublicProfile(ctx context.Context, username string) (*PublicProfileResponse, error)
+	// a stored avatar image and its content type
+	OpenAvatar(ctx context.Context, userID uint, name string) (io.ReadCloser, string, error)
+	// the locale the user saved, or "" when they have not saved a profile
+	PreferredLocale(ctx context.Context, userID uint) (string, error)
+}
+
+// fields left out are kept as they are
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name" validate:"omitempty,min=1,max=100"`
+	Bio         *string `json:"bio" validate:"omitempty,max=500"`
+	Timezone    *string `json:"timezone" validate:"omitempty,timezone"`
+	Locale      *string `json:"locale" validate:"omitempty,bcp47_language_tag"`
+}
+
+// an uploaded picture as received by the handler; Size is the length of Body
+type UploadAvatarRequest struct {
+	Size int64
+	Body io.Reader
+}
+
+type ProfileResponse struct {
+	UserID             uint   `json:"user_id"`
+	Email              string `json:"email"`
+	Username           string `json:"username,omitempty"`
+	DisplayName        string `json:"display_name"`
+	Bio                string `json:"bio"`
+	AvatarURL          string `json:"avatar_url,omitempty"`
+	AvatarThumbnailURL string `json:"avatar_thumbnail_url,omitempty"`
+	Timezone           string `json:"timezone"`
+	Locale             string `json:"locale"`
+	UpdatedAt          string `json:"updated_at,omitempty"`
+}
+
+// a profile as shown to other users: no email, bio or preferences
+type PublicProfileResponse struct {
+	UserID             uint   `json:"user_id"`
+	Username           string `json:"username"`
+	DisplayName        string `json:"display_name"`
+	AvatarURL          string `json:"avatar_url,omitempty"`
+	AvatarThumbnailURL string `json:"avatar_thumbnail_url,omitempty"`
+}
+
+type profileService struct {
+	repo      repositories.ProfileRepository
+	users     identity.UserDirectory
+	validator *validators.Validator
+	cfg       *config.Config
+	storage   storage.Storage
+	log       logger.Logger
+}
+
+func NewProfileService(repo repositories.ProfileRepository, users identity.UserDirectory, validator *validators.Validator, cfg *config.Config, storage storage.Storage, log logger.Logger) ProfileService {
+	return &profileService{
+		repo:      repo,
+		users:     users,
+		validator: validator,
+		cfg:       cfg,
+		storage:   storage,
+		log:       log,
+	}
+}
+
+func (s *profileService) GetProfile(ctx context.Context, userID uint) (*ProfileResponse, error) {
+	profile, user, err := s.loadProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toProfileResponse(profile, user), nil
+}
+
+func (s *profileService) PreferredLocale(ctx context.Context, userID uint) (string, error) {
+	profile, err := s.repo.GetProfile(ctx, userID)
+	if err != nil {
+		if isNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return profile.Locale, nil
+}
+
+func (s *profileService) GetPublicProfile(ctx context.Context, username string) (*PublicProfileResponse, error) {
+	user, err := s.users.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	profile, _, err := s.loadProfile(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &PublicProfileResponse{
+		UserID:             user.ID,
+		Username:           user.Username,
+		DisplayName:        profile.DisplayName,
+		AvatarURL:          profile.AvatarURL,
+		AvatarThumbnailURL: profile.AvatarThumbnailURL,
+	}, nil
+}
+
+func (s *profileService) UpdateProfile(ctx context.Context, userID uint, updateReq *UpdateProfileRequest) (*ProfileResponse, error) {
+	if fieldErrors := s.validator.StructCtx(ctx, updateReq); fieldErrors != nil {
+		return nil, appErrors.ValidationError("invalid profile data", nil, fieldErrors)
+	}
+	profile, user, err := s.loadProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if updateReq.DisplayName != nil {
+		name := strings.TrimSpace(*updateReq.DisplayName)
+		if name == "" {
+			return nil, appErrors.ValidationError("invalid profile data", nil, map[string]string{"display_name": "This field is required"})
+		}
+		profile.DisplayName = name
+	}
+	if updateReq.Bio != nil {
+		profile.Bio = strings.TrimSpace(*updateReq.Bio)
+	}
+	if updateReq.Timezone != nil {
+		profile.Timezone = *updateReq.Timezone
+	}
+	if updateReq.Locale != nil {
+		profile.Locale = *updateReq.Locale
+	}
+	if err := s.repo.SaveProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	s.log.Info("service: profile updated", "userID", userID)
+	return toProfileResponse(profile, user), nil
+}
+
+// replace the user's avatar. The picture is cropped to a square and stored at two
+// sizes; what is kept is always re-encoded, never the uploaded bytes.
+func (s *profileService) UploadAvatar(ctx context.Context, userID uint, uploadReq *UploadAvatarRequest) (*ProfileResponse, error) {
+	if s.storage == nil {
+		return nil, appErrors.InternalServerError("file storage is not configured", nil)
+	}
+	if uploadReq.Size <= 0 {
+		return nil, appErrors.ValidationError("invalid avatar", nil, map[string]string{"file": "The file is empty"})
+	}
+	max := s.avatarMaxSize()
+	if uploadReq.Size > max {
+		return nil, appErrors.PayloadTooLargeError(fmt.Sprintf("avatars may be at most %d bytes", max), nil)
+	}
+	data, err := io.ReadAll(io.LimitReader(uploadReq.Body, max+1))
+	if err != nil {
+		return nil, appErrors.ValidationError("could not read the uploaded file", err, nil)
+	}
+	if int64(len(data)) > max {
+		return nil, appErrors.PayloadTooLargeError(fmt.Sprintf("avatars may be at most %d bytes", max), nil)
+	}
+	if detected := mimetype.Detect(data); !mimetype.EqualsAny(detected.String(), avatarTypes...) {
+		return nil, appErrors.UnsupportedMediaTypeError(fmt.Sprintf("avatars must be PNG, JPEG or GIF images, not %s", detected.String()), nil)
+	}
+	img, format, err := imaging.Decode(data)
+	if err != nil {
+		if errors.Is(err, imaging.ErrTooLarge) {
+			return nil, appErrors.ValidationError("invalid avatar", err, map[string]string{"file": fmt.Sprintf("Images may be at most %d pixels wide and high", imaging.MaxDimension)})
+		}
+		return nil, appErrors.ValidationError("invalid avatar", err, map[string]string{"file": "The image could not be decoded"})
+	}
+
+	profile, user, err := s.loadProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	previous := []string{profile.AvatarKey, profile.AvatarThumbnailKey}
+
+	id := uuid.NewString()
+	ext := imaging.Extension(format)
+	avatarKey := fmt.Sprintf("avatars/%d/%s%s", userID, id, ext)
+	thumbnailKey := fmt.Sprintf("avatars/%d/%s_thumb%s", userID, id, ext)
+	if err := s.storeAvatar(ctx, avatarKey, imaging.Square(img, avatarSize), format); err != nil {
+		return nil, err
+	}
+	if err := s.storeAvatar(ctx, thumbnailKey, imaging.Square(img, avatarThumbnailSize), format); err != nil {
+		s.deleteFiles(ctx, avatarKey)
+		return nil, err
+	}
+
+	profile.AvatarKey = avatarKey
+	profile.AvatarThumbnailKey = thumbnailKey
+	profile.AvatarURL = s.avatarURL(avatarKey)
+	profile.AvatarThumbnailURL = s.avatarURL(thumbnailKey)
+	if err := s.repo.SaveProfile(ctx, profile); err != nil {
+		s.deleteFiles(ctx, avatarKey, thumbnailKey)
+		return nil, err
+	}
+	s.deleteFiles(ctx, previous...)
+	s.log.Info("service: avatar uploaded", "userID", userID, "key", avatarKey)
+	return toProfileResponse(profile, user), nil
+}
+
+func (s *profileService) DeleteAvatar(ctx context.Context, userID uint) (*ProfileResponse, error) {
+	profile, user, err := s.loadProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.AvatarKey == "" && profile.AvatarURL == "" {
+		return toProfileResponse(profile, user), nil
+	}
+	previous := []string{profile.AvatarKey, profile.AvatarThumbnailKey}
+	profile.AvatarKey, profile.AvatarThumbnailKey = "", ""
+	profile.AvatarURL, profile.AvatarThumbnailURL = "", ""
+	if err := s.repo.SaveProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	s.deleteFiles(ctx, previous...)
+	s.log.Info("service: avatar removed", "userID", userID)
+	return toProfileResponse(profile, user), nil
+}
+
+// avatars are served to anyone holding the link: the names are random and change
+// with every upload, and replaced images are deleted
+func (s *profileService) OpenAvatar(ctx context.Context, userID uint, name string) (io.ReadCloser, string, error) {
+	if s.storage == nil {
+		return nil, "", appErrors.InternalServerError("file storage is not configured", nil)
+	}
+	if !avatarNamePattern.MatchString(name) {
+		return nil, "", appErrors.NotFoundError("avatar not found", nil)
+	}
+	body, err := s.storage.Open(ctx, fmt.Sprintf("avatars/%d/%s", userID, name))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, "", appErrors.NotFoundError("avatar not found", err)
+		}
+		s.log.Error("service: failed to open avatar", err, "userID", userID, "name", name)
+		return nil, "", appErrors.ExternalServiceError("failed to read avatar", err)
+	}
+	contentType := "image/png"
+	if path.Ext(name) == ".jpg" {
+		contentType = "image/jpeg"
+	}
+	return body, contentType, nil
+}
+
+// the saved profile, or an unsaved one holding the defaults, and the account it
+// belongs to
+func (s *profileService) loadProfile(ctx context.Context, userID uint) (*models.Profile, *identity.UserInfo, error) {
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	profile, err := s.repo.GetProfile(ctx, userID)
+	if err == nil {
+		return profile, user, nil
+	}
+	if !isNotFound(err) {
+		return nil, nil, err
+	}
+	return &models.Profile{
+		UserID:      userID,
+		DisplayName: defaultDisplayName(user),
+		Timezone:    defaultTimezone,
+		Locale:      defaultLocale,
+	}, user, nil
+}
+
+func (s *profileService) storeAvatar(ctx context.Context, key string, img *image.RGBA, format string) error {
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format); err != nil {
+		return appErrors.InternalServerError("failed to encode avatar", err)
+	}
+	contentType := "image/png"
+	if format == "jpeg" {
+		contentType = "image/jpeg"
+	}
+	if err := s.storage.Put(ctx, key, &buf, int64(buf.Len()), contentType); err != nil {
+		s.log.Error("service: failed to store avatar", err, "key", key)
+		return appErrors.ExternalServiceError("failed to store avatar", err)
+	}
+	return nil
+}
+
+// failures only leave orphaned files behind, so they are logged
+func (s *profileService) deleteFiles(ctx context.Context, keys ...string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			s.log.Error("service: failed to delete stored file", err, "key", key)
+		}
+	}
+}
+
+// stable public link to a stored avatar, served by this application whatever
+// the storage backend
+func (s *profileService) avatarURL(key string) string {
+	base := ""
+	if s.cfg != nil {
+		base = strings.TrimRight(s.cfg.AppURL, "/")
+	}
+	return base + "/api/" + key
+}
+
+func (s *profileService) avatarMaxSize() int64 {
+	if s.cfg != nil && s.cfg.AvatarMaxSize > 0 {
+		return s.cfg.AvatarMaxSize
+	}
+	return defaultAvatarMaxSize
+}
+
+// the username, or else the part of the email address before the @
+func defaultDisplayName(user *identity.UserInfo) string {
+	if user.Username != "" {
+		return user.Username
+	}
+	if at := strings.IndexByte(user.Email, '@'); at > 0 {
+		return user.Email[:at]
+	}
+	return user.Email
+}
+
+func isNotFound(err error) bool {
+	var appErr appErrors.AppError
+	return errors.As(err, &appErr) && appErr.Code() == appErrors.CodeNotFound
+}
+
+func toProfileResponse(profile *models.Profile, user *identity.UserInfo) *ProfileResponse {
+	res := &ProfileResponse{
+		UserID:             profile.UserID,
+		Email:              user.Email,
+		Username:           user.Username,
+		DisplayName:        profile.DisplayName,
+		Bio:                profile.Bio,
+		AvatarURL:          profile.AvatarURL,
+		AvatarThumbnailURL: profile.AvatarThumbnailURL,
+		Timezone:           profile.Timezone,
+		Locale:             profile.Locale,
+	}
+	if profile.ID != 0 {
+		res.UpdatedAt = profile.UpdatedAt.Format("2006-01-02 15:04:05")
+	}
+	return res
+}
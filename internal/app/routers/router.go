@@ -5,6 +5,7 @@ import (
 
 	_ "github.com/codetheuri/todolist/docs"
 	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/openapi"
 	"github.com/go-chi/chi/v5"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
@@ -16,6 +17,11 @@ type Router interface {
 	Put(pattern string, h http.HandlerFunc)
 	Patch(pattern string, h http.HandlerFunc)
 	Delete(pattern string, h http.HandlerFunc)
+	// Method registers h for any method; handlers that describe themselves, like tonic
+	// endpoints, are added to the API documentation
+	Method(method, pattern string, h http.Handler)
+	// Module registers the routes of a named module, documented under that name
+	Module(name string, fn func(r Router))
 	Group(fn func(r Router))
 	Route(pattern string, fn func(r Router))
 	Use(middlewares ...func(http.Handler) http.Handler)
@@ -24,6 +30,10 @@ type Router interface {
 
 type chiRouter struct {
 	r chi.Router
+	// the pattern the routes are mounted under, and the module registering them
+	prefix string
+	module string
+	docs   *openapi.Registry
 }
 
 // Implement all the interface methods by calling the underlying chi router.
@@ -32,6 +42,12 @@ func (cr *chiRouter) Post(pattern string, h http.HandlerFunc)   { cr.r.Post(patt
 func (cr *chiRouter) Put(pattern string, h http.HandlerFunc)    { cr.r.Put(pattern, h) }
 func (cr *chiRouter) Patch(pattern string, h http.HandlerFunc)  { cr.r.Patch(pattern, h) }
 func (cr *chiRouter) Delete(pattern string, h http.HandlerFunc) { cr.r.Delete(pattern, h) }
+func (cr *chiRouter) Method(method, pattern string, h http.Handler) {
+	cr.r.Method(method, pattern, h)
+	if d, ok := h.(openapi.Describer); ok && cr.docs != nil {
+		cr.docs.Add(cr.module, method, cr.prefix+pattern, d.Describe())
+	}
+}
 func (cr *chiRouter) Use(middlewares ...func(http.Handler) http.Handler) {
 	cr.r.Use(middlewares...)
 }
@@ -41,13 +57,19 @@ func (cr *chiRouter) Mount(pattern string, h http.Handler) {
 
 func (cr *chiRouter) Group(fn func(r Router)) {
 	cr.r.Group(func(subRouter chi.Router) {
-		fn(&chiRouter{r: subRouter})
+		fn(&chiRouter{r: subRouter, prefix: cr.prefix, module: cr.module, docs: cr.docs})
+	})
+}
+
+func (cr *chiRouter) Module(name string, fn func(r Router)) {
+	cr.r.Group(func(subRouter chi.Router) {
+		fn(&chiRouter{r: subRouter, prefix: cr.prefix, module: name, docs: cr.docs})
 	})
 }
 
 func (cr *chiRouter) Route(pattern string, fn func(r Router)) {
 	cr.r.Route(pattern, func(subRouter chi.Router) {
-		fn(&chiRouter{r: subRouter})
+		fn(&chiRouter{r: subRouter, prefix: cr.prefix + pattern, module: cr.module, docs: cr.docs})
 	})
 }
 
@@ -55,7 +77,8 @@ func (cr *chiRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	cr.r.ServeHTTP(w, r)
 }
 
-func NewRouter(log logger.Logger) Router {
+// NewRouter builds the base router; the tonic routes registered on it are documented in docs
+func NewRouter(log logger.Logger, docs *openapi.Registry) Router {
 	// r := chi.NewRouter()
 	r := chi.NewMux()
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -75,6 +98,6 @@ func NewRouter(log logger.Logger) Router {
 	r.Handle("/swagger/*", swaggerHandler)
 
 	log.Info("Base HTTP router initialized. ")
-	return &chiRouter{r: r}
+	return &chiRouter{r: r, docs: docs}
 
 }
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"github.com/codetheuri/todolist/internal/app/todo/services"
+	"github.com/codetheuri/todolist/pkg/pagination"
+	"github.com/codetheuri/todolist/pkg/tonic"
+)
+
+// requests of the tonic routes, bound from the route, the query string and the body
+
+// TodoParams identifies the todo of a /todos/{id} route
+type TodoParams struct {
+	ID uint `json:"-" path:"id" validate:"required"`
+}
+
+// Precondition is the If-Match header of a change to a todo, checked against its ETag
+type Precondition struct {
+	IfMatch string `json:"-" header:"If-Match"`
+}
+
+// ConditionalTodoParams identifies a todo to change only if it still matches If-Match
+type ConditionalTodoParams struct {
+	TodoParams
+	Precondition
+}
+
+type UpdateTodoRequest struct {
+	services.UpdateTodoRequest
+	Precondition
+}
+
+// PatchTodoRequest is a merge patch or a JSON Patch, told apart by its Content-Type
+type PatchTodoRequest struct {
+	ConditionalTodoParams
+	Patch *tonic.Body `json:"-"`
+}
+
+// CompleteTodoRequest completes the todo unless completed is false; the body may be left out
+type CompleteTodoRequest struct {
+	ConditionalTodoParams
+	Completed *bool `json:"completed"`
+	Cascade   bool  `json:"cascade"`
+}
+
+type ReorderSubtasksRequest struct {
+	TodoParams
+	services.ReorderSubtasksRequest
+}
+
+// BulkResult is a batch of operations, answered with 422 when it was rolled back
+type BulkResult struct {
+	tonic.Response
+	*services.BulkTodosResponse
+}
+
+// TodoPageRequest is a page of something nested under a todo
+type TodoPageRequest struct {
+	TodoParams
+	pagination.Params
+}
+
+type CreateSubtaskRequest struct {
+	TodoParams
+	services.CreateTodoRequest
+}
+
+type GetTodoRequest struct {
+	TodoParams
+	IfNoneMatch string `json:"-" header:"If-None-Match"`
+}
+
+// TodoResult is a todo with its ETag
+type TodoResult struct {
+	tonic.Response
+	*services.TodoResponse
+}
+
+type ExportTodosRequest struct {
+	Format string `json:"-" query:"format" default:"json"`
+}
+
+// ImportTodosRequest is a file sent as the request body or as the "file" field of a
+// multipart form, with its format told by ?format=, the file name or the Content-Type
+type ImportTodosRequest struct {
+	Format string      `json:"-" query:"format"`
+	DryRun bool        `json:"-" query:"dry_run"`
+	File   *tonic.File `json:"-" form:"file"`
+	Body   *tonic.Body `json:"-"`
+}
+
+// ImportResult is an import, answered with 422 when it was rejected and 200 for a dry run
+type ImportResult struct {
+	tonic.Response
+	*services.ImportTodosResponse
+}
+
+type OccurrencesRequest struct {
+	TodoParams
+	// how many occurrences to list; the service picks a default for 0
+	Count int `json:"-" query:"count"`
+}
+
+type CreateCommentRequest struct {
+	TodoParams
+	services.CommentRequest
+}
+
+type CommentParams struct {
+	TodoParams
+	CommentID uint `json:"-" path:"commentID" validate:"required"`
+}
+
+type UpdateCommentRequest struct {
+	CommentParams
+	services.CommentRequest
+}
+
+// UploadAttachmentRequest is a multipart/form-data upload of one file
+type UploadAttachmentRequest struct {
+	TodoParams
+	File *tonic.File `json:"-" form:"file" validate:"required"`
+}
+
+type ShareParams struct {
+	TodoParams
+	ShareID uint `json:"-" path:"shareID" validate:"required"`
+}
+
+type AttachmentParams struct {
+	TodoParams
+	AttachmentID uint `json:"-" path:"attachmentID" validate:"required"`
+}
+
+// WebhookParams identifies the webhook of a /webhooks/{id} route
+type WebhookParams struct {
+	ID uint `json:"-" path:"id" validate:"required"`
+}
+
+type WebhookDeliveriesRequest struct {
+	WebhookParams
+	pagination.Params
+	Status string `json:"-" query:"status"`
+}
+
+type DeliveryParams struct {
+	WebhookParams
+	DeliveryID uint `json:"-" path:"deliveryID" validate:"required"`
+}
@@ -3,233 +3,781 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"math"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
-	"strconv"
+	"path"
+	"strings"
 	"time"
 
+	"github.com/codetheuri/todolist/config"
 	"github.com/codetheuri/todolist/internal/app/todo/services"
 	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/jsonpatch"
 	"github.com/codetheuri/todolist/pkg/logger"
 	"github.com/codetheuri/todolist/pkg/pagination"
+	"github.com/codetheuri/todolist/pkg/storage"
+	"github.com/codetheuri/todolist/pkg/tonic"
 	"github.com/codetheuri/todolist/pkg/web"
-	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/websocket"
 )
 
+// largest import file accepted
+const ImportMaxBody = 10 << 20
+
+// largest patch document accepted
+const PatchMaxBody = 64 << 10
+
+// patch formats PATCH /todos/{id} understands, advertised in Accept-Patch
+var AcceptPatch = jsonpatch.MergePatchType + ", " + jsonpatch.JSONPatchType
+
+// the media types an import may be sent as, and the format of each when no format is given
+var ImportTypes = []string{"text/csv", "application/json", "text/calendar"}
+
+// import format by request media type, when no format is given
+var importFormats = map[string]string{
+	"text/csv":         services.FormatCSV,
+	"application/json": services.FormatJSON,
+	"text/calendar":    services.FormatICS,
+}
+
 type TodoHandler struct {
 	todoService services.TodoService
+	cfg         *config.Config
 	log         logger.Logger
 }
 
 // instance of the TodoHandler
-func NewTodoHandler(svc services.TodoService, log logger.Logger) *TodoHandler {
+func NewTodoHandler(svc services.TodoService, cfg *config.Config, log logger.Logger) *TodoHandler {
 	return &TodoHandler{
 		todoService: svc,
+		cfg:         cfg,
 		log:         log,
 	}
 }
 
 // post todos
-func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
+func (h *TodoHandler) CreateTodo(ctx context.Context, req *services.CreateTodoRequest) (*services.TodoResponse, error) {
 	h.log.Debug("Handler: Received CreateTodo request")
-	var req services.CreateTodoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("handler: Failed to decode request body", "error", err)
-		web.RespondError(w, appErrors.New("INVALID_INPUT", "Invalid request body format", err), http.StatusBadRequest)
-		return
-	}
 	//call service
-	ctx, cancel := context.WithTimeout(r.Context(), 5* time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5* time.Second)
 	defer cancel()
-	res, err := h.todoService.CreateTodo(ctx,&req)
+	res, err := h.todoService.CreateTodo(ctx, req)
 	if err != nil {
 		h.log.Error("Handler: Service call failed", err)
-		web.RespondError(w, err, http.StatusInternalServerError)
-		return
+		return nil, err
 	}
-	
-	
-	web.RespondData(w,http.StatusCreated,res, "todo created succssfully")
 	h.log.Info("Handler: Todo request handled successfully", "todoID", res.ID)
+	return res, nil
 }
 
-// get todo by id
-func (h *TodoHandler) GetTodoByID(w http.ResponseWriter, r *http.Request) {
+// get todo by id; a matching If-None-Match is answered with 304 Not Modified
+func (h *TodoHandler) GetTodoByID(ctx context.Context, req *GetTodoRequest) (*TodoResult, error) {
 	h.log.Debug("Hander: Received GetTodoByID request")
-	// idStr := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
-	// id, err := strconv.ParseUint(idStr, 10, 32)
-	idStr := chi.URLParam(r, "id")
-	// if idStr == "" {
-	// 	web.RespondError(w, r, h.Log, errors.NewError(errors.ENonExistent, "ID is missing in the URL"))
-	// 	return
-	// }
-
-	id, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil {
-		h.log.Warn("Handler: Invalid ID format", "id", idStr, "error", err)
-		web.RespondError(w, appErrors.ValidationError("Invalid ID format", err, nil), http.StatusBadRequest)
-		return
-	}
-	// Check if the parsed ID is within the bounds of the uint type
-	if id > math.MaxUint {
-		h.log.Warn("Handler: ID exceeds the maximum allowed value for uint", "id", id)
-		web.RespondError(w, appErrors.ValidationError("ID exceeds the maximum allowed value", nil, nil), http.StatusBadRequest)
-		return
-	}
-	res, err := h.todoService.GetTodoByID(uint(id))
+	res, err := h.todoService.GetTodoByID(ctx, req.ID)
 	if err != nil {
-		h.log.Error("Handler: Service call failed for GetTodoByID", err, "todoID", id)
-		web.RespondError(w, err, http.StatusInternalServerError)
-		return
+		h.log.Error("Handler: Service call failed for GetTodoByID", err, "todoID", req.ID)
+		return nil, err
+	}
+	result := &TodoResult{TodoResponse: res}
+	etag := services.TodoETag(res.Version)
+	result.SetHeader("ETag", etag).SetHeader("Accept-Patch", AcceptPatch)
+	if req.IfNoneMatch != "" && web.MatchETag(req.IfNoneMatch, etag, true) {
+		result.Status = http.StatusNotModified
+		return result, nil
 	}
-	
-	web.RespondData(w,http.StatusOK, res, "")
 	h.log.Info("Handler: Todo retrieved successfully", "todoID", res.ID)
+	return result, nil
 }
 
 // get all todos
-func (h *TodoHandler) GetAllTodos(w http.ResponseWriter, r *http.Request) {
+func (h *TodoHandler) GetAllTodos(ctx context.Context, req *pagination.Params) (*pagination.PaginationResponse, error) {
 	h.log.Debug("Handler: Received GetAllTodos request")
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-	page, err := strconv.Atoi(pageStr)
+	p, err := h.todoService.GetAllTodos(ctx, req.Page, req.Limit)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetAllTodos", err)
+		return nil, err
+	}
+	h.log.Info("Handler: Todos retrieved successfully", "page", p.Metadata.Page, "limit", p.Metadata.Limit, "total_items", p.Metadata.TotalItems)
+	return p, nil
+}
+
+func (h *TodoHandler) GetAllIncludingDeleted(ctx context.Context, req *pagination.Params) (*pagination.PaginationResponse, error) {
+	h.log.Debug("Handler: Received GetAllIncludingDeleted request")
+	p, err := h.todoService.GetAllIncludingDeleted(ctx, req.Page, req.Limit)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetAllIncludingDeleted", err)
+		return nil, err
+	}
+	return p, nil
+}
+func (h *TodoHandler) UpdateTodo(ctx context.Context, req *UpdateTodoRequest) (*TodoResult, error) {
+	h.log.Debug("Handler: Received UpdateTodo request")
+	res, err := h.todoService.UpdateTodo(services.WithIfMatch(ctx, req.IfMatch), &req.UpdateTodoRequest)
 	if err != nil {
-		page = pagination.DefaultPage
+		h.log.Error("Handler: Service call failed for UpdateTodo", err, "todoID", req.ID)
+		return nil, err
 	}
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil{
-		limit = pagination.DefaultLimit
+	result := &TodoResult{TodoResponse: res}
+	result.SetHeader("ETag", services.TodoETag(res.Version))
+	h.log.Info("Handler: Todo updated successfully", "todoID", res.ID)
+	return result, nil
+}
+
+// partially update a todo with a JSON merge patch or JSON Patch, picked by Content-Type
+func (h *TodoHandler) PatchTodo(ctx context.Context, req *PatchTodoRequest) (*TodoResult, error) {
+	h.log.Debug("Handler: Received PatchTodo request")
+	patch, err := io.ReadAll(req.Patch)
+	if err != nil {
+		h.log.Warn("Handler: Failed to read patch body", "error", err)
+		return nil, appErrors.InvalidInputError("Invalid request body", err)
 	}
-    ctx := r.Context()
-	p, err := h.todoService.GetAllTodos(ctx,page,limit)
+	res, err := h.todoService.PatchTodo(services.WithIfMatch(ctx, req.IfMatch), &services.PatchTodoRequest{ID: req.ID, MediaType: req.Patch.MediaType, Patch: patch})
 	if err != nil {
-		h.log.Error("Handler: Service call failed for GetAllTodos", err)
-		web.RespondError(w, err, http.StatusInternalServerError)
-		return
+		h.log.Error("Handler: Service call failed for PatchTodo", err, "todoID", req.ID)
+		return nil, err
 	}
-	// web.RespondJSON(w, http.StatusOK, p)
-	web.RespondListData(w,http.StatusOK,p.Data,p.Metadata)
-	h.log.Info("Handler: Todos retrieved successfully", "page", p.Metadata.Page, "limit", p.Metadata.Limit, "total_items", p.Metadata.TotalItems)
+	result := &TodoResult{TodoResponse: res}
+	result.SetHeader("ETag", services.TodoETag(res.Version))
+	h.log.Info("Handler: Todo patched successfully", "todoID", res.ID)
+	return result, nil
+}
 
+// DeleteTodo
+func (h *TodoHandler) SoftDeleteTodo(ctx context.Context, req *ConditionalTodoParams) (*tonic.Response, error) {
+	h.log.Debug("Handler: received DeleteTodo request")
+	if err := h.todoService.SoftDeleteTodo(services.WithIfMatch(ctx, req.IfMatch), req.ID); err != nil {
+		h.log.Error("Handler: Service call failed for DeleteTodo", err, "todoID", req.ID)
+		return nil, err
+	}
+	h.log.Info("Handler: Todo deleted successfully", "todoID", req.ID)
+	return nil, nil
+}
+func (h *TodoHandler) RestoreTodo(ctx context.Context, req *ConditionalTodoParams) (*tonic.Response, error) {
+	h.log.Debug("Handler: Received RestoreTodo request")
+	if err := h.todoService.RestoreTodo(services.WithIfMatch(ctx, req.IfMatch), req.ID); err != nil {
+		h.log.Error("Handler: Service call failed for RestoreTodo", err, "todoID", req.ID)
+		return nil, err
+	}
+	h.log.Info("Handler: Todo restored successfully", "todoID", req.ID)
+	return nil, nil
+}
+func (h *TodoHandler) HardDeleteTodo(ctx context.Context, req *ConditionalTodoParams) (*tonic.Response, error) {
+	h.log.Debug("Handler: Received HardDeleteTodo request")
+	if err := h.todoService.HardDeleteTodo(services.WithIfMatch(ctx, req.IfMatch), req.ID); err != nil {
+		h.log.Error("Handler: Service call failed for HardDeleteTodo", err, "todoID", req.ID)
+		return nil, err
+	}
+	h.log.Info("Handler: Todo hard deleted successfully", "todoID", req.ID)
+	return tonic.NoContent(), nil
 }
 
-func (h *TodoHandler) GetAllIncludingDeleted(w http.ResponseWriter, r *http.Request) {
-	h.log.Debug("Handler: Received GetAllIncludingDeleted request")
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-	page, err := strconv.Atoi(pageStr)
+// deleted todos, most recently deleted first, with when each will be purged
+func (h *TodoHandler) GetTrash(ctx context.Context, req *pagination.Params) (*pagination.PaginationResponse, error) {
+	h.log.Debug("Handler: Received GetTrash request")
+	p, err := h.todoService.GetTrash(ctx, req.Page, req.Limit)
 	if err != nil {
-		page = pagination.DefaultPage
+		h.log.Error("Handler: Service call failed for GetTrash", err)
+		return nil, err
 	}
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil{
-		limit = pagination.DefaultLimit
+	return p, nil
+}
+
+// permanently delete one todo from the trash
+func (h *TodoHandler) PurgeTrashedTodo(ctx context.Context, req *ConditionalTodoParams) (*tonic.Response, error) {
+	h.log.Debug("Handler: Received PurgeTrashedTodo request")
+	if err := h.todoService.PurgeTrashedTodo(services.WithIfMatch(ctx, req.IfMatch), req.ID); err != nil {
+		h.log.Error("Handler: Service call failed for PurgeTrashedTodo", err, "todoID", req.ID)
+		return nil, err
 	}
-    ctx := r.Context()
-	p, err := h.todoService.GetAllIncludingDeleted(ctx,page, limit)
+	h.log.Info("Handler: Todo purged from trash", "todoID", req.ID)
+	return tonic.NoContent(), nil
+}
+
+// permanently delete everything the current user has in the trash
+func (h *TodoHandler) EmptyTrash(ctx context.Context, _ *struct{}) (*services.EmptyTrashResponse, error) {
+	h.log.Debug("Handler: Received EmptyTrash request")
+	res, err := h.todoService.EmptyTrash(ctx)
 	if err != nil {
-		h.log.Error("Handler: Service call failed for GetAllIncludingDeleted", err)
-		web.RespondError(w, err, http.StatusInternalServerError)
-		return
+		h.log.Error("Handler: Service call failed for EmptyTrash", err)
+		return nil, err
 	}
-	// web.RespondJSON(w, http.StatusOK, p)
-	web.RespondListData(w,http.StatusOK,p.Data,p.Metadata)
-	// h.log.Info("Handler: Todos including deleted retrieved successfully", "page", p.Metadata.Page, "limit", p.Metadata.Limit, "total_item", p.Metadata.TotalItems)
+	return res, nil
 }
-func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
-	h.log.Debug("Handler: Received UpdateTodo request")
-	// idStr := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+
+// counts, a completion timeline and tag breakdowns over the current user's todos
+func (h *TodoHandler) GetStats(ctx context.Context, req *services.StatsRequest) (*services.StatsResponse, error) {
+	h.log.Debug("Handler: Received GetStats request")
+	res, err := h.todoService.GetStats(ctx, req)
 	if err != nil {
-		h.log.Warn("Handler: Invalid ID format in UpdateTodo request", "idStr", idStr, "error", err)
-		web.RespondError(w, appErrors.ValidationError("Invalid todo ID format", err, nil), http.StatusBadRequest)
-		return
+		h.log.Error("Handler: Service call failed for GetStats", err)
+		return nil, err
 	}
+	return res, nil
+}
 
-	var req services.UpdateTodoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Warn("Handler: Failed to decode update todo request body", "error", err)
-		web.RespondError(w, appErrors.New("INVALID_INPUT", "Invalid request body format", err), http.StatusBadRequest)
-		return
+// add a subtask to a todo
+func (h *TodoHandler) CreateSubtask(ctx context.Context, req *CreateSubtaskRequest) (*services.TodoResponse, error) {
+	h.log.Debug("Handler: Received CreateSubtask request")
+	res, err := h.todoService.AddSubtask(ctx, req.ID, &req.CreateTodoRequest)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for CreateSubtask", err, "parentID", req.ID)
+		return nil, err
 	}
+	h.log.Info("Handler: Subtask created successfully", "parentID", req.ID, "todoID", res.ID)
+	return res, nil
+}
 
-	req.ID = uint(id)
+// list the subtasks of a todo in order
+func (h *TodoHandler) GetSubtasks(ctx context.Context, req *TodoParams) (*[]services.TodoResponse, error) {
+	h.log.Debug("Handler: Received GetSubtasks request")
+	res, err := h.todoService.GetSubtasks(ctx, req.ID)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetSubtasks", err, "parentID", req.ID)
+		return nil, err
+	}
+	return &res, nil
+}
 
-	res, err := h.todoService.UpdateTodo(&req)
+// rewrite the order of a todo's subtasks
+func (h *TodoHandler) ReorderSubtasks(ctx context.Context, req *ReorderSubtasksRequest) (*[]services.TodoResponse, error) {
+	h.log.Debug("Handler: Received ReorderSubtasks request")
+	res, err := h.todoService.ReorderSubtasks(ctx, req.ID, &req.ReorderSubtasksRequest)
 	if err != nil {
-		h.log.Error("Handler: Service call failed for UpdateTodo", err, "todoID", id)
-		web.RespondError(w, err, http.StatusInternalServerError)
-		return
+		h.log.Error("Handler: Service call failed for ReorderSubtasks", err, "parentID", req.ID)
+		return nil, err
 	}
-	
-	web.RespondData(w,http.StatusOK, res, "Todo updated successfully")
-	h.log.Info("Handler: Todo updated successfully", "todoID", res.ID)
+	return &res, nil
 }
 
-// DeleteTodo
-func (h *TodoHandler) SoftDeleteTodo(w http.ResponseWriter, r *http.Request) {
-	h.log.Debug("Handler: received DeleteTodo request")
+// complete or reopen a todo or subtask
+func (h *TodoHandler) CompleteTodo(ctx context.Context, req *CompleteTodoRequest) (*TodoResult, error) {
+	h.log.Debug("Handler: Received CompleteTodo request")
+	completeReq := services.CompleteTodoRequest{ID: req.ID, Completed: req.Completed == nil || *req.Completed, Cascade: req.Cascade}
+	res, err := h.todoService.CompleteTodo(services.WithIfMatch(ctx, req.IfMatch), &completeReq)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for CompleteTodo", err, "todoID", req.ID)
+		return nil, err
+	}
+	result := &TodoResult{TodoResponse: res}
+	result.SetHeader("ETag", services.TodoETag(res.Version))
+	h.log.Info("Handler: Todo completion updated", "todoID", req.ID, "completed", res.Completed)
+	return result, nil
+}
 
+// list the upcoming occurrences of a recurring todo
+func (h *TodoHandler) GetOccurrences(ctx context.Context, req *OccurrencesRequest) (*services.OccurrencesResponse, error) {
+	h.log.Debug("Handler: Received GetOccurrences request")
+	res, err := h.todoService.GetOccurrences(ctx, req.ID, req.Count)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetOccurrences", err, "todoID", req.ID)
+		return nil, err
+	}
+	return res, nil
+}
 
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+// preview the occurrences of a recurrence rule before saving it
+func (h *TodoHandler) PreviewOccurrences(ctx context.Context, req *services.PreviewOccurrencesRequest) (*services.OccurrencesResponse, error) {
+	h.log.Debug("Handler: Received PreviewOccurrences request")
+	res, err := h.todoService.PreviewOccurrences(ctx, req)
 	if err != nil {
-		h.log.Warn("Handler: Invalid ID format in DeleteTodo request", "idStr", idStr, "error", err)
-		web.RespondError(w, appErrors.ValidationError("Invalid todo ID format", err, nil), http.StatusBadRequest)
-		return
+		h.log.Warn("Handler: Service call failed for PreviewOccurrences", "error", err)
+		return nil, err
 	}
-	//call service
-	err = h.todoService.SoftDeleteTodo(uint(id))
+	return res, nil
+}
+
+// apply a batch of operations atomically; a rolled back batch is reported per item
+func (h *TodoHandler) BulkTodos(ctx context.Context, req *services.BulkTodosRequest) (*BulkResult, error) {
+	h.log.Debug("Handler: Received BulkTodos request")
+	res, err := h.todoService.BulkTodos(ctx, req)
 	if err != nil {
-		h.log.Error("Handler: Service call failed for DeleteTodo", err, "todoID", id)
-		web.RespondError(w, err, http.StatusInternalServerError)
-		return
+		h.log.Error("Handler: Service call failed for BulkTodos", err)
+		return nil, err
 	}
-	
-	
-	 web.RespondMessage(w, http.StatusOK, "Todo soft-deleted successfully", "success", "toast")
-	h.log.Info("Handler: Todo deleted successfully", "todoID", id)
+	result := &BulkResult{BulkTodosResponse: res}
+	if !res.Applied {
+		result.Status = http.StatusUnprocessableEntity
+		result.Message = "Bulk operations rolled back"
+		return result, nil
+	}
+	h.log.Info("Handler: Bulk operations applied", "count", len(res.Results))
+	return result, nil
 }
-func (h *TodoHandler) RestoreTodo(w http.ResponseWriter, r *http.Request) {
-	h.log.Debug("Handler: Received RestoreTodo request")
-	// idStr := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+
+// invite a user by email to view or edit a todo
+func (h *TodoHandler) ShareTodo(ctx context.Context, req *services.ShareTodoRequest) (*services.ShareResponse, error) {
+	h.log.Debug("Handler: Received ShareTodo request")
+	res, err := h.todoService.ShareTodo(ctx, req)
 	if err != nil {
-		h.log.Warn("Handler: Invalid ID format in RestoreTodo request", "idStr", idStr, "error", err)
-		web.RespondError(w, appErrors.ValidationError("Invalid todo ID format", err, nil), http.StatusBadRequest)
-		return
+		h.log.Error("Handler: Service call failed for ShareTodo", err, "todoID", req.TodoID)
+		return nil, err
 	}
-	//call service
-	err = h.todoService.RestoreTodo(uint(id))
+	h.log.Info("Handler: Todo shared", "todoID", req.TodoID, "shareID", res.ID)
+	return res, nil
+}
+
+// list who a todo is shared with
+func (h *TodoHandler) GetShares(ctx context.Context, req *TodoParams) (*[]services.ShareResponse, error) {
+	h.log.Debug("Handler: Received GetShares request")
+	res, err := h.todoService.GetShares(ctx, req.ID)
 	if err != nil {
-		h.log.Error("Handler: Service call failed for RestoreTodo", err, "todoID", id)
-		web.RespondError(w, err, http.StatusInternalServerError)
-		return
+		h.log.Error("Handler: Service call failed for GetShares", err, "todoID", req.ID)
+		return nil, err
 	}
-	
-	web.RespondMessage(w,http.StatusOK, "Todo restored successfully", "success", "alert")
-	h.log.Info("Handler: Todo restored successfully", "todoID", id)
+	return &res, nil
 }
-func (h *TodoHandler) HardDeleteTodo(w http.ResponseWriter, r *http.Request) {
-	h.log.Debug("Handler: Received HardDeleteTodo request")
-	// idStr := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+
+// revoke a share, or leave a todo shared with you
+func (h *TodoHandler) RevokeShare(ctx context.Context, req *ShareParams) (*tonic.Response, error) {
+	h.log.Debug("Handler: Received RevokeShare request")
+	if err := h.todoService.RevokeShare(ctx, req.ID, req.ShareID); err != nil {
+		h.log.Error("Handler: Service call failed for RevokeShare", err, "todoID", req.ID, "shareID", req.ShareID)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// pending invitations for the current user
+func (h *TodoHandler) GetInvitations(ctx context.Context, _ *struct{}) (*[]services.ShareResponse, error) {
+	h.log.Debug("Handler: Received GetInvitations request")
+	res, err := h.todoService.GetInvitations(ctx)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetInvitations", err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+// accept an invitation with its emailed token
+func (h *TodoHandler) AcceptShare(ctx context.Context, req *services.AcceptShareRequest) (*services.ShareResponse, error) {
+	h.log.Debug("Handler: Received AcceptShare request")
+	res, err := h.todoService.AcceptShare(ctx, req)
 	if err != nil {
-		h.log.Warn("Handler: Invalid ID format in HardDeleteTodo request", "idStr", idStr, "error", err)
-		web.RespondError(w, appErrors.ValidationError("Invalid todo ID format", err, nil), http.StatusBadRequest)
+		h.log.Error("Handler: Service call failed for AcceptShare", err)
+		return nil, err
+	}
+	h.log.Info("Handler: Todo share accepted", "shareID", res.ID)
+	return res, nil
+}
+
+// comment on a todo
+func (h *TodoHandler) CreateComment(ctx context.Context, req *CreateCommentRequest) (*services.CommentResponse, error) {
+	h.log.Debug("Handler: Received CreateComment request")
+	res, err := h.todoService.AddComment(ctx, req.ID, &req.CommentRequest)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for CreateComment", err, "todoID", req.ID)
+		return nil, err
+	}
+	return res, nil
+}
+
+// list the comments on a todo
+func (h *TodoHandler) GetComments(ctx context.Context, req *TodoPageRequest) (*pagination.PaginationResponse, error) {
+	h.log.Debug("Handler: Received GetComments request")
+	p, err := h.todoService.GetComments(ctx, req.ID, req.Page, req.Limit)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetComments", err, "todoID", req.ID)
+		return nil, err
+	}
+	return p, nil
+}
+
+// edit your own comment
+func (h *TodoHandler) UpdateComment(ctx context.Context, req *UpdateCommentRequest) (*services.CommentResponse, error) {
+	h.log.Debug("Handler: Received UpdateComment request")
+	res, err := h.todoService.UpdateComment(ctx, req.ID, req.CommentID, &req.CommentRequest)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for UpdateComment", err, "todoID", req.ID, "commentID", req.CommentID)
+		return nil, err
+	}
+	return res, nil
+}
+
+// delete a comment
+func (h *TodoHandler) DeleteComment(ctx context.Context, req *CommentParams) (*tonic.Response, error) {
+	h.log.Debug("Handler: Received DeleteComment request")
+	if err := h.todoService.DeleteComment(ctx, req.ID, req.CommentID); err != nil {
+		h.log.Error("Handler: Service call failed for DeleteComment", err, "todoID", req.ID, "commentID", req.CommentID)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// the activity feed of a todo, newest first
+func (h *TodoHandler) GetActivity(ctx context.Context, req *TodoPageRequest) (*pagination.PaginationResponse, error) {
+	h.log.Debug("Handler: Received GetActivity request")
+	p, err := h.todoService.GetActivity(ctx, req.ID, req.Page, req.Limit)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetActivity", err, "todoID", req.ID)
+		return nil, err
+	}
+	return p, nil
+}
+
+// multipart upload with the file in the "file" field
+func (h *TodoHandler) UploadAttachment(ctx context.Context, req *UploadAttachmentRequest) (*services.AttachmentResponse, error) {
+	h.log.Debug("Handler: Received UploadAttachment request")
+	body, err := req.File.Open()
+	if err != nil {
+		return nil, appErrors.InternalServerError("could not read the uploaded file", err)
+	}
+	defer body.Close()
+	res, err := h.todoService.UploadAttachment(ctx, &services.UploadAttachmentRequest{
+		TodoID:   req.ID,
+		FileName: req.File.Filename,
+		Size:     req.File.Size,
+		Body:     body,
+	})
+	if err != nil {
+		h.log.Error("Handler: Service call failed for UploadAttachment", err, "todoID", req.ID)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (h *TodoHandler) GetAttachments(ctx context.Context, req *TodoParams) (*[]services.AttachmentResponse, error) {
+	h.log.Debug("Handler: Received GetAttachments request")
+	res, err := h.todoService.GetAttachments(ctx, req.ID)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetAttachments", err, "todoID", req.ID)
+		return nil, err
+	}
+	return &res, nil
+}
+
+// attachment metadata with a signed download URL
+func (h *TodoHandler) GetAttachment(ctx context.Context, req *AttachmentParams) (*services.AttachmentResponse, error) {
+	h.log.Debug("Handler: Received GetAttachment request")
+	res, err := h.todoService.GetAttachment(ctx, req.ID, req.AttachmentID)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetAttachment", err, "todoID", req.ID, "attachmentID", req.AttachmentID)
+		return nil, err
+	}
+	return res, nil
+}
+
+// redirect to a signed download URL
+func (h *TodoHandler) DownloadAttachment(ctx context.Context, req *AttachmentParams) (*tonic.Response, error) {
+	h.log.Debug("Handler: Received DownloadAttachment request")
+	res, err := h.todoService.GetAttachment(ctx, req.ID, req.AttachmentID)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for DownloadAttachment", err, "todoID", req.ID, "attachmentID", req.AttachmentID)
+		return nil, err
+	}
+	return tonic.Redirect(res.URL, http.StatusFound).SetHeader("Cache-Control", "no-store"), nil
+}
+
+func (h *TodoHandler) DeleteAttachment(ctx context.Context, req *AttachmentParams) (*tonic.Response, error) {
+	h.log.Debug("Handler: Received DeleteAttachment request")
+	if err := h.todoService.DeleteAttachment(ctx, req.ID, req.AttachmentID); err != nil {
+		h.log.Error("Handler: Service call failed for DeleteAttachment", err, "todoID", req.ID, "attachmentID", req.AttachmentID)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// stream the caller's todos as a csv, json or ics download
+func (h *TodoHandler) ExportTodos(ctx context.Context, req *ExportTodosRequest) (*tonic.Response, error) {
+	h.log.Debug("Handler: Received ExportTodos request")
+	format := strings.ToLower(req.Format)
+	contentType, ok := services.ExportContentType(format)
+	if !ok {
+		return nil, appErrors.ValidationError("invalid export format", nil, map[string]string{"format": "Must be one of csv, json, ics"})
+	}
+	// written as it is read; the response closes the reader, which stops the export early
+	body, pw := io.Pipe()
+	go func() {
+		err := h.todoService.ExportTodos(ctx, format, pw)
+		if err != nil {
+			h.log.Error("Handler: Service call failed for ExportTodos", err, "format", format)
+		}
+		pw.CloseWithError(err)
+	}()
+	return tonic.Stream(contentType, body).Attachment(fmt.Sprintf("tusk-todos-%s.%s", time.Now().Format("20060102"), format)), nil
+}
+
+// import todos from a csv, json or ics file sent as the request body or as the
+// "file" field of a multipart form; ?dry_run=true only validates
+func (h *TodoHandler) ImportTodos(ctx context.Context, req *ImportTodosRequest) (*ImportResult, error) {
+	h.log.Debug("Handler: Received ImportTodos request")
+	importReq := services.ImportTodosRequest{Format: strings.ToLower(req.Format), DryRun: req.DryRun}
+	var mediaType string
+	switch {
+	case req.File != nil:
+		file, err := req.File.Open()
+		if err != nil {
+			return nil, appErrors.InternalServerError("could not read the uploaded file", err)
+		}
+		defer file.Close()
+		importReq.Body = file
+		mediaType, _, _ = mime.ParseMediaType(req.File.Header.Get("Content-Type"))
+		if importReq.Format == "" {
+			importReq.Format = strings.TrimPrefix(strings.ToLower(path.Ext(req.File.Filename)), ".")
+		}
+	case req.Body != nil:
+		importReq.Body, mediaType = req.Body, req.Body.MediaType
+	default:
+		// a multipart form without its file
+		return nil, appErrors.ValidationError("invalid import", nil, map[string]string{"file": "A file is required"})
+	}
+	if importReq.Format == "" {
+		importReq.Format = importFormats[mediaType]
+	}
+	res, err := h.todoService.ImportTodos(ctx, &importReq)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for ImportTodos", err)
+		return nil, err
+	}
+	result := &ImportResult{ImportTodosResponse: res}
+	switch {
+	case res.DryRun:
+		result.Status = http.StatusOK
+		result.Message = "Import validated, nothing was saved"
+	case !res.Applied:
+		result.Status = http.StatusUnprocessableEntity
+		result.Message = "Import rejected, nothing was saved"
+	default:
+		h.log.Info("Handler: Todos imported", "count", res.Total)
+	}
+	return result, nil
+}
+
+// push changes to the todos the caller can see as Server-Sent Events. A
+// reconnecting EventSource sends Last-Event-ID and is replayed what it missed.
+func (h *TodoHandler) StreamTodos(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug("Handler: Received StreamTodos request")
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	stream, err := h.todoService.SubscribeChanges(r.Context(), lastEventID)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for StreamTodos", err)
+		web.RespondError(w, err, http.StatusInternalServerError)
 		return
 	}
+	defer stream.Close()
+
+	rc := http.NewResponseController(w)
+	// a stream outlives the server's write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(id, eventType string, data []byte) error {
+		if id != "" {
+			if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	if !stream.Complete {
+		if err := send("", services.EventStreamReset, []byte("{}")); err != nil {
+			return
+		}
+	}
+	for _, event := range stream.Backlog {
+		if err := send(event.ID, event.Type, event.Data); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.cfg.EventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-stream.Events():
+			// closed when the client fell behind or the server is stopping; it reconnects and resumes
+			if !ok {
+				return
+			}
+			if err := send(event.ID, event.Type, event.Data); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// streamMessage is a change event as sent over the WebSocket stream
+type streamMessage struct {
+	ID   string          `json:"id,omitempty"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
 
-	err = h.todoService.HardDeleteTodo(uint(id))
+// the same change stream as StreamTodos over a WebSocket; ?last_event_id= resumes
+func (h *TodoHandler) StreamTodosWebSocket(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug("Handler: Received StreamTodosWebSocket request")
+	stream, err := h.todoService.SubscribeChanges(r.Context(), r.URL.Query().Get("last_event_id"))
 	if err != nil {
-		h.log.Error("Handler: Service call failed for HardDeleteTodo", err, "todoID", id)
+		h.log.Error("Handler: Service call failed for StreamTodosWebSocket", err)
 		web.RespondError(w, err, http.StatusInternalServerError)
 		return
 	}
-	
-	  w.WriteHeader(http.StatusNoContent)
-	h.log.Info("Handler: Todo hard deleted successfully", "todoID", id)
+	defer stream.Close()
+
+	server := websocket.Server{
+		Handshake: h.checkWebSocketOrigin,
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			// the upgraded connection keeps the deadlines the server set for the request
+			_ = ws.SetDeadline(time.Time{})
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			// reading notices the client closing; incoming messages are ignored
+			go func() {
+				_, _ = io.Copy(io.Discard, ws)
+				cancel()
+			}()
+
+			if !stream.Complete {
+				if err := websocket.JSON.Send(ws, streamMessage{Type: services.EventStreamReset}); err != nil {
+					return
+				}
+			}
+			for _, event := range stream.Backlog {
+				if err := websocket.JSON.Send(ws, streamMessage{ID: event.ID, Type: event.Type, Data: event.Data}); err != nil {
+					return
+				}
+			}
+			heartbeat := time.NewTicker(h.cfg.EventHeartbeat)
+			defer heartbeat.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event, ok := <-stream.Events():
+					if !ok {
+						return
+					}
+					if err := websocket.JSON.Send(ws, streamMessage{ID: event.ID, Type: event.Type, Data: event.Data}); err != nil {
+						return
+					}
+				case <-heartbeat.C:
+					if err := websocket.JSON.Send(ws, streamMessage{Type: "heartbeat"}); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// browsers may only open the stream from an allowed CORS origin; other clients send no Origin
+func (h *TodoHandler) checkWebSocketOrigin(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	for _, allowed := range h.cfg.CORSOrigins {
+		if allowed == "*" || allowed == origin {
+			return nil
+		}
+	}
+	h.log.Warn("Handler: WebSocket stream rejected for origin", "origin", origin)
+	return fmt.Errorf("origin %q is not allowed", origin)
+}
+
+// register a webhook; the response carries its signing secret, shown this once
+func (h *TodoHandler) CreateWebhook(ctx context.Context, req *services.CreateWebhookRequest) (*services.WebhookResponse, error) {
+	h.log.Debug("Handler: Received CreateWebhook request")
+	res, err := h.todoService.CreateWebhook(ctx, req)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for CreateWebhook", err)
+		return nil, err
+	}
+	h.log.Info("Handler: Webhook created", "id", res.ID)
+	return res, nil
+}
+
+// list the current user's webhooks
+func (h *TodoHandler) GetWebhooks(ctx context.Context, _ *struct{}) (*[]services.WebhookResponse, error) {
+	h.log.Debug("Handler: Received GetWebhooks request")
+	res, err := h.todoService.GetWebhooks(ctx)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetWebhooks", err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (h *TodoHandler) GetWebhook(ctx context.Context, req *WebhookParams) (*services.WebhookResponse, error) {
+	h.log.Debug("Handler: Received GetWebhook request")
+	res, err := h.todoService.GetWebhook(ctx, req.ID)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetWebhook", err, "id", req.ID)
+		return nil, err
+	}
+	return res, nil
+}
+
+// change a webhook's URL, events or secret, or pause it with active=false
+func (h *TodoHandler) UpdateWebhook(ctx context.Context, req *services.UpdateWebhookRequest) (*services.WebhookResponse, error) {
+	h.log.Debug("Handler: Received UpdateWebhook request")
+	res, err := h.todoService.UpdateWebhook(ctx, req)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for UpdateWebhook", err, "id", req.ID)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (h *TodoHandler) DeleteWebhook(ctx context.Context, req *WebhookParams) (*tonic.Response, error) {
+	h.log.Debug("Handler: Received DeleteWebhook request")
+	if err := h.todoService.DeleteWebhook(ctx, req.ID); err != nil {
+		h.log.Error("Handler: Service call failed for DeleteWebhook", err, "id", req.ID)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// the delivery log of a webhook, newest first; ?status= filters it
+func (h *TodoHandler) GetWebhookDeliveries(ctx context.Context, req *WebhookDeliveriesRequest) (*pagination.PaginationResponse, error) {
+	h.log.Debug("Handler: Received GetWebhookDeliveries request")
+	p, err := h.todoService.GetWebhookDeliveries(ctx, req.ID, req.Status, req.Page, req.Limit)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for GetWebhookDeliveries", err, "id", req.ID)
+		return nil, err
+	}
+	return p, nil
+}
+
+// queue a logged delivery to be sent again
+func (h *TodoHandler) RedeliverWebhook(ctx context.Context, req *DeliveryParams) (*services.WebhookDeliveryResponse, error) {
+	h.log.Debug("Handler: Received RedeliverWebhook request")
+	res, err := h.todoService.RedeliverWebhook(ctx, req.ID, req.DeliveryID)
+	if err != nil {
+		h.log.Error("Handler: Service call failed for RedeliverWebhook", err, "id", req.ID, "deliveryID", req.DeliveryID)
+		return nil, err
+	}
+	return res, nil
+}
+
+// ServeFile answers the signed URLs of a storage backend that serves its own files
+func ServeFile(server storage.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.ServeSigned(w, r, chi.URLParam(r, "*"))
+	}
 }
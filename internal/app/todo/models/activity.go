@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// activity actions
+const (
+	ActivityCreated      = "created"
+	ActivityTitleChanged = "title_changed"
+	ActivityCompleted    = "completed"
+	ActivityReopened     = "reopened"
+	ActivityDeleted      = "deleted"
+	ActivityRestored     = "restored"
+	ActivityCommented    = "commented"
+	ActivityAttached     = "attached"
+	ActivityDetached     = "detached"
+	// a patch; Before and After hold only the fields it changed
+	ActivityUpdated = "updated"
+)
+
+// Comment is a note left on a todo by anyone who can see it
+type Comment struct {
+	gorm.Model
+	TodoID uint   `json:"todo_id" gorm:"not null;index"`
+	UserID uint   `json:"user_id" gorm:"not null;index"`
+	Body   string `json:"body" gorm:"type:text;not null"`
+}
+
+// Activity is an append-only record of a change to a todo. Before and After
+// hold the JSON-encoded values of whatever changed, when that makes sense.
+type Activity struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	TodoID    uint      `json:"todo_id" gorm:"not null;index:idx_activities_todo_created"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	Action    string    `json:"action" gorm:"not null;size:32"`
+	Before    *string   `json:"before" gorm:"type:text"`
+	After     *string   `json:"after" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_activities_todo_created"`
+}
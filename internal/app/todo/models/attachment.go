@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+// Attachment is a file uploaded to a todo. The bytes live in the configured
+// storage backend under StorageKey; this row only holds their metadata.
+type Attachment struct {
+	gorm.Model
+	TodoID      uint   `json:"todo_id" gorm:"not null;index"`
+	UserID      uint   `json:"user_id" gorm:"not null;index"`
+	FileName    string `json:"file_name" gorm:"not null;size:255"`
+	ContentType string `json:"content_type" gorm:"not null;size:127"`
+	Size        int64  `json:"size" gorm:"not null"`
+	StorageKey  string `json:"-" gorm:"not null;size:255;uniqueIndex"`
+	// hex sha256 of the file contents
+	Checksum string `json:"checksum" gorm:"not null;size:64"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// share roles; an editor can do everything a viewer can plus change the todo
+const (
+	ShareRoleViewer = "viewer"
+	ShareRoleEditor = "editor"
+)
+
+// TodoShare grants another user access to a top-level todo and its subtasks.
+// It starts as an invitation to Email and becomes a grant once the invitee
+// accepts it, which sets UserID and AcceptedAt. Only a hash of the invite
+// token is stored.
+type TodoShare struct {
+	gorm.Model
+	TodoID     uint       `json:"todo_id" gorm:"not null;index"`
+	OwnerID    uint       `json:"owner_id" gorm:"not null;index"`
+	Email      string     `json:"email" gorm:"not null;size:255;index"`
+	Role       string     `json:"role" gorm:"not null;size:16;default:'viewer'"`
+	UserID     *uint      `json:"user_id" gorm:"index"`
+	TokenHash  string     `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	Todo       Todo       `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// stats timeline bucket sizes
+const (
+	StatsBucketDay   = "day"
+	StatsBucketWeek  = "week"
+	StatsBucketMonth = "month"
+)
+
+// TodoCounts aggregates a user's todos by status. AvgCompletionSeconds is the
+// mean time from creation to completion, nil while nothing has been completed.
+type TodoCounts struct {
+	Total                int64
+	Completed            int64
+	Overdue              int64
+	AvgCompletionSeconds *float64
+}
+
+// StatsBucket is one period of the stats timeline, keyed by the date (YYYY-MM-DD,
+// UTC) it starts on. CreatedCompleted counts the todos created in the period
+// that are completed now; Completed counts those completed during it.
+type StatsBucket struct {
+	Start            string
+	Created          int64
+	CreatedCompleted int64
+	Completed        int64
+}
+
+// TagStats aggregates the todos carrying one tag
+type TagStats struct {
+	Tag       string
+	Total     int64
+	Completed int64
+	Overdue   int64
+}
@@ -0,0 +1,10 @@
+package models
+
+import "gorm.io/gorm"
+
+// Tag is a per-user label that can be attached to any number of todos
+type Tag struct {
+	gorm.Model
+	UserID uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Name   string `json:"name" gorm:"not null;size:50;uniqueIndex:idx_tags_user_name"`
+}
@@ -1,10 +1,42 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Todo struct {
 	gorm.Model
+	UserID uint `json:"user_id" gorm:"not null;default:0;index"`
 	Title string `json:"title" gorm:"not null"`
 	Description string `json:"description"`
 	Completed  bool  `json:"completed" gorm:"default:false"`
+	CompletedAt *time.Time `json:"completed_at"`
+
+	// subtasks: a todo with a ParentID is a checklist item of its parent
+	ParentID *uint  `json:"parent_id" gorm:"index"`
+	Position int    `json:"position" gorm:"not null;default:0"`
+	Subtasks []Todo `json:"subtasks,omitempty" gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	// recurrence: RRule is an iCalendar RRULE evaluated in Timezone, starting at RecurrenceStart.
+	// Completing an occurrence creates the next one and links it through NextOccurrenceID.
+	DueAt            *time.Time `json:"due_at" gorm:"index"`
+	RRule            string     `json:"rrule" gorm:"size:255"`
+	Timezone         string     `json:"timezone" gorm:"size:64"`
+	RecurrenceStart  *time.Time `json:"recurrence_start"`
+	NextOccurrenceID *uint      `json:"next_occurrence_id"`
+
+	Tags []Tag `json:"tags,omitempty" gorm:"many2many:todo_tags;"`
+
+	// Version goes up with every change to the todo (a parent's also changes with its
+	// subtasks, since it reports their progress); writes are conditional on it
+	Version uint `json:"version" gorm:"not null;default:1"`
+}
+
+// SubtaskProgress aggregates the completion state of a parent's subtasks
+type SubtaskProgress struct {
+	ParentID  uint
+	Total     int64
+	Completed int64
 }
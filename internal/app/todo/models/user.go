@@ -1,11 +0,0 @@
-package models
-
-import "gorm.io/gorm"
-
-type User struct {
-	gorm.Model
-	Username string `json:"username" gorm:"uniqueIndex;not null"`
-	Email    string `json:"email" gorm:"uniqueIndex;not null"`
-	Password string `json:"password" gorm:"not null"`
-	Role     string `json:"role" gorm:"default:'user'"`
-}
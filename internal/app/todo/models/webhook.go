@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	// gave up after the last retry
+	DeliveryDead = "dead"
+)
+
+// Webhook subscribes a user's URL to changes on the todos they can see.
+// Events is a comma-separated list of event types; Secret signs each delivery.
+type Webhook struct {
+	gorm.Model
+	UserID uint   `json:"user_id" gorm:"not null;index"`
+	URL    string `json:"url" gorm:"not null;size:2048"`
+	Events string `json:"events" gorm:"not null;size:255"`
+	Secret string `json:"-" gorm:"not null;size:128"`
+	Active bool   `json:"active" gorm:"not null;default:true"`
+}
+
+// WebhookDelivery is both the outbox and the delivery log: a row is written in
+// the same transaction as the change it reports, then the worker sends it,
+// retrying with backoff until it succeeds or runs out of attempts. Payload is
+// the exact JSON body that is sent and signed.
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primarykey"`
+	WebhookID      uint       `json:"webhook_id" gorm:"not null;index"`
+	EventID        string     `json:"event_id" gorm:"not null;size:36;index"`
+	EventType      string     `json:"event_type" gorm:"not null;size:32"`
+	Payload        string     `json:"payload" gorm:"type:text;not null"`
+	Status         string     `json:"status" gorm:"not null;size:16;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index:idx_webhook_deliveries_due,priority:2"`
+	LockedUntil    *time.Time `json:"-"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error" gorm:"size:1024"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Webhook        Webhook    `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
@@ -1,51 +1,134 @@
 package todo
 
 import (
+	"net/http"
+
 	todoHandlers "github.com/codetheuri/todolist/internal/app/todo/handlers"
 	todoRepositories "github.com/codetheuri/todolist/internal/app/todo/repositories"
 	todoServices "github.com/codetheuri/todolist/internal/app/todo/services"
+	"github.com/codetheuri/todolist/config"
 	tokenPkg "github.com/codetheuri/todolist/pkg/auth/token"
+	"github.com/codetheuri/todolist/pkg/events"
+	"github.com/codetheuri/todolist/pkg/jsonpatch"
 	"github.com/codetheuri/todolist/pkg/middleware"
 	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/mailer"
+	"github.com/codetheuri/todolist/pkg/openapi"
+	"github.com/codetheuri/todolist/pkg/storage"
+	"github.com/codetheuri/todolist/pkg/tonic"
 	"github.com/codetheuri/todolist/pkg/validators"
 	"github.com/codetheuri/todolist/internal/app/routers"
+	"github.com/codetheuri/todolist/internal/platform/identity"
 	"gorm.io/gorm"
 )
 
 type Module struct {
 	Handlers *todoHandlers.TodoHandler
 	log      logger.Logger
+	validator    *validators.Validator
+	// the upload limit of attachments, from the configuration
+	attachmentMaxSize int64
 	TokenService tokenPkg.TokenService
+	Storage      storage.Storage
+	// sends queued webhook deliveries; run it with Start
+	Webhooks *todoServices.WebhookWorker
+	// purges todos that outlived the trash retention; run it with Start
+	TrashPurger *todoServices.TrashPurger
 }
 
-func NewModule(db *gorm.DB, log logger.Logger, validator *validators.Validator, tokenService tokenPkg.TokenService) *Module {
+func NewModule(db *gorm.DB, log logger.Logger, validator *validators.Validator, cfg *config.Config, users identity.UserDirectory, tokenService tokenPkg.TokenService, fileStorage storage.Storage, hub *events.Hub) *Module {
 	// Initialize the repository
-	todoRepo := todoRepositories.NewGormTodoRepository(db, log)
+	todoRepos := todoRepositories.NewTodoRepositories(db, log)
 
 	// Initialize the service
-	todoService := todoServices.NewTodoService(todoRepo, validator, log)
+	mailerService := mailer.NewMailerService(cfg, log)
+	todoService := todoServices.NewTodoService(todoRepos, users, validator, cfg, mailerService, fileStorage, hub, log)
 
 	// Initialize the handler
-	todoHandler := todoHandlers.NewTodoHandler(todoService, log)
+	todoHandler := todoHandlers.NewTodoHandler(todoService, cfg, log)
 
 	return &Module{
 		Handlers: todoHandler,
 		log: 	log,
+		validator:    validator,
+		attachmentMaxSize: cfg.AttachmentMaxSize,
 		TokenService: tokenService,
+		Storage:      fileStorage,
+		Webhooks:     todoServices.NewWebhookWorker(todoRepos.WebhookRepo, cfg, log),
+		TrashPurger:  todoServices.NewTrashPurger(todoRepos, cfg, fileStorage, hub, log),
 	}
 }
 
+func (m *Module) Name() string { return "todo" }
+
 func (m *Module) RegisterRoutes(r router.Router) {
+	// every tonic route below is behind the Authenticator
+	v := tonic.Options(tonic.WithValidator(m.validator), tonic.WithSecurity(openapi.BearerAuth))
+	// signed download links of a backend that serves its own files; the signature is the credential
+	if server, ok := m.Storage.(storage.Server); ok {
+		r.Get("/files/*", todoHandlers.ServeFile(server))
+	}
 	// Register the routes for the todo module
 	r.Route("/todos", func(r router.Router) {
+		r.Use(middleware.StreamToken("access_token"))
 		r.Use(middleware.Authenticator(m.TokenService, m.log)) // Apply authentication middleware
-		r.Post("/", m.Handlers.CreateTodo)
-		r.Get("/all", m.Handlers.GetAllIncludingDeleted)
-		r.Get("/{id}", m.Handlers.GetTodoByID)
-		r.Get("/", m.Handlers.GetAllTodos)
-		r.Put("/{id}", m.Handlers.UpdateTodo)
-		r.Delete("/{id}", m.Handlers.SoftDeleteTodo)
-		r.Patch("/{id}/restore", m.Handlers.RestoreTodo)
-		r.Delete("/{id}/hard", m.Handlers.HardDeleteTodo)
+		r.Method(http.MethodPost, "/", tonic.Handle(m.Handlers.CreateTodo, v, tonic.WithStatus(http.StatusCreated), tonic.WithMessage("todo created succssfully")))
+		r.Method(http.MethodGet, "/all", tonic.Handle(m.Handlers.GetAllIncludingDeleted, v, tonic.ListOf[todoServices.TodoResponse]()))
+		r.Method(http.MethodGet, "/trash", tonic.Handle(m.Handlers.GetTrash, v, tonic.ListOf[todoServices.TrashedTodoResponse]()))
+		r.Method(http.MethodDelete, "/trash", tonic.Handle(m.Handlers.EmptyTrash, v, tonic.WithMessage("Trash emptied successfully")))
+		r.Method(http.MethodDelete, "/trash/{id}", tonic.Handle(m.Handlers.PurgeTrashedTodo, v, tonic.WithStatus(http.StatusNoContent)))
+		r.Method(http.MethodGet, "/stats", tonic.Handle(m.Handlers.GetStats, v, tonic.WithMessage("Todo statistics retrieved successfully")))
+		r.Method(http.MethodGet, "/{id}", tonic.Handle(m.Handlers.GetTodoByID, v))
+		r.Method(http.MethodGet, "/", tonic.Handle(m.Handlers.GetAllTodos, v, tonic.ListOf[todoServices.TodoResponse]()))
+		r.Method(http.MethodPut, "/{id}", tonic.Handle(m.Handlers.UpdateTodo, v, tonic.WithMessage("Todo updated successfully")))
+		r.Method(http.MethodPatch, "/{id}", tonic.Handle(m.Handlers.PatchTodo, v,
+			tonic.WithBodyLimit(todoHandlers.PatchMaxBody),
+			tonic.WithBodyTypes(jsonpatch.MergePatchType, jsonpatch.JSONPatchType),
+			tonic.WithHeader("Accept-Patch", todoHandlers.AcceptPatch),
+			tonic.WithMessage("Todo updated successfully")))
+		r.Method(http.MethodDelete, "/{id}", tonic.Handle(m.Handlers.SoftDeleteTodo, v, tonic.WithToast("Todo soft-deleted successfully")))
+		r.Method(http.MethodPatch, "/{id}/restore", tonic.Handle(m.Handlers.RestoreTodo, v, tonic.WithMessage("Todo restored successfully")))
+		r.Method(http.MethodDelete, "/{id}/hard", tonic.Handle(m.Handlers.HardDeleteTodo, v, tonic.WithStatus(http.StatusNoContent)))
+		r.Method(http.MethodPatch, "/{id}/complete", tonic.Handle(m.Handlers.CompleteTodo, v, tonic.WithMessage("Todo updated successfully")))
+		r.Method(http.MethodPost, "/{id}/subtasks", tonic.Handle(m.Handlers.CreateSubtask, v, tonic.WithStatus(http.StatusCreated), tonic.WithMessage("Subtask created successfully")))
+		r.Method(http.MethodGet, "/{id}/subtasks", tonic.Handle(m.Handlers.GetSubtasks, v))
+		r.Method(http.MethodPut, "/{id}/subtasks/order", tonic.Handle(m.Handlers.ReorderSubtasks, v, tonic.WithMessage("Subtasks reordered successfully")))
+		r.Method(http.MethodGet, "/{id}/occurrences", tonic.Handle(m.Handlers.GetOccurrences, v))
+		r.Method(http.MethodPost, "/occurrences/preview", tonic.Handle(m.Handlers.PreviewOccurrences, v))
+		r.Method(http.MethodPost, "/bulk", tonic.Handle(m.Handlers.BulkTodos, v, tonic.WithMessage("Bulk operations applied successfully")))
+		r.Method(http.MethodGet, "/export", tonic.Handle(m.Handlers.ExportTodos, v, tonic.WithStream("text/csv", "application/json", "text/calendar"), tonic.WithoutWriteDeadline()))
+		r.Method(http.MethodPost, "/import", tonic.Handle(m.Handlers.ImportTodos, v,
+			tonic.WithFileLimit("file", todoHandlers.ImportMaxBody),
+			tonic.WithBodyLimit(todoHandlers.ImportMaxBody),
+			tonic.WithBodyTypes(todoHandlers.ImportTypes...),
+			tonic.WithStatus(http.StatusCreated),
+			tonic.WithMessage("Todos imported successfully")))
+		r.Get("/stream", m.Handlers.StreamTodos)
+		r.Get("/stream/ws", m.Handlers.StreamTodosWebSocket)
+		r.Method(http.MethodPost, "/{id}/shares", tonic.Handle(m.Handlers.ShareTodo, v, tonic.WithStatus(http.StatusCreated), tonic.WithMessage("Invitation created successfully")))
+		r.Method(http.MethodGet, "/{id}/shares", tonic.Handle(m.Handlers.GetShares, v))
+		r.Method(http.MethodDelete, "/{id}/shares/{shareID}", tonic.Handle(m.Handlers.RevokeShare, v, tonic.WithMessage("Share revoked successfully")))
+		r.Method(http.MethodGet, "/shares/invitations", tonic.Handle(m.Handlers.GetInvitations, v))
+		r.Method(http.MethodPost, "/shares/accept", tonic.Handle(m.Handlers.AcceptShare, v, tonic.WithMessage("Invitation accepted successfully")))
+		r.Method(http.MethodPost, "/{id}/comments", tonic.Handle(m.Handlers.CreateComment, v, tonic.WithStatus(http.StatusCreated), tonic.WithMessage("Comment added successfully")))
+		r.Method(http.MethodGet, "/{id}/comments", tonic.Handle(m.Handlers.GetComments, v, tonic.ListOf[todoServices.CommentResponse]()))
+		r.Method(http.MethodPut, "/{id}/comments/{commentID}", tonic.Handle(m.Handlers.UpdateComment, v, tonic.WithMessage("Comment updated successfully")))
+		r.Method(http.MethodDelete, "/{id}/comments/{commentID}", tonic.Handle(m.Handlers.DeleteComment, v, tonic.WithToast("Comment deleted successfully")))
+		r.Method(http.MethodGet, "/{id}/activity", tonic.Handle(m.Handlers.GetActivity, v, tonic.ListOf[todoServices.ActivityResponse]()))
+		r.Method(http.MethodPost, "/{id}/attachments", tonic.Handle(m.Handlers.UploadAttachment, v, tonic.WithFileLimit("file", m.attachmentMaxSize), tonic.WithStatus(http.StatusCreated), tonic.WithMessage("Attachment uploaded successfully")))
+		r.Method(http.MethodGet, "/{id}/attachments", tonic.Handle(m.Handlers.GetAttachments, v))
+		r.Method(http.MethodGet, "/{id}/attachments/{attachmentID}", tonic.Handle(m.Handlers.GetAttachment, v))
+		r.Method(http.MethodGet, "/{id}/attachments/{attachmentID}/download", tonic.Handle(m.Handlers.DownloadAttachment, v, tonic.WithStatus(http.StatusFound)))
+		r.Method(http.MethodDelete, "/{id}/attachments/{attachmentID}", tonic.Handle(m.Handlers.DeleteAttachment, v, tonic.WithToast("Attachment deleted successfully")))
+	})
+	r.Route("/webhooks", func(r router.Router) {
+		r.Use(middleware.Authenticator(m.TokenService, m.log))
+		r.Method(http.MethodPost, "/", tonic.Handle(m.Handlers.CreateWebhook, v, tonic.WithStatus(http.StatusCreated), tonic.WithMessage("Webhook created successfully")))
+		r.Method(http.MethodGet, "/", tonic.Handle(m.Handlers.GetWebhooks, v))
+		r.Method(http.MethodGet, "/{id}", tonic.Handle(m.Handlers.GetWebhook, v))
+		r.Method(http.MethodPut, "/{id}", tonic.Handle(m.Handlers.UpdateWebhook, v, tonic.WithMessage("Webhook updated successfully")))
+		r.Method(http.MethodDelete, "/{id}", tonic.Handle(m.Handlers.DeleteWebhook, v, tonic.WithToast("Webhook deleted successfully")))
+		r.Method(http.MethodGet, "/{id}/deliveries", tonic.Handle(m.Handlers.GetWebhookDeliveries, v, tonic.ListOf[todoServices.WebhookDeliveryResponse]()))
+		r.Method(http.MethodPost, "/{id}/deliveries/{deliveryID}/redeliver", tonic.Handle(m.Handlers.RedeliverWebhook, v, tonic.WithStatus(http.StatusAccepted), tonic.WithMessage("Delivery queued")))
 	})
 }
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"golang.org/x/net/context"
+	"gorm.io/gorm"
+)
+
+// every read and write is limited to todos the user owns or holds an accepted share grant for;
+// a grant on a todo also covers its subtasks. accessOwner admits the owner only.
+const accessOwner = "owner"
+
+// todoAccess decides which todos a user may view or change, for the repositories of
+// records that belong to a todo
+type todoAccess struct {
+	db  *gorm.DB
+	log logger.Logger
+}
+
+// limit a todos query to rows the user can access with at least the given role
+func (r *todoAccess) accessibleBy(userID uint, role string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		var roles []string
+		switch role {
+		case models.ShareRoleViewer:
+			roles = []string{models.ShareRoleViewer, models.ShareRoleEditor}
+		case models.ShareRoleEditor:
+			roles = []string{models.ShareRoleEditor}
+		}
+		if len(roles) == 0 {
+			return db.Where("todos.user_id = ?", userID)
+		}
+		conn := r.db.Session(&gorm.Session{NewDB: true})
+		granted := conn.Model(&models.TodoShare{}).Select("todo_id").
+			Where("user_id = ? AND accepted_at IS NOT NULL AND role IN ?", userID, roles)
+		return db.Where(conn.Where("todos.user_id = ?", userID).
+			Or("todos.id IN (?)", granted).
+			Or("todos.parent_id IN (?)", granted))
+	}
+}
+
+// load a todo through query if the user has the role on it; a todo the user
+// can see but not change is a 403, one they cannot see at all is a 404
+func (r *todoAccess) findAccessible(ctx context.Context, query *gorm.DB, userID, id uint, role string) (*models.Todo, error) {
+	var todo models.Todo
+	err := query.WithContext(ctx).Scopes(r.accessibleBy(userID, models.ShareRoleViewer)).First(&todo, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.log.Warn("todo not found", "id", id, "userID", userID)
+			return nil, appErrors.NotFoundError(fmt.Sprintf("todo with id %d not found", id), err)
+		}
+		r.log.Error("failed to get todo by id", err, "id", id)
+		return nil, appErrors.DatabaseError(fmt.Sprintf("failed to get todo by id %d", id), err)
+	}
+	if role == models.ShareRoleViewer || todo.UserID == userID {
+		return &todo, nil
+	}
+	var count int64
+	err = r.db.WithContext(ctx).Unscoped().Model(&models.Todo{}).Scopes(r.accessibleBy(userID, role)).Where("todos.id = ?", id).Count(&count).Error
+	if err != nil {
+		r.log.Error("failed to check todo access", err, "id", id)
+		return nil, appErrors.DatabaseError("failed to check todo access", err)
+	}
+	if count == 0 {
+		r.log.Warn("todo access denied", "id", id, "userID", userID, "role", role)
+		return nil, appErrors.AuthorizationError(fmt.Sprintf("you do not have %s access to todo %d", role, id), nil)
+	}
+	return &todo, nil
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"golang.org/x/net/context"
+	"gorm.io/gorm"
+)
+
+// ActivityRepository stores the history of changes to todos
+type ActivityRepository interface {
+	RecordActivity(ctx context.Context, activity *models.Activity) error
+	GetActivity(ctx context.Context, userID, todoID uint, offset, limit int) ([]models.Activity, int64, error)
+}
+
+type gormActivityRepository struct {
+	todoAccess
+}
+
+func NewActivityRepository(db *gorm.DB, log logger.Logger) ActivityRepository {
+	return &gormActivityRepository{
+		todoAccess: todoAccess{db: db, log: log},
+	}
+}
+
+func (r *gormActivityRepository) RecordActivity(ctx context.Context, activity *models.Activity) error {
+	if err := r.db.WithContext(ctx).Create(activity).Error; err != nil {
+		r.log.Error("failed to record activity", err, "todoID", activity.TodoID, "action", activity.Action)
+		return appErrors.DatabaseError("failed to record activity", err)
+	}
+	return nil
+}
+
+// activity on a todo, newest first; the history of a deleted todo stays readable
+func (r *gormActivityRepository) GetActivity(ctx context.Context, userID, todoID uint, offset, limit int) ([]models.Activity, int64, error) {
+	if _, err := r.findAccessible(ctx, r.db.Unscoped(), userID, todoID, models.ShareRoleViewer); err != nil {
+		return nil, 0, err
+	}
+	var activities []models.Activity
+	var totalCount int64
+	if err := r.db.WithContext(ctx).Model(&models.Activity{}).Where("todo_id = ?", todoID).Count(&totalCount).Error; err != nil {
+		r.log.Error("Repository: Failed to count activity", err, "todoID", todoID)
+		return nil, 0, appErrors.DatabaseError("failed to count activity", err)
+	}
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", todoID).Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&activities).Error; err != nil {
+		r.log.Error("Repository: Failed to fetch activity", err, "todoID", todoID)
+		return nil, 0, appErrors.DatabaseError("failed to fetch activity", err)
+	}
+	return activities, totalCount, nil
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"golang.org/x/net/context"
+	"gorm.io/gorm"
+)
+
+// AttachmentRepository stores the metadata of files attached to todos; the files
+// themselves live in storage
+type AttachmentRepository interface {
+	CreateAttachment(ctx context.Context, userID uint, attachment *models.Attachment) error
+	GetAttachments(ctx context.Context, userID, todoID uint) ([]models.Attachment, error)
+	GetAttachment(ctx context.Context, userID, todoID, attachmentID uint) (*models.Attachment, error)
+	DeleteAttachment(ctx context.Context, userID uint, attachment *models.Attachment) error
+}
+
+type gormAttachmentRepository struct {
+	todoAccess
+}
+
+func NewAttachmentRepository(db *gorm.DB, log logger.Logger) AttachmentRepository {
+	return &gormAttachmentRepository{
+		todoAccess: todoAccess{db: db, log: log},
+	}
+}
+
+// anyone who can edit a todo can attach files to it
+func (r *gormAttachmentRepository) CreateAttachment(ctx context.Context, userID uint, attachment *models.Attachment) error {
+	if _, err := r.findAccessible(ctx, r.db, userID, attachment.TodoID, models.ShareRoleEditor); err != nil {
+		return err
+	}
+	attachment.UserID = userID
+	if err := r.db.WithContext(ctx).Create(attachment).Error; err != nil {
+		r.log.Error("failed to create attachment", err, "todoID", attachment.TodoID)
+		return appErrors.DatabaseError("failed to save attachment", err)
+	}
+	return nil
+}
+
+// attachments on a todo, oldest first
+func (r *gormAttachmentRepository) GetAttachments(ctx context.Context, userID, todoID uint) ([]models.Attachment, error) {
+	if _, err := r.findAccessible(ctx, r.db, userID, todoID, models.ShareRoleViewer); err != nil {
+		return nil, err
+	}
+	var attachments []models.Attachment
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", todoID).Order("created_at ASC, id ASC").Find(&attachments).Error; err != nil {
+		r.log.Error("Repository: Failed to fetch attachments", err, "todoID", todoID)
+		return nil, appErrors.DatabaseError("failed to fetch attachments", err)
+	}
+	return attachments, nil
+}
+
+func (r *gormAttachmentRepository) GetAttachment(ctx context.Context, userID, todoID, attachmentID uint) (*models.Attachment, error) {
+	if _, err := r.findAccessible(ctx, r.db, userID, todoID, models.ShareRoleViewer); err != nil {
+		return nil, err
+	}
+	var attachment models.Attachment
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", todoID).First(&attachment, attachmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.NotFoundError(fmt.Sprintf("attachment with id %d not found", attachmentID), err)
+		}
+		r.log.Error("failed to get attachment", err, "id", attachmentID)
+		return nil, appErrors.DatabaseError("failed to get attachment", err)
+	}
+	return &attachment, nil
+}
+
+// the row is removed outright; the caller deletes the stored file
+func (r *gormAttachmentRepository) DeleteAttachment(ctx context.Context, userID uint, attachment *models.Attachment) error {
+	if _, err := r.findAccessible(ctx, r.db, userID, attachment.TodoID, models.ShareRoleEditor); err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Unscoped().Delete(attachment).Error; err != nil {
+		r.log.Error("failed to delete attachment", err, "id", attachment.ID)
+		return appErrors.DatabaseError("failed to delete attachment", err)
+	}
+	return nil
+}
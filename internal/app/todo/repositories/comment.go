@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"golang.org/x/net/context"
+	"gorm.io/gorm"
+)
+
+// CommentRepository stores the comments on todos
+type CommentRepository interface {
+	CreateComment(ctx context.Context, userID uint, comment *models.Comment) error
+	GetComments(ctx context.Context, userID, todoID uint, offset, limit int) ([]models.Comment, int64, error)
+	GetComment(ctx context.Context, userID, todoID, commentID uint) (*models.Comment, error)
+	UpdateComment(ctx context.Context, comment *models.Comment) error
+	DeleteComment(ctx context.Context, comment *models.Comment) error
+}
+
+type gormCommentRepository struct {
+	todoAccess
+}
+
+func NewCommentRepository(db *gorm.DB, log logger.Logger) CommentRepository {
+	return &gormCommentRepository{
+		todoAccess: todoAccess{db: db, log: log},
+	}
+}
+
+// anyone who can view a todo can comment on it
+func (r *gormCommentRepository) CreateComment(ctx context.Context, userID uint, comment *models.Comment) error {
+	if _, err := r.findAccessible(ctx, r.db, userID, comment.TodoID, models.ShareRoleViewer); err != nil {
+		return err
+	}
+	comment.UserID = userID
+	if err := r.db.WithContext(ctx).Create(comment).Error; err != nil {
+		r.log.Error("failed to create comment", err, "todoID", comment.TodoID)
+		return appErrors.DatabaseError("failed to create comment", err)
+	}
+	return nil
+}
+
+// comments on a todo, oldest first
+func (r *gormCommentRepository) GetComments(ctx context.Context, userID, todoID uint, offset, limit int) ([]models.Comment, int64, error) {
+	if _, err := r.findAccessible(ctx, r.db, userID, todoID, models.ShareRoleViewer); err != nil {
+		return nil, 0, err
+	}
+	var comments []models.Comment
+	var totalCount int64
+	if err := r.db.WithContext(ctx).Model(&models.Comment{}).Where("todo_id = ?", todoID).Count(&totalCount).Error; err != nil {
+		r.log.Error("Repository: Failed to count comments", err, "todoID", todoID)
+		return nil, 0, appErrors.DatabaseError("failed to count comments", err)
+	}
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", todoID).Order("created_at ASC, id ASC").Offset(offset).Limit(limit).Find(&comments).Error; err != nil {
+		r.log.Error("Repository: Failed to fetch comments", err, "todoID", todoID)
+		return nil, 0, appErrors.DatabaseError("failed to fetch comments", err)
+	}
+	return comments, totalCount, nil
+}
+
+func (r *gormCommentRepository) GetComment(ctx context.Context, userID, todoID, commentID uint) (*models.Comment, error) {
+	if _, err := r.findAccessible(ctx, r.db, userID, todoID, models.ShareRoleViewer); err != nil {
+		return nil, err
+	}
+	var comment models.Comment
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", todoID).First(&comment, commentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.NotFoundError(fmt.Sprintf("comment with id %d not found", commentID), err)
+		}
+		r.log.Error("failed to get comment", err, "id", commentID)
+		return nil, appErrors.DatabaseError("failed to get comment", err)
+	}
+	return &comment, nil
+}
+
+func (r *gormCommentRepository) UpdateComment(ctx context.Context, comment *models.Comment) error {
+	if err := r.db.WithContext(ctx).Model(comment).Update("body", comment.Body).Error; err != nil {
+		r.log.Error("failed to update comment", err, "id", comment.ID)
+		return appErrors.DatabaseError("failed to update comment", err)
+	}
+	return nil
+}
+
+func (r *gormCommentRepository) DeleteComment(ctx context.Context, comment *models.Comment) error {
+	if err := r.db.WithContext(ctx).Delete(comment).Error; err != nil {
+		r.log.Error("failed to delete comment", err, "id", comment.ID)
+		return appErrors.DatabaseError("failed to delete comment", err)
+	}
+	return nil
+}
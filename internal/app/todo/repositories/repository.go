@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"github.com/codetheuri/todolist/pkg/logger"
+	"golang.org/x/net/context"
+	"gorm.io/gorm"
+)
+
+// TodoRepositories groups the repositories of the todo module
+type TodoRepositories struct {
+	TodoRepo       TodoRepository
+	ShareRepo      ShareRepository
+	CommentRepo    CommentRepository
+	ActivityRepo   ActivityRepository
+	AttachmentRepo AttachmentRepository
+	WebhookRepo    WebhookRepository
+	TrashRepo      TrashRepository
+	StatsRepo      StatsRepository
+
+	db  *gorm.DB
+	log logger.Logger
+}
+
+// repo constructor
+func NewTodoRepositories(db *gorm.DB, log logger.Logger) *TodoRepositories {
+	return &TodoRepositories{
+		TodoRepo:       NewGormTodoRepository(db, log),
+		ShareRepo:      NewShareRepository(db, log),
+		CommentRepo:    NewCommentRepository(db, log),
+		ActivityRepo:   NewActivityRepository(db, log),
+		AttachmentRepo: NewAttachmentRepository(db, log),
+		WebhookRepo:    NewWebhookRepository(db, log),
+		TrashRepo:      NewTrashRepository(db, log),
+		StatsRepo:      NewStatsRepository(db, log),
+		db:             db,
+		log:            log,
+	}
+}
+
+// run fn with every repository bound to one database transaction; fn must only use txRepos
+func (r *TodoRepositories) Transaction(ctx context.Context, fn func(txRepos *TodoRepositories) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewTodoRepositories(tx, r.log))
+	})
+}
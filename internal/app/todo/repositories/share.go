@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"golang.org/x/net/context"
+	"gorm.io/gorm"
+)
+
+// ShareRepository stores share grants and invitations on todos
+type ShareRepository interface {
+	SaveShare(ctx context.Context, share *models.TodoShare) error
+	GetShare(ctx context.Context, id uint) (*models.TodoShare, error)
+	GetShareByEmail(ctx context.Context, todoID uint, email string) (*models.TodoShare, error)
+	GetShareByTokenHash(ctx context.Context, tokenHash string) (*models.TodoShare, error)
+	GetTodoShares(ctx context.Context, ownerID, todoID uint) ([]models.TodoShare, error)
+	GetInvitations(ctx context.Context, email string, now time.Time) ([]models.TodoShare, error)
+	DeleteShare(ctx context.Context, share *models.TodoShare) error
+	CopyShares(ctx context.Context, fromTodoID, toTodoID uint) error
+	// the owner and accepted grantees of a todo, deleted or not
+	GetTodoAudience(ctx context.Context, id uint) ([]uint, error)
+}
+
+type gormShareRepository struct {
+	db  *gorm.DB
+	log logger.Logger
+}
+
+func NewShareRepository(db *gorm.DB, log logger.Logger) ShareRepository {
+	return &gormShareRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// create or update a share
+func (r *gormShareRepository) SaveShare(ctx context.Context, share *models.TodoShare) error {
+	if err := r.db.WithContext(ctx).Omit("Todo").Save(share).Error; err != nil {
+		r.log.Error("failed to save todo share", err, "todoID", share.TodoID)
+		return appErrors.DatabaseError("failed to save share", err)
+	}
+	return nil
+}
+
+func (r *gormShareRepository) GetShare(ctx context.Context, id uint) (*models.TodoShare, error) {
+	return r.findShare(r.db.WithContext(ctx).Where("id = ?", id))
+}
+
+func (r *gormShareRepository) GetShareByEmail(ctx context.Context, todoID uint, email string) (*models.TodoShare, error) {
+	return r.findShare(r.db.WithContext(ctx).Where("todo_id = ? AND email = ?", todoID, email))
+}
+
+func (r *gormShareRepository) GetShareByTokenHash(ctx context.Context, tokenHash string) (*models.TodoShare, error) {
+	return r.findShare(r.db.WithContext(ctx).Where("token_hash = ?", tokenHash))
+}
+
+func (r *gormShareRepository) findShare(query *gorm.DB) (*models.TodoShare, error) {
+	var share models.TodoShare
+	if err := query.Preload("Todo").First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.NotFoundError("share not found", err)
+		}
+		r.log.Error("failed to get todo share", err)
+		return nil, appErrors.DatabaseError("failed to get share", err)
+	}
+	return &share, nil
+}
+
+// subtasks are shared through their parent, so grantees come from the top-level todo
+func (r *gormShareRepository) GetTodoAudience(ctx context.Context, id uint) ([]uint, error) {
+	var todo models.Todo
+	if err := r.db.WithContext(ctx).Unscoped().Select("id", "user_id", "parent_id").First(&todo, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.NotFoundError(fmt.Sprintf("todo with id %d not found", id), err)
+		}
+		r.log.Error("failed to load todo audience", err, "id", id)
+		return nil, appErrors.DatabaseError("failed to load todo audience", err)
+	}
+	rootID := todo.ID
+	if todo.ParentID != nil {
+		rootID = *todo.ParentID
+	}
+	var grantees []uint
+	if err := r.db.WithContext(ctx).Model(&models.TodoShare{}).
+		Where("todo_id = ? AND accepted_at IS NOT NULL AND user_id IS NOT NULL", rootID).
+		Pluck("user_id", &grantees).Error; err != nil {
+		r.log.Error("failed to load todo grantees", err, "id", id)
+		return nil, appErrors.DatabaseError("failed to load todo audience", err)
+	}
+	return append([]uint{todo.UserID}, grantees...), nil
+}
+
+// all shares and pending invitations on a todo, for its owner
+func (r *gormShareRepository) GetTodoShares(ctx context.Context, ownerID, todoID uint) ([]models.TodoShare, error) {
+	var shares []models.TodoShare
+	if err := r.db.WithContext(ctx).Where("owner_id = ? AND todo_id = ?", ownerID, todoID).Order("id ASC").Find(&shares).Error; err != nil {
+		r.log.Error("failed to list todo shares", err, "todoID", todoID)
+		return nil, appErrors.DatabaseError("failed to list shares", err)
+	}
+	return shares, nil
+}
+
+// unexpired invitations sent to an email address that have not been accepted yet
+func (r *gormShareRepository) GetInvitations(ctx context.Context, email string, now time.Time) ([]models.TodoShare, error) {
+	var shares []models.TodoShare
+	err := r.db.WithContext(ctx).Preload("Todo").
+		Where("email = ? AND accepted_at IS NULL AND expires_at > ?", email, now).
+		Order("id ASC").Find(&shares).Error
+	if err != nil {
+		r.log.Error("failed to list invitations", err)
+		return nil, appErrors.DatabaseError("failed to list invitations", err)
+	}
+	return shares, nil
+}
+
+func (r *gormShareRepository) DeleteShare(ctx context.Context, share *models.TodoShare) error {
+	if err := r.db.WithContext(ctx).Delete(share).Error; err != nil {
+		r.log.Error("failed to delete todo share", err, "id", share.ID)
+		return appErrors.DatabaseError("failed to delete share", err)
+	}
+	return nil
+}
+
+// carry accepted grants over to another todo, e.g. the next occurrence of a recurring todo
+func (r *gormShareRepository) CopyShares(ctx context.Context, fromTodoID, toTodoID uint) error {
+	var shares []models.TodoShare
+	if err := r.db.WithContext(ctx).Where("todo_id = ? AND accepted_at IS NOT NULL", fromTodoID).Find(&shares).Error; err != nil {
+		r.log.Error("failed to load shares to copy", err, "todoID", fromTodoID)
+		return appErrors.DatabaseError("failed to copy shares", err)
+	}
+	for _, share := range shares {
+		copied := share
+		copied.Model = gorm.Model{}
+		copied.TodoID = toTodoID
+		copied.Todo = models.Todo{}
+		// the copy is already accepted, so its token only has to be unique
+		copied.TokenHash = hashedCopyToken(share.TokenHash, toTodoID)
+		if err := r.db.WithContext(ctx).Create(&copied).Error; err != nil {
+			r.log.Error("failed to copy share", err, "todoID", toTodoID)
+			return appErrors.DatabaseError("failed to copy shares", err)
+		}
+	}
+	return nil
+}
+
+func hashedCopyToken(tokenHash string, todoID uint) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", tokenHash, todoID)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"golang.org/x/net/context"
+	"gorm.io/gorm"
+)
+
+// StatsRepository computes aggregates over the user's own top-level todos
+type StatsRepository interface {
+	GetTodoCounts(ctx context.Context, userID uint, now time.Time) (*models.TodoCounts, error)
+	GetStatsTimeline(ctx context.Context, userID uint, bucket, from, to string) ([]models.StatsBucket, error)
+	GetTagStats(ctx context.Context, userID uint, now time.Time) ([]models.TagStats, error)
+}
+
+type gormStatsRepository struct {
+	db  *gorm.DB
+	log logger.Logger
+}
+
+func NewStatsRepository(db *gorm.DB, log logger.Logger) StatsRepository {
+	return &gormStatsRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// statistics cover the user's own top-level todos; subtasks are checklist items
+// and count towards their parent's progress instead
+func ownTopLevel(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("todos.user_id = ? AND todos.parent_id IS NULL", userID)
+	}
+}
+
+func (r *gormStatsRepository) GetTodoCounts(ctx context.Context, userID uint, now time.Time) (*models.TodoCounts, error) {
+	var counts models.TodoCounts
+	err := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(ownTopLevel(userID)).
+		Select("COUNT(*) AS total, "+
+			"COALESCE(SUM(CASE WHEN completed THEN 1 ELSE 0 END), 0) AS completed, "+
+			"COALESCE(SUM(CASE WHEN NOT completed AND due_at < ? THEN 1 ELSE 0 END), 0) AS overdue, "+
+			"AVG(CASE WHEN completed AND completed_at IS NOT NULL THEN "+r.secondsBetween("created_at", "completed_at")+" END) AS avg_completion_seconds", now).
+		Scan(&counts).Error
+	if err != nil {
+		r.log.Error("Repository: Failed to count todos for stats", err, "userID", userID)
+		return nil, appErrors.DatabaseError("failed to compute todo statistics", err)
+	}
+	return &counts, nil
+}
+
+// the timeline between the buckets starting on from and to (YYYY-MM-DD), in date
+// order; periods with no activity are left out
+func (r *gormStatsRepository) GetStatsTimeline(ctx context.Context, userID uint, bucket, from, to string) ([]models.StatsBucket, error) {
+	created, err := r.bucketExpr(bucket, "created_at")
+	if err != nil {
+		return nil, err
+	}
+	completed, err := r.bucketExpr(bucket, "completed_at")
+	if err != nil {
+		return nil, err
+	}
+
+	var createdRows []models.StatsBucket
+	err = r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(ownTopLevel(userID)).
+		Select(created+" AS start, COUNT(*) AS created, COALESCE(SUM(CASE WHEN completed THEN 1 ELSE 0 END), 0) AS created_completed").
+		Where(created+" BETWEEN ? AND ?", from, to).
+		Group("start").Scan(&createdRows).Error
+	if err != nil {
+		r.log.Error("Repository: Failed to bucket created todos", err, "userID", userID)
+		return nil, appErrors.DatabaseError("failed to compute todo statistics", err)
+	}
+	var completedRows []models.StatsBucket
+	err = r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(ownTopLevel(userID)).
+		Select(completed+" AS start, COUNT(*) AS completed").
+		Where("completed AND completed_at IS NOT NULL").
+		Where(completed+" BETWEEN ? AND ?", from, to).
+		Group("start").Scan(&completedRows).Error
+	if err != nil {
+		r.log.Error("Repository: Failed to bucket completed todos", err, "userID", userID)
+		return nil, appErrors.DatabaseError("failed to compute todo statistics", err)
+	}
+
+	byStart := make(map[string]*models.StatsBucket, len(createdRows))
+	for i := range createdRows {
+		byStart[createdRows[i].Start] = &createdRows[i]
+	}
+	for _, row := range completedRows {
+		if b, ok := byStart[row.Start]; ok {
+			b.Completed = row.Completed
+			continue
+		}
+		createdRows = append(createdRows, row)
+	}
+	sort.Slice(createdRows, func(i, j int) bool { return createdRows[i].Start < createdRows[j].Start })
+	return createdRows, nil
+}
+
+// per-tag counts over the user's tags, most used first
+func (r *gormStatsRepository) GetTagStats(ctx context.Context, userID uint, now time.Time) ([]models.TagStats, error) {
+	var stats []models.TagStats
+	err := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(ownTopLevel(userID)).
+		Select("tags.name AS tag, COUNT(*) AS total, "+
+			"COALESCE(SUM(CASE WHEN todos.completed THEN 1 ELSE 0 END), 0) AS completed, "+
+			"COALESCE(SUM(CASE WHEN NOT todos.completed AND todos.due_at < ? THEN 1 ELSE 0 END), 0) AS overdue", now).
+		Joins("JOIN todo_tags ON todo_tags.todo_id = todos.id").
+		Joins("JOIN tags ON tags.id = todo_tags.tag_id AND tags.deleted_at IS NULL").
+		Group("tags.name").
+		Order("total DESC, tags.name ASC").
+		Scan(&stats).Error
+	if err != nil {
+		r.log.Error("Repository: Failed to compute tag stats", err, "userID", userID)
+		return nil, appErrors.DatabaseError("failed to compute tag statistics", err)
+	}
+	return stats, nil
+}
+
+// SQL for the first day (YYYY-MM-DD, UTC) of the bucket a timestamp column falls
+// in; weeks start on Monday. The formats go through Sprintf, hence the %%.
+func (r *gormStatsRepository) bucketExpr(bucket, column string) (string, error) {
+	var formats map[string]string
+	switch r.db.Dialector.Name() {
+	case "sqlite":
+		formats = map[string]string{
+			models.StatsBucketDay:   "strftime('%%Y-%%m-%%d', %[1]s)",
+			models.StatsBucketWeek:  "date(%[1]s, 'weekday 0', '-6 days')",
+			models.StatsBucketMonth: "strftime('%%Y-%%m-01', %[1]s)",
+		}
+	case "mysql":
+		formats = map[string]string{
+			models.StatsBucketDay:   "DATE_FORMAT(%[1]s, '%%Y-%%m-%%d')",
+			models.StatsBucketWeek:  "DATE_FORMAT(DATE_SUB(%[1]s, INTERVAL WEEKDAY(%[1]s) DAY), '%%Y-%%m-%%d')",
+			models.StatsBucketMonth: "DATE_FORMAT(%[1]s, '%%Y-%%m-01')",
+		}
+	case "postgres":
+		formats = map[string]string{
+			models.StatsBucketDay:   "to_char(date_trunc('day', %[1]s AT TIME ZONE 'UTC'), 'YYYY-MM-DD')",
+			models.StatsBucketWeek:  "to_char(date_trunc('week', %[1]s AT TIME ZONE 'UTC'), 'YYYY-MM-DD')",
+			models.StatsBucketMonth: "to_char(date_trunc('month', %[1]s AT TIME ZONE 'UTC'), 'YYYY-MM-DD')",
+		}
+	default:
+		return "", appErrors.InternalServerError(fmt.Sprintf("statistics are not supported on %s", r.db.Dialector.Name()), nil)
+	}
+	format, ok := formats[bucket]
+	if !ok {
+		return "", appErrors.ValidationError("invalid stats bucket", nil, map[string]string{"bucket": "Must be one of day, week, month"})
+	}
+	return fmt.Sprintf(format, "todos."+column), nil
+}
+
+// SQL for the number of seconds between two timestamp columns
+func (r *gormStatsRepository) secondsBetween(from, to string) string {
+	switch r.db.Dialector.Name() {
+	case "mysql":
+		return fmt.Sprintf("TIMESTAMPDIFF(SECOND, todos.%s, todos.%s)", from, to)
+	case "postgres":
+		return fmt.Sprintf("EXTRACT(EPOCH FROM (todos.%s - todos.%s))", to, from)
+	}
+	return fmt.Sprintf("(julianday(todos.%s) - julianday(todos.%s)) * 86400", to, from)
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/codetheuri/todolist/internal/app/todo/models"
 	appErrors "github.com/codetheuri/todolist/pkg/errors"
@@ -13,65 +14,120 @@ import (
 
 // define the TodoRepository interface
 type TodoRepository interface {
-	CreateTodo(ctx context.Context, todo *models.Todo) (*models.Todo, error)
-	GetTodoByID(id uint) (*models.Todo, error)
-	GetAllTodos(ctx context.Context, offset, limit int) ([]models.Todo, int64, error)
-	UpdateTodo(todo *models.Todo) (*models.Todo, error)
-	GetAllIncludingDeleted(ctx context.Context, offset, limit int) ([]models.Todo, int64, error)
-	SoftDeleteTodo(id uint) error
-	RestoreTodo(id uint) error
-	HardDeleteTodo(id uint) error
+	CreateTodo(ctx context.Context, userID uint, todo *models.Todo) (*models.Todo, error)
+	GetTodoByID(ctx context.Context, userID, id uint) (*models.Todo, error)
+	GetAllTodos(ctx context.Context, userID uint, offset, limit int) ([]models.Todo, int64, error)
+	// saves only if the stored version still equals todo.Version, then bumps it
+	UpdateTodo(ctx context.Context, userID uint, todo *models.Todo) (*models.Todo, error)
+	GetAllIncludingDeleted(ctx context.Context, userID uint, offset, limit int) ([]models.Todo, int64, error)
+	// call fn with the user's own todos in ID order, batchSize at a time
+	StreamOwnedTodos(ctx context.Context, userID uint, batchSize int, fn func(todos []models.Todo) error) error
+	// the current version of a todo the user can view, deleted or not
+	GetTodoVersion(ctx context.Context, userID, id uint) (uint, error)
+	// delete, restore and hard delete only apply at the given version; 0 skips the check
+	SoftDeleteTodo(ctx context.Context, userID, id, version uint) error
+	RestoreTodo(ctx context.Context, userID, id, version uint) error
+	// returns the storage keys of the attachments deleted along with the todo
+	HardDeleteTodo(ctx context.Context, userID, id, version uint) ([]string, error)
+
+	// fail unless the user may act on the todo with the given role
+	CheckAccess(ctx context.Context, userID, todoID uint, role string) error
+
+	// subtasks
+	GetSubtasks(ctx context.Context, userID, parentID uint) ([]models.Todo, error)
+	NextSubtaskPosition(ctx context.Context, userID, parentID uint) (int, error)
+	ReorderSubtasks(ctx context.Context, userID, parentID uint, orderedIDs []uint) error
+	GetSubtaskProgress(ctx context.Context, userID uint, parentIDs []uint) (map[uint]models.SubtaskProgress, error)
+	// bumps the parent's version along with its subtasks', and parent.Version with it
+	SetSubtasksCompleted(ctx context.Context, userID uint, parent *models.Todo, completed bool, completedAt *time.Time) error
+	MoveTodo(ctx context.Context, userID uint, todo *models.Todo, parentID *uint, position int) error
+
+	// tags
+	AddTags(ctx context.Context, userID uint, todo *models.Todo, names []string) error
+	RemoveTags(ctx context.Context, userID uint, todo *models.Todo, names []string) error
 }
 
 // implement the TodoRepository interface
 type gormTodoRepository struct {
-	db  *gorm.DB
-	log logger.Logger
+	todoAccess
 }
 
 // NewGormTodoRepository creates a new instance of gormTodoRepository
 func NewGormTodoRepository(db *gorm.DB, log logger.Logger) TodoRepository {
 	return &gormTodoRepository{
-		db:  db,
-		log: log,
+		todoAccess: todoAccess{db: db, log: log},
 	}
 }
 
-// create a new todo
-func (r *gormTodoRepository) CreateTodo(ctx context.Context, todo *models.Todo) (*models.Todo, error) {
+// create a new todo; subtasks need edit access to their parent
+func (r *gormTodoRepository) CreateTodo(ctx context.Context, userID uint, todo *models.Todo) (*models.Todo, error) {
+	if todo.ParentID != nil {
+		if _, err := r.findAccessible(ctx, r.db, userID, *todo.ParentID, models.ShareRoleEditor); err != nil {
+			return nil, err
+		}
+	} else if todo.UserID != userID {
+		return nil, appErrors.AuthorizationError("todos can only be created for yourself", nil)
+	}
 	if err := r.db.WithContext(ctx).Create(todo).Error; err != nil {
 		r.log.Error("failed to create todo", err, "todo", todo)
 		return nil, appErrors.DatabaseError("failed to create todo", err)
 	}
+	if err := r.bumpVersions(ctx, r.db, todo.ParentID); err != nil {
+		return nil, err
+	}
 	return todo, nil
 }
 
-// retrieve a todo by ID
-func (r *gormTodoRepository) GetTodoByID(id uint) (*models.Todo, error) {
-	var todo models.Todo
-	if err := r.db.First(&todo, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			r.log.Warn("todo not found", "id", id)
-			return nil, appErrors.NotFoundError(fmt.Sprintf("todo with id %d not found", id), err)
+// bump the version of each given todo; nil IDs (no parent) are skipped
+func (r *gormTodoRepository) bumpVersions(ctx context.Context, db *gorm.DB, ids ...*uint) error {
+	var todoIDs []uint
+	for _, id := range ids {
+		if id != nil {
+			todoIDs = append(todoIDs, *id)
 		}
-		r.log.Error("failed to get todo by id", err, "id", id)
-		return nil, appErrors.DatabaseError(fmt.Sprintf("failed to get todo by id %d", id), err)
+	}
+	if len(todoIDs) == 0 {
+		return nil
+	}
+	err := db.WithContext(ctx).Unscoped().Model(&models.Todo{}).Where("id IN ?", todoIDs).
+		UpdateColumn("version", gorm.Expr("version + 1")).Error
+	if err != nil {
+		r.log.Error("failed to bump todo versions", err, "ids", todoIDs)
+		return appErrors.DatabaseError("failed to update todo version", err)
+	}
+	return nil
+}
+
+func staleVersionError(id uint) error {
+	return appErrors.PreconditionFailedError(fmt.Sprintf("todo %d has been changed since it was loaded; fetch it again and retry", id), nil)
+}
+
+func (r *gormTodoRepository) CheckAccess(ctx context.Context, userID, todoID uint, role string) error {
+	_, err := r.findAccessible(ctx, r.db, userID, todoID, role)
+	return err
+}
+
+// retrieve a todo by ID the user can view
+func (r *gormTodoRepository) GetTodoByID(ctx context.Context, userID, id uint) (*models.Todo, error) {
+	todo, err := r.findAccessible(ctx, r.db.Preload("Tags"), userID, id, models.ShareRoleViewer)
+	if err != nil {
+		return nil, err
 	}
 	r.log.Debug("todo retrieved successfully", "id", id)
-	return &todo, nil
+	return todo, nil
 }
 
-// retrieve all todos
-func (r *gormTodoRepository) GetAllTodos(ctx context.Context, offset, limit int) ([]models.Todo, int64, error) {
+// retrieve all todos the user owns or has been shared
+func (r *gormTodoRepository) GetAllTodos(ctx context.Context, userID uint, offset, limit int) ([]models.Todo, int64, error) {
 	var todos []models.Todo
 	var totalCount int64
-	//count records
-	if err := r.db.WithContext(ctx).Model(&models.Todo{}).Count(&totalCount).Error; err != nil {
+	//count records (subtasks are listed under their parent)
+	if err := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(r.accessibleBy(userID, models.ShareRoleViewer)).Where("parent_id IS NULL").Count(&totalCount).Error; err != nil {
 		r.log.Error("Repository: Failed to count todos", err)
 		return nil, 0, err
 	}
 	//fetch todos with pagination
-	if err := r.db.WithContext(ctx).Offset(offset).Limit(limit).Find(&todos).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Tags").Scopes(r.accessibleBy(userID, models.ShareRoleViewer)).Where("parent_id IS NULL").Offset(offset).Limit(limit).Find(&todos).Error; err != nil {
 		r.log.Error("Repository: Failed to fetch all todos", err)
 		return nil, 0, err
 	}
@@ -80,36 +136,47 @@ func (r *gormTodoRepository) GetAllTodos(ctx context.Context, offset, limit int)
 }
 
 // update a todo by ID
-func (r *gormTodoRepository) UpdateTodo(todo *models.Todo) (*models.Todo, error) {
-	existingTodo := &models.Todo{}
-	if err := r.db.First(existingTodo, todo.ID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			r.log.Warn("todo not found for update", "id", todo.ID)
-			return nil, appErrors.NotFoundError(fmt.Sprintf("todo with id %d not found", todo.ID), err)
-		}
-		r.log.Error("failed to find todo for update", err, "id", todo.ID)
-		return nil, appErrors.DatabaseError(fmt.Sprintf("failed to find todo with id %d for update", todo.ID), err)
+func (r *gormTodoRepository) UpdateTodo(ctx context.Context, userID uint, todo *models.Todo) (*models.Todo, error) {
+	existingTodo, err := r.findAccessible(ctx, r.db, userID, todo.ID, models.ShareRoleEditor)
+	if err != nil {
+		return nil, err
 	}
-	existingTodo.Title = todo.Title
-	existingTodo.Description = todo.Description
-	existingTodo.Completed = todo.Completed
-
-	if err := r.db.Save(existingTodo).Error; err != nil {
-		r.log.Error("failed to update todo", err, "todo", todo)
-		return nil, appErrors.DatabaseError("failed to update todo", err)
+	result := r.db.WithContext(ctx).Model(existingTodo).Where("version = ?", todo.Version).Updates(map[string]interface{}{
+		"title":              todo.Title,
+		"description":        todo.Description,
+		"completed":          todo.Completed,
+		"completed_at":       todo.CompletedAt,
+		"due_at":             todo.DueAt,
+		"r_rule":             todo.RRule,
+		"timezone":           todo.Timezone,
+		"recurrence_start":   todo.RecurrenceStart,
+		"next_occurrence_id": todo.NextOccurrenceID,
+		"version":            gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		r.log.Error("failed to update todo", result.Error, "todo", todo)
+		return nil, appErrors.DatabaseError("failed to update todo", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		r.log.Warn("stale todo update rejected", "id", todo.ID, "version", todo.Version)
+		return nil, staleVersionError(todo.ID)
+	}
+	todo.Version++
+	if err := r.bumpVersions(ctx, r.db, existingTodo.ParentID); err != nil {
+		return nil, err
 	}
 	r.log.Info("todo updated successfully", "id", existingTodo.ID)
 	return todo, nil
 }
-func (r *gormTodoRepository) GetAllIncludingDeleted(ctx context.Context, offset, limit int) ([]models.Todo, int64, error) {
+func (r *gormTodoRepository) GetAllIncludingDeleted(ctx context.Context, userID uint, offset, limit int) ([]models.Todo, int64, error) {
 	var todos []models.Todo
 	var totalCount int64
-	if err := r.db.Unscoped().WithContext(ctx).Model(&models.Todo{}).Count(&totalCount).Error; err != nil {
+	if err := r.db.Unscoped().WithContext(ctx).Model(&models.Todo{}).Scopes(r.accessibleBy(userID, models.ShareRoleViewer)).Count(&totalCount).Error; err != nil {
 		r.log.Error("Repository: Failed to count todos", err)
 		return nil, 0, err
 	}
 	//fetch todos with pagination
-	if err := r.db.WithContext(ctx).Offset(offset).Limit(limit).Find(&todos).Error; err != nil {
+	if err := r.db.Unscoped().WithContext(ctx).Preload("Tags").Scopes(r.accessibleBy(userID, models.ShareRoleViewer)).Offset(offset).Limit(limit).Find(&todos).Error; err != nil {
 		r.log.Error("Repository: Failed to fetch all todos", err)
 		return nil, 0, err
 	}
@@ -117,57 +184,323 @@ func (r *gormTodoRepository) GetAllIncludingDeleted(ctx context.Context, offset,
 	return todos, totalCount, nil
 }
 
+func (r *gormTodoRepository) GetTodoVersion(ctx context.Context, userID, id uint) (uint, error) {
+	todo, err := r.findAccessible(ctx, r.db.Unscoped(), userID, id, models.ShareRoleViewer)
+	if err != nil {
+		return 0, err
+	}
+	return todo.Version, nil
+}
+
+// restrict a write to a todo at the given version (0 for any)
+func atVersion(query *gorm.DB, id, version uint) *gorm.DB {
+	query = query.Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+	return query
+}
+
 // delete a todo by ID
-func (r *gormTodoRepository) SoftDeleteTodo(id uint) error {
-	_, err := r.GetTodoByID(id)
+func (r *gormTodoRepository) SoftDeleteTodo(ctx context.Context, userID, id, version uint) error {
+	todo, err := r.findAccessible(ctx, r.db, userID, id, models.ShareRoleEditor)
 	if err != nil {
 		return err // if todo not found, return the error
 	}
-	if err := r.db.Delete(&models.Todo{}, id).Error; err != nil {
-		r.log.Error("failed to delete todo", err, "id", id)
-		return appErrors.DatabaseError("failed to delete todo", err)
+	result := atVersion(r.db.WithContext(ctx).Model(&models.Todo{}), id, version).
+		Updates(map[string]interface{}{"deleted_at": time.Now(), "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		r.log.Error("failed to delete todo", result.Error, "id", id)
+		return appErrors.DatabaseError("failed to delete todo", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return staleVersionError(id)
+	}
+	if err := r.bumpVersions(ctx, r.db, todo.ParentID); err != nil {
+		return err
 	}
 	r.log.Info("todo deleted successfully", "id", id)
 	return nil
 }
 
-func (r *gormTodoRepository) RestoreTodo(id uint) error {
-	var todo models.Todo
-	if err := r.db.Unscoped().First(&todo, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			r.log.Warn("todo not found for restore", "id", id)
-			return appErrors.NotFoundError(fmt.Sprintf("todo with id %d not found", id), err)
-		}
-		r.log.Error("failed to find todo for restore", err, "id", id)
-		return appErrors.DatabaseError(fmt.Sprintf("failed to find todo with id %d for restore", id), err)
-	}
-	todo.DeletedAt = gorm.DeletedAt{} // reset the DeletedAt field to restore the record
-	// if err := r.db.Model(&todo).Update("deleted_at", nil).Error; err != nil {
-	// 	r.log.Error("failed to restore todo", err, "id", id)
-	// 	return appErrors.DatabaseError("failed to restore todo", err)
-	// }
-	if err := r.db.Save(&todo).Error; err != nil {
-		r.log.Error("failed to restore todo", err, "id", id)
-		return appErrors.DatabaseError("failed to restore todo", err)
+func (r *gormTodoRepository) RestoreTodo(ctx context.Context, userID, id, version uint) error {
+	todo, err := r.findAccessible(ctx, r.db.Unscoped(), userID, id, models.ShareRoleEditor)
+	if err != nil {
+		return err
+	}
+	// reset the DeletedAt field to restore the record
+	result := atVersion(r.db.WithContext(ctx).Unscoped().Model(&models.Todo{}), id, version).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		r.log.Error("failed to restore todo", result.Error, "id", id)
+		return appErrors.DatabaseError("failed to restore todo", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return staleVersionError(id)
+	}
+	if err := r.bumpVersions(ctx, r.db, todo.ParentID); err != nil {
+		return err
 	}
 	r.log.Info("todo restored successfully", "id", id)
 	return nil
 }
-func (r *gormTodoRepository) HardDeleteTodo(id uint) error {
-	var todo models.Todo
-	if err := r.db.Unscoped().First(&todo, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			r.log.Warn("todo not found for hard delete", "id", id)
-			return appErrors.NotFoundError(fmt.Sprintf("todo with id %d not found", id), err)
-		}
-		r.log.Error("failed to find todo for hard delete", err, "id", id)
-		return appErrors.DatabaseError(fmt.Sprintf("failed to find todo with id %d for hard delete", id), err)
+
+func (r *gormTodoRepository) StreamOwnedTodos(ctx context.Context, userID uint, batchSize int, fn func(todos []models.Todo) error) error {
+	var batch []models.Todo
+	var fnErr error
+	err := r.db.WithContext(ctx).Preload("Tags").Where("user_id = ?", userID).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			fnErr = fn(batch)
+			return fnErr
+		}).Error
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		r.log.Error("Repository: Failed to stream todos", err, "userID", userID)
+		return appErrors.DatabaseError("failed to fetch todos", err)
 	}
+	return nil
+}
 
-	if err := r.db.Unscoped().Delete(&todo).Error; err != nil {
+// only the owner can permanently delete a todo
+func (r *gormTodoRepository) HardDeleteTodo(ctx context.Context, userID, id, version uint) ([]string, error) {
+	todo, err := r.findAccessible(ctx, r.db.Unscoped(), userID, id, accessOwner)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && todo.Version != version {
+		return nil, staleVersionError(id)
+	}
+
+	var storageKeys []string
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// comments, history, shares and attachments of the todo and its subtasks go with it
+		todoIDs := []uint{todo.ID}
+		var subtaskIDs []uint
+		if err := tx.Unscoped().Model(&models.Todo{}).Where("parent_id = ?", todo.ID).Pluck("id", &subtaskIDs).Error; err != nil {
+			return err
+		}
+		todoIDs = append(todoIDs, subtaskIDs...)
+		if err := tx.Unscoped().Model(&models.Attachment{}).Where("todo_id IN ?", todoIDs).Pluck("storage_key", &storageKeys).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.Comment{}, &models.Activity{}, &models.TodoShare{}, &models.Attachment{}} {
+			if err := tx.Unscoped().Where("todo_id IN ?", todoIDs).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		// the tag links of the subtasks too: the join table has no ON DELETE action, so they
+		// would block the cascade that deletes the subtasks with their parent
+		if err := tx.Exec("DELETE FROM todo_tags WHERE todo_id IN ?", todoIDs).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("version = ?", todo.Version).Delete(todo)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleVersionError(id)
+		}
+		return r.bumpVersions(ctx, tx, todo.ParentID)
+	})
+	var appErr appErrors.AppError
+	if errors.As(err, &appErr) {
+		return nil, err
+	}
+	if err != nil {
 		r.log.Error("failed to hard delete todo", err, "id", id)
-		return appErrors.DatabaseError("failed to hard delete todo", err)
+		return nil, appErrors.DatabaseError("failed to hard delete todo", err)
 	}
 	r.log.Info("todo hard deleted successfully", "id", id)
+	return storageKeys, nil
+}
+
+// retrieve the subtasks of a todo in their display order
+func (r *gormTodoRepository) GetSubtasks(ctx context.Context, userID, parentID uint) ([]models.Todo, error) {
+	if _, err := r.findAccessible(ctx, r.db, userID, parentID, models.ShareRoleViewer); err != nil {
+		return nil, err
+	}
+	var subtasks []models.Todo
+	if err := r.db.WithContext(ctx).Preload("Tags").Where("parent_id = ?", parentID).Order("position ASC, id ASC").Find(&subtasks).Error; err != nil {
+		r.log.Error("Repository: Failed to fetch subtasks", err, "parentID", parentID)
+		return nil, appErrors.DatabaseError("failed to fetch subtasks", err)
+	}
+	return subtasks, nil
+}
+
+// position for a subtask appended at the end of its parent's checklist
+func (r *gormTodoRepository) NextSubtaskPosition(ctx context.Context, userID, parentID uint) (int, error) {
+	if _, err := r.findAccessible(ctx, r.db, userID, parentID, models.ShareRoleViewer); err != nil {
+		return 0, err
+	}
+	var maxPosition *int
+	if err := r.db.WithContext(ctx).Model(&models.Todo{}).Where("parent_id = ?", parentID).Select("MAX(position)").Scan(&maxPosition).Error; err != nil {
+		r.log.Error("Repository: Failed to compute next subtask position", err, "parentID", parentID)
+		return 0, appErrors.DatabaseError("failed to compute subtask position", err)
+	}
+	if maxPosition == nil {
+		return 0, nil
+	}
+	return *maxPosition + 1, nil
+}
+
+// rewrite subtask positions to follow orderedIDs; every subtask of the parent must be listed
+func (r *gormTodoRepository) ReorderSubtasks(ctx context.Context, userID, parentID uint, orderedIDs []uint) error {
+	if _, err := r.findAccessible(ctx, r.db, userID, parentID, models.ShareRoleEditor); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existingIDs []uint
+		if err := tx.Model(&models.Todo{}).Where("parent_id = ?", parentID).Pluck("id", &existingIDs).Error; err != nil {
+			r.log.Error("Repository: Failed to load subtasks for reorder", err, "parentID", parentID)
+			return appErrors.DatabaseError("failed to load subtasks", err)
+		}
+		if len(existingIDs) != len(orderedIDs) {
+			return appErrors.ValidationError("order must list every subtask exactly once", nil, nil)
+		}
+		known := make(map[uint]bool, len(existingIDs))
+		for _, id := range existingIDs {
+			known[id] = true
+		}
+		for position, id := range orderedIDs {
+			if !known[id] {
+				return appErrors.ValidationError(fmt.Sprintf("todo %d is not a subtask of todo %d", id, parentID), nil, nil)
+			}
+			delete(known, id)
+			if err := tx.Model(&models.Todo{}).Where("id = ?", id).Updates(map[string]interface{}{"position": position, "version": gorm.Expr("version + 1")}).Error; err != nil {
+				r.log.Error("Repository: Failed to update subtask position", err, "id", id)
+				return appErrors.DatabaseError("failed to reorder subtasks", err)
+			}
+		}
+		// the parent reports its subtasks in order
+		if err := r.bumpVersions(ctx, tx, &parentID); err != nil {
+			return err
+		}
+		r.log.Info("subtasks reordered successfully", "parentID", parentID)
+		return nil
+	})
+}
+
+// subtask counts for each of the given parents, keyed by parent ID
+func (r *gormTodoRepository) GetSubtaskProgress(ctx context.Context, userID uint, parentIDs []uint) (map[uint]models.SubtaskProgress, error) {
+	progress := make(map[uint]models.SubtaskProgress, len(parentIDs))
+	if len(parentIDs) == 0 {
+		return progress, nil
+	}
+	var rows []models.SubtaskProgress
+	err := r.db.WithContext(ctx).Model(&models.Todo{}).
+		Select("parent_id, COUNT(*) AS total, SUM(CASE WHEN completed THEN 1 ELSE 0 END) AS completed").
+		Scopes(r.accessibleBy(userID, models.ShareRoleViewer)).
+		Where("parent_id IN ?", parentIDs).
+		Group("parent_id").
+		Scan(&rows).Error
+	if err != nil {
+		r.log.Error("Repository: Failed to compute subtask progress", err)
+		return nil, appErrors.DatabaseError("failed to compute subtask progress", err)
+	}
+	for _, row := range rows {
+		progress[row.ParentID] = row
+	}
+	return progress, nil
+}
+
+// mark every subtask of a parent as completed or not
+func (r *gormTodoRepository) SetSubtasksCompleted(ctx context.Context, userID uint, parent *models.Todo, completed bool, completedAt *time.Time) error {
+	parentID := parent.ID
+	if _, err := r.findAccessible(ctx, r.db, userID, parentID, models.ShareRoleEditor); err != nil {
+		return err
+	}
+	err := r.db.WithContext(ctx).Model(&models.Todo{}).
+		Where("parent_id = ?", parentID).
+		Updates(map[string]interface{}{"completed": completed, "completed_at": completedAt, "version": gorm.Expr("version + 1")}).Error
+	if err != nil {
+		r.log.Error("failed to cascade completion to subtasks", err, "parentID", parentID)
+		return appErrors.DatabaseError("failed to update subtasks", err)
+	}
+	if err := r.bumpVersions(ctx, r.db, &parentID); err != nil {
+		return err
+	}
+	parent.Version++
+	r.log.Info("subtask completion cascaded", "parentID", parentID, "completed", completed)
+	return nil
+}
+
+// move a todo under another parent (nil for top level) at the given position
+func (r *gormTodoRepository) MoveTodo(ctx context.Context, userID uint, todo *models.Todo, parentID *uint, position int) error {
+	if _, err := r.findAccessible(ctx, r.db, userID, todo.ID, models.ShareRoleEditor); err != nil {
+		return err
+	}
+	if parentID != nil {
+		if _, err := r.findAccessible(ctx, r.db, userID, *parentID, models.ShareRoleEditor); err != nil {
+			return err
+		}
+	}
+	err := r.db.WithContext(ctx).Model(todo).Updates(map[string]interface{}{"parent_id": parentID, "position": position, "version": gorm.Expr("version + 1")}).Error
+	if err != nil {
+		r.log.Error("failed to move todo", err, "id", todo.ID)
+		return appErrors.DatabaseError("failed to move todo", err)
+	}
+	if err := r.bumpVersions(ctx, r.db, todo.ParentID, parentID); err != nil {
+		return err
+	}
+	todo.ParentID = parentID
+	todo.Position = position
+	todo.Version++
+	return nil
+}
+
+// attach tags by name, creating the owner's tags on first use (collaborators tag in the owner's namespace)
+func (r *gormTodoRepository) AddTags(ctx context.Context, userID uint, todo *models.Todo, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	if _, err := r.findAccessible(ctx, r.db, userID, todo.ID, models.ShareRoleEditor); err != nil {
+		return err
+	}
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tag := models.Tag{UserID: todo.UserID, Name: name}
+		if err := r.db.WithContext(ctx).Where(models.Tag{UserID: todo.UserID, Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			r.log.Error("failed to find or create tag", err, "name", name)
+			return appErrors.DatabaseError("failed to save tag", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := r.db.WithContext(ctx).Model(todo).Association("Tags").Append(tags); err != nil {
+		r.log.Error("failed to tag todo", err, "id", todo.ID)
+		return appErrors.DatabaseError("failed to tag todo", err)
+	}
+	if err := r.bumpVersions(ctx, r.db, &todo.ID); err != nil {
+		return err
+	}
+	todo.Version++
+	return nil
+}
+
+// detach tags by name; the tags themselves are kept for reuse
+func (r *gormTodoRepository) RemoveTags(ctx context.Context, userID uint, todo *models.Todo, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	if _, err := r.findAccessible(ctx, r.db, userID, todo.ID, models.ShareRoleEditor); err != nil {
+		return err
+	}
+	var tags []models.Tag
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND name IN ?", todo.UserID, names).Find(&tags).Error; err != nil {
+		r.log.Error("failed to load tags", err, "id", todo.ID)
+		return appErrors.DatabaseError("failed to load tags", err)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Model(todo).Association("Tags").Delete(tags); err != nil {
+		r.log.Error("failed to untag todo", err, "id", todo.ID)
+		return appErrors.DatabaseError("failed to untag todo", err)
+	}
+	if err := r.bumpVersions(ctx, r.db, &todo.ID); err != nil {
+		return err
+	}
+	todo.Version++
 	return nil
 }
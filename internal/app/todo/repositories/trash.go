@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"golang.org/x/net/context"
+	"gorm.io/gorm"
+)
+
+// TrashRepository finds soft-deleted todos; restoring and purging them goes
+// through TodoRepository
+type TrashRepository interface {
+	GetTrash(ctx context.Context, userID uint, offset, limit int) ([]models.Todo, int64, error)
+	GetTrashedTodo(ctx context.Context, userID, id uint) (*models.Todo, error)
+	// IDs of the user's own trashed todos, subtasks first so that purging them in
+	// order never reaches a subtask whose parent is already gone
+	GetOwnedTrashIDs(ctx context.Context, userID uint) ([]uint, error)
+	// todos of any user deleted before the given time, oldest first
+	GetExpiredTrash(ctx context.Context, deletedBefore time.Time, limit int) ([]models.Todo, error)
+}
+
+type gormTrashRepository struct {
+	todoAccess
+}
+
+func NewTrashRepository(db *gorm.DB, log logger.Logger) TrashRepository {
+	return &gormTrashRepository{
+		todoAccess: todoAccess{db: db, log: log},
+	}
+}
+
+// the deleted todos the user can view, most recently deleted first
+func (r *gormTrashRepository) GetTrash(ctx context.Context, userID uint, offset, limit int) ([]models.Todo, int64, error) {
+	query := r.db.Unscoped().WithContext(ctx).Model(&models.Todo{}).
+		Scopes(r.accessibleBy(userID, models.ShareRoleViewer)).
+		Where("todos.deleted_at IS NOT NULL")
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		r.log.Error("Repository: Failed to count trashed todos", err, "userID", userID)
+		return nil, 0, appErrors.DatabaseError("failed to retrieve trash", err)
+	}
+	var todos []models.Todo
+	if err := query.Preload("Tags").Order("todos.deleted_at DESC, todos.id DESC").Offset(offset).Limit(limit).Find(&todos).Error; err != nil {
+		r.log.Error("Repository: Failed to fetch trashed todos", err, "userID", userID)
+		return nil, 0, appErrors.DatabaseError("failed to retrieve trash", err)
+	}
+	return todos, totalCount, nil
+}
+
+// a todo the user can view that is in the trash; one that is not deleted is a 404
+func (r *gormTrashRepository) GetTrashedTodo(ctx context.Context, userID, id uint) (*models.Todo, error) {
+	todo, err := r.findAccessible(ctx, r.db.Unscoped(), userID, id, models.ShareRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if !todo.DeletedAt.Valid {
+		return nil, appErrors.NotFoundError(fmt.Sprintf("todo with id %d is not in the trash", id), nil)
+	}
+	return todo, nil
+}
+
+func (r *gormTrashRepository) GetOwnedTrashIDs(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Unscoped().WithContext(ctx).Model(&models.Todo{}).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order(gorm.Expr("CASE WHEN parent_id IS NULL THEN 1 ELSE 0 END, id")).
+		Pluck("id", &ids).Error
+	if err != nil {
+		r.log.Error("Repository: Failed to list trashed todos", err, "userID", userID)
+		return nil, appErrors.DatabaseError("failed to retrieve trash", err)
+	}
+	return ids, nil
+}
+
+func (r *gormTrashRepository) GetExpiredTrash(ctx context.Context, deletedBefore time.Time, limit int) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := r.db.Unscoped().WithContext(ctx).Select("id", "user_id", "parent_id", "deleted_at").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Order("deleted_at ASC, id ASC").Limit(limit).Find(&todos).Error
+	if err != nil {
+		r.log.Error("Repository: Failed to find expired trash", err)
+		return nil, appErrors.DatabaseError("failed to find expired trash", err)
+	}
+	return todos, nil
+}
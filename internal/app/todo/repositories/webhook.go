@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"golang.org/x/net/context"
+	"gorm.io/gorm"
+)
+
+// WebhookRepository stores webhook subscriptions and their delivery outbox
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetWebhooks(ctx context.Context, userID uint) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, userID, id uint) (*models.Webhook, error)
+	SaveWebhook(ctx context.Context, webhook *models.Webhook) error
+	DeleteWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetActiveWebhooks(ctx context.Context, userIDs []uint) ([]models.Webhook, error)
+	CreateWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	GetWebhookDeliveries(ctx context.Context, webhookID uint, status string, offset, limit int) ([]models.WebhookDelivery, int64, error)
+	GetWebhookDelivery(ctx context.Context, webhookID, id uint) (*models.WebhookDelivery, error)
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	SaveWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+type gormWebhookRepository struct {
+	db  *gorm.DB
+	log logger.Logger
+}
+
+func NewWebhookRepository(db *gorm.DB, log logger.Logger) WebhookRepository {
+	return &gormWebhookRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *gormWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	if err := r.db.WithContext(ctx).Create(webhook).Error; err != nil {
+		r.log.Error("failed to create webhook", err, "userID", webhook.UserID)
+		return appErrors.DatabaseError("failed to save webhook", err)
+	}
+	return nil
+}
+
+func (r *gormWebhookRepository) GetWebhooks(ctx context.Context, userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id ASC").Find(&webhooks).Error; err != nil {
+		r.log.Error("failed to list webhooks", err, "userID", userID)
+		return nil, appErrors.DatabaseError("failed to list webhooks", err)
+	}
+	return webhooks, nil
+}
+
+// a webhook belonging to the user; anyone else's is a 404
+func (r *gormWebhookRepository) GetWebhook(ctx context.Context, userID, id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&webhook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.NotFoundError(fmt.Sprintf("webhook with id %d not found", id), err)
+		}
+		r.log.Error("failed to get webhook", err, "id", id)
+		return nil, appErrors.DatabaseError("failed to get webhook", err)
+	}
+	return &webhook, nil
+}
+
+func (r *gormWebhookRepository) SaveWebhook(ctx context.Context, webhook *models.Webhook) error {
+	if err := r.db.WithContext(ctx).Save(webhook).Error; err != nil {
+		r.log.Error("failed to save webhook", err, "id", webhook.ID)
+		return appErrors.DatabaseError("failed to save webhook", err)
+	}
+	return nil
+}
+
+// the webhook is removed outright along with its delivery log
+func (r *gormWebhookRepository) DeleteWebhook(ctx context.Context, webhook *models.Webhook) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(webhook).Error
+	})
+	if err != nil {
+		r.log.Error("failed to delete webhook", err, "id", webhook.ID)
+		return appErrors.DatabaseError("failed to delete webhook", err)
+	}
+	return nil
+}
+
+// the active webhooks of any of the given users
+func (r *gormWebhookRepository) GetActiveWebhooks(ctx context.Context, userIDs []uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := r.db.WithContext(ctx).Where("user_id IN ? AND active = ?", userIDs, true).Find(&webhooks).Error; err != nil {
+		r.log.Error("failed to load active webhooks", err)
+		return nil, appErrors.DatabaseError("failed to load webhooks", err)
+	}
+	return webhooks, nil
+}
+
+// write deliveries to the outbox; inside a transaction they commit with the change
+func (r *gormWebhookRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Omit("Webhook").Create(&deliveries).Error; err != nil {
+		r.log.Error("failed to queue webhook deliveries", err, "count", len(deliveries))
+		return appErrors.DatabaseError("failed to queue webhook deliveries", err)
+	}
+	return nil
+}
+
+// a webhook's delivery log, newest first, optionally filtered by status
+func (r *gormWebhookRepository) GetWebhookDeliveries(ctx context.Context, webhookID uint, status string, offset, limit int) ([]models.WebhookDelivery, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		r.log.Error("failed to count webhook deliveries", err, "webhookID", webhookID)
+		return nil, 0, appErrors.DatabaseError("failed to fetch webhook deliveries", err)
+	}
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&deliveries).Error; err != nil {
+		r.log.Error("failed to fetch webhook deliveries", err, "webhookID", webhookID)
+		return nil, 0, appErrors.DatabaseError("failed to fetch webhook deliveries", err)
+	}
+	return deliveries, totalCount, nil
+}
+
+func (r *gormWebhookRepository) GetWebhookDelivery(ctx context.Context, webhookID, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID).First(&delivery, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.NotFoundError(fmt.Sprintf("delivery with id %d not found", id), err)
+		}
+		r.log.Error("failed to get webhook delivery", err, "id", id)
+		return nil, appErrors.DatabaseError("failed to get webhook delivery", err)
+	}
+	return &delivery, nil
+}
+
+// lease up to limit due deliveries so that only one worker sends each; a
+// lease left by a worker that died runs out and the delivery is picked up again
+func (r *gormWebhookRepository) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	unlocked := "(locked_until IS NULL OR locked_until < ?)"
+	var candidates []uint
+	err := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Where(unlocked, now).
+		Order("next_attempt_at ASC").Limit(limit).
+		Pluck("id", &candidates).Error
+	if err != nil {
+		r.log.Error("failed to find due webhook deliveries", err)
+		return nil, appErrors.DatabaseError("failed to find due webhook deliveries", err)
+	}
+	claimed := make([]uint, 0, len(candidates))
+	for _, id := range candidates {
+		result := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ?", id, models.DeliveryPending).Where(unlocked, now).
+			UpdateColumn("locked_until", now.Add(lease))
+		if result.Error != nil {
+			r.log.Error("failed to claim webhook delivery", result.Error, "id", id)
+			return nil, appErrors.DatabaseError("failed to claim webhook delivery", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			claimed = append(claimed, id)
+		}
+	}
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+	var deliveries []models.WebhookDelivery
+	if err := r.db.WithContext(ctx).Preload("Webhook").Where("id IN ?", claimed).Order("id ASC").Find(&deliveries).Error; err != nil {
+		r.log.Error("failed to load claimed webhook deliveries", err)
+		return nil, appErrors.DatabaseError("failed to load webhook deliveries", err)
+	}
+	return deliveries, nil
+}
+
+// record the outcome of an attempt and release the lease
+func (r *gormWebhookRepository) SaveWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	delivery.LockedUntil = nil
+	if err := r.db.WithContext(ctx).Omit("Webhook").Save(delivery).Error; err != nil {
+		r.log.Error("failed to save webhook delivery", err, "id", delivery.ID)
+		return appErrors.DatabaseError("failed to save webhook delivery", err)
+	}
+	return nil
+}
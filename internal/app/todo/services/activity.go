@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/pagination"
+)
+
+type CommentRequest struct {
+	Body string `json:"body" validate:"required,max=2000"`
+}
+
+type CommentResponse struct {
+	ID        uint   `json:"id"`
+	TodoID    uint   `json:"todo_id"`
+	UserID    uint   `json:"user_id"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// Before and After are the raw JSON values recorded with the change
+type ActivityResponse struct {
+	ID        uint            `json:"id"`
+	TodoID    uint            `json:"todo_id"`
+	UserID    uint            `json:"user_id"`
+	Action    string          `json:"action"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt string          `json:"created_at"`
+}
+
+func (s *todoService) AddComment(ctx context.Context, todoID uint, commentReq *CommentRequest) (*CommentResponse, error) {
+	if fieldErrors := s.validator.StructCtx(ctx, commentReq); fieldErrors != nil {
+		s.log.Warn("validation failed for comment request", "error", fieldErrors)
+		return nil, appErrors.ValidationError("invalid comment", nil, fieldErrors)
+	}
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	comment := &models.Comment{TodoID: todoID, Body: commentReq.Body}
+	if err := s.comments.CreateComment(ctx, userID, comment); err != nil {
+		return nil, err
+	}
+	s.recordActivity(ctx, todoID, models.ActivityCommented, nil, map[string]uint{"comment_id": comment.ID})
+	return toCommentResponse(comment), nil
+}
+
+func (s *todoService) GetComments(ctx context.Context, todoID uint, page, limit int) (*pagination.PaginationResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := pagination.NewPaginationParams(page, limit)
+	comments, totalCount, err := s.comments.GetComments(ctx, userID, todoID, p.Offset(), p.Limit)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]CommentResponse, len(comments))
+	for i := range comments {
+		responses[i] = *toCommentResponse(&comments[i])
+	}
+	return &pagination.PaginationResponse{
+		Data:     responses,
+		Metadata: pagination.NewPaginationmetadata(p.Page, p.Limit, totalCount),
+	}, nil
+}
+
+// only the author can edit a comment
+func (s *todoService) UpdateComment(ctx context.Context, todoID, commentID uint, commentReq *CommentRequest) (*CommentResponse, error) {
+	if fieldErrors := s.validator.StructCtx(ctx, commentReq); fieldErrors != nil {
+		return nil, appErrors.ValidationError("invalid comment", nil, fieldErrors)
+	}
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.comments.GetComment(ctx, userID, todoID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != userID {
+		return nil, appErrors.AuthorizationError("only the author can edit a comment", nil)
+	}
+	comment.Body = commentReq.Body
+	if err := s.comments.UpdateComment(ctx, comment); err != nil {
+		return nil, err
+	}
+	return toCommentResponse(comment), nil
+}
+
+// the author or the todo's owner can delete a comment
+func (s *todoService) DeleteComment(ctx context.Context, todoID, commentID uint) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	comment, err := s.comments.GetComment(ctx, userID, todoID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != userID {
+		todo, err := s.findTodo(ctx, todoID)
+		if err != nil {
+			return err
+		}
+		if todo.UserID != userID {
+			return appErrors.AuthorizationError("only the author or the todo's owner can delete a comment", nil)
+		}
+	}
+	return s.comments.DeleteComment(ctx, comment)
+}
+
+// a todo's history, newest first
+func (s *todoService) GetActivity(ctx context.Context, todoID uint, page, limit int) (*pagination.PaginationResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := pagination.NewPaginationParams(page, limit)
+	activities, totalCount, err := s.activity.GetActivity(ctx, userID, todoID, p.Offset(), p.Limit)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]ActivityResponse, len(activities))
+	for i, activity := range activities {
+		responses[i] = ActivityResponse{
+			ID:        activity.ID,
+			TodoID:    activity.TodoID,
+			UserID:    activity.UserID,
+			Action:    activity.Action,
+			CreatedAt: activity.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if activity.Before != nil {
+			responses[i].Before = json.RawMessage(*activity.Before)
+		}
+		if activity.After != nil {
+			responses[i].After = json.RawMessage(*activity.After)
+		}
+	}
+	return &pagination.PaginationResponse{
+		Data:     responses,
+		Metadata: pagination.NewPaginationmetadata(p.Page, p.Limit, totalCount),
+	}, nil
+}
+
+// append to a todo's history as the current user; nil before/after values are left out.
+// History is best effort: a failure is logged rather than failing the change itself.
+func (s *todoService) recordActivity(ctx context.Context, todoID uint, action string, before, after interface{}) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return
+	}
+	activity := &models.Activity{TodoID: todoID, UserID: userID, Action: action}
+	if activity.Before, err = activityValue(before); err == nil {
+		activity.After, err = activityValue(after)
+	}
+	if err != nil {
+		s.log.Error("service: failed to encode activity values", err, "todoID", todoID, "action", action)
+		return
+	}
+	if err := s.activity.RecordActivity(ctx, activity); err != nil {
+		s.log.Error("service: failed to record activity", err, "todoID", todoID, "action", action)
+	}
+}
+
+// record what changed between two versions of a todo
+func (s *todoService) recordChanges(ctx context.Context, before, after *models.Todo) {
+	if before.Title != after.Title {
+		s.recordActivity(ctx, after.ID, models.ActivityTitleChanged, before.Title, after.Title)
+	}
+	if before.Completed != after.Completed {
+		action := models.ActivityReopened
+		if after.Completed {
+			action = models.ActivityCompleted
+		}
+		s.recordActivity(ctx, after.ID, action, before.Completed, after.Completed)
+	}
+}
+
+func activityValue(value interface{}) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	str := string(encoded)
+	return &str, nil
+}
+
+func toCommentResponse(comment *models.Comment) *CommentResponse {
+	return &CommentResponse{
+		ID:        comment.ID,
+		TodoID:    comment.TodoID,
+		UserID:    comment.UserID,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt: comment.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/storage"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+)
+
+// fallbacks when the attachment settings are not configured
+const (
+	defaultAttachmentMaxSize = 10 << 20
+	defaultSignedURLTTL      = 15 * time.Minute
+	// bytes read up front to detect a file's type
+	sniffLength = 3072
+)
+
+var defaultAttachmentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf", "text/plain"}
+
+// an uploaded file as received by the handler; Size is the length of Body
+type UploadAttachmentRequest struct {
+	TodoID   uint
+	FileName string
+	Size     int64
+	Body     io.Reader
+}
+
+// URL is a signed, time-limited download link, set when a single attachment is requested
+type AttachmentResponse struct {
+	ID           uint   `json:"id"`
+	TodoID       uint   `json:"todo_id"`
+	UserID       uint   `json:"user_id"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	Checksum     string `json:"checksum"`
+	URL          string `json:"url,omitempty"`
+	URLExpiresAt string `json:"url_expires_at,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// store a file against a todo the current user can edit. The type is detected from
+// the content, not the client's claim, and must be on the allow-list.
+func (s *todoService) UploadAttachment(ctx context.Context, uploadReq *UploadAttachmentRequest) (*AttachmentResponse, error) {
+	if s.storage == nil {
+		return nil, appErrors.InternalServerError("file storage is not configured", nil)
+	}
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CheckAccess(ctx, userID, uploadReq.TodoID, models.ShareRoleEditor); err != nil {
+		return nil, err
+	}
+	if uploadReq.Size <= 0 {
+		return nil, appErrors.ValidationError("invalid attachment", nil, map[string]string{"file": "The file is empty"})
+	}
+	if max := s.attachmentMaxSize(); uploadReq.Size > max {
+		return nil, appErrors.PayloadTooLargeError(fmt.Sprintf("attachments may be at most %d bytes", max), nil)
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(uploadReq.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, appErrors.ValidationError("could not read the uploaded file", err, nil)
+	}
+	head = head[:n]
+	detected := mimetype.Detect(head)
+	if !mimetype.EqualsAny(detected.String(), s.attachmentTypes()...) {
+		return nil, appErrors.UnsupportedMediaTypeError(fmt.Sprintf("files of type %s cannot be attached", detected.String()), nil)
+	}
+
+	attachment := &models.Attachment{
+		TodoID:      uploadReq.TodoID,
+		FileName:    attachmentFileName(uploadReq.FileName, detected.Extension()),
+		ContentType: detected.String(),
+		Size:        uploadReq.Size,
+		StorageKey:  fmt.Sprintf("todos/%d/%s%s", uploadReq.TodoID, uuid.NewString(), detected.Extension()),
+	}
+	hash := sha256.New()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), uploadReq.Body), hash)
+	if err := s.storage.Put(ctx, attachment.StorageKey, body, uploadReq.Size, attachment.ContentType); err != nil {
+		s.log.Error("service: failed to store attachment", err, "todoID", uploadReq.TodoID)
+		return nil, appErrors.ExternalServiceError("failed to store attachment", err)
+	}
+	attachment.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	if err := s.attachments.CreateAttachment(ctx, userID, attachment); err != nil {
+		s.deleteFiles(ctx, []string{attachment.StorageKey})
+		return nil, err
+	}
+	s.recordActivity(ctx, attachment.TodoID, models.ActivityAttached, nil, map[string]interface{}{"attachment_id": attachment.ID, "file_name": attachment.FileName})
+	s.log.Info("service: attachment uploaded", "todoID", attachment.TodoID, "attachmentID", attachment.ID, "size", attachment.Size)
+	return toAttachmentResponse(attachment), nil
+}
+
+func (s *todoService) GetAttachments(ctx context.Context, todoID uint) ([]AttachmentResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := s.attachments.GetAttachments(ctx, userID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]AttachmentResponse, len(attachments))
+	for i := range attachments {
+		responses[i] = *toAttachmentResponse(&attachments[i])
+	}
+	return responses, nil
+}
+
+// a single attachment with a fresh signed download URL
+func (s *todoService) GetAttachment(ctx context.Context, todoID, attachmentID uint) (*AttachmentResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attachment, err := s.attachments.GetAttachment(ctx, userID, todoID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if s.storage == nil {
+		return nil, appErrors.InternalServerError("file storage is not configured", nil)
+	}
+	ttl := s.signedURLTTL()
+	url, err := s.storage.SignedURL(ctx, attachment.StorageKey, attachment.FileName, ttl)
+	if err != nil {
+		s.log.Error("service: failed to sign attachment url", err, "attachmentID", attachment.ID)
+		return nil, appErrors.ExternalServiceError("failed to create download link", err)
+	}
+	res := toAttachmentResponse(attachment)
+	res.URL = url
+	res.URLExpiresAt = time.Now().Add(ttl).Format("2006-01-02 15:04:05")
+	return res, nil
+}
+
+// remove an attachment from a todo the current user can edit
+func (s *todoService) DeleteAttachment(ctx context.Context, todoID, attachmentID uint) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	attachment, err := s.attachments.GetAttachment(ctx, userID, todoID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if err := s.attachments.DeleteAttachment(ctx, userID, attachment); err != nil {
+		return err
+	}
+	s.deleteFiles(ctx, []string{attachment.StorageKey})
+	s.recordActivity(ctx, todoID, models.ActivityDetached, map[string]interface{}{"attachment_id": attachment.ID, "file_name": attachment.FileName}, nil)
+	s.log.Info("service: attachment deleted", "todoID", todoID, "attachmentID", attachmentID)
+	return nil
+}
+
+// remove stored files whose rows are gone. Inside a transaction the deletes wait
+// until it commits; failures only leave orphaned files behind, so they are logged.
+func (s *todoService) deleteFiles(ctx context.Context, keys []string) {
+	if len(keys) == 0 || s.storage == nil {
+		return
+	}
+	if s.pendingFileDeletes != nil {
+		*s.pendingFileDeletes = append(*s.pendingFileDeletes, keys...)
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			s.log.Error("service: failed to delete stored file", err, "key", key)
+		}
+	}
+}
+
+func (s *todoService) attachmentMaxSize() int64 {
+	if s.cfg != nil && s.cfg.AttachmentMaxSize > 0 {
+		return s.cfg.AttachmentMaxSize
+	}
+	return defaultAttachmentMaxSize
+}
+
+func (s *todoService) attachmentTypes() []string {
+	if s.cfg != nil && len(s.cfg.AttachmentAllowedTypes) > 0 {
+		return s.cfg.AttachmentAllowedTypes
+	}
+	return defaultAttachmentTypes
+}
+
+func (s *todoService) signedURLTTL() time.Duration {
+	if s.cfg != nil && s.cfg.StorageURLTTL > 0 {
+		return s.cfg.StorageURLTTL
+	}
+	return defaultSignedURLTTL
+}
+
+// keep only the base name of what the client sent, falling back to a generic name
+func attachmentFileName(name, extension string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment" + extension
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+func toAttachmentResponse(attachment *models.Attachment) *AttachmentResponse {
+	return &AttachmentResponse{
+		ID:          attachment.ID,
+		TodoID:      attachment.TodoID,
+		UserID:      attachment.UserID,
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Checksum:    attachment.Checksum,
+		CreatedAt:   attachment.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"github.com/codetheuri/todolist/internal/app/todo/repositories"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+)
+
+// bulk operation names
+const (
+	BulkComplete   = "complete"
+	BulkUncomplete = "uncomplete"
+	BulkSoftDelete = "soft_delete"
+	BulkRestore    = "restore"
+	BulkHardDelete = "hard_delete"
+	BulkTag        = "tag"
+	BulkMove       = "move"
+)
+
+// per-item result statuses
+const (
+	BulkStatusOK         = "ok"
+	BulkStatusFailed     = "failed"
+	BulkStatusRolledBack = "rolled_back"
+)
+
+const defaultBulkMaxBatchSize = 100
+
+type BulkOperation struct {
+	Op string `json:"op" validate:"required,oneof=complete uncomplete soft_delete restore hard_delete tag move"`
+	ID uint   `json:"id" validate:"required"`
+	// tag: names to attach and detach
+	Tags       []string `json:"tags" validate:"omitempty,max=20,dive,required,max=50"`
+	RemoveTags []string `json:"remove_tags" validate:"omitempty,max=20,dive,required,max=50"`
+	// move: new parent, or null to move to the top level
+	ParentID *uint `json:"parent_id"`
+	// complete: also complete the todo's subtasks
+	Cascade bool `json:"cascade"`
+}
+
+type BulkTodosRequest struct {
+	Operations []BulkOperation `json:"operations" validate:"required,min=1,dive"`
+}
+
+type BulkOperationResult struct {
+	Index  int           `json:"index"`
+	Op     string        `json:"op"`
+	ID     uint          `json:"id"`
+	Status string        `json:"status"`
+	Error  string        `json:"error,omitempty"`
+	Todo   *TodoResponse `json:"todo,omitempty"`
+}
+
+// Applied is false when any operation failed and the whole batch was rolled back
+type BulkTodosResponse struct {
+	Applied bool                  `json:"applied"`
+	Results []BulkOperationResult `json:"results"`
+}
+
+var errBulkRollback = errors.New("bulk operation failed")
+
+// run every operation in one transaction; a single failure rolls the batch back
+func (s *todoService) BulkTodos(ctx context.Context, bulkReq *BulkTodosRequest) (*BulkTodosResponse, error) {
+	if fieldErrors := s.validator.StructCtx(ctx, bulkReq); fieldErrors != nil {
+		s.log.Warn("validation failed for bulk todos request", "error", fieldErrors)
+		return nil, appErrors.ValidationError("invalid bulk request", nil, fieldErrors)
+	}
+	if max := s.bulkMaxBatchSize(); len(bulkReq.Operations) > max {
+		return nil, appErrors.ValidationError(fmt.Sprintf("a bulk request may contain at most %d operations", max), nil,
+			map[string]string{"operations": fmt.Sprintf("Maximum length is %d", max)})
+	}
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BulkOperationResult, len(bulkReq.Operations))
+	err = s.inTransaction(ctx, func(txService *todoService) error {
+		failed := false
+		for i, op := range bulkReq.Operations {
+			results[i] = BulkOperationResult{Index: i, Op: op.Op, ID: op.ID, Status: BulkStatusOK}
+			var todo *TodoResponse
+			// each operation under a savepoint: a failed statement aborts the whole transaction
+			// on Postgres, and the operations after it would fail for that reason alone
+			opErr := txService.repos.Transaction(ctx, func(opRepos *repositories.TodoRepositories) (err error) {
+				todo, err = txService.withRepos(opRepos).applyBulkOperation(ctx, userID, op)
+				return err
+			})
+			if opErr != nil {
+				failed = true
+				results[i].Status = BulkStatusFailed
+				results[i].Error = bulkErrorMessage(opErr)
+				continue
+			}
+			results[i].Todo = todo
+		}
+		if failed {
+			return errBulkRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBulkRollback) {
+		s.log.Error("service: bulk transaction failed", err)
+		return nil, appErrors.DatabaseError("failed to apply bulk operations", err)
+	}
+
+	res := &BulkTodosResponse{Applied: err == nil, Results: results}
+	if !res.Applied {
+		for i := range res.Results {
+			if res.Results[i].Status == BulkStatusOK {
+				res.Results[i].Status = BulkStatusRolledBack
+				res.Results[i].Todo = nil
+			}
+		}
+	}
+	s.log.Info("Service: bulk operations processed", "count", len(results), "applied", res.Applied)
+	return res, nil
+}
+
+func (s *todoService) applyBulkOperation(ctx context.Context, userID uint, op BulkOperation) (*TodoResponse, error) {
+	switch op.Op {
+	case BulkComplete, BulkUncomplete:
+		return s.CompleteTodo(ctx, &CompleteTodoRequest{ID: op.ID, Completed: op.Op == BulkComplete, Cascade: op.Cascade})
+	case BulkSoftDelete:
+		return nil, s.SoftDeleteTodo(ctx, op.ID)
+	case BulkRestore:
+		if err := s.RestoreTodo(ctx, op.ID); err != nil {
+			return nil, err
+		}
+		return s.GetTodoByID(ctx, op.ID)
+	case BulkHardDelete:
+		return nil, s.HardDeleteTodo(ctx, op.ID)
+	case BulkTag:
+		if len(op.Tags) == 0 && len(op.RemoveTags) == 0 {
+			return nil, appErrors.ValidationError("tag requires tags or remove_tags", nil, nil)
+		}
+		todo, err := s.findTodo(ctx, op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.AddTags(ctx, userID, todo, normalizeTags(op.Tags)); err != nil {
+			return nil, err
+		}
+		if err := s.repo.RemoveTags(ctx, userID, todo, normalizeTags(op.RemoveTags)); err != nil {
+			return nil, err
+		}
+		if err := s.publishTodo(ctx, EventTodoUpdated, op.ID); err != nil {
+			return nil, err
+		}
+		return s.GetTodoByID(ctx, op.ID)
+	case BulkMove:
+		return s.moveTodo(ctx, userID, op.ID, op.ParentID)
+	}
+	return nil, appErrors.ValidationError(fmt.Sprintf("unknown bulk operation %q", op.Op), nil, nil)
+}
+
+// re-parent a todo, keeping checklists one level deep
+func (s *todoService) moveTodo(ctx context.Context, userID, id uint, parentID *uint) (*TodoResponse, error) {
+	todo, err := s.findTodo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	position := 0
+	if parentID != nil {
+		if *parentID == todo.ID {
+			return nil, appErrors.ValidationError("a todo cannot be moved under itself", nil, nil)
+		}
+		parent, err := s.getParent(ctx, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.UserID != todo.UserID {
+			return nil, appErrors.ValidationError("a todo can only be moved under a todo with the same owner", nil, nil)
+		}
+		progress, err := s.repo.GetSubtaskProgress(ctx, userID, []uint{todo.ID})
+		if err != nil {
+			return nil, err
+		}
+		if progress[todo.ID].Total > 0 {
+			return nil, appErrors.ValidationError("a todo with subtasks cannot become a subtask", nil, nil)
+		}
+		if position, err = s.repo.NextSubtaskPosition(ctx, userID, parent.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.MoveTodo(ctx, userID, todo, parentID, position); err != nil {
+		return nil, err
+	}
+	s.log.Info("service: todo moved", "id", id, "userID", userID)
+	if err := s.publishTodo(ctx, EventTodoUpdated, id); err != nil {
+		return nil, err
+	}
+	return s.toTodoResponseWithProgress(ctx, todo)
+}
+
+func (s *todoService) bulkMaxBatchSize() int {
+	if s.cfg != nil && s.cfg.BulkMaxBatchSize > 0 {
+		return s.cfg.BulkMaxBatchSize
+	}
+	return defaultBulkMaxBatchSize
+}
+
+func bulkErrorMessage(err error) string {
+	var appErr appErrors.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Message()
+	}
+	return "unexpected error"
+}
+
+// trim, lowercase and de-duplicate tag names
+func normalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var tags []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/web"
+)
+
+type ifMatchKey struct{}
+
+// WithIfMatch attaches a request's If-Match header ("" when it was not sent) so the
+// single-todo writes made with ctx are conditional on it. Writes made without it,
+// such as bulk operations, are not checked.
+func WithIfMatch(ctx context.Context, header string) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, header)
+}
+
+// TodoETag is the entity tag of a todo at the given version
+func TodoETag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// check the If-Match precondition, if any, against the todo's current version
+func (s *todoService) checkIfMatch(ctx context.Context, id, version uint) error {
+	header, ok := ctx.Value(ifMatchKey{}).(string)
+	if !ok {
+		return nil
+	}
+	if header == "" {
+		if s.cfg != nil && s.cfg.RequireIfMatch {
+			return appErrors.PreconditionRequiredError("send If-Match with the todo's ETag to change it", nil)
+		}
+		return nil
+	}
+	if !web.MatchETag(header, TodoETag(version), false) {
+		s.log.Warn("service: If-Match precondition failed", "id", id, "version", version, "ifMatch", header)
+		return appErrors.PreconditionFailedError(fmt.Sprintf("todo %d has been changed since it was loaded; fetch it again and retry", id), nil)
+	}
+	return nil
+}
+
+// the version a delete or restore must apply at: the current one when If-Match
+// holds, or 0 (any) when the request is unconditional
+func (s *todoService) ifMatchVersion(ctx context.Context, id uint) (uint, error) {
+	if _, ok := ctx.Value(ifMatchKey{}).(string); !ok {
+		return 0, nil
+	}
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	version, err := s.repo.GetTodoVersion(ctx, userID, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.checkIfMatch(ctx, id, version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+
+	"github.com/codetheuri/todolist/pkg/events"
+)
+
+// change stream event types
+const (
+	EventTodoCreated = "todo.created"
+	EventTodoUpdated = "todo.updated"
+	EventTodoDeleted = "todo.deleted"
+	// sent first when a stream could not resume from Last-Event-ID
+	EventStreamReset = "stream.reset"
+)
+
+// TodoEvent is the payload of a change event. Todo is the todo as it is now,
+// left out of deletions.
+type TodoEvent struct {
+	TodoID  uint          `json:"todo_id"`
+	ActorID uint          `json:"actor_id"`
+	Hard    bool          `json:"hard,omitempty"`
+	Todo    *TodoResponse `json:"todo,omitempty"`
+}
+
+// ChangeStream is a live subscription plus the buffered events a client missed.
+// Complete is false when Last-Event-ID could not be resumed and the client
+// should reload rather than trust the backlog.
+type ChangeStream struct {
+	*events.Subscription
+	Backlog  []events.Event
+	Complete bool
+}
+
+type pendingEvent struct {
+	eventType string
+	payload   *TodoEvent
+	audience  []uint
+}
+
+// subscribe the current user to changes on every todo they can see
+func (s *todoService) SubscribeChanges(ctx context.Context, lastEventID string) (*ChangeStream, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sub, backlog, complete := s.events.Subscribe(userID, lastEventID)
+	if !complete {
+		s.log.Info("service: change stream could not resume", "userID", userID, "lastEventID", lastEventID)
+	}
+	return &ChangeStream{Subscription: sub, Backlog: backlog, Complete: complete}, nil
+}
+
+// publish the current state of a todo to everyone who can see it
+func (s *todoService) publishTodo(ctx context.Context, eventType string, id uint) error {
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	todo, err := s.repo.GetTodoByID(ctx, actorID, id)
+	if err != nil {
+		s.log.Error("service: failed to load todo for change event", err, "id", id, "event", eventType)
+		return err
+	}
+	audience, err := s.shares.GetTodoAudience(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.emit(ctx, eventType, &TodoEvent{TodoID: id, ActorID: actorID, Todo: s.toTodoResponse(todo)}, audience)
+}
+
+// publish each subtask of a parent, after a change that touched all of them
+func (s *todoService) publishSubtasks(ctx context.Context, parentID uint) error {
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	subtasks, err := s.repo.GetSubtasks(ctx, actorID, parentID)
+	if err != nil {
+		s.log.Error("service: failed to load subtasks for change events", err, "parentID", parentID)
+		return err
+	}
+	audience, err := s.shares.GetTodoAudience(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	for i := range subtasks {
+		if err := s.emit(ctx, EventTodoUpdated, &TodoEvent{TodoID: subtasks[i].ID, ActorID: actorID, Todo: s.toTodoResponse(&subtasks[i])}, audience); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish a deletion; the audience has to be looked up before the todo is gone
+func (s *todoService) publishDeleted(ctx context.Context, id uint, hard bool, audience []uint) error {
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return s.emit(ctx, EventTodoDeleted, &TodoEvent{TodoID: id, ActorID: actorID, Hard: hard}, audience)
+}
+
+// queue webhook deliveries for an event alongside the change that caused it. Inside
+// a transaction the stream event waits in pendingEvents until it commits.
+func (s *todoService) emit(ctx context.Context, eventType string, payload *TodoEvent, audience []uint) error {
+	if len(audience) == 0 {
+		return nil
+	}
+	if err := s.enqueueWebhooks(ctx, eventType, payload, audience); err != nil {
+		return err
+	}
+	event := pendingEvent{eventType: eventType, payload: payload, audience: audience}
+	if s.pendingEvents != nil {
+		*s.pendingEvents = append(*s.pendingEvents, event)
+		return nil
+	}
+	s.flushEvents([]pendingEvent{event})
+	return nil
+}
+
+// publish the events held back by a committed transaction
+func (s *todoService) flushEvents(pending []pendingEvent) {
+	for _, event := range pending {
+		if err := s.events.Publish(event.eventType, event.payload, event.audience); err != nil {
+			s.log.Error("service: failed to publish change event", err, "event", event.eventType, "todoID", event.payload.TodoID)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+)
+
+// export and import formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatICS  = "ics"
+)
+
+// todos read from the database per batch while exporting
+const exportBatchSize = 200
+
+var exportContentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatJSON: "application/json",
+	FormatICS:  "text/calendar; charset=utf-8",
+}
+
+// csv columns, in order; tags are separated by semicolons
+var csvColumns = []string{"id", "parent_id", "position", "title", "description", "completed", "completed_at", "due_at", "rrule", "timezone", "tags", "created_at", "updated_at"}
+
+// TodoRecord is a todo as it is exported, and as JSON imports are read.
+// ID and ParentID tie subtasks to their parent within one file.
+type TodoRecord struct {
+	ID          uint       `json:"id"`
+	ParentID    *uint      `json:"parent_id,omitempty"`
+	Position    int        `json:"position"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	RRule       string     `json:"rrule,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+	Tags        []string   `json:"tags"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ExportContentType is the media type written for an export format
+func ExportContentType(format string) (string, bool) {
+	contentType, ok := exportContentTypes[format]
+	return contentType, ok
+}
+
+// todoWriter encodes todos one at a time in an export format
+type todoWriter interface {
+	write(todo *models.Todo) error
+	close() error
+}
+
+// stream the current user's own todos to w without holding them all in memory
+func (s *todoService) ExportTodos(ctx context.Context, format string, w io.Writer) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	var tw todoWriter
+	switch format {
+	case FormatCSV:
+		tw, err = newCSVTodoWriter(w)
+	case FormatJSON:
+		tw, err = newJSONTodoWriter(w)
+	case FormatICS:
+		tw, err = newICSTodoWriter(w)
+	default:
+		return appErrors.ValidationError("invalid export format", nil, map[string]string{"format": "Must be one of csv, json, ics"})
+	}
+	if err != nil {
+		return err
+	}
+	count := 0
+	err = s.repo.StreamOwnedTodos(ctx, userID, exportBatchSize, func(todos []models.Todo) error {
+		for i := range todos {
+			if err := tw.write(&todos[i]); err != nil {
+				return err
+			}
+		}
+		count += len(todos)
+		return nil
+	})
+	if err != nil {
+		s.log.Error("service: todo export failed", err, "userID", userID, "format", format)
+		return err
+	}
+	if err := tw.close(); err != nil {
+		return err
+	}
+	s.log.Info("service: todos exported", "userID", userID, "format", format, "count", count)
+	return nil
+}
+
+func toTodoRecord(todo *models.Todo) *TodoRecord {
+	record := &TodoRecord{
+		ID:          todo.ID,
+		ParentID:    todo.ParentID,
+		Position:    todo.Position,
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   todo.Completed,
+		CompletedAt: todo.CompletedAt,
+		RRule:       todo.RRule,
+		Timezone:    todo.Timezone,
+		Tags:        tagNames(todo.Tags),
+		CreatedAt:   todo.CreatedAt,
+		UpdatedAt:   todo.UpdatedAt,
+	}
+	if todo.DueAt != nil {
+		due := todo.DueAt.In(todoLocation(todo))
+		record.DueAt = &due
+	}
+	return record
+}
+
+type csvTodoWriter struct {
+	w *csv.Writer
+}
+
+func newCSVTodoWriter(w io.Writer) (*csvTodoWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return nil, err
+	}
+	return &csvTodoWriter{w: cw}, nil
+}
+
+func (c *csvTodoWriter) write(todo *models.Todo) error {
+	record := toTodoRecord(todo)
+	parentID := ""
+	if record.ParentID != nil {
+		parentID = strconv.FormatUint(uint64(*record.ParentID), 10)
+	}
+	return c.w.Write([]string{
+		strconv.FormatUint(uint64(record.ID), 10),
+		parentID,
+		strconv.Itoa(record.Position),
+		record.Title,
+		record.Description,
+		strconv.FormatBool(record.Completed),
+		formatRecordTime(record.CompletedAt),
+		formatRecordTime(record.DueAt),
+		record.RRule,
+		record.Timezone,
+		strings.Join(record.Tags, ";"),
+		record.CreatedAt.UTC().Format(time.RFC3339),
+		record.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func (c *csvTodoWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func formatRecordTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// writes a JSON array element by element
+type jsonTodoWriter struct {
+	w     io.Writer
+	first bool
+}
+
+func newJSONTodoWriter(w io.Writer) (*jsonTodoWriter, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	return &jsonTodoWriter{w: w, first: true}, nil
+}
+
+func (j *jsonTodoWriter) write(todo *models.Todo) error {
+	encoded, err := json.Marshal(toTodoRecord(todo))
+	if err != nil {
+		return err
+	}
+	if !j.first {
+		if _, err := io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.first = false
+	_, err = j.w.Write(append([]byte("\n"), encoded...))
+	return err
+}
+
+func (j *jsonTodoWriter) close() error {
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+// writes an iCalendar (RFC 5545) calendar with one VTODO per todo
+type icsTodoWriter struct {
+	w io.Writer
+}
+
+// iCalendar date-time layouts
+const (
+	icsUTCLayout   = "20060102T150405Z"
+	icsLocalLayout = "20060102T150405"
+	icsDateLayout  = "20060102"
+)
+
+func newICSTodoWriter(w io.Writer) (*icsTodoWriter, error) {
+	i := &icsTodoWriter{w: w}
+	for _, line := range []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Tusk//Todos//EN", "CALSCALE:GREGORIAN"} {
+		if err := i.line(line); err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
+
+func (i *icsTodoWriter) write(todo *models.Todo) error {
+	lines := []string{
+		"BEGIN:VTODO",
+		"UID:" + icsUID(todo.ID),
+		"DTSTAMP:" + todo.UpdatedAt.UTC().Format(icsUTCLayout),
+		"CREATED:" + todo.CreatedAt.UTC().Format(icsUTCLayout),
+		"LAST-MODIFIED:" + todo.UpdatedAt.UTC().Format(icsUTCLayout),
+		"SUMMARY:" + icsEscape(todo.Title),
+	}
+	if todo.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+icsEscape(todo.Description))
+	}
+	if todo.Completed {
+		lines = append(lines, "STATUS:COMPLETED")
+		if todo.CompletedAt != nil {
+			lines = append(lines, "COMPLETED:"+todo.CompletedAt.UTC().Format(icsUTCLayout))
+		}
+	} else {
+		lines = append(lines, "STATUS:NEEDS-ACTION")
+	}
+	if todo.DueAt != nil {
+		if todo.Timezone != "" {
+			lines = append(lines, "DUE;TZID="+todo.Timezone+":"+todo.DueAt.In(todoLocation(todo)).Format(icsLocalLayout))
+		} else {
+			lines = append(lines, "DUE:"+todo.DueAt.UTC().Format(icsUTCLayout))
+		}
+	}
+	if todo.RRule != "" {
+		lines = append(lines, "RRULE:"+todo.RRule)
+	}
+	if len(todo.Tags) > 0 {
+		names := tagNames(todo.Tags)
+		for n := range names {
+			names[n] = icsEscape(names[n])
+		}
+		lines = append(lines, "CATEGORIES:"+strings.Join(names, ","))
+	}
+	if todo.ParentID != nil {
+		lines = append(lines, "RELATED-TO;RELTYPE=PARENT:"+icsUID(*todo.ParentID))
+	}
+	lines = append(lines, "END:VTODO")
+	for _, line := range lines {
+		if err := i.line(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *icsTodoWriter) close() error {
+	return i.line("END:VCALENDAR")
+}
+
+// write a content line, folded at 75 octets without splitting UTF-8 sequences
+func (i *icsTodoWriter) line(line string) error {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+	_, err := io.WriteString(i.w, b.String())
+	return err
+}
+
+func icsUID(id uint) string {
+	return fmt.Sprintf("todo-%d@tusk", id)
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func icsEscape(text string) string {
+	return icsEscaper.Replace(text)
+}
@@ -0,0 +1,520 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/rrule"
+)
+
+// per-row import statuses
+const (
+	ImportStatusValid   = "valid"
+	ImportStatusCreated = "created"
+	ImportStatusFailed  = "failed"
+	ImportStatusSkipped = "skipped"
+)
+
+const defaultImportMaxRows = 1000
+
+// Body holds the file in Format (csv, json or ics). With DryRun the rows are only validated.
+type ImportTodosRequest struct {
+	Format string
+	DryRun bool
+	Body   io.Reader
+}
+
+// Row counts data rows from 1 (CSV rows after the header, JSON array elements, VTODOs)
+type ImportRowResult struct {
+	Row    int               `json:"row"`
+	Ref    string            `json:"ref,omitempty"`
+	Status string            `json:"status"`
+	Errors map[string]string `json:"errors,omitempty"`
+	TodoID uint              `json:"todo_id,omitempty"`
+}
+
+// Applied is true when the rows were written; an import with any invalid row writes nothing
+type ImportTodosResponse struct {
+	DryRun  bool              `json:"dry_run"`
+	Applied bool              `json:"applied"`
+	Total   int               `json:"total"`
+	Valid   int               `json:"valid"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// a parsed row; ref and parentRef tie subtasks to a parent elsewhere in the file
+type importRow struct {
+	ref       string
+	parentRef string
+	position  int
+	req       CreateTodoRequest
+	errors    map[string]string
+}
+
+func (row *importRow) fail(field, message string) {
+	if row.errors == nil {
+		row.errors = make(map[string]string)
+	}
+	if _, exists := row.errors[field]; !exists {
+		row.errors[field] = message
+	}
+}
+
+var errImportRollback = errors.New("import failed")
+
+// validate every row of an import file and, unless it is a dry run and only if every
+// row is valid, create the todos in one transaction
+func (s *todoService) ImportTodos(ctx context.Context, importReq *ImportTodosRequest) (*ImportTodosResponse, error) {
+	if _, err := currentUserID(ctx); err != nil {
+		return nil, err
+	}
+	maxRows := s.importMaxRows()
+	var rows []importRow
+	var err error
+	switch importReq.Format {
+	case FormatCSV:
+		rows, err = parseCSVImport(importReq.Body, maxRows)
+	case FormatJSON:
+		rows, err = parseJSONImport(importReq.Body, maxRows)
+	case FormatICS:
+		rows, err = parseICSImport(importReq.Body, maxRows)
+	default:
+		return nil, appErrors.ValidationError("invalid import format", nil, map[string]string{"format": "Must be one of csv, json, ics"})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, appErrors.ValidationError("the import file contains no todos", nil, nil)
+	}
+	s.validateImportRows(ctx, rows)
+
+	res := &ImportTodosResponse{DryRun: importReq.DryRun, Total: len(rows), Rows: make([]ImportRowResult, len(rows))}
+	for i, row := range rows {
+		res.Rows[i] = ImportRowResult{Row: i + 1, Ref: row.ref, Status: ImportStatusValid}
+		if row.errors != nil {
+			res.Rows[i].Status = ImportStatusFailed
+			res.Rows[i].Errors = row.errors
+			res.Failed++
+		}
+	}
+	res.Valid = res.Total - res.Failed
+	if importReq.DryRun {
+		return res, nil
+	}
+	if res.Failed == 0 {
+		err = s.inTransaction(ctx, func(txService *todoService) error {
+			return txService.applyImport(ctx, rows, res)
+		})
+		if err != nil && !errors.Is(err, errImportRollback) {
+			s.log.Error("service: import transaction failed", err)
+			return nil, appErrors.DatabaseError("failed to import todos", err)
+		}
+		res.Applied = err == nil
+	}
+	if !res.Applied {
+		res.Valid = res.Total - res.Failed
+		for i := range res.Rows {
+			if res.Rows[i].Status != ImportStatusFailed {
+				res.Rows[i].Status = ImportStatusSkipped
+				res.Rows[i].TodoID = 0
+			}
+		}
+	}
+	s.log.Info("Service: todo import processed", "format", importReq.Format, "rows", res.Total, "failed", res.Failed, "applied", res.Applied)
+	return res, nil
+}
+
+// check each row on its own, then the parent references between rows
+func (s *todoService) validateImportRows(ctx context.Context, rows []importRow) {
+	byRef := make(map[string]int, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		for field, message := range s.validator.StructCtx(ctx, &row.req) {
+			row.fail(field, message)
+		}
+		if row.req.RRule != "" {
+			if _, err := rrule.Parse(row.req.RRule); err != nil {
+				row.fail("rrule", err.Error())
+			}
+		}
+		if row.ref == "" {
+			continue
+		}
+		if _, exists := byRef[row.ref]; exists {
+			row.fail("id", "Duplicate id in the file")
+			continue
+		}
+		byRef[row.ref] = i
+	}
+	for i := range rows {
+		row := &rows[i]
+		if row.parentRef == "" {
+			continue
+		}
+		parent, ok := byRef[row.parentRef]
+		switch {
+		case !ok || parent == i:
+			row.fail("parent_id", "No other todo in the file has this id")
+		case rows[parent].parentRef != "":
+			row.fail("parent_id", "Subtasks cannot have subtasks of their own")
+		case row.req.RRule != "":
+			row.fail("rrule", "Subtasks cannot recur on their own")
+		}
+	}
+	// a subtask cannot be imported without its parent
+	for i := range rows {
+		row := &rows[i]
+		if parent, ok := byRef[row.parentRef]; ok && row.parentRef != "" && rows[parent].errors != nil && row.errors == nil {
+			row.fail("parent_id", "The parent todo has errors")
+		}
+	}
+}
+
+// create top-level todos in file order, then subtasks under them by position
+func (s *todoService) applyImport(ctx context.Context, rows []importRow, res *ImportTodosResponse) error {
+	created := make(map[string]uint, len(rows))
+	var subtasks []int
+	for i := range rows {
+		if rows[i].parentRef != "" {
+			subtasks = append(subtasks, i)
+			continue
+		}
+		todo, err := s.createTodo(ctx, &rows[i].req, false)
+		if err != nil {
+			return importRowFailed(res, i, err)
+		}
+		created[rows[i].ref] = todo.ID
+		res.Rows[i].Status = ImportStatusCreated
+		res.Rows[i].TodoID = todo.ID
+	}
+	sort.SliceStable(subtasks, func(a, b int) bool {
+		return rows[subtasks[a]].position < rows[subtasks[b]].position
+	})
+	for _, i := range subtasks {
+		todo, err := s.AddSubtask(ctx, created[rows[i].parentRef], &rows[i].req)
+		if err != nil {
+			return importRowFailed(res, i, err)
+		}
+		res.Rows[i].Status = ImportStatusCreated
+		res.Rows[i].TodoID = todo.ID
+	}
+	return nil
+}
+
+func importRowFailed(res *ImportTodosResponse, i int, err error) error {
+	res.Rows[i].Status = ImportStatusFailed
+	res.Rows[i].Errors = map[string]string{"row": bulkErrorMessage(err)}
+	res.Failed++
+	return errImportRollback
+}
+
+func (s *todoService) importMaxRows() int {
+	if s.cfg != nil && s.cfg.ImportMaxRows > 0 {
+		return s.cfg.ImportMaxRows
+	}
+	return defaultImportMaxRows
+}
+
+func tooManyImportRows(max int) error {
+	return appErrors.ValidationError(fmt.Sprintf("an import may contain at most %d todos", max), nil, nil)
+}
+
+// CSV with a header row naming the columns (see csvColumns); unknown columns are ignored
+func parseCSVImport(body io.Reader, maxRows int) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, appErrors.ValidationError("the import file is empty", nil, nil)
+		}
+		return nil, appErrors.ValidationError("malformed CSV header", err, nil)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, appErrors.ValidationError("the CSV header must include a title column", nil, nil)
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, appErrors.ValidationError(fmt.Sprintf("malformed CSV after row %d", len(rows)), err, nil)
+		}
+		if len(rows) == maxRows {
+			return nil, tooManyImportRows(maxRows)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := importRow{ref: field("id"), parentRef: field("parent_id")}
+		row.req.Title = field("title")
+		row.req.Description = field("description")
+		row.req.RRule = field("rrule")
+		row.req.Timezone = field("timezone")
+		if value := field("position"); value != "" {
+			if row.position, err = strconv.Atoi(value); err != nil {
+				row.fail("position", "Must be a whole number")
+			}
+		}
+		if value := field("completed"); value != "" {
+			if row.req.Completed, err = strconv.ParseBool(value); err != nil {
+				row.fail("completed", "Must be true or false")
+			}
+		}
+		if value := field("due_at"); value != "" {
+			if row.req.DueAt, err = parseImportTime(value, row.req.Timezone); err != nil {
+				row.fail("due_at", "Must be an RFC 3339 date-time or a YYYY-MM-DD date")
+			}
+		}
+		for _, tag := range strings.Split(field("tags"), ";") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				row.req.Tags = append(row.req.Tags, tag)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// an RFC 3339 date-time, or a date taken as midnight in the row's timezone
+func parseImportTime(value, timezone string) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loadLocation(timezone))
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// a JSON array of TodoRecord objects, decoded one element at a time
+func parseJSONImport(body io.Reader, maxRows int) ([]importRow, error) {
+	decoder := json.NewDecoder(body)
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('[') {
+		return nil, appErrors.ValidationError("the import file must be a JSON array of todos", err, nil)
+	}
+	var rows []importRow
+	for decoder.More() {
+		if len(rows) == maxRows {
+			return nil, tooManyImportRows(maxRows)
+		}
+		var record TodoRecord
+		row := importRow{}
+		if err := decoder.Decode(&record); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return nil, appErrors.ValidationError(fmt.Sprintf("malformed JSON after row %d", len(rows)), err, nil)
+			}
+			row.fail(typeErr.Field, fmt.Sprintf("Must be a %s", jsonTypeName(typeErr.Type.Kind().String())))
+		}
+		if record.ID != 0 {
+			row.ref = strconv.FormatUint(uint64(record.ID), 10)
+		}
+		if record.ParentID != nil {
+			row.parentRef = strconv.FormatUint(uint64(*record.ParentID), 10)
+		}
+		row.position = record.Position
+		row.req = CreateTodoRequest{
+			Title:       strings.TrimSpace(record.Title),
+			Description: record.Description,
+			Completed:   record.Completed,
+			DueAt:       record.DueAt,
+			RRule:       record.RRule,
+			Timezone:    record.Timezone,
+			Tags:        record.Tags,
+		}
+		rows = append(rows, row)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, appErrors.ValidationError("malformed JSON at the end of the file", err, nil)
+	}
+	return rows, nil
+}
+
+func jsonTypeName(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "number"
+	case kind == "slice":
+		return "list"
+	case kind == "struct":
+		return "valid value"
+	}
+	return kind
+}
+
+// VTODO components of an iCalendar file; other components are ignored
+func parseICSImport(body io.Reader, maxRows int) ([]importRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var rows []importRow
+	var current *importRow
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// a line starting with a space or tab continues the previous one
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, appErrors.ValidationError("could not read the iCalendar file", err, nil)
+	}
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return nil, appErrors.ValidationError("the import file is not an iCalendar file", nil, nil)
+	}
+
+	for _, line := range lines {
+		name, params, value := parseICSLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VTODO"):
+			if len(rows) == maxRows {
+				return nil, tooManyImportRows(maxRows)
+			}
+			current = &importRow{}
+			continue
+		case name == "END" && strings.EqualFold(value, "VTODO") && current != nil:
+			rows = append(rows, *current)
+			current = nil
+			continue
+		case current == nil:
+			continue
+		}
+		switch name {
+		case "UID":
+			current.ref = value
+		case "SUMMARY":
+			current.req.Title = strings.TrimSpace(icsUnescape(value))
+		case "DESCRIPTION":
+			current.req.Description = icsUnescape(value)
+		case "STATUS":
+			current.req.Completed = strings.EqualFold(value, "COMPLETED")
+		case "COMPLETED":
+			current.req.Completed = true
+		case "DUE":
+			due, timezone, err := parseICSTime(value, params)
+			if err != nil {
+				current.fail("due_at", "Invalid DUE value")
+				continue
+			}
+			current.req.DueAt = &due
+			current.req.Timezone = timezone
+		case "RRULE":
+			current.req.RRule = value
+		case "CATEGORIES":
+			for _, tag := range splitICSList(value) {
+				if tag = strings.TrimSpace(icsUnescape(tag)); tag != "" {
+					current.req.Tags = append(current.req.Tags, tag)
+				}
+			}
+		case "RELATED-TO":
+			if reltype := params["RELTYPE"]; reltype == "" || strings.EqualFold(reltype, "PARENT") {
+				current.parentRef = value
+			}
+		}
+	}
+	return rows, nil
+}
+
+// split a content line into its upper-cased name, parameters and value
+func parseICSLine(line string) (string, map[string]string, string) {
+	inQuotes := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, ""
+	}
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:]
+}
+
+// a DATE or DATE-TIME value, with the IANA timezone it was given in (if any)
+func parseICSTime(value string, params map[string]string) (time.Time, string, error) {
+	timezone := params["TZID"]
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return time.Time{}, "", err
+		}
+	}
+	switch {
+	case strings.EqualFold(params["VALUE"], "DATE") || len(value) == len(icsDateLayout):
+		t, err := time.ParseInLocation(icsDateLayout, value, loc)
+		return t, timezone, err
+	case strings.HasSuffix(value, "Z"):
+		t, err := time.Parse(icsUTCLayout, value)
+		return t, timezone, err
+	default:
+		t, err := time.ParseInLocation(icsLocalLayout, value, loc)
+		return t, timezone, err
+	}
+}
+
+// split a comma-separated value, keeping escaped commas
+func splitICSList(value string) []string {
+	var items []string
+	var b strings.Builder
+	escaped := false
+	for _, c := range value {
+		switch {
+		case escaped:
+			b.WriteRune('\\')
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ',':
+			items = append(items, b.String())
+			b.Reset()
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return append(items, b.String())
+}
+
+var icsUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func icsUnescape(text string) string {
+	return icsUnescaper.Replace(text)
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/jsonpatch"
+)
+
+// PatchTodoRequest carries a raw patch and the media type that says how to read it:
+// jsonpatch.MergePatchType (RFC 7396) or jsonpatch.JSONPatchType (RFC 6902).
+type PatchTodoRequest struct {
+	ID        uint
+	MediaType string
+	Patch     []byte
+}
+
+// TodoPatchDocument is the view of a todo that patches are applied to. Every
+// field is always present, so false, "" and null are ordinary values and a
+// removed member falls back to its zero value.
+type TodoPatchDocument struct {
+	Title       string     `json:"title" validate:"required,min=3,max=100"`
+	Description string     `json:"description" validate:"max=255"`
+	Completed   bool       `json:"completed"`
+	DueAt       *time.Time `json:"due_at" validate:"required_with=RRule"`
+	RRule       string     `json:"rrule" validate:"omitempty,max=255"`
+	Timezone    string     `json:"timezone" validate:"omitempty,timezone"`
+	Tags        []string   `json:"tags" validate:"omitempty,max=20,dive,required,max=50"`
+}
+
+// apply a merge patch or JSON Patch to a todo and save whatever it changed
+func (s *todoService) PatchTodo(ctx context.Context, patchReq *PatchTodoRequest) (*TodoResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := s.findTodo(ctx, patchReq.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkIfMatch(ctx, todo.ID, todo.Version); err != nil {
+		return nil, err
+	}
+	before := toPatchDocument(todo)
+	doc, err := applyPatch(before, patchReq)
+	if err != nil {
+		return nil, err
+	}
+	if fieldErrors := s.validator.StructCtx(ctx, doc); fieldErrors != nil {
+		s.log.Warn("validation failed for patched todo", "id", todo.ID, "error", fieldErrors)
+		return nil, appErrors.ValidationError("patched todo is invalid", nil, fieldErrors)
+	}
+
+	saved := *todo
+	err = s.inTransaction(ctx, func(txService *todoService) error {
+		patched, err := txService.savePatch(ctx, userID, &saved, before, doc)
+		if err != nil {
+			return err
+		}
+		saved = *patched
+		return nil
+	})
+	if err != nil {
+		s.log.Error("service: failed to patch todo", err, "id", todo.ID)
+		return nil, err
+	}
+	return s.toTodoResponseWithProgress(ctx, &saved)
+}
+
+// decode the patch named by the request's media type and run it against doc
+func applyPatch(doc *TodoPatchDocument, patchReq *PatchTodoRequest) (*TodoPatchDocument, error) {
+	original, err := json.Marshal(doc)
+	if err != nil {
+		return nil, appErrors.InternalServerError("failed to encode todo for patching", err)
+	}
+	var patched []byte
+	switch patchReq.MediaType {
+	case jsonpatch.MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, patchReq.Patch)
+	case jsonpatch.JSONPatchType:
+		var patch jsonpatch.Patch
+		if patch, err = jsonpatch.DecodePatch(patchReq.Patch); err == nil {
+			patched, err = patch.Apply(original)
+		}
+	default:
+		return nil, appErrors.UnsupportedMediaTypeError("patches must be sent as "+jsonpatch.MergePatchType+" or "+jsonpatch.JSONPatchType, nil)
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return nil, appErrors.ConflictError(err.Error(), err)
+	}
+	if err != nil {
+		return nil, appErrors.ValidationError("invalid patch", err, map[string]string{"patch": err.Error()})
+	}
+
+	result := &TodoPatchDocument{}
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(result); err != nil {
+		return nil, appErrors.ValidationError("patched todo is invalid", err, map[string]string{"patch": err.Error()})
+	}
+	result.Tags = patchTags(result.Tags)
+	return result, nil
+}
+
+// write the patched fields inside a transaction and record exactly what changed
+func (s *todoService) savePatch(ctx context.Context, userID uint, todo *models.Todo, before, doc *TodoPatchDocument) (*models.Todo, error) {
+	wasCompleted := todo.Completed
+	todo.Title = doc.Title
+	todo.Description = doc.Description
+	todo.DueAt = doc.DueAt
+	todo.Timezone = doc.Timezone
+	if err := applyRecurrence(todo, doc.RRule); err != nil {
+		return nil, err
+	}
+	if doc.Completed != todo.Completed {
+		setCompleted(todo, doc.Completed)
+	}
+
+	after := toPatchDocument(todo)
+	after.Tags = doc.Tags
+	changedBefore, changedAfter := patchChanges(before, after)
+	if len(changedAfter) == 0 {
+		return todo, nil
+	}
+	_, tagsChanged := changedAfter["tags"]
+	if len(changedAfter) > 1 || !tagsChanged {
+		if _, err := s.repo.UpdateTodo(ctx, userID, todo); err != nil {
+			return nil, err
+		}
+	}
+	if tagsChanged {
+		added, removed := diffTags(before.Tags, after.Tags)
+		if err := s.repo.AddTags(ctx, userID, todo, added); err != nil {
+			return nil, err
+		}
+		if err := s.repo.RemoveTags(ctx, userID, todo, removed); err != nil {
+			return nil, err
+		}
+	}
+	s.recordActivity(ctx, todo.ID, models.ActivityUpdated, changedBefore, changedAfter)
+	if todo.Completed && !wasCompleted {
+		if err := s.scheduleNextOccurrence(ctx, todo); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.publishTodo(ctx, EventTodoUpdated, todo.ID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTodoByID(ctx, userID, todo.ID)
+}
+
+func toPatchDocument(todo *models.Todo) *TodoPatchDocument {
+	return &TodoPatchDocument{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   todo.Completed,
+		DueAt:       todo.DueAt,
+		RRule:       todo.RRule,
+		Timezone:    todo.Timezone,
+		Tags:        patchTags(tagNames(todo.Tags)),
+	}
+}
+
+// normalized tag names, never nil so that "/tags/-" can always be appended to
+func patchTags(names []string) []string {
+	tags := normalizeTags(names)
+	if tags == nil {
+		tags = []string{}
+	}
+	return tags
+}
+
+// the previous and new values of each field that differs, keyed by JSON name
+func patchChanges(before, after *TodoPatchDocument) (map[string]interface{}, map[string]interface{}) {
+	changedBefore := map[string]interface{}{}
+	changedAfter := map[string]interface{}{}
+	set := func(field string, old, new interface{}) {
+		changedBefore[field] = old
+		changedAfter[field] = new
+	}
+	if before.Title != after.Title {
+		set("title", before.Title, after.Title)
+	}
+	if before.Description != after.Description {
+		set("description", before.Description, after.Description)
+	}
+	if before.Completed != after.Completed {
+		set("completed", before.Completed, after.Completed)
+	}
+	if !sameTime(before.DueAt, after.DueAt) {
+		set("due_at", before.DueAt, after.DueAt)
+	}
+	if before.RRule != after.RRule {
+		set("rrule", before.RRule, after.RRule)
+	}
+	if before.Timezone != after.Timezone {
+		set("timezone", before.Timezone, after.Timezone)
+	}
+	if !reflect.DeepEqual(before.Tags, after.Tags) {
+		set("tags", before.Tags, after.Tags)
+	}
+	return changedBefore, changedAfter
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// names in after but not before, and in before but not after; both lists are normalized
+func diffTags(before, after []string) ([]string, []string) {
+	inBefore := make(map[string]bool, len(before))
+	for _, name := range before {
+		inBefore[name] = true
+	}
+	var added []string
+	for _, name := range after {
+		if inBefore[name] {
+			delete(inBefore, name)
+			continue
+		}
+		added = append(added, name)
+	}
+	var removed []string
+	for _, name := range before {
+		if inBefore[name] {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed
+}
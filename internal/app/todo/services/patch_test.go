@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/jsonpatch"
+)
+
+func TestApplyPatch(t *testing.T) {
+	doc := &TodoPatchDocument{Title: "buy milk", Description: "semi-skimmed", Tags: []string{"shop"}}
+	tests := []struct {
+		name      string
+		mediaType string
+		patch     string
+		code      appErrors.Code // "" when the patch applies
+		want      func(*TodoPatchDocument) bool
+	}{
+		{
+			name: "merge patch clears and sets", mediaType: jsonpatch.MergePatchType,
+			patch: `{"description":null,"completed":true}`,
+			want:  func(d *TodoPatchDocument) bool { return d.Description == "" && d.Completed && d.Title == "buy milk" },
+		},
+		{
+			name: "json patch appends a tag", mediaType: jsonpatch.JSONPatchType,
+			patch: `[{"op":"test","path":"/title","value":"buy milk"},{"op":"add","path":"/tags/-","value":" Dairy "}]`,
+			want: func(d *TodoPatchDocument) bool {
+				return len(d.Tags) == 2 && d.Tags[0] == "dairy" && d.Tags[1] == "shop"
+			},
+		},
+		{name: "failed test is a conflict", mediaType: jsonpatch.JSONPatchType, patch: `[{"op":"test","path":"/title","value":"buy bread"}]`, code: appErrors.CodeConflict},
+		{name: "unknown media type", mediaType: "application/json", patch: `{"title":"x"}`, code: appErrors.CodeUnsupportedMediaType},
+		{name: "no media type", patch: `{"title":"x"}`, code: appErrors.CodeUnsupportedMediaType},
+		{name: "malformed patch", mediaType: jsonpatch.JSONPatchType, patch: `[{"op":"add"`, code: appErrors.CodeValidation},
+		{name: "failed operation", mediaType: jsonpatch.JSONPatchType, patch: `[{"op":"remove","path":"/owner"}]`, code: appErrors.CodeValidation},
+		{name: "unknown member", mediaType: jsonpatch.MergePatchType, patch: `{"owner_id":2}`, code: appErrors.CodeValidation},
+		{name: "wrong type", mediaType: jsonpatch.MergePatchType, patch: `{"completed":"yes"}`, code: appErrors.CodeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(doc, &PatchTodoRequest{ID: 1, MediaType: tt.mediaType, Patch: []byte(tt.patch)})
+			if tt.code != "" {
+				var appErr appErrors.AppError
+				if !errors.As(err, &appErr) || appErr.Code() != tt.code {
+					t.Fatalf("error %v, want %s", err, tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.want(got) {
+				t.Fatalf("patched to %+v", got)
+			}
+		})
+	}
+	if doc.Description != "semi-skimmed" || len(doc.Tags) != 1 {
+		t.Fatalf("the original document was changed: %+v", doc)
+	}
+}
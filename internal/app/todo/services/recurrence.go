@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/rrule"
+)
+
+const (
+	defaultOccurrenceCount = 5
+	maxOccurrenceCount     = 100
+)
+
+type PreviewOccurrencesRequest struct {
+	RRule    string    `json:"rrule" validate:"required,max=255"`
+	DTStart  time.Time `json:"dtstart" validate:"required"`
+	Timezone string    `json:"timezone" validate:"omitempty,timezone"`
+	Count    int       `json:"count" validate:"omitempty,min=1,max=100"`
+}
+
+type OccurrencesResponse struct {
+	RRule       string   `json:"rrule"`
+	Timezone    string   `json:"timezone"`
+	Occurrences []string `json:"occurrences"`
+}
+
+// next occurrences of a recurring todo after its current due date
+func (s *todoService) GetOccurrences(ctx context.Context, id uint, count int) (*OccurrencesResponse, error) {
+	todo, err := s.findTodo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if todo.RRule == "" || todo.DueAt == nil {
+		return nil, appErrors.ValidationError("todo does not recur", nil, nil)
+	}
+	rule, err := rrule.Parse(todo.RRule)
+	if err != nil {
+		s.log.Error("service: stored rrule failed to parse", err, "id", id)
+		return nil, appErrors.InternalServerError("stored recurrence rule is invalid", err)
+	}
+	loc := todoLocation(todo)
+	occurrences := rule.After(seriesStart(todo), loc, *todo.DueAt, clampOccurrenceCount(count))
+	return toOccurrencesResponse(rule, loc, occurrences), nil
+}
+
+// occurrences of an arbitrary rule, for previewing a schedule before saving it
+func (s *todoService) PreviewOccurrences(ctx context.Context, previewReq *PreviewOccurrencesRequest) (*OccurrencesResponse, error) {
+	if fieldErrors := s.validator.StructCtx(ctx, previewReq); fieldErrors != nil {
+		return nil, appErrors.ValidationError("invalid recurrence preview", nil, fieldErrors)
+	}
+	rule, err := rrule.Parse(previewReq.RRule)
+	if err != nil {
+		return nil, appErrors.ValidationError("invalid recurrence rule", err, map[string]string{"rrule": err.Error()})
+	}
+	loc := loadLocation(previewReq.Timezone)
+	var occurrences []time.Time
+	it := rule.Iterator(previewReq.DTStart, loc)
+	for len(occurrences) < clampOccurrenceCount(previewReq.Count) {
+		next, ok := it.Next()
+		if !ok {
+			break
+		}
+		occurrences = append(occurrences, next)
+	}
+	return toOccurrencesResponse(rule, loc, occurrences), nil
+}
+
+// once an occurrence is completed, create the next one in the series (at most once).
+// The series belongs to the owner, so the next occurrence is created on their behalf
+// and keeps the same share grants, whoever completed this one.
+func (s *todoService) scheduleNextOccurrence(ctx context.Context, todo *models.Todo) error {
+	if todo.RRule == "" || todo.DueAt == nil || todo.NextOccurrenceID != nil {
+		return nil
+	}
+	rule, err := rrule.Parse(todo.RRule)
+	if err != nil {
+		s.log.Error("service: stored rrule failed to parse", err, "id", todo.ID)
+		return appErrors.InternalServerError("stored recurrence rule is invalid", err)
+	}
+	next := rule.After(seriesStart(todo), todoLocation(todo), *todo.DueAt, 1)
+	if len(next) == 0 {
+		s.log.Info("service: recurring todo series finished", "id", todo.ID)
+		return nil
+	}
+	start := seriesStart(todo)
+	dueAt := next[0].UTC()
+	occurrence := &models.Todo{
+		UserID:          todo.UserID,
+		Title:           todo.Title,
+		Description:     todo.Description,
+		DueAt:           &dueAt,
+		RRule:           todo.RRule,
+		Timezone:        todo.Timezone,
+		RecurrenceStart: &start,
+	}
+	owner := todo.UserID
+	created, err := s.repo.CreateTodo(ctx, owner, occurrence)
+	if err != nil {
+		s.log.Error("service: failed to create next occurrence", err, "id", todo.ID)
+		return err
+	}
+	// the checklist comes along, reset
+	subtasks, err := s.repo.GetSubtasks(ctx, owner, todo.ID)
+	if err != nil {
+		return err
+	}
+	copiedIDs := make([]uint, 0, len(subtasks))
+	for _, subtask := range subtasks {
+		copied := &models.Todo{
+			UserID:      todo.UserID,
+			Title:       subtask.Title,
+			Description: subtask.Description,
+			ParentID:    &created.ID,
+			Position:    subtask.Position,
+		}
+		if _, err := s.repo.CreateTodo(ctx, owner, copied); err != nil {
+			return err
+		}
+		copiedIDs = append(copiedIDs, copied.ID)
+	}
+	if err := s.repo.AddTags(ctx, owner, created, tagNames(todo.Tags)); err != nil {
+		return err
+	}
+	if err := s.shares.CopyShares(ctx, todo.ID, created.ID); err != nil {
+		return err
+	}
+	s.recordActivity(ctx, created.ID, models.ActivityCreated, nil, map[string]interface{}{"title": created.Title, "previous_occurrence_id": todo.ID})
+	if err := s.publishTodo(ctx, EventTodoCreated, created.ID); err != nil {
+		return err
+	}
+	for _, id := range copiedIDs {
+		if err := s.publishTodo(ctx, EventTodoCreated, id); err != nil {
+			return err
+		}
+	}
+	todo.NextOccurrenceID = &created.ID
+	if _, err := s.repo.UpdateTodo(ctx, owner, todo); err != nil {
+		s.log.Error("service: failed to link next occurrence", err, "id", todo.ID)
+		return err
+	}
+	s.log.Info("service: next occurrence scheduled", "id", todo.ID, "nextID", created.ID, "dueAt", dueAt)
+	return nil
+}
+
+// validate and normalise an rrule onto a todo; an empty rule ends the series
+func applyRecurrence(todo *models.Todo, value string) error {
+	if value == "" {
+		todo.RRule = ""
+		todo.RecurrenceStart = nil
+		return nil
+	}
+	rule, err := rrule.Parse(value)
+	if err != nil {
+		return appErrors.ValidationError("invalid recurrence rule", err, map[string]string{"rrule": err.Error()})
+	}
+	if todo.DueAt == nil {
+		return appErrors.ValidationError("invalid recurrence rule", nil, map[string]string{"due_at": "A due date is required for recurring todos"})
+	}
+	if normalized := rule.String(); normalized != todo.RRule || todo.RecurrenceStart == nil {
+		todo.RRule = normalized
+		start := *todo.DueAt
+		todo.RecurrenceStart = &start
+	}
+	return nil
+}
+
+func seriesStart(todo *models.Todo) time.Time {
+	if todo.RecurrenceStart != nil {
+		return *todo.RecurrenceStart
+	}
+	return *todo.DueAt
+}
+
+func todoLocation(todo *models.Todo) *time.Location {
+	return loadLocation(todo.Timezone)
+}
+
+// unknown or empty timezones fall back to UTC
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func clampOccurrenceCount(count int) int {
+	if count < 1 {
+		return defaultOccurrenceCount
+	}
+	if count > maxOccurrenceCount {
+		return maxOccurrenceCount
+	}
+	return count
+}
+
+func toOccurrencesResponse(rule *rrule.Rule, loc *time.Location, occurrences []time.Time) *OccurrencesResponse {
+	res := &OccurrencesResponse{
+		RRule:       rule.String(),
+		Timezone:    loc.String(),
+		Occurrences: make([]string, len(occurrences)),
+	}
+	for i, occurrence := range occurrences {
+		res.Occurrences[i] = occurrence.In(loc).Format(time.RFC3339)
+	}
+	return res
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codetheuri/todolist/config"
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"github.com/codetheuri/todolist/internal/app/todo/repositories"
+	tokenPkg "github.com/codetheuri/todolist/pkg/auth/token"
+	"github.com/codetheuri/todolist/pkg/events"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/validators"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// a todo service over a private in-memory SQLite database
+func newTestService(t *testing.T, cfg *config.Config) (*todoService, *gorm.DB) {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared&_foreign_keys=1", name)), &gorm.Config{
+		Logger: gormLogger.Default.LogMode(gormLogger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one connection keeps the shared in-memory database alive and transactions serial
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	err = db.AutoMigrate(&models.Todo{}, &models.Tag{}, &models.TodoShare{}, &models.Comment{}, &models.Activity{},
+		&models.Attachment{}, &models.Webhook{}, &models.WebhookDelivery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	log := logger.NewConsoleLogger()
+	service := NewTodoService(repositories.NewTodoRepositories(db, log), nil, validators.NewValidator(), cfg, nil, nil, events.NewHub(16), log)
+	return service.(*todoService), db
+}
+
+func asUser(userID uint) context.Context {
+	return tokenPkg.WithUserID(context.Background(), userID)
+}
+
+func TestCompleteRecurringTodoWithCascade(t *testing.T) {
+	s, _ := newTestService(t, nil)
+	ctx := asUser(1)
+	dueAt := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	parent, err := s.CreateTodo(ctx, &CreateTodoRequest{Title: "weekly review", Description: "review the week", DueAt: &dueAt, RRule: "FREQ=WEEKLY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range []string{"inbox zero", "plan next week"} {
+		if _, err := s.AddSubtask(ctx, parent.ID, &CreateTodoRequest{Title: title, Description: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loaded, err := s.GetTodoByID(ctx, parent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the precondition is the version the client last saw
+	ctx = WithIfMatch(ctx, TodoETag(loaded.Version))
+	completed, err := s.CompleteTodo(ctx, &CompleteTodoRequest{ID: parent.ID, Completed: true, Cascade: true})
+	if err != nil {
+		t.Fatalf("CompleteTodo with cascade: %v", err)
+	}
+	if !completed.Completed || completed.CompletedSubtasks != 2 {
+		t.Fatalf("completed = %v with %d/%d subtasks done", completed.Completed, completed.CompletedSubtasks, completed.SubtaskCount)
+	}
+
+	stored, err := s.GetTodoByID(asUser(1), parent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed.Version != stored.Version {
+		t.Fatalf("CompleteTodo returned version %d, the stored todo is at %d", completed.Version, stored.Version)
+	}
+	if completed.Version <= loaded.Version {
+		t.Fatalf("version did not move: %d after %d", completed.Version, loaded.Version)
+	}
+	if stored.NextOccurrenceID == nil {
+		t.Fatal("the next occurrence was not scheduled")
+	}
+	next, err := s.GetTodoByID(asUser(1), *stored.NextOccurrenceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Completed || next.SubtaskCount != 2 || next.CompletedSubtasks != 0 {
+		t.Fatalf("next occurrence: completed=%v, %d/%d subtasks done", next.Completed, next.CompletedSubtasks, next.SubtaskCount)
+	}
+}
+
+func TestUpdateTodoWithCascadeReturnsCurrentVersion(t *testing.T) {
+	s, _ := newTestService(t, nil)
+	ctx := asUser(1)
+	parent, err := s.CreateTodo(ctx, &CreateTodoRequest{Title: "release", Description: "ship it"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddSubtask(ctx, parent.ID, &CreateTodoRequest{Title: "tag", Description: "tag the release"}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := s.UpdateTodo(ctx, &UpdateTodoRequest{ID: parent.ID, Completed: true, Cascade: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.GetTodoByID(ctx, parent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != stored.Version {
+		t.Fatalf("UpdateTodo returned version %d, the stored todo is at %d", updated.Version, stored.Version)
+	}
+}
+
+// the subtasks' tag links must go before the database cascade deletes the subtasks
+func TestHardDeleteTodoWithTaggedSubtask(t *testing.T) {
+	s, db := newTestService(t, nil)
+	ctx := asUser(1)
+	parent, err := s.CreateTodo(ctx, &CreateTodoRequest{Title: "move house", Description: "by friday", Tags: []string{"home"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subtask, err := s.AddSubtask(ctx, parent.ID, &CreateTodoRequest{Title: "pack", Description: "the kitchen", Tags: []string{"home", "boxes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.HardDeleteTodo(ctx, parent.ID); err != nil {
+		t.Fatalf("HardDeleteTodo: %v", err)
+	}
+	var todos, links int64
+	db.Unscoped().Model(&models.Todo{}).Where("id IN ?", []uint{parent.ID, subtask.ID}).Count(&todos)
+	db.Table("todo_tags").Count(&links)
+	if todos != 0 || links != 0 {
+		t.Fatalf("%d todos and %d tag links left, want none", todos, links)
+	}
+}
+
+// every operation of a failed batch is reported on its own, and none of them is kept
+func TestBulkTodosReportsEachOperation(t *testing.T) {
+	s, db := newTestService(t, nil)
+	ctx := asUser(1)
+	todo, err := s.CreateTodo(ctx, &CreateTodoRequest{Title: "water plants", Description: "all of them"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.BulkTodos(ctx, &BulkTodosRequest{Operations: []BulkOperation{
+		{Op: BulkComplete, ID: todo.ID},
+		{Op: BulkSoftDelete, ID: 99},
+		{Op: BulkTag, ID: todo.ID, Tags: []string{"garden"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{BulkStatusRolledBack, BulkStatusFailed, BulkStatusRolledBack}
+	for i, result := range res.Results {
+		if result.Status != want[i] {
+			t.Errorf("operation %d: status %s (%s), want %s", i, result.Status, result.Error, want[i])
+		}
+	}
+	if res.Applied {
+		t.Fatal("a batch with a failed operation was applied")
+	}
+	stored, err := s.GetTodoByID(ctx, todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var links int64
+	db.Table("todo_tags").Count(&links)
+	if stored.Completed || links != 0 {
+		t.Fatalf("completed=%v with %d tag links after the batch was rolled back", stored.Completed, links)
+	}
+}
+
+// the parent lists its subtasks in order, so reordering them changes its ETag
+func TestReorderSubtasksBumpsParentVersion(t *testing.T) {
+	s, _ := newTestService(t, nil)
+	ctx := asUser(1)
+	parent, err := s.CreateTodo(ctx, &CreateTodoRequest{Title: "trip", Description: "pack for the trip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for _, title := range []string{"passport", "charger"} {
+		subtask, err := s.AddSubtask(ctx, parent.ID, &CreateTodoRequest{Title: title, Description: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, subtask.ID)
+	}
+	before, err := s.GetTodoByID(ctx, parent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReorderSubtasks(ctx, parent.ID, &ReorderSubtasksRequest{SubtaskIDs: []uint{ids[1], ids[0]}}); err != nil {
+		t.Fatal(err)
+	}
+	after, err := s.GetTodoByID(ctx, parent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Version <= before.Version {
+		t.Fatalf("parent version %d after reordering, was %d", after.Version, before.Version)
+	}
+}
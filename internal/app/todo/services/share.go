@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+)
+
+// how long an emailed invitation can be accepted for
+const shareInviteTTL = 7 * 24 * time.Hour
+
+// share statuses
+const (
+	ShareStatusPending  = "pending"
+	ShareStatusAccepted = "accepted"
+	ShareStatusExpired  = "expired"
+)
+
+type ShareTodoRequest struct {
+	TodoID uint   `json:"-" path:"id" validate:"required"`
+	Email  string `json:"email" validate:"required,email,max=255"`
+	Role   string `json:"role" validate:"required,oneof=viewer editor"`
+}
+
+type AcceptShareRequest struct {
+	Token string `json:"token" validate:"required,len=64,hexadecimal"`
+}
+
+type ShareResponse struct {
+	ID         uint   `json:"id"`
+	TodoID     uint   `json:"todo_id"`
+	TodoTitle  string `json:"todo_title,omitempty"`
+	OwnerID    uint   `json:"owner_id"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	Status     string `json:"status"`
+	InviteSent *bool  `json:"invite_sent,omitempty"`
+	ExpiresAt  string `json:"expires_at,omitempty"`
+	AcceptedAt string `json:"accepted_at,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// invite someone by email to view or edit a todo the current user owns;
+// inviting an address again refreshes a pending invitation or changes an accepted grant's role
+func (s *todoService) ShareTodo(ctx context.Context, shareReq *ShareTodoRequest) (*ShareResponse, error) {
+	shareReq.Email = strings.ToLower(strings.TrimSpace(shareReq.Email))
+	if fieldErrors := s.validator.StructCtx(ctx, shareReq); fieldErrors != nil {
+		s.log.Warn("validation failed for share todo request", "error", fieldErrors)
+		return nil, appErrors.ValidationError("invalid share data", nil, fieldErrors)
+	}
+	todo, err := s.findOwnedTodo(ctx, shareReq.TodoID)
+	if err != nil {
+		return nil, err
+	}
+	if todo.ParentID != nil {
+		return nil, appErrors.ValidationError("subtasks are shared through their parent todo", nil, nil)
+	}
+	owner, err := s.users.GetUser(ctx, todo.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if shareReq.Email == strings.ToLower(owner.Email) {
+		return nil, appErrors.ValidationError("invalid share data", nil, map[string]string{"email": "You cannot share a todo with yourself"})
+	}
+
+	share, err := s.shares.GetShareByEmail(ctx, todo.ID, shareReq.Email)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+	if share == nil {
+		share = &models.TodoShare{TodoID: todo.ID, OwnerID: todo.UserID, Email: shareReq.Email}
+	} else if share.AcceptedAt != nil {
+		// an accepted grant only changes role
+		if share.Role == shareReq.Role {
+			return nil, appErrors.ConflictError(fmt.Sprintf("todo is already shared with %s", shareReq.Email), nil)
+		}
+		share.Role = shareReq.Role
+		if err := s.shares.SaveShare(ctx, share); err != nil {
+			return nil, err
+		}
+		s.log.Info("service: todo share role changed", "todoID", todo.ID, "shareID", share.ID, "role", share.Role)
+		res := toShareResponse(share)
+		res.TodoTitle = todo.Title
+		return res, nil
+	}
+	token, tokenHash, err := newShareToken()
+	if err != nil {
+		s.log.Error("service: failed to generate share token", err)
+		return nil, appErrors.InternalServerError("failed to create invitation", err)
+	}
+	share.Role = shareReq.Role
+	share.TokenHash = tokenHash
+	share.ExpiresAt = time.Now().Add(shareInviteTTL)
+	if err := s.shares.SaveShare(ctx, share); err != nil {
+		return nil, err
+	}
+
+	res := toShareResponse(share)
+	res.TodoTitle = todo.Title
+	sent := s.sendShareInvite(share, todo, owner.Email, token)
+	res.InviteSent = &sent
+	s.log.Info("service: todo shared", "todoID", todo.ID, "shareID", share.ID, "role", share.Role)
+	return res, nil
+}
+
+// shares and pending invitations on a todo, visible to its owner
+func (s *todoService) GetShares(ctx context.Context, todoID uint) ([]ShareResponse, error) {
+	todo, err := s.findOwnedTodo(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := s.shares.GetTodoShares(ctx, todo.UserID, todo.ID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]ShareResponse, len(shares))
+	for i := range shares {
+		responses[i] = *toShareResponse(&shares[i])
+		responses[i].TodoTitle = todo.Title
+	}
+	return responses, nil
+}
+
+// the owner can revoke any share; a recipient can remove their own access
+func (s *todoService) RevokeShare(ctx context.Context, todoID, shareID uint) error {
+	return s.inTransaction(ctx, func(txService *todoService) error {
+		return txService.revokeShare(ctx, todoID, shareID)
+	})
+}
+
+func (s *todoService) revokeShare(ctx context.Context, todoID, shareID uint) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	share, err := s.shares.GetShare(ctx, shareID)
+	if err != nil || share.TodoID != todoID {
+		return appErrors.NotFoundError(fmt.Sprintf("share with id %d not found", shareID), err)
+	}
+	isRecipient := share.UserID != nil && *share.UserID == userID
+	if share.OwnerID != userID && !isRecipient {
+		return appErrors.NotFoundError(fmt.Sprintf("share with id %d not found", shareID), nil)
+	}
+	if err := s.shares.DeleteShare(ctx, share); err != nil {
+		return err
+	}
+	if share.UserID != nil {
+		// the todo disappears for the former grantee
+		if err := s.publishDeleted(ctx, todoID, false, []uint{*share.UserID}); err != nil {
+			return err
+		}
+	}
+	s.log.Info("service: todo share revoked", "todoID", todoID, "shareID", shareID, "userID", userID)
+	return nil
+}
+
+// pending invitations addressed to the current user's email
+func (s *todoService) GetInvitations(ctx context.Context) ([]ShareResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := s.shares.GetInvitations(ctx, strings.ToLower(user.Email), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]ShareResponse, len(shares))
+	for i := range shares {
+		responses[i] = *toShareResponse(&shares[i])
+		responses[i].TodoTitle = shares[i].Todo.Title
+	}
+	return responses, nil
+}
+
+// accept an invitation with the token from the invite email; it must have been sent
+// to the current user's address
+func (s *todoService) AcceptShare(ctx context.Context, acceptReq *AcceptShareRequest) (*ShareResponse, error) {
+	var res *ShareResponse
+	err := s.inTransaction(ctx, func(txService *todoService) (err error) {
+		res, err = txService.acceptShare(ctx, acceptReq)
+		return err
+	})
+	return res, err
+}
+
+func (s *todoService) acceptShare(ctx context.Context, acceptReq *AcceptShareRequest) (*ShareResponse, error) {
+	acceptReq.Token = strings.ToLower(strings.TrimSpace(acceptReq.Token))
+	if fieldErrors := s.validator.StructCtx(ctx, acceptReq); fieldErrors != nil {
+		return nil, appErrors.ValidationError("invalid invitation token", nil, fieldErrors)
+	}
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	share, err := s.shares.GetShareByTokenHash(ctx, hashShareToken(acceptReq.Token))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, appErrors.NotFoundError("invitation not found", err)
+		}
+		return nil, err
+	}
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(user.Email, share.Email) {
+		s.log.Warn("service: invitation accepted by another account", "shareID", share.ID, "userID", userID)
+		return nil, appErrors.NotFoundError("invitation not found", nil)
+	}
+	if share.AcceptedAt != nil {
+		return nil, appErrors.ConflictError("invitation has already been accepted", nil)
+	}
+	if time.Now().After(share.ExpiresAt) {
+		return nil, appErrors.ValidationError("invitation has expired", nil, nil)
+	}
+	now := time.Now()
+	share.UserID = &userID
+	share.AcceptedAt = &now
+	if err := s.shares.SaveShare(ctx, share); err != nil {
+		return nil, err
+	}
+	if todo, err := s.repo.GetTodoByID(ctx, userID, share.TodoID); err == nil {
+		if err := s.emit(ctx, EventTodoCreated, &TodoEvent{TodoID: todo.ID, ActorID: userID, Todo: s.toTodoResponse(todo)}, []uint{userID}); err != nil {
+			return nil, err
+		}
+	}
+	res := toShareResponse(share)
+	res.TodoTitle = share.Todo.Title
+	s.log.Info("service: todo share accepted", "todoID", share.TodoID, "shareID", share.ID, "userID", userID)
+	return res, nil
+}
+
+// load a todo only if the current user owns it
+func (s *todoService) findOwnedTodo(ctx context.Context, id uint) (*models.Todo, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := s.findTodo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if todo.UserID != userID {
+		return nil, appErrors.AuthorizationError("only the owner can manage sharing for this todo", nil)
+	}
+	return todo, nil
+}
+
+// email the invitation; a mail failure leaves the invitation in place so it can be re-sent
+func (s *todoService) sendShareInvite(share *models.TodoShare, todo *models.Todo, ownerEmail, token string) bool {
+	if s.mailer == nil {
+		return false
+	}
+	subject := fmt.Sprintf("%s shared \"%s\" with you on Tusk", ownerEmail, todo.Title)
+	body := fmt.Sprintf(
+		"%s invited you to %s the todo \"%s\".\r\n\r\n"+
+			"Sign in to Tusk with this email address and accept the invitation with this code:\r\n\r\n%s\r\n\r\n"+
+			"The invitation expires on %s.",
+		ownerEmail, shareVerb(share.Role), todo.Title, token, share.ExpiresAt.UTC().Format("2006-01-02 15:04 MST"),
+	)
+	if err := s.mailer.SendEmail([]string{share.Email}, subject, body); err != nil {
+		s.log.Warn("service: failed to send share invitation", "shareID", share.ID, "error", err)
+		if s.cfg != nil && s.cfg.AppMode == "dev" {
+			s.log.Debug("service: share invitation token", "shareID", share.ID, "token", token)
+		}
+		return false
+	}
+	return true
+}
+
+func shareVerb(role string) string {
+	if role == models.ShareRoleEditor {
+		return "edit"
+	}
+	return "view"
+}
+
+// random invite token; only its hash is stored
+func newShareToken() (token, tokenHash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(buf)
+	return token, hashShareToken(token), nil
+}
+
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func isNotFound(err error) bool {
+	var appErr appErrors.AppError
+	return errors.As(err, &appErr) && appErr.Code() == appErrors.CodeNotFound
+}
+
+func toShareResponse(share *models.TodoShare) *ShareResponse {
+	res := &ShareResponse{
+		ID:        share.ID,
+		TodoID:    share.TodoID,
+		OwnerID:   share.OwnerID,
+		Email:     share.Email,
+		Role:      share.Role,
+		Status:    ShareStatusPending,
+		CreatedAt: share.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	switch {
+	case share.AcceptedAt != nil:
+		res.Status = ShareStatusAccepted
+		res.AcceptedAt = share.AcceptedAt.Format("2006-01-02 15:04:05")
+	case time.Now().After(share.ExpiresAt):
+		res.Status = ShareStatusExpired
+	}
+	if share.AcceptedAt == nil {
+		res.ExpiresAt = share.ExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	return res
+}
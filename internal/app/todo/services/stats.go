@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+)
+
+const (
+	// the most buckets one stats request may span
+	maxStatsBuckets = 366
+	statsDateLayout = "2006-01-02"
+)
+
+// StatsRequest selects the timeline; From and To are dates, and default to the
+// last 30 days, 12 weeks or 12 months up to today. All dates are UTC.
+type StatsRequest struct {
+	Bucket string     `json:"bucket" query:"bucket" validate:"omitempty,oneof=day week month"`
+	From   *time.Time `json:"from" query:"from" format:"2006-01-02"`
+	To     *time.Time `json:"to" query:"to" format:"2006-01-02"`
+}
+
+type StatsCounts struct {
+	Total     int64 `json:"total"`
+	Open      int64 `json:"open"`
+	Completed int64 `json:"completed"`
+	Overdue   int64 `json:"overdue"`
+}
+
+// CompletionRate is the share of the todos created in the bucket that are done
+type StatsTimelineEntry struct {
+	Start          string  `json:"start"`
+	Created        int64   `json:"created"`
+	Completed      int64   `json:"completed"`
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+type StatsTimeline struct {
+	Bucket   string               `json:"bucket"`
+	From     string               `json:"from"`
+	To       string               `json:"to"`
+	Timezone string               `json:"timezone"`
+	Entries  []StatsTimelineEntry `json:"entries"`
+}
+
+type TagStatsResponse struct {
+	Tag       string `json:"tag"`
+	Total     int64  `json:"total"`
+	Open      int64  `json:"open"`
+	Completed int64  `json:"completed"`
+	Overdue   int64  `json:"overdue"`
+}
+
+type StatsResponse struct {
+	Counts                   StatsCounts        `json:"counts"`
+	CompletionRate           float64            `json:"completion_rate"`
+	AverageCompletionSeconds *float64           `json:"average_completion_seconds"`
+	Timeline                 StatsTimeline      `json:"timeline"`
+	Tags                     []TagStatsResponse `json:"tags"`
+}
+
+// statistics over the current user's own todos; subtasks and deleted todos are
+// left out
+func (s *todoService) GetStats(ctx context.Context, statsReq *StatsRequest) (*StatsResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fieldErrors := s.validator.StructCtx(ctx, statsReq); fieldErrors != nil {
+		return nil, appErrors.ValidationError("invalid stats request", nil, fieldErrors)
+	}
+	now := time.Now().UTC()
+	bucket := statsReq.Bucket
+	if bucket == "" {
+		bucket = models.StatsBucketDay
+	}
+	from, to, err := statsRange(bucket, statsReq.From, statsReq.To, now)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.stats.GetTodoCounts(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := s.stats.GetStatsTimeline(ctx, userID, bucket, from.Format(statsDateLayout), to.Format(statsDateLayout))
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.stats.GetTagStats(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &StatsResponse{
+		Counts: StatsCounts{
+			Total:     counts.Total,
+			Open:      counts.Total - counts.Completed,
+			Completed: counts.Completed,
+			Overdue:   counts.Overdue,
+		},
+		CompletionRate:           ratio(counts.Completed, counts.Total),
+		AverageCompletionSeconds: counts.AvgCompletionSeconds,
+		Timeline: StatsTimeline{
+			Bucket:   bucket,
+			From:     from.Format(statsDateLayout),
+			To:       to.Format(statsDateLayout),
+			Timezone: "UTC",
+			Entries:  statsEntries(bucket, from, to, buckets),
+		},
+		Tags: make([]TagStatsResponse, len(tags)),
+	}
+	for i, tag := range tags {
+		res.Tags[i] = TagStatsResponse{
+			Tag:       tag.Tag,
+			Total:     tag.Total,
+			Open:      tag.Total - tag.Completed,
+			Completed: tag.Completed,
+			Overdue:   tag.Overdue,
+		}
+	}
+	return res, nil
+}
+
+// the first and last bucket of the timeline, both aligned to a bucket start
+func statsRange(bucket string, from, to *time.Time, now time.Time) (time.Time, time.Time, error) {
+	end := bucketStart(bucket, now)
+	if to != nil {
+		end = bucketStart(bucket, *to)
+	}
+	var start time.Time
+	if from != nil {
+		start = bucketStart(bucket, *from)
+	} else {
+		switch bucket {
+		case models.StatsBucketDay:
+			start = end.AddDate(0, 0, -29)
+		case models.StatsBucketWeek:
+			start = end.AddDate(0, 0, -7*11)
+		default:
+			start = end.AddDate(0, -11, 0)
+		}
+	}
+	if start.After(end) {
+		return time.Time{}, time.Time{}, appErrors.ValidationError("invalid stats range", nil, map[string]string{"from": "Must not be after to"})
+	}
+	n := 0
+	for t := start; !t.After(end); t = nextBucket(bucket, t) {
+		if n++; n > maxStatsBuckets {
+			return time.Time{}, time.Time{}, appErrors.ValidationError(fmt.Sprintf("a stats timeline may span at most %d buckets", maxStatsBuckets), nil, nil)
+		}
+	}
+	return start, end, nil
+}
+
+// midnight UTC on the day, the Monday or the first of the month t falls in
+func bucketStart(bucket string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch bucket {
+	case models.StatsBucketWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case models.StatsBucketMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+func nextBucket(bucket string, t time.Time) time.Time {
+	switch bucket {
+	case models.StatsBucketWeek:
+		return t.AddDate(0, 0, 7)
+	case models.StatsBucketMonth:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// one entry per bucket from start to end, including the empty ones the
+// database has no rows for
+func statsEntries(bucket string, start, end time.Time, buckets []models.StatsBucket) []StatsTimelineEntry {
+	byStart := make(map[string]models.StatsBucket, len(buckets))
+	for _, b := range buckets {
+		byStart[b.Start] = b
+	}
+	var entries []StatsTimelineEntry
+	for t := start; !t.After(end); t = nextBucket(bucket, t) {
+		key := t.Format(statsDateLayout)
+		b := byStart[key]
+		entries = append(entries, StatsTimelineEntry{
+			Start:          key,
+			Created:        b.Created,
+			Completed:      b.Completed,
+			CompletionRate: ratio(b.CreatedCompleted, b.Created),
+		})
+	}
+	return entries
+}
+
+func ratio(part, whole int64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
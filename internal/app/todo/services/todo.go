@@ -4,25 +4,96 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"time"
 
+	"github.com/codetheuri/todolist/config"
 	"github.com/codetheuri/todolist/internal/app/todo/models"
 	"github.com/codetheuri/todolist/internal/app/todo/repositories"
+	"github.com/codetheuri/todolist/internal/platform/identity"
+	tokenPkg "github.com/codetheuri/todolist/pkg/auth/token"
 	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/events"
 	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/mailer"
 	"github.com/codetheuri/todolist/pkg/pagination"
+	"github.com/codetheuri/todolist/pkg/storage"
 	"github.com/codetheuri/todolist/pkg/validators"
 )
 
+// codes of the todo service's own failures
+var (
+	codeCreateFailed = appErrors.Register("CREATE_FAILED", http.StatusInternalServerError, appErrors.LevelError, false)
+	codeUpdateFailed = appErrors.Register("UPDATE_FAILED", http.StatusInternalServerError, appErrors.LevelError, false)
+)
+
 // interface
 type TodoService interface {
 	CreateTodo(ctx context.Context,createReq *CreateTodoRequest) (*TodoResponse, error)
-	GetTodoByID(id uint) (*TodoResponse, error)
+	GetTodoByID(ctx context.Context, id uint) (*TodoResponse, error)
 	GetAllTodos(ctx context.Context, page, limit int) (*pagination.PaginationResponse, error)
-	UpdateTodo(updateReq *UpdateTodoRequest) (*TodoResponse, error)
+	UpdateTodo(ctx context.Context, updateReq *UpdateTodoRequest) (*TodoResponse, error)
+	PatchTodo(ctx context.Context, patchReq *PatchTodoRequest) (*TodoResponse, error)
 	GetAllIncludingDeleted(ctx context.Context, page, limit int) (*pagination.PaginationResponse, error)
-	SoftDeleteTodo(id uint) error
-	RestoreTodo(id uint) error
-	HardDeleteTodo(id uint) error
+	SoftDeleteTodo(ctx context.Context, id uint) error
+	RestoreTodo(ctx context.Context, id uint) error
+	HardDeleteTodo(ctx context.Context, id uint) error
+	BulkTodos(ctx context.Context, bulkReq *BulkTodosRequest) (*BulkTodosResponse, error)
+
+	// trash
+	GetTrash(ctx context.Context, page, limit int) (*pagination.PaginationResponse, error)
+	PurgeTrashedTodo(ctx context.Context, id uint) error
+	EmptyTrash(ctx context.Context) (*EmptyTrashResponse, error)
+
+	// stats
+	GetStats(ctx context.Context, statsReq *StatsRequest) (*StatsResponse, error)
+
+	// subtasks
+	AddSubtask(ctx context.Context, parentID uint, createReq *CreateTodoRequest) (*TodoResponse, error)
+	GetSubtasks(ctx context.Context, parentID uint) ([]TodoResponse, error)
+	ReorderSubtasks(ctx context.Context, parentID uint, reorderReq *ReorderSubtasksRequest) ([]TodoResponse, error)
+	CompleteTodo(ctx context.Context, completeReq *CompleteTodoRequest) (*TodoResponse, error)
+
+	// recurrence
+	GetOccurrences(ctx context.Context, id uint, count int) (*OccurrencesResponse, error)
+	PreviewOccurrences(ctx context.Context, previewReq *PreviewOccurrencesRequest) (*OccurrencesResponse, error)
+
+	// comments and activity
+	AddComment(ctx context.Context, todoID uint, commentReq *CommentRequest) (*CommentResponse, error)
+	GetComments(ctx context.Context, todoID uint, page, limit int) (*pagination.PaginationResponse, error)
+	UpdateComment(ctx context.Context, todoID, commentID uint, commentReq *CommentRequest) (*CommentResponse, error)
+	DeleteComment(ctx context.Context, todoID, commentID uint) error
+	GetActivity(ctx context.Context, todoID uint, page, limit int) (*pagination.PaginationResponse, error)
+
+	// sharing
+	ShareTodo(ctx context.Context, shareReq *ShareTodoRequest) (*ShareResponse, error)
+	GetShares(ctx context.Context, todoID uint) ([]ShareResponse, error)
+	RevokeShare(ctx context.Context, todoID, shareID uint) error
+	GetInvitations(ctx context.Context) ([]ShareResponse, error)
+	AcceptShare(ctx context.Context, acceptReq *AcceptShareRequest) (*ShareResponse, error)
+
+	// export and import
+	ExportTodos(ctx context.Context, format string, w io.Writer) error
+	ImportTodos(ctx context.Context, importReq *ImportTodosRequest) (*ImportTodosResponse, error)
+
+	// attachments
+	UploadAttachment(ctx context.Context, uploadReq *UploadAttachmentRequest) (*AttachmentResponse, error)
+	GetAttachments(ctx context.Context, todoID uint) ([]AttachmentResponse, error)
+	GetAttachment(ctx context.Context, todoID, attachmentID uint) (*AttachmentResponse, error)
+	DeleteAttachment(ctx context.Context, todoID, attachmentID uint) error
+
+	// change stream
+	SubscribeChanges(ctx context.Context, lastEventID string) (*ChangeStream, error)
+
+	// webhooks
+	CreateWebhook(ctx context.Context, createReq *CreateWebhookRequest) (*WebhookResponse, error)
+	GetWebhooks(ctx context.Context) ([]WebhookResponse, error)
+	GetWebhook(ctx context.Context, id uint) (*WebhookResponse, error)
+	UpdateWebhook(ctx context.Context, updateReq *UpdateWebhookRequest) (*WebhookResponse, error)
+	DeleteWebhook(ctx context.Context, id uint) error
+	GetWebhookDeliveries(ctx context.Context, webhookID uint, status string, page, limit int) (*pagination.PaginationResponse, error)
+	RedeliverWebhook(ctx context.Context, webhookID, deliveryID uint) (*WebhookDeliveryResponse, error)
 }
 
 // implement dtos
@@ -30,19 +101,51 @@ type CreateTodoRequest struct {
 	Title       string `json:"title" validate:"required,min=3,max=100"`
 	Description string `json:"description" validate:"required,max=255"`
 	Completed   bool   `json:"completed"`
+	DueAt       *time.Time `json:"due_at" validate:"required_with=RRule"`
+	RRule       string     `json:"rrule" validate:"omitempty,max=255"`
+	Timezone    string     `json:"timezone" validate:"omitempty,timezone"`
+	Tags        []string   `json:"tags" validate:"omitempty,max=20,dive,required,max=50"`
 }
 type UpdateTodoRequest struct {
-	ID          uint   `json:"id" validate:"required"`
+	ID          uint   `json:"-" path:"id" validate:"required"`
 	Title       *string `json:"title" validate:"omitempty,min=3,max=100"`
 	Description string `json:"description" validate:"omitempty,max=255"`
 	Completed   bool   `json:"completed"`
+	DueAt       *time.Time `json:"due_at"`
+	// an empty rrule stops the series
+	RRule    *string `json:"rrule" validate:"omitempty,max=255"`
+	Timezone *string `json:"timezone" validate:"omitempty,timezone"`
+	// when completing a parent, also complete its subtasks
+	Cascade bool `json:"cascade"`
+}
+type ReorderSubtasksRequest struct {
+	SubtaskIDs []uint `json:"subtask_ids" validate:"required,min=1,dive,required"`
+}
+type CompleteTodoRequest struct {
+	ID        uint `json:"-" validate:"required"`
+	Completed bool `json:"completed"`
+	Cascade   bool `json:"cascade"`
 }
 
 type TodoResponse struct {
 	ID          uint   `json:"id"`
+	OwnerID     uint   `json:"owner_id"`
+	ParentID    *uint  `json:"parent_id,omitempty"`
+	Position    int    `json:"position"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Completed   bool   `json:"completed"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	DueAt            string `json:"due_at,omitempty"`
+	RRule            string `json:"rrule,omitempty"`
+	Timezone         string `json:"timezone,omitempty"`
+	NextOccurrenceID *uint  `json:"next_occurrence_id,omitempty"`
+	Version          uint   `json:"version"`
+	Tags             []string `json:"tags"`
+	// subtask progress; Progress is a percentage
+	SubtaskCount      int64 `json:"subtask_count"`
+	CompletedSubtasks int64 `json:"completed_subtasks"`
+	Progress          int   `json:"progress"`
 	DeletedAt   string `json:"deleted_at,omitempty"` 
 	CreatedAt   string `json:"created_at"`
 	UpdatedAt   string `json:"updated_at"`
@@ -50,70 +153,167 @@ type TodoResponse struct {
 
 // implement TodoService interface
 type todoService struct {
-	repo      repositories.TodoRepository
+	repos     *repositories.TodoRepositories
+	repo        repositories.TodoRepository
+	shares      repositories.ShareRepository
+	comments    repositories.CommentRepository
+	activity    repositories.ActivityRepository
+	attachments repositories.AttachmentRepository
+	webhooks    repositories.WebhookRepository
+	trash       repositories.TrashRepository
+	stats       repositories.StatsRepository
+	users     identity.UserDirectory
 	validator *validators.Validator
+	cfg       *config.Config
+	mailer    mailer.MailerService
+	storage   storage.Storage
+	events    *events.Hub
 	log       logger.Logger
+	// set inside a transaction: stored files to delete and change events to
+	// publish once it commits
+	pendingFileDeletes *[]string
+	pendingEvents      *[]pendingEvent
 }
 
 // new todo service instance
-func NewTodoService(repo repositories.TodoRepository, validator *validators.Validator, log logger.Logger) TodoService {
-	return &todoService{
-		repo:      repo,
+func NewTodoService(repos *repositories.TodoRepositories, users identity.UserDirectory, validator *validators.Validator, cfg *config.Config, mailer mailer.MailerService, storage storage.Storage, hub *events.Hub, log logger.Logger) TodoService {
+	s := &todoService{
+		users:     users,
 		validator: validator,
+		cfg:       cfg,
+		mailer:    mailer,
+		storage:   storage,
+		events:    hub,
 		log:       log,
 	}
+	s.useRepos(repos)
+	return s
+}
+
+func (s *todoService) useRepos(repos *repositories.TodoRepositories) {
+	s.repos = repos
+	s.repo = repos.TodoRepo
+	s.shares = repos.ShareRepo
+	s.comments = repos.CommentRepo
+	s.activity = repos.ActivityRepo
+	s.attachments = repos.AttachmentRepo
+	s.webhooks = repos.WebhookRepo
+	s.trash = repos.TrashRepo
+	s.stats = repos.StatsRepo
+}
+
+// copy of the service whose repository calls all run in the given transaction
+func (s *todoService) withRepos(repos *repositories.TodoRepositories) *todoService {
+	txService := *s
+	txService.useRepos(repos)
+	return &txService
+}
+
+// run fn in a transaction, or in the caller's when already inside one, so that a
+// change and the webhook deliveries it queues commit together; stored files are
+// deleted and change events published only once it has committed
+func (s *todoService) inTransaction(ctx context.Context, fn func(txService *todoService) error) error {
+	if s.pendingEvents != nil {
+		return fn(s)
+	}
+	var fileDeletes []string
+	var pendingEvents []pendingEvent
+	err := s.repos.Transaction(ctx, func(txRepos *repositories.TodoRepositories) error {
+		txService := s.withRepos(txRepos)
+		txService.pendingFileDeletes = &fileDeletes
+		txService.pendingEvents = &pendingEvents
+		return fn(txService)
+	})
+	if err != nil {
+		return err
+	}
+	s.deleteFiles(ctx, fileDeletes)
+	s.flushEvents(pendingEvents)
+	return nil
 }
 
 // CreateTodo
 func (s *todoService) CreateTodo(ctx context.Context,createReq *CreateTodoRequest) (*TodoResponse, error) {
+	var res *TodoResponse
+	err := s.inTransaction(ctx, func(txService *todoService) (err error) {
+		res, err = txService.createTodo(ctx, createReq, true)
+		return err
+	})
+	return res, err
+}
+
+// scheduleNext is off for imports, whose files already hold the following occurrence
+func (s *todoService) createTodo(ctx context.Context, createReq *CreateTodoRequest, scheduleNext bool) (*TodoResponse, error) {
 	//validate
-	fieldErrors := s.validator.Struct(createReq)
+	fieldErrors := s.validator.StructCtx(ctx, createReq)
 	if fieldErrors != nil {
 		s.log.Warn("validation failed for create todo request", "error", fieldErrors)
 		return nil, appErrors.ValidationError("invalid todo data", nil, fieldErrors)
 	}
 
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	//logic
 
 	todo := &models.Todo{
+		UserID:      userID,
 		Title:       createReq.Title,
 		Description: createReq.Description,
-		Completed:   createReq.Completed,
+		DueAt:       createReq.DueAt,
+		Timezone:    createReq.Timezone,
+	}
+	if err := applyRecurrence(todo, createReq.RRule); err != nil {
+		return nil, err
 	}
+	setCompleted(todo, createReq.Completed)
 	//persist
-	createdTodo, err := s.repo.CreateTodo(ctx,todo)
+	createdTodo, err := s.repo.CreateTodo(ctx, userID, todo)
 	if err != nil {
 		s.log.Error("service: failed to create todo in repository", err)
 
 		var dbErr appErrors.AppError
-		if errors.As(err, &dbErr) && dbErr.Code() == "DATABASE_ERROR" {
-			return nil, appErrors.New("CREATE_FAILED", "failed to create todo due to database issue", err)
+		if errors.As(err, &dbErr) && dbErr.Code() == appErrors.CodeDatabase {
+			return nil, appErrors.New(codeCreateFailed, "failed to create todo due to database issue", err)
 		}
 		return nil, err
 	}
+	if err := s.repo.AddTags(ctx, userID, createdTodo, normalizeTags(createReq.Tags)); err != nil {
+		return nil, err
+	}
+	s.recordActivity(ctx, createdTodo.ID, models.ActivityCreated, nil, map[string]string{"title": createdTodo.Title})
+	if createdTodo.Completed && scheduleNext {
+		if err := s.scheduleNextOccurrence(ctx, createdTodo); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.publishTodo(ctx, EventTodoCreated, createdTodo.ID); err != nil {
+		return nil, err
+	}
 	return s.toTodoResponse(createdTodo), nil
 }
-func (s *todoService) GetTodoByID(id uint) (*TodoResponse, error) {
+func (s *todoService) GetTodoByID(ctx context.Context, id uint) (*TodoResponse, error) {
 	//fetch
 
-	todo, err := s.repo.GetTodoByID(id)
+	todo, err := s.findTodo(ctx, id)
 	if err != nil {
 		s.log.Error("service: failed to get todo by id", err, "id", id)
-		var notFoundErr appErrors.AppError
-		if errors.As(err, &notFoundErr) && notFoundErr.Code() == "NOT_FOUND" {
-			return nil, appErrors.NotFoundError(fmt.Sprintf("todo with id %d not found", id), err)
-		}
 		return nil, err
 	}
 	//map to response
-	return s.toTodoResponse(todo), nil
+	return s.toTodoResponseWithProgress(ctx, todo)
 }
 
 // get all
 func (s *todoService) GetAllTodos(ctx context.Context,page, limit int) (*pagination.PaginationResponse, error) {
 	//fetch
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	p := pagination.NewPaginationParams(page, limit)
-	todos,totalCount, err := s.repo.GetAllTodos(ctx, p.Offset(), p.Limit)
+	todos,totalCount, err := s.repo.GetAllTodos(ctx, userID, p.Offset(), p.Limit)
 
 	if err != nil {
 		  s.log.Error("Service: Failed to get all todos from repository", err)
@@ -124,6 +324,9 @@ func (s *todoService) GetAllTodos(ctx context.Context,page, limit int) (*paginat
 	for i, todo := range todos {
 		todoResponses[i] = *s.toTodoResponse(&todo)
 	}
+	if err := s.attachProgress(ctx, todoResponses); err != nil {
+		return nil, err
+	}
 	//pagination metadata
      metadata := pagination.NewPaginationmetadata(p.Page,p.Limit, totalCount)
     s.log.Info("Service: Successfully retrieved paginated todos", "page", p.Page, "limit", p.Limit, "total_items", totalCount)
@@ -135,24 +338,37 @@ func (s *todoService) GetAllTodos(ctx context.Context,page, limit int) (*paginat
 }
 
 // update
-func (s *todoService) UpdateTodo(updateReq *UpdateTodoRequest) (*TodoResponse, error) {
+func (s *todoService) UpdateTodo(ctx context.Context, updateReq *UpdateTodoRequest) (*TodoResponse, error) {
+	var res *TodoResponse
+	err := s.inTransaction(ctx, func(txService *todoService) (err error) {
+		res, err = txService.updateTodo(ctx, updateReq)
+		return err
+	})
+	return res, err
+}
+
+func (s *todoService) updateTodo(ctx context.Context, updateReq *UpdateTodoRequest) (*TodoResponse, error) {
 	//validate\
-	fieldErrors := s.validator.Struct(updateReq)
+	fieldErrors := s.validator.StructCtx(ctx, updateReq)
 	if fieldErrors != nil {
 		s.log.Warn("validation failed ", "error", fieldErrors)
 		return nil, appErrors.ValidationError("invalid todo data", nil, fieldErrors)
 	}
 
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	//fetch existing
-	existingTodo, err := s.repo.GetTodoByID(updateReq.ID)
+	existingTodo, err := s.findTodo(ctx, updateReq.ID)
 	if err != nil {
 		s.log.Error("service : failed to get data", err, "id", updateReq.ID)
-		var notFoundErr appErrors.AppError
-		if errors.As(err, &notFoundErr) && notFoundErr.Code() == "NOT_FOUND" {
-			return nil, appErrors.NotFoundError(fmt.Sprintf("todo with id %d not found", updateReq.ID), err)
-		}
 		return nil, err
 	}
+	if err := s.checkIfMatch(ctx, existingTodo.ID, existingTodo.Version); err != nil {
+		return nil, err
+	}
+	before := *existingTodo
 	//update fields
 	if updateReq.Title != nil {
 		existingTodo.Title = *updateReq.Title
@@ -160,27 +376,61 @@ func (s *todoService) UpdateTodo(updateReq *UpdateTodoRequest) (*TodoResponse, e
 	if updateReq.Description != "" {
 		existingTodo.Description = updateReq.Description
 	}
+	if updateReq.DueAt != nil {
+		existingTodo.DueAt = updateReq.DueAt
+	}
+	if updateReq.Timezone != nil {
+		existingTodo.Timezone = *updateReq.Timezone
+	}
+	if updateReq.RRule != nil {
+		if err := applyRecurrence(existingTodo, *updateReq.RRule); err != nil {
+			return nil, err
+		}
+	}
 	// if updateReq.Completed is false, we don't update it
 	if updateReq.Completed != existingTodo.Completed {
-		existingTodo.Completed = updateReq.Completed
+		setCompleted(existingTodo, updateReq.Completed)
 	}
 	//persist
-	updatedTodo, err := s.repo.UpdateTodo(existingTodo)
+	updatedTodo, err := s.repo.UpdateTodo(ctx, userID, existingTodo)
 	if err != nil {
 		s.log.Error("service: failed to update todo in repository", err, "id", existingTodo.ID)
 		var dbErr appErrors.AppError
-		if errors.As(err, &dbErr) && dbErr.Code() == "DATABASE_ERROR" {
-			return nil, appErrors.New("UPDATE_FAILED", "failed to update due to database issue", err)
+		if errors.As(err, &dbErr) && dbErr.Code() == appErrors.CodeDatabase {
+			return nil, appErrors.New(codeUpdateFailed, "failed to update due to database issue", err)
 		}
 		return nil, err
 	}
-	return s.toTodoResponse(updatedTodo), nil
+	s.recordChanges(ctx, &before, updatedTodo)
+	if updateReq.Cascade && updatedTodo.Completed {
+		if err := s.repo.SetSubtasksCompleted(ctx, userID, updatedTodo, true, updatedTodo.CompletedAt); err != nil {
+			return nil, err
+		}
+	}
+	if updatedTodo.Completed {
+		if err := s.scheduleNextOccurrence(ctx, updatedTodo); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.publishTodo(ctx, EventTodoUpdated, updatedTodo.ID); err != nil {
+		return nil, err
+	}
+	if updateReq.Cascade && updatedTodo.Completed {
+		if err := s.publishSubtasks(ctx, updatedTodo.ID); err != nil {
+			return nil, err
+		}
+	}
+	return s.toTodoResponseWithProgress(ctx, updatedTodo)
 
 }
 // get all including deleted
 func (s *todoService) GetAllIncludingDeleted(ctx context.Context, page, limit int) (*pagination.PaginationResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	p := pagination.NewPaginationParams(page, limit)
-	todos,totalCount, err := s.repo.GetAllIncludingDeleted(ctx, p.Offset(), p.Limit)
+	todos,totalCount, err := s.repo.GetAllIncludingDeleted(ctx, userID, p.Offset(), p.Limit)
 
 	if err != nil {
 		  s.log.Error("Service: Failed to get all todos from repository", err)
@@ -191,6 +441,9 @@ func (s *todoService) GetAllIncludingDeleted(ctx context.Context, page, limit in
 	for i, todo := range todos {
 		todoResponses[i] = *s.toTodoResponse(&todo)
 	}
+	if err := s.attachProgress(ctx, todoResponses); err != nil {
+		return nil, err
+	}
 	//pagination metadata
      metadata := pagination.NewPaginationmetadata(p.Page,p.Limit, totalCount)
     s.log.Info("Service: Successfully retrieved paginated todos", "page", p.Page, "limit", p.Limit, "total_items", totalCount)
@@ -202,54 +455,389 @@ func (s *todoService) GetAllIncludingDeleted(ctx context.Context, page, limit in
 }
 
 // soft delete
-func (s *todoService) SoftDeleteTodo(id uint) error {
+func (s *todoService) SoftDeleteTodo(ctx context.Context, id uint) error {
+	return s.inTransaction(ctx, func(txService *todoService) error {
+		return txService.softDeleteTodo(ctx, id)
+	})
+}
+
+func (s *todoService) softDeleteTodo(ctx context.Context, id uint) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	version, err := s.ifMatchVersion(ctx, id)
+	if err != nil {
+		return err
+	}
+	audience, err := s.shares.GetTodoAudience(ctx, id)
+	if err != nil {
+		return err
+	}
 	// call
-	err := s.repo.SoftDeleteTodo(id)
+	err = s.repo.SoftDeleteTodo(ctx, userID, id, version)
 	if err != nil {
 		s.log.Error("serrvice: failed to delete todo from repository", err, "id", id)
 		var notFoundErr appErrors.AppError
-		if errors.As(err, &notFoundErr) && notFoundErr.Code() == "NOT_FOUND" {
+		if errors.As(err, &notFoundErr) && notFoundErr.Code() == appErrors.CodeNotFound {
 			return appErrors.NotFoundError(fmt.Sprintf("todo with  ID %d not found", id), err)
 		}
 		return err
 	}
-	return nil
+	s.recordActivity(ctx, id, models.ActivityDeleted, nil, nil)
+	return s.publishDeleted(ctx, id, false, audience)
+}
+func (s *todoService) RestoreTodo(ctx context.Context, id uint) error {
+	return s.inTransaction(ctx, func(txService *todoService) error {
+		return txService.restoreTodo(ctx, id)
+	})
 }
-func (s *todoService) RestoreTodo(id uint) error {
 
-	err := s.repo.RestoreTodo(id)
+func (s *todoService) restoreTodo(ctx context.Context, id uint) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	version, err := s.ifMatchVersion(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = s.repo.RestoreTodo(ctx, userID, id, version)
 	if err != nil {
 		s.log.Error("service: failed to restore todo from repository", err, "id", id)
 		var notFoundErr appErrors.AppError
-		if errors.As(err, &notFoundErr) && notFoundErr.Code() == "NOT_FOUND" {
+		if errors.As(err, &notFoundErr) && notFoundErr.Code() == appErrors.CodeNotFound {
 			return appErrors.NotFoundError(fmt.Sprintf("todo with ID %d not found", id), err)
 		}
 		return err
 	}
-	return nil
+	s.recordActivity(ctx, id, models.ActivityRestored, nil, nil)
+	// back in everyone's lists
+	return s.publishTodo(ctx, EventTodoCreated, id)
 }
 
-func (s *todoService) HardDeleteTodo(id uint) error {
-	err := s.repo.HardDeleteTodo(id)
+func (s *todoService) HardDeleteTodo(ctx context.Context, id uint) error {
+	return s.inTransaction(ctx, func(txService *todoService) error {
+		return txService.hardDeleteTodo(ctx, id)
+	})
+}
+
+func (s *todoService) hardDeleteTodo(ctx context.Context, id uint) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	version, err := s.ifMatchVersion(ctx, id)
+	if err != nil {
+		return err
+	}
+	audience, err := s.shares.GetTodoAudience(ctx, id)
+	if err != nil {
+		return err
+	}
+	storageKeys, err := s.repo.HardDeleteTodo(ctx, userID, id, version)
 	if err != nil {
 		s.log.Error("service: failed to hard delete todo from repository", err, "id", id)
 		var notFoundErr appErrors.AppError
-		if errors.As(err, &notFoundErr) && notFoundErr.Code() == "NOT_FOUND" {
+		if errors.As(err, &notFoundErr) && notFoundErr.Code() == appErrors.CodeNotFound {
 			return appErrors.NotFoundError(fmt.Sprintf("todo with ID %d not found", id), err)
 		}
 		return err
 	}
+	s.deleteFiles(ctx, storageKeys)
+	return s.publishDeleted(ctx, id, true, audience)
+}
+// add a checklist item under a top-level todo
+func (s *todoService) AddSubtask(ctx context.Context, parentID uint, createReq *CreateTodoRequest) (*TodoResponse, error) {
+	var res *TodoResponse
+	err := s.inTransaction(ctx, func(txService *todoService) (err error) {
+		res, err = txService.addSubtask(ctx, parentID, createReq)
+		return err
+	})
+	return res, err
+}
+
+func (s *todoService) addSubtask(ctx context.Context, parentID uint, createReq *CreateTodoRequest) (*TodoResponse, error) {
+	fieldErrors := s.validator.StructCtx(ctx, createReq)
+	if fieldErrors != nil {
+		s.log.Warn("validation failed for create subtask request", "error", fieldErrors)
+		return nil, appErrors.ValidationError("invalid subtask data", nil, fieldErrors)
+	}
+	if createReq.RRule != "" {
+		return nil, appErrors.ValidationError("invalid subtask data", nil, map[string]string{"rrule": "Subtasks cannot recur on their own"})
+	}
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.getParent(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	position, err := s.repo.NextSubtaskPosition(ctx, userID, parent.ID)
+	if err != nil {
+		return nil, err
+	}
+	subtask := &models.Todo{
+		UserID:      parent.UserID,
+		Title:       createReq.Title,
+		Description: createReq.Description,
+		DueAt:       createReq.DueAt,
+		ParentID:    &parent.ID,
+		Position:    position,
+	}
+	setCompleted(subtask, createReq.Completed)
+	createdSubtask, err := s.repo.CreateTodo(ctx, userID, subtask)
+	if err != nil {
+		s.log.Error("service: failed to create subtask in repository", err, "parentID", parentID)
+		var dbErr appErrors.AppError
+		if errors.As(err, &dbErr) && dbErr.Code() == appErrors.CodeDatabase {
+			return nil, appErrors.New(codeCreateFailed, "failed to create subtask due to database issue", err)
+		}
+		return nil, err
+	}
+	if err := s.repo.AddTags(ctx, userID, createdSubtask, normalizeTags(createReq.Tags)); err != nil {
+		return nil, err
+	}
+	s.recordActivity(ctx, createdSubtask.ID, models.ActivityCreated, nil, map[string]interface{}{"title": createdSubtask.Title, "parent_id": parent.ID})
+	if err := s.publishTodo(ctx, EventTodoCreated, createdSubtask.ID); err != nil {
+		return nil, err
+	}
+	return s.toTodoResponse(createdSubtask), nil
+}
+
+func (s *todoService) GetSubtasks(ctx context.Context, parentID uint) ([]TodoResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.getParent(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	subtasks, err := s.repo.GetSubtasks(ctx, userID, parent.ID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]TodoResponse, len(subtasks))
+	for i, subtask := range subtasks {
+		responses[i] = *s.toTodoResponse(&subtask)
+	}
+	return responses, nil
+}
+
+func (s *todoService) ReorderSubtasks(ctx context.Context, parentID uint, reorderReq *ReorderSubtasksRequest) ([]TodoResponse, error) {
+	var res []TodoResponse
+	err := s.inTransaction(ctx, func(txService *todoService) (err error) {
+		res, err = txService.reorderSubtasks(ctx, parentID, reorderReq)
+		return err
+	})
+	return res, err
+}
+
+func (s *todoService) reorderSubtasks(ctx context.Context, parentID uint, reorderReq *ReorderSubtasksRequest) ([]TodoResponse, error) {
+	fieldErrors := s.validator.StructCtx(ctx, reorderReq)
+	if fieldErrors != nil {
+		s.log.Warn("validation failed for reorder subtasks request", "error", fieldErrors)
+		return nil, appErrors.ValidationError("invalid subtask order", nil, fieldErrors)
+	}
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.getParent(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReorderSubtasks(ctx, userID, parent.ID, reorderReq.SubtaskIDs); err != nil {
+		s.log.Warn("service: failed to reorder subtasks", "parentID", parentID, "error", err)
+		return nil, err
+	}
+	if err := s.publishSubtasks(ctx, parent.ID); err != nil {
+		return nil, err
+	}
+	return s.GetSubtasks(ctx, parent.ID)
+}
+
+// complete or reopen a todo; Cascade carries completion down to its subtasks
+func (s *todoService) CompleteTodo(ctx context.Context, completeReq *CompleteTodoRequest) (*TodoResponse, error) {
+	var res *TodoResponse
+	err := s.inTransaction(ctx, func(txService *todoService) (err error) {
+		res, err = txService.completeTodo(ctx, completeReq)
+		return err
+	})
+	return res, err
+}
+
+func (s *todoService) completeTodo(ctx context.Context, completeReq *CompleteTodoRequest) (*TodoResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := s.findTodo(ctx, completeReq.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkIfMatch(ctx, todo.ID, todo.Version); err != nil {
+		return nil, err
+	}
+	changed := todo.Completed != completeReq.Completed
+	if changed {
+		before := *todo
+		setCompleted(todo, completeReq.Completed)
+		if todo, err = s.repo.UpdateTodo(ctx, userID, todo); err != nil {
+			s.log.Error("service: failed to update completion state", err, "id", completeReq.ID)
+			return nil, err
+		}
+		s.recordChanges(ctx, &before, todo)
+	}
+	if completeReq.Cascade && todo.Completed {
+		if err := s.repo.SetSubtasksCompleted(ctx, userID, todo, true, todo.CompletedAt); err != nil {
+			return nil, err
+		}
+	}
+	if todo.Completed {
+		if err := s.scheduleNextOccurrence(ctx, todo); err != nil {
+			return nil, err
+		}
+	}
+	if changed {
+		if err := s.publishTodo(ctx, EventTodoUpdated, todo.ID); err != nil {
+			return nil, err
+		}
+	}
+	if completeReq.Cascade && todo.Completed {
+		if err := s.publishSubtasks(ctx, todo.ID); err != nil {
+			return nil, err
+		}
+	}
+	return s.toTodoResponseWithProgress(ctx, todo)
+}
+
+// subtasks can only hang off top-level todos, keeping checklists one level deep
+func (s *todoService) getParent(ctx context.Context, parentID uint) (*models.Todo, error) {
+	parent, err := s.findTodo(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.ParentID != nil {
+		return nil, appErrors.ValidationError("subtasks cannot have subtasks of their own", nil, nil)
+	}
+	return parent, nil
+}
+
+// load a todo the current user owns or has been shared
+func (s *todoService) findTodo(ctx context.Context, id uint) (*models.Todo, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := s.repo.GetTodoByID(ctx, userID, id)
+	if err != nil {
+		var notFoundErr appErrors.AppError
+		if errors.As(err, &notFoundErr) && notFoundErr.Code() == appErrors.CodeNotFound {
+			return nil, appErrors.NotFoundError(fmt.Sprintf("todo with id %d not found", id), err)
+		}
+		return nil, err
+	}
+	return todo, nil
+}
+
+// the authenticated user every todo operation is scoped to
+func currentUserID(ctx context.Context) (uint, error) {
+	userID, ok := tokenPkg.GetUserIDFromContext(ctx)
+	if !ok {
+		return 0, appErrors.AuthError("authentication context missing", nil)
+	}
+	return userID, nil
+}
+
+// keep Completed and CompletedAt in step
+func setCompleted(todo *models.Todo, completed bool) {
+	todo.Completed = completed
+	if completed {
+		now := time.Now()
+		todo.CompletedAt = &now
+	} else {
+		todo.CompletedAt = nil
+	}
+}
+
+// fill subtask counts and progress percentage for a page of responses
+func (s *todoService) attachProgress(ctx context.Context, responses []TodoResponse) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	ids := make([]uint, 0, len(responses))
+	for _, res := range responses {
+		if res.ParentID == nil {
+			ids = append(ids, res.ID)
+		}
+	}
+	progress, err := s.repo.GetSubtaskProgress(ctx, userID, ids)
+	if err != nil {
+		s.log.Error("service: failed to load subtask progress", err)
+		return err
+	}
+	for i := range responses {
+		p := progress[responses[i].ID]
+		responses[i].SubtaskCount = p.Total
+		responses[i].CompletedSubtasks = p.Completed
+		responses[i].Progress = progressPercent(responses[i].Completed, p)
+	}
 	return nil
 }
+
+func (s *todoService) toTodoResponseWithProgress(ctx context.Context, todo *models.Todo) (*TodoResponse, error) {
+	res := []TodoResponse{*s.toTodoResponse(todo)}
+	if err := s.attachProgress(ctx, res); err != nil {
+		return nil, err
+	}
+	return &res[0], nil
+}
+
+// a todo without subtasks is either 0% or 100% done
+func progressPercent(completed bool, p models.SubtaskProgress) int {
+	if p.Total == 0 {
+		if completed {
+			return 100
+		}
+		return 0
+	}
+	return int(p.Completed * 100 / p.Total)
+}
+
 // helper convert models.Todo to TodoResponse
 func (s *todoService) toTodoResponse(todo *models.Todo) *TodoResponse {
-	return &TodoResponse{
+	res := &TodoResponse{
 		ID:          todo.ID,
+		OwnerID:     todo.UserID,
+		ParentID:    todo.ParentID,
+		Position:    todo.Position,
 		Title:       todo.Title,
 		Description: todo.Description,
 		Completed:   todo.Completed,
-		DeletedAt:  todo.DeletedAt.Time.Format("2006-01-02 15:04:05"),
+		Progress:    progressPercent(todo.Completed, models.SubtaskProgress{}),
 		CreatedAt:   todo.CreatedAt.Format("2006-01-02 15:04:05"),
 		UpdatedAt:   todo.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
+	if todo.CompletedAt != nil {
+		res.CompletedAt = todo.CompletedAt.Format("2006-01-02 15:04:05")
+	}
+	if todo.DeletedAt.Valid {
+		res.DeletedAt = todo.DeletedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if todo.DueAt != nil {
+		res.DueAt = todo.DueAt.In(todoLocation(todo)).Format(time.RFC3339)
+	}
+	res.RRule = todo.RRule
+	res.Timezone = todo.Timezone
+	res.NextOccurrenceID = todo.NextOccurrenceID
+	res.Version = todo.Version
+	res.Tags = make([]string, len(todo.Tags))
+	for i, tag := range todo.Tags {
+		res.Tags[i] = tag.Name
+	}
+	return res
 }
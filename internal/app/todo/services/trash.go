@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/codetheuri/todolist/config"
+	"github.com/codetheuri/todolist/internal/app/todo/repositories"
+	tokenPkg "github.com/codetheuri/todolist/pkg/auth/token"
+	"github.com/codetheuri/todolist/pkg/events"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/pagination"
+	"github.com/codetheuri/todolist/pkg/storage"
+)
+
+// expired todos purged per query
+const trashPurgeBatchSize = 100
+
+// PurgeAt is when the retention job will delete the todo for good, if it is configured to
+type TrashedTodoResponse struct {
+	TodoResponse
+	PurgeAt string `json:"purge_at,omitempty"`
+}
+
+type EmptyTrashResponse struct {
+	Purged int `json:"purged"`
+}
+
+// the deleted todos the current user can see, most recently deleted first
+func (s *todoService) GetTrash(ctx context.Context, page, limit int) (*pagination.PaginationResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := pagination.NewPaginationParams(page, limit)
+	todos, totalCount, err := s.trash.GetTrash(ctx, userID, p.Offset(), p.Limit)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]TrashedTodoResponse, len(todos))
+	for i := range todos {
+		responses[i] = TrashedTodoResponse{TodoResponse: *s.toTodoResponse(&todos[i])}
+		if retention := s.trashRetention(); retention > 0 {
+			responses[i].PurgeAt = todos[i].DeletedAt.Time.Add(retention).Format("2006-01-02 15:04:05")
+		}
+	}
+	return &pagination.PaginationResponse{
+		Data:     responses,
+		Metadata: pagination.NewPaginationmetadata(p.Page, p.Limit, totalCount),
+	}, nil
+}
+
+// permanently delete one todo from the trash; only its owner can
+func (s *todoService) PurgeTrashedTodo(ctx context.Context, id uint) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return s.inTransaction(ctx, func(txService *todoService) error {
+		if _, err := txService.trash.GetTrashedTodo(ctx, userID, id); err != nil {
+			return err
+		}
+		return txService.hardDeleteTodo(ctx, id)
+	})
+}
+
+// permanently delete every todo the current user owns that is in the trash
+func (s *todoService) EmptyTrash(ctx context.Context) (*EmptyTrashResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &EmptyTrashResponse{}
+	err = s.inTransaction(ctx, func(txService *todoService) error {
+		ids, err := txService.trash.GetOwnedTrashIDs(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := txService.hardDeleteTodo(ctx, id); err != nil {
+				return err
+			}
+		}
+		res.Purged = len(ids)
+		return nil
+	})
+	if err != nil {
+		s.log.Error("service: failed to empty trash", err, "userID", userID)
+		return nil, err
+	}
+	s.log.Info("service: trash emptied", "userID", userID, "purged", res.Purged)
+	return res, nil
+}
+
+func (s *todoService) trashRetention() time.Duration {
+	if s.cfg == nil {
+		return 0
+	}
+	return s.cfg.TrashRetention
+}
+
+// TrashPurger permanently deletes todos that have been in the trash for longer
+// than TrashRetention. Each todo is purged in its own transaction, on behalf of
+// its owner, exactly as if they had purged it themselves.
+type TrashPurger struct {
+	service *todoService
+}
+
+func NewTrashPurger(repos *repositories.TodoRepositories, cfg *config.Config, storage storage.Storage, hub *events.Hub, log logger.Logger) *TrashPurger {
+	service := &todoService{cfg: cfg, storage: storage, events: hub, log: log}
+	service.useRepos(repos)
+	return &TrashPurger{service: service}
+}
+
+// Start purges expired trash every TrashPurgeInterval until ctx is cancelled; it
+// returns straight away when retention is disabled
+func (p *TrashPurger) Start(ctx context.Context) {
+	s := p.service
+	if s.trashRetention() <= 0 {
+		s.log.Info("trash retention disabled, deleted todos are kept")
+		return
+	}
+	ticker := time.NewTicker(s.cfg.TrashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		if purged := p.purgeExpired(ctx, time.Now()); purged > 0 {
+			s.log.Info("expired trash purged", "purged", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge everything deleted longer than the retention before now
+func (p *TrashPurger) purgeExpired(ctx context.Context, now time.Time) int {
+	s := p.service
+	cutoff := now.Add(-s.trashRetention())
+	purged := 0
+	for ctx.Err() == nil {
+		todos, err := s.trash.GetExpiredTrash(ctx, cutoff, trashPurgeBatchSize)
+		if err != nil || len(todos) == 0 {
+			return purged
+		}
+		failed := 0
+		for _, todo := range todos {
+			ownerCtx := tokenPkg.WithUserID(ctx, todo.UserID)
+			err := s.HardDeleteTodo(ownerCtx, todo.ID)
+			switch {
+			case err == nil:
+				purged++
+			case isNotFound(err):
+				// went with a parent purged earlier in the batch
+			default:
+				failed++
+				s.log.Error("failed to purge expired todo", err, "id", todo.ID, "userID", todo.UserID)
+			}
+		}
+		// a batch that only failed would come back unchanged
+		if failed == len(todos) {
+			return purged
+		}
+	}
+	return purged
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/pagination"
+	"github.com/google/uuid"
+)
+
+// Secret is generated when left out; it is only ever returned by CreateWebhook
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=todo.created todo.updated todo.deleted"`
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=128"`
+	Active *bool    `json:"active"`
+}
+
+// fields left out are kept
+type UpdateWebhookRequest struct {
+	ID     uint     `json:"-" path:"id" validate:"required"`
+	URL    *string  `json:"url" validate:"omitempty,url,max=2048"`
+	Events []string `json:"events" validate:"omitempty,min=1,dive,oneof=todo.created todo.updated todo.deleted"`
+	Secret *string  `json:"secret" validate:"omitempty,min=16,max=128"`
+	Active *bool    `json:"active"`
+}
+
+type WebhookResponse struct {
+	ID        uint     `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Active    bool     `json:"active"`
+	Secret    string   `json:"secret,omitempty"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             uint            `json:"id"`
+	WebhookID      uint            `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  string          `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    string          `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	CreatedAt      string          `json:"created_at"`
+}
+
+// the body of every delivery; ID is the same for each webhook told about one event
+type webhookEnvelope struct {
+	ID        string     `json:"id"`
+	Type      string     `json:"type"`
+	CreatedAt time.Time  `json:"created_at"`
+	Data      *TodoEvent `json:"data"`
+}
+
+// subscribe one of the current user's URLs to changes on the todos they can see
+func (s *todoService) CreateWebhook(ctx context.Context, createReq *CreateWebhookRequest) (*WebhookResponse, error) {
+	if fieldErrors := s.validator.StructCtx(ctx, createReq); fieldErrors != nil {
+		s.log.Warn("validation failed for create webhook request", "error", fieldErrors)
+		return nil, appErrors.ValidationError("invalid webhook data", nil, fieldErrors)
+	}
+	if err := checkWebhookURL(createReq.URL); err != nil {
+		return nil, err
+	}
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	secret := createReq.Secret
+	if secret == "" {
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, appErrors.InternalServerError("failed to generate webhook secret", err)
+		}
+	}
+	webhook := &models.Webhook{
+		UserID: userID,
+		URL:    createReq.URL,
+		Events: joinWebhookEvents(createReq.Events),
+		Secret: secret,
+		Active: createReq.Active == nil || *createReq.Active,
+	}
+	if err := s.webhooks.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	s.log.Info("service: webhook created", "id", webhook.ID, "userID", userID)
+	res := toWebhookResponse(webhook)
+	res.Secret = webhook.Secret
+	return res, nil
+}
+
+func (s *todoService) GetWebhooks(ctx context.Context) ([]WebhookResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := s.webhooks.GetWebhooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]WebhookResponse, len(webhooks))
+	for i := range webhooks {
+		responses[i] = *toWebhookResponse(&webhooks[i])
+	}
+	return responses, nil
+}
+
+func (s *todoService) GetWebhook(ctx context.Context, id uint) (*WebhookResponse, error) {
+	webhook, err := s.findWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookResponse(webhook), nil
+}
+
+func (s *todoService) UpdateWebhook(ctx context.Context, updateReq *UpdateWebhookRequest) (*WebhookResponse, error) {
+	if fieldErrors := s.validator.StructCtx(ctx, updateReq); fieldErrors != nil {
+		s.log.Warn("validation failed for update webhook request", "error", fieldErrors)
+		return nil, appErrors.ValidationError("invalid webhook data", nil, fieldErrors)
+	}
+	webhook, err := s.findWebhook(ctx, updateReq.ID)
+	if err != nil {
+		return nil, err
+	}
+	if updateReq.URL != nil {
+		if err := checkWebhookURL(*updateReq.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *updateReq.URL
+	}
+	if updateReq.Events != nil {
+		webhook.Events = joinWebhookEvents(updateReq.Events)
+	}
+	if updateReq.Secret != nil {
+		webhook.Secret = *updateReq.Secret
+	}
+	if updateReq.Active != nil {
+		webhook.Active = *updateReq.Active
+	}
+	if err := s.webhooks.SaveWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return toWebhookResponse(webhook), nil
+}
+
+// deleting a webhook also drops its delivery log and anything still queued
+func (s *todoService) DeleteWebhook(ctx context.Context, id uint) error {
+	webhook, err := s.findWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.webhooks.DeleteWebhook(ctx, webhook); err != nil {
+		return err
+	}
+	s.log.Info("service: webhook deleted", "id", id, "userID", webhook.UserID)
+	return nil
+}
+
+// the delivery log of a webhook, newest first; status narrows it to pending,
+// succeeded or dead deliveries
+func (s *todoService) GetWebhookDeliveries(ctx context.Context, webhookID uint, status string, page, limit int) (*pagination.PaginationResponse, error) {
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryDead:
+	default:
+		return nil, appErrors.ValidationError("invalid delivery status", nil, map[string]string{"status": "Must be one of pending, succeeded, dead"})
+	}
+	webhook, err := s.findWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	p := pagination.NewPaginationParams(page, limit)
+	deliveries, totalCount, err := s.webhooks.GetWebhookDeliveries(ctx, webhook.ID, status, p.Offset(), p.Limit)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]WebhookDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		responses[i] = *toWebhookDeliveryResponse(&deliveries[i])
+	}
+	return &pagination.PaginationResponse{
+		Data:     responses,
+		Metadata: pagination.NewPaginationmetadata(p.Page, p.Limit, totalCount),
+	}, nil
+}
+
+// send a logged delivery again as a new delivery of the same event, whatever
+// became of the original; receivers can tell it is a repeat by the event ID
+func (s *todoService) RedeliverWebhook(ctx context.Context, webhookID, deliveryID uint) (*WebhookDeliveryResponse, error) {
+	webhook, err := s.findWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	original, err := s.webhooks.GetWebhookDelivery(ctx, webhook.ID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	delivery := models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+	deliveries := []models.WebhookDelivery{delivery}
+	if err := s.webhooks.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return nil, err
+	}
+	s.log.Info("service: webhook delivery requeued", "webhookID", webhook.ID, "deliveryID", deliveryID, "newDeliveryID", deliveries[0].ID)
+	return toWebhookDeliveryResponse(&deliveries[0]), nil
+}
+
+// write a delivery for every active webhook in the audience that subscribes to the
+// event. It goes through s.repo, so inside a transaction it commits with the change.
+func (s *todoService) enqueueWebhooks(ctx context.Context, eventType string, payload *TodoEvent, audience []uint) error {
+	webhooks, err := s.webhooks.GetActiveWebhooks(ctx, audience)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	envelope := webhookEnvelope{ID: uuid.NewString(), Type: eventType, CreatedAt: now.UTC(), Data: payload}
+	var body []byte
+	var deliveries []models.WebhookDelivery
+	for i := range webhooks {
+		if !subscribesTo(&webhooks[i], eventType) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(envelope); err != nil {
+				return appErrors.InternalServerError("failed to encode webhook payload", err)
+			}
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhooks[i].ID,
+			EventID:       envelope.ID,
+			EventType:     eventType,
+			Payload:       string(body),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	return s.webhooks.CreateWebhookDeliveries(ctx, deliveries)
+}
+
+// load a webhook only if the current user owns it
+func (s *todoService) findWebhook(ctx context.Context, id uint) (*models.Webhook, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.webhooks.GetWebhook(ctx, userID, id)
+}
+
+// deliveries are only ever POSTed over http or https
+func checkWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return appErrors.ValidationError("invalid webhook data", err, map[string]string{"url": "Must be an http or https URL"})
+	}
+	return nil
+}
+
+func subscribesTo(webhook *models.Webhook, eventType string) bool {
+	for _, event := range strings.Split(webhook.Events, ",") {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// event types in a stable order without repeats
+func joinWebhookEvents(events []string) string {
+	var joined []string
+	for _, event := range []string{EventTodoCreated, EventTodoUpdated, EventTodoDeleted} {
+		for _, requested := range events {
+			if requested == event {
+				joined = append(joined, event)
+				break
+			}
+		}
+	}
+	return strings.Join(joined, ",")
+}
+
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func toWebhookResponse(webhook *models.Webhook) *WebhookResponse {
+	return &WebhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    strings.Split(webhook.Events, ","),
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt: webhook.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func toWebhookDeliveryResponse(delivery *models.WebhookDelivery) *WebhookDeliveryResponse {
+	res := &WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		Payload:        json.RawMessage(delivery.Payload),
+		CreatedAt:      delivery.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if delivery.Status == models.DeliveryPending {
+		res.NextAttemptAt = delivery.NextAttemptAt.Format("2006-01-02 15:04:05")
+	}
+	if delivery.DeliveredAt != nil {
+		res.DeliveredAt = delivery.DeliveredAt.Format("2006-01-02 15:04:05")
+	}
+	return res
+}
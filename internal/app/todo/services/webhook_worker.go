@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/codetheuri/todolist/config"
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"github.com/codetheuri/todolist/internal/app/todo/repositories"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"github.com/codetheuri/todolist/pkg/webhook"
+)
+
+const (
+	// deliveries claimed per poll, and sent concurrently
+	webhookBatchSize = 20
+	// the longest wait between two attempts at one delivery
+	webhookMaxBackoff = 6 * time.Hour
+	// how much of a response body is read before the connection is reused
+	webhookResponseLimit = 64 << 10
+)
+
+// WebhookWorker sends the deliveries queued in the outbox. A failed attempt is
+// retried after an exponentially growing delay; once WebhookMaxAttempts have
+// failed the delivery is marked dead and only comes back if it is redelivered.
+// Several workers, in one process or many, can share the outbox: each delivery
+// is leased to one of them at a time.
+type WebhookWorker struct {
+	repo   repositories.WebhookRepository
+	cfg    *config.Config
+	client *http.Client
+	log    logger.Logger
+}
+
+func NewWebhookWorker(repo repositories.WebhookRepository, cfg *config.Config, log logger.Logger) *WebhookWorker {
+	return &WebhookWorker{
+		repo:   repo,
+		cfg:    cfg,
+		client: webhook.NewClient(cfg.WebhookTimeout, cfg.WebhookAllowPrivate),
+		log:    log,
+	}
+}
+
+// Start polls for due deliveries until ctx is cancelled
+func (w *WebhookWorker) Start(ctx context.Context) {
+	w.log.Info("webhook worker started", "pollInterval", w.cfg.WebhookPollInterval.String())
+	ticker := time.NewTicker(w.cfg.WebhookPollInterval)
+	defer ticker.Stop()
+	for {
+		// a full batch means more may be waiting, so go again straight away
+		if w.deliverDue(ctx) == webhookBatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			w.log.Info("webhook worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// send one batch of due deliveries and report how many were claimed
+func (w *WebhookWorker) deliverDue(ctx context.Context) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+	deliveries, err := w.repo.ClaimWebhookDeliveries(ctx, time.Now(), w.lease(), webhookBatchSize)
+	if err != nil {
+		return 0
+	}
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			w.deliver(ctx, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+	return len(deliveries)
+}
+
+// make one attempt at a delivery and record the outcome
+func (w *WebhookWorker) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	now := time.Now()
+	if delivery.Webhook.ID == 0 || !delivery.Webhook.Active {
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = "webhook is disabled"
+		w.save(delivery)
+		return
+	}
+	statusCode, sendErr := w.send(ctx, delivery, now)
+	if ctx.Err() != nil {
+		// shutting down; the lease runs out and the attempt is made again later
+		return
+	}
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	if sendErr == nil {
+		delivery.Status = models.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		w.save(delivery)
+		return
+	}
+	delivery.LastError = truncate(sendErr.Error(), 1024)
+	if delivery.Attempts >= w.cfg.WebhookMaxAttempts {
+		delivery.Status = models.DeliveryDead
+		w.log.Warn("webhook delivery dead after final attempt", "deliveryID", delivery.ID, "webhookID", delivery.WebhookID, "attempts", delivery.Attempts, "error", sendErr)
+	} else {
+		delivery.NextAttemptAt = now.Add(w.backoff(delivery.Attempts))
+		w.log.Debug("webhook delivery failed, will retry", "deliveryID", delivery.ID, "attempts", delivery.Attempts, "nextAttemptAt", delivery.NextAttemptAt, "error", sendErr)
+	}
+	w.save(delivery)
+}
+
+// POST the payload; anything other than a 2xx response is a failure
+func (w *WebhookWorker) send(ctx context.Context, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	req, err := webhook.NewRequest(ctx, delivery.Webhook.URL, delivery.Webhook.Secret, delivery.EventType, delivery.EventID, []byte(delivery.Payload), now)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return resp.StatusCode, nil
+}
+
+// the outcome is recorded even while shutting down
+func (w *WebhookWorker) save(delivery *models.WebhookDelivery) {
+	_ = w.repo.SaveWebhookDelivery(context.Background(), delivery)
+}
+
+// WebhookRetryBase doubled for each failed attempt, capped, plus up to 10% jitter
+// so that deliveries that failed together do not all retry together
+func (w *WebhookWorker) backoff(attempts int) time.Duration {
+	delay := webhookMaxBackoff
+	if attempts-1 < 32 {
+		if d := w.cfg.WebhookRetryBase << (attempts - 1); d > 0 && d < webhookMaxBackoff {
+			delay = d
+		}
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
+
+// long enough to cover an attempt that runs into the request timeout
+func (w *WebhookWorker) lease() time.Duration {
+	return 2*w.cfg.WebhookTimeout + time.Minute
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
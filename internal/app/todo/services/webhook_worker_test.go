@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codetheuri/todolist/config"
+	"github.com/codetheuri/todolist/internal/app/todo/models"
+	"github.com/codetheuri/todolist/pkg/webhook"
+	"gorm.io/gorm"
+)
+
+// receiver is a webhook endpoint that verifies every delivery it is sent
+type receiver struct {
+	*httptest.Server
+	secret string
+
+	mu         sync.Mutex
+	status     int
+	deliveries []receivedDelivery
+}
+
+type receivedDelivery struct {
+	event, id string
+	body      webhookEnvelope
+	verifyErr error
+}
+
+func newReceiver(t *testing.T) *receiver {
+	rec := &receiver{status: http.StatusNoContent}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		delivery := receivedDelivery{
+			event:     r.Header.Get(webhook.EventHeader),
+			id:        r.Header.Get(webhook.DeliveryHeader),
+			verifyErr: webhook.Verify(rec.secret, r.Header.Get(webhook.SignatureHeader), body, 5*time.Minute, time.Now()),
+		}
+		_ = json.Unmarshal(body, &delivery.body)
+		rec.deliveries = append(rec.deliveries, delivery)
+		w.WriteHeader(rec.status)
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (rec *receiver) respondWith(status int) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.status = status
+}
+
+func (rec *receiver) received() []receivedDelivery {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]receivedDelivery(nil), rec.deliveries...)
+}
+
+func webhookTestConfig() *config.Config {
+	return &config.Config{
+		WebhookMaxAttempts:  3,
+		WebhookRetryBase:    time.Minute,
+		WebhookTimeout:      2 * time.Second,
+		WebhookAllowPrivate: true,
+	}
+}
+
+// a service and worker, with user 1 subscribed to todo.created at a receiver,
+// and one todo created so that a delivery is waiting in the outbox
+func newWebhookTest(t *testing.T) (*WebhookWorker, *receiver, *gorm.DB) {
+	cfg := webhookTestConfig()
+	s, db := newTestService(t, cfg)
+	rec := newReceiver(t)
+	hook, err := s.CreateWebhook(asUser(1), &CreateWebhookRequest{URL: rec.URL, Events: []string{EventTodoCreated}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.secret = hook.Secret
+	if _, err := s.CreateTodo(asUser(1), &CreateTodoRequest{Title: "write the tests", Description: "for the webhook worker"}); err != nil {
+		t.Fatal(err)
+	}
+	return NewWebhookWorker(s.webhooks, cfg, s.log), rec, db
+}
+
+func loadDelivery(t *testing.T, db *gorm.DB) models.WebhookDelivery {
+	t.Helper()
+	var deliveries []models.WebhookDelivery
+	if err := db.Find(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("%d deliveries in the outbox, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+// make the delivery due again without waiting out its backoff
+func makeDue(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	err := db.Model(&models.WebhookDelivery{}).Where("1 = 1").Update("next_attempt_at", time.Now().Add(-time.Second)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	worker, rec, db := newWebhookTest(t)
+
+	if claimed := worker.deliverDue(context.Background()); claimed != 1 {
+		t.Fatalf("claimed %d deliveries, want 1", claimed)
+	}
+	received := rec.received()
+	if len(received) != 1 {
+		t.Fatalf("the receiver got %d deliveries, want 1", len(received))
+	}
+	if received[0].verifyErr != nil {
+		t.Fatalf("signature did not verify: %v", received[0].verifyErr)
+	}
+	if received[0].event != EventTodoCreated || received[0].body.Type != EventTodoCreated {
+		t.Fatalf("event header %q, body type %q", received[0].event, received[0].body.Type)
+	}
+	if received[0].id == "" || received[0].id != received[0].body.ID {
+		t.Fatalf("delivery header %q does not match the event ID %q", received[0].id, received[0].body.ID)
+	}
+
+	delivery := loadDelivery(t, db)
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 1 || delivery.DeliveredAt == nil {
+		t.Fatalf("status %s after %d attempts, delivered at %v", delivery.Status, delivery.Attempts, delivery.DeliveredAt)
+	}
+	if delivery.LastStatusCode != http.StatusNoContent || delivery.LockedUntil != nil {
+		t.Fatalf("last status %d, locked until %v", delivery.LastStatusCode, delivery.LockedUntil)
+	}
+	if claimed := worker.deliverDue(context.Background()); claimed != 0 {
+		t.Fatalf("a delivered event was claimed again")
+	}
+}
+
+func TestWebhookDeliveryRetriesWithBackoff(t *testing.T) {
+	worker, rec, db := newWebhookTest(t)
+	rec.respondWith(http.StatusServiceUnavailable)
+	base := worker.cfg.WebhookRetryBase
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		before := time.Now()
+		if claimed := worker.deliverDue(context.Background()); claimed != 1 {
+			t.Fatalf("attempt %d: claimed %d deliveries, want 1", attempt, claimed)
+		}
+		delivery := loadDelivery(t, db)
+		if delivery.Status != models.DeliveryPending || delivery.Attempts != attempt {
+			t.Fatalf("attempt %d: status %s after %d attempts", attempt, delivery.Status, delivery.Attempts)
+		}
+		if delivery.LastStatusCode != http.StatusServiceUnavailable || delivery.LastError == "" {
+			t.Fatalf("attempt %d: last status %d, last error %q", attempt, delivery.LastStatusCode, delivery.LastError)
+		}
+		// the delay doubles each attempt, with up to 10% jitter on top
+		delay := base << (attempt - 1)
+		if wait := delivery.NextAttemptAt.Sub(before); wait < delay || wait > delay+delay/10+time.Second {
+			t.Fatalf("attempt %d: next attempt in %s, want %s plus jitter", attempt, wait, delay)
+		}
+		if claimed := worker.deliverDue(context.Background()); claimed != 0 {
+			t.Fatalf("attempt %d: the delivery was retried before its backoff ran out", attempt)
+		}
+		makeDue(t, db)
+	}
+
+	rec.respondWith(http.StatusOK)
+	if claimed := worker.deliverDue(context.Background()); claimed != 1 {
+		t.Fatalf("claimed %d deliveries, want 1", claimed)
+	}
+	delivery := loadDelivery(t, db)
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 3 || delivery.LastError != "" {
+		t.Fatalf("status %s after %d attempts, last error %q", delivery.Status, delivery.Attempts, delivery.LastError)
+	}
+	if received := rec.received(); len(received) != 3 || received[0].id != received[2].id {
+		t.Fatalf("the receiver got %d deliveries, retries must keep the event ID", len(received))
+	}
+}
+
+func TestWebhookDeliveryIsDeadAfterMaxAttempts(t *testing.T) {
+	worker, rec, db := newWebhookTest(t)
+	rec.respondWith(http.StatusInternalServerError)
+
+	for attempt := 1; attempt <= worker.cfg.WebhookMaxAttempts; attempt++ {
+		if claimed := worker.deliverDue(context.Background()); claimed != 1 {
+			t.Fatalf("attempt %d: claimed %d deliveries, want 1", attempt, claimed)
+		}
+		makeDue(t, db)
+	}
+	delivery := loadDelivery(t, db)
+	if delivery.Status != models.DeliveryDead || delivery.Attempts != worker.cfg.WebhookMaxAttempts {
+		t.Fatalf("status %s after %d attempts", delivery.Status, delivery.Attempts)
+	}
+	if delivery.LastStatusCode != http.StatusInternalServerError || delivery.LastError == "" {
+		t.Fatalf("last status %d, last error %q", delivery.LastStatusCode, delivery.LastError)
+	}
+	if claimed := worker.deliverDue(context.Background()); claimed != 0 {
+		t.Fatalf("a dead delivery was claimed again")
+	}
+	if received := rec.received(); len(received) != worker.cfg.WebhookMaxAttempts {
+		t.Fatalf("the receiver got %d deliveries, want %d", len(received), worker.cfg.WebhookMaxAttempts)
+	}
+}
+
+func TestWebhookDeliveryToDisabledWebhookIsDead(t *testing.T) {
+	worker, rec, db := newWebhookTest(t)
+	if err := db.Model(&models.Webhook{}).Where("1 = 1").Update("active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if claimed := worker.deliverDue(context.Background()); claimed != 1 {
+		t.Fatalf("claimed %d deliveries, want 1", claimed)
+	}
+	delivery := loadDelivery(t, db)
+	if delivery.Status != models.DeliveryDead || delivery.Attempts != 0 || delivery.LastError != "webhook is disabled" {
+		t.Fatalf("status %s after %d attempts, last error %q", delivery.Status, delivery.Attempts, delivery.LastError)
+	}
+	if received := rec.received(); len(received) != 0 {
+		t.Fatalf("a disabled webhook was sent %d deliveries", len(received))
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	worker := &WebhookWorker{cfg: &config.Config{WebhookRetryBase: 30 * time.Second}}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 5, want: 8 * time.Minute},
+		{attempts: 10, want: 256 * time.Minute},
+		{attempts: 11, want: webhookMaxBackoff},
+		{attempts: 40, want: webhookMaxBackoff},
+		{attempts: 100, want: webhookMaxBackoff},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if got := worker.backoff(tt.attempts); got < tt.want || got > tt.want+tt.want/10 {
+				t.Fatalf("backoff(%d) = %s, want %s plus up to 10%%", tt.attempts, got, tt.want)
+			}
+		}
+	}
+}
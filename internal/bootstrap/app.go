@@ -14,12 +14,19 @@ import (
 	modules "github.com/codetheuri/todolist/internal/app"
 
 	authModule "github.com/codetheuri/todolist/internal/app/auth"
+	docsModule "github.com/codetheuri/todolist/internal/app/docs"
+	profileModule "github.com/codetheuri/todolist/internal/app/profile"
 	router "github.com/codetheuri/todolist/internal/app/routers"
 	todoModule "github.com/codetheuri/todolist/internal/app/todo"
 	"github.com/codetheuri/todolist/internal/platform/database"
+	"github.com/codetheuri/todolist/internal/platform/identity"
+	"github.com/codetheuri/todolist/pkg/events"
 	"github.com/codetheuri/todolist/pkg/logger"
 	"github.com/codetheuri/todolist/pkg/middleware"
+	"github.com/codetheuri/todolist/pkg/openapi"
+	"github.com/codetheuri/todolist/pkg/storage"
 	"github.com/codetheuri/todolist/pkg/validators"
+	"github.com/codetheuri/todolist/pkg/web"
 	// "github.com/codetheuri/todolist/pkg/validators"
 )
 
@@ -35,22 +42,43 @@ func Run(cfg *config.Config, log logger.Logger) error {
 
 	//initilialize app components
 	appValidator := validators.NewValidator()
+	// the tables and columns the unique and exists rules may read
+	appValidator.UseDatabase(db, validators.Tables{
+		"users": {Columns: []string{"email"}, SoftDelete: true},
+	})
+	fileStorage, err := storage.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialise file storage: %w", err)
+	}
+	// in-process todo change events, streamed to clients
+	eventHub := events.NewHub(cfg.EventBufferSize)
+
+	// read-only access to user accounts for the modules that do not own them
+	userDirectory := identity.NewUserDirectory(db, log)
 
 	//application modules
 	var appModules []modules.Module
-	authMod := authModule.NewModule(db, log, appValidator, cfg)
+	// profiles are returned on login, and the profile routes take auth tokens
+	profileMod := profileModule.NewModule(db, log, appValidator, cfg, userDirectory, fileStorage)
+	authMod := authModule.NewModule(db, log, appValidator, cfg, profileMod.Service)
+	profileMod.TokenService = authMod.TokenService
 	// Example of adding a new module))
-	appModules = append(appModules, authModule.NewModule(db, log, appValidator, cfg)) // Example of adding a new module
-	appModules = append(appModules, todoModule.NewModule(db, log, appValidator, authMod.TokenService))
+	appModules = append(appModules, authModule.NewModule(db, log, appValidator, cfg, profileMod.Service)) // Example of adding a new module
+	appModules = append(appModules, profileMod)
+	todoMod := todoModule.NewModule(db, log, appValidator, cfg, userDirectory, authMod.TokenService, fileStorage, eventHub)
+	appModules = append(appModules, todoMod)
+	// documents the tonic routes registered by the modules above
+	apiDocs := openapi.NewRegistry("Tusk API", "1.0.0")
+	appModules = append(appModules, docsModule.NewModule(log, apiDocs))
 	//register routes from all modules
-	mainRouter := router.NewRouter(log)
+	mainRouter := router.NewRouter(log, apiDocs)
 	// for _, module := range appModules {
 	// 	module.RegisterRoutes(router)
 	// }
    mainRouter.Route("/api", func(r router.Router) {
 		// Register routes from all modules onto this sub-router.
 		for _, module := range appModules {
-			module.RegisterRoutes(r)
+			r.Module(module.Name(), module.RegisterRoutes)
 		}
 	})
 	//middleware
@@ -59,6 +87,9 @@ func Run(cfg *config.Config, log logger.Logger) error {
 	handler = middleware.SecurityHeaders(handler)
 	handler = middleware.Logger(log)(handler)
 	handler = middleware.Recovery(log)(handler)
+	handler = middleware.ContentNegotiation()(handler)
+	handler = middleware.Locale(profileMod.Service.PreferredLocale)(handler)
+	handler = middleware.ErrorFormat(web.ErrorFormat(cfg.ErrorFormat))(handler)
 	handler = middleware.RequestID()(handler)
 
 	// Setup HTTP Server with Timeouts
@@ -69,6 +100,15 @@ func Run(cfg *config.Config, log logger.Logger) error {
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// end open change streams so that shutdown does not wait on them
+	srv.RegisterOnShutdown(eventHub.Close)
+
+	// background webhook deliveries and trash purging, stopped along with the server
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go todoMod.Webhooks.Start(workerCtx)
+	go todoMod.TrashPurger.Start(workerCtx)
+	srv.RegisterOnShutdown(stopWorkers)
 
 	// 1. Create Listener and check port availability early
 	ln, err := net.Listen("tcp", srv.Addr) // Use srv.Addr for consistent port definition
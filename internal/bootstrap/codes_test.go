@@ -0,0 +1,254 @@
+package bootstrap
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+)
+
+// this package links every module, so the codes they register are in appErrors.Codes()
+
+const errorsPath = "github.com/codetheuri/todolist/pkg/errors"
+
+// a Go source file of the module, with the name it imports pkg/errors under
+type sourceFile struct {
+	path  string
+	pkg   string // directory relative to the module root
+	file  *ast.File
+	alias string // "" when the file does not import pkg/errors
+}
+
+func moduleSources(t *testing.T) []sourceFile {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	var files []sourceFile
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || name == "smoke" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, filepath.Dir(path))
+		source := sourceFile{path: path, pkg: filepath.ToSlash(rel), file: file}
+		if source.pkg == "pkg/errors" {
+			source.alias = "."
+		}
+		for _, imp := range file.Imports {
+			if p, _ := strconv.Unquote(imp.Path.Value); p == errorsPath {
+				source.alias = "errors"
+				if imp.Name != nil {
+					source.alias = imp.Name.Name
+				}
+			}
+		}
+		files = append(files, source)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no sources found")
+	}
+	return files
+}
+
+// the function of pkg/errors a call is to, if it is one
+func (f sourceFile) errorsFunc(call *ast.CallExpr) (string, bool) {
+	switch fn := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		if x, ok := fn.X.(*ast.Ident); ok && f.alias != "" && x.Name == f.alias {
+			return fn.Sel.Name, true
+		}
+	case *ast.Ident:
+		if f.alias == "." {
+			return fn.Name, true
+		}
+	}
+	return "", false
+}
+
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// the codes declared with Register, by package and variable
+func registeredCodes(t *testing.T, files []sourceFile) map[string]string {
+	t.Helper()
+	registered := make(map[string]string)
+	for _, f := range files {
+		ast.Inspect(f.file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.ValueSpec)
+			if !ok {
+				return true
+			}
+			for i, value := range spec.Values {
+				call, ok := value.(*ast.CallExpr)
+				if !ok || i >= len(spec.Names) {
+					continue
+				}
+				if name, ok := f.errorsFunc(call); !ok || name != "Register" {
+					continue
+				}
+				code, ok := stringLiteral(call.Args[0])
+				if !ok {
+					t.Errorf("%s: %s is registered with a code that is not a string literal", f.path, spec.Names[i].Name)
+					continue
+				}
+				registered[f.pkg+"."+spec.Names[i].Name] = code
+			}
+			return true
+		})
+	}
+	return registered
+}
+
+// the code each constructor of pkg/errors makes, read from the code variable it passes
+// to New or sets in the error it builds
+func helperCodes(t *testing.T, files []sourceFile, registered map[string]string) map[string]string {
+	t.Helper()
+	helpers := make(map[string]string)
+	for _, f := range files {
+		if f.pkg != "pkg/errors" {
+			continue
+		}
+		for _, decl := range f.file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !fn.Name.IsExported() || fn.Name.Name == "New" || fn.Body == nil || fn.Type.Results == nil {
+				continue
+			}
+			if result, ok := fn.Type.Results.List[0].Type.(*ast.Ident); !ok || result.Name != "AppError" {
+				continue
+			}
+			var variable string
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				var arg ast.Expr
+				switch n := n.(type) {
+				case *ast.CallExpr:
+					if name, ok := f.errorsFunc(n); ok && name == "New" && len(n.Args) > 0 {
+						arg = n.Args[0]
+					}
+				case *ast.KeyValueExpr:
+					if key, ok := n.Key.(*ast.Ident); ok && key.Name == "code" {
+						arg = n.Value
+					}
+				}
+				if ident, ok := arg.(*ast.Ident); ok && variable == "" {
+					variable = ident.Name
+				}
+				return variable == ""
+			})
+			code, ok := registered["pkg/errors."+variable]
+			if !ok {
+				t.Errorf("%s: cannot tell which registered code %s makes", f.path, fn.Name.Name)
+				continue
+			}
+			helpers[fn.Name.Name] = code
+		}
+	}
+	if len(helpers) == 0 {
+		t.Fatal("found no constructors in pkg/errors")
+	}
+	return helpers
+}
+
+// every code used in the module, through New or a helper, must be registered; an unregistered
+// one is reported to clients as an unexpected 500
+func TestUsedCodesAreRegistered(t *testing.T) {
+	files := moduleSources(t)
+	registered := registeredCodes(t, files)
+	helpers := helperCodes(t, files, registered)
+
+	linked := make(map[string]bool)
+	for _, code := range appErrors.Codes() {
+		linked[string(code)] = true
+	}
+	for variable, code := range registered {
+		if !linked[code] {
+			t.Errorf("%s registers %s, but the app does not link it", variable, code)
+		}
+	}
+
+	used := make(map[appErrors.Code][]string)
+	for _, f := range files {
+		if f.alias == "" {
+			continue
+		}
+		ast.Inspect(f.file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			name, ok := f.errorsFunc(call)
+			if !ok {
+				return true
+			}
+			if code, ok := helpers[name]; ok {
+				used[appErrors.Code(code)] = append(used[appErrors.Code(code)], f.path)
+				return true
+			}
+			if name != "New" || len(call.Args) == 0 {
+				return true
+			}
+			var code string
+			found := false
+			switch arg := call.Args[0].(type) {
+			case *ast.SelectorExpr:
+				if x, ok := arg.X.(*ast.Ident); ok && x.Name == f.alias {
+					code, found = registered["pkg/errors."+arg.Sel.Name]
+				}
+			case *ast.Ident:
+				code, found = registered[f.pkg+"."+arg.Name]
+				if !found && f.alias == "." {
+					code, found = registered["pkg/errors."+arg.Name]
+				}
+			case *ast.CallExpr:
+				// a conversion such as appErrors.Code("SOMETHING")
+				if conv, ok := f.errorsFunc(arg); ok && conv == "Code" && len(arg.Args) == 1 {
+					code, found = stringLiteral(arg.Args[0])
+				}
+			}
+			if !found {
+				t.Errorf("%s: New is called with a code that is not a registered variable", f.path)
+				return true
+			}
+			used[appErrors.Code(code)] = append(used[appErrors.Code(code)], f.path)
+			return true
+		})
+	}
+	if len(used) == 0 {
+		t.Fatal("found no uses of New or the helpers")
+	}
+	for code, where := range used {
+		if _, ok := appErrors.Lookup(code); !ok {
+			t.Errorf("code %s is not registered; used in %s", code, strings.Join(where, ", "))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// UserInfo is the read-only view of an account that the directory hands out
+type UserInfo struct {
+	ID        uint
+	Email     string
+	Username  string
+	Role      string
+	CreatedAt time.Time
+}
+
+// UserDirectory looks up active accounts. Unknown and deleted users are a
+// NOT_FOUND error.
+type UserDirectory interface {
+	GetUser(ctx context.Context, id uint) (*UserInfo, error)
+	GetUserByEmail(ctx context.Context, email string) (*UserInfo, error)
+	GetUserByUsername(ctx context.Context, username string) (*UserInfo, error)
+}
+
+type gormUserDirectory struct {
+	db  *gorm.DB
+	log logger.Logger
+}
+
+func NewUserDirectory(db *gorm.DB, log logger.Logger) UserDirectory {
+	return &gormUserDirectory{
+		db:  db,
+		log: log,
+	}
+}
+
+func (d *gormUserDirectory) GetUser(ctx context.Context, id uint) (*UserInfo, error) {
+	return d.find(ctx, fmt.Sprintf("user with id %d not found", id), "id = ?", id)
+}
+
+func (d *gormUserDirectory) GetUserByEmail(ctx context.Context, email string) (*UserInfo, error) {
+	return d.find(ctx, "user not found", "email = ?", email)
+}
+
+func (d *gormUserDirectory) GetUserByUsername(ctx context.Context, username string) (*UserInfo, error) {
+	username = NormalizeUsername(username)
+	if !ValidUsername(username) {
+		return nil, appErrors.NotFoundError(fmt.Sprintf("user @%s not found", username), nil)
+	}
+	return d.find(ctx, fmt.Sprintf("user @%s not found", username), "username = ?", username)
+}
+
+func (d *gormUserDirectory) find(ctx context.Context, notFound string, query string, args ...interface{}) (*UserInfo, error) {
+	var user User
+	if err := d.db.WithContext(ctx).Where(query, args...).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.NotFoundError(notFound, err)
+		}
+		d.log.Error("identity: failed to look up user", err)
+		return nil, appErrors.DatabaseError("failed to look up user", err)
+	}
+	return toUserInfo(&user), nil
+}
+
+// everything about an account other modules may see
+func toUserInfo(user *User) *UserInfo {
+	return &UserInfo{
+		ID:        user.ID,
+		Email:     user.Email,
+		Username:  user.GetUsername(),
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+	}
+}
@@ -0,0 +1,43 @@
+// Package identity holds the user account shared by every module. The auth
+// module creates and changes accounts; everything else reads them through a
+// UserDirectory and never sees the password hash.
+package identity
+
+import (
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type User struct {
+	gorm.Model
+	Email string `gorm:"unique;not null" json:"email" validate:"required,email"`
+	// optional public handle, stored lowercased; NULL for users without one so
+	// that the unique index only covers those who picked one
+	Username *string `gorm:"uniqueIndex;size:30" json:"username,omitempty"`
+	Password string  `gorm:"not null" json:"-" validate:"required,min=8"`
+	Role     string  `gorm:"not null;default:'user'" json:"role"`
+}
+
+// GetUsername is the username, or "" for users without one
+func (u *User) GetUsername() string {
+	if u.Username == nil {
+		return ""
+	}
+	return *u.Username
+}
+
+// 3 to 30 lowercase letters, digits and underscores, starting with a letter
+var usernamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{2,29}$`)
+
+// NormalizeUsername is how usernames are stored and compared: trimmed, without a
+// leading @, and lowercased
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+}
+
+// ValidUsername reports whether a normalized username is acceptable as a handle
+func ValidUsername(username string) bool {
+	return usernamePattern.MatchString(username)
+}
@@ -45,6 +45,12 @@ const (
 	ContextKeyExpiresAt contextKey = "expiresAt"
 )
 
+// attach a userID the way the authenticator does, for work done on a user's
+// behalf outside a request
+func WithUserID(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, ContextKeyUserID, strconv.FormatUint(uint64(userID), 10))
+}
+
 // retrieve the userID from the request context
 func GetUserIDFromContext(ctx context.Context) (uint, bool) {
 	val := ctx.Value(ContextKeyUserID)
@@ -71,3 +71,19 @@ func toTitleCase(s string) string {
 	// Capitalize the first rune and append the rest of the string
 	return string(unicode.ToUpper(rune(s[0]))) + s[1:]
 }
+
+// IsUniqueViolation reports whether err, returned by a query on db, broke a unique
+// constraint. The driver of db recognises its own errors, so this works on every
+// supported database.
+func IsUniqueViolation(db *gorm.DB, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		return errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Code identifies a kind of application error. Each code is registered once with how errors
+// carrying it are reported, so a module adds its own codes without touching pkg/web.
+type Code string
+
+// LogLevel is the level a request that fails with a code is logged at
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// CodeInfo is how errors with a code are reported
+type CodeInfo struct {
+	Status int
+	Level  LogLevel
+	// whether the error's message may be shown to clients; the others get a generic one
+	Safe bool
+}
+
+var (
+	codesMu sync.RWMutex
+	codes   = make(map[Code]CodeInfo)
+)
+
+// Register adds code with the status, log level and message safety of its errors and returns
+// it, for a package-level variable. Registering a code twice panics.
+func Register(code Code, status int, level LogLevel, safe bool) Code {
+	codesMu.Lock()
+	defer codesMu.Unlock()
+	if _, exists := codes[code]; exists {
+		panic(fmt.Sprintf("errors: code %s registered twice", code))
+	}
+	codes[code] = CodeInfo{Status: status, Level: level, Safe: safe}
+	return code
+}
+
+// Lookup returns how errors with code are reported, and false for an unregistered code
+func Lookup(code Code) (CodeInfo, bool) {
+	codesMu.RLock()
+	defer codesMu.RUnlock()
+	info, ok := codes[code]
+	return info, ok
+}
+
+// Codes lists the registered codes in order
+func Codes() []Code {
+	codesMu.RLock()
+	defer codesMu.RUnlock()
+	list := make([]Code, 0, len(codes))
+	for code := range codes {
+		list = append(list, code)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// LevelOf is the level to log err at: its code's, or LevelError for any other error
+func LevelOf(err error) LogLevel {
+	var appErr AppError
+	if stderrors.As(err, &appErr) {
+		if info, ok := Lookup(appErr.Code()); ok {
+			return info.Level
+		}
+	}
+	return LevelError
+}
+
+// the codes of this package's constructors
+var (
+	CodeConfig               = Register("CONFIG_ERROR", http.StatusInternalServerError, LevelError, false)
+	CodeDatabase             = Register("DATABASE_ERROR", http.StatusInternalServerError, LevelError, false)
+	CodeInternal             = Register("INTERNAL_SERVER_ERROR", http.StatusInternalServerError, LevelError, false)
+	CodeExternalService      = Register("EXTERNAL_SERVICE_ERROR", http.StatusBadGateway, LevelError, false)
+	CodeNotFound             = Register("NOT_FOUND", http.StatusNotFound, LevelInfo, true)
+	CodeConflict             = Register("CONFLICT_ERROR", http.StatusConflict, LevelInfo, true)
+	CodeValidation           = Register("VALIDATION_ERROR", http.StatusUnprocessableEntity, LevelInfo, true)
+	CodeInvalidInput         = Register("INVALID_INPUT", http.StatusBadRequest, LevelInfo, true)
+	CodeAuth                 = Register("AUTH_ERROR", http.StatusUnauthorized, LevelWarn, true)
+	CodeUnauthorized         = Register("UNAUTHORIZED", http.StatusUnauthorized, LevelWarn, true)
+	CodeAuthorization        = Register("AUTHORIZATION_ERROR", http.StatusForbidden, LevelWarn, true)
+	CodeForbidden            = Register("FORBIDDEN", http.StatusForbidden, LevelWarn, true)
+	CodePreconditionFailed   = Register("PRECONDITION_FAILED", http.StatusPreconditionFailed, LevelInfo, true)
+	CodePreconditionRequired = Register("PRECONDITION_REQUIRED", http.StatusPreconditionRequired, LevelInfo, true)
+	CodePayloadTooLarge      = Register("PAYLOAD_TOO_LARGE", http.StatusRequestEntityTooLarge, LevelInfo, true)
+	CodeNotAcceptable        = Register("NOT_ACCEPTABLE", http.StatusNotAcceptable, LevelInfo, true)
+	CodeUnsupportedMediaType = Register("UNSUPPORTED_MEDIA_TYPE", http.StatusUnsupportedMediaType, LevelInfo, true)
+)
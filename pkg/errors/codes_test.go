@@ -0,0 +1,80 @@
+package errors_test
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+)
+
+func TestLookup(t *testing.T) {
+	info, ok := appErrors.Lookup(appErrors.CodeNotFound)
+	if !ok || info != (appErrors.CodeInfo{Status: http.StatusNotFound, Level: appErrors.LevelInfo, Safe: true}) {
+		t.Fatalf("NOT_FOUND: %+v, %v", info, ok)
+	}
+	info, ok = appErrors.Lookup(appErrors.CodeDatabase)
+	if !ok || info.Safe || info.Status != http.StatusInternalServerError || info.Level != appErrors.LevelError {
+		t.Fatalf("DATABASE_ERROR: %+v, %v", info, ok)
+	}
+	if _, ok := appErrors.Lookup("NEVER_REGISTERED"); ok {
+		t.Fatal("an unregistered code was found")
+	}
+	codes := appErrors.Codes()
+	for i := 1; i < len(codes); i++ {
+		if codes[i-1] >= codes[i] {
+			t.Fatalf("Codes is not sorted: %v", codes)
+		}
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering NOT_FOUND again did not panic")
+		}
+	}()
+	appErrors.Register("NOT_FOUND", http.StatusTeapot, appErrors.LevelDebug, true)
+}
+
+func TestLevelOf(t *testing.T) {
+	unregistered := appErrors.New("NEVER_REGISTERED", "odd", nil)
+	tests := []struct {
+		name string
+		err  error
+		want appErrors.LogLevel
+	}{
+		{name: "not found", err: appErrors.NotFoundError("todo with id 1 not found", nil), want: appErrors.LevelInfo},
+		{name: "auth", err: appErrors.AuthError("token expired", nil), want: appErrors.LevelWarn},
+		{name: "database", err: appErrors.DatabaseError("failed to get todo", stderrors.New("connection refused")), want: appErrors.LevelError},
+		{name: "wrapped", err: fmt.Errorf("loading: %w", appErrors.ConflictError("taken", nil)), want: appErrors.LevelInfo},
+		{name: "unregistered code", err: unregistered, want: appErrors.LevelError},
+		{name: "plain error", err: stderrors.New("boom"), want: appErrors.LevelError},
+		{name: "nil", err: nil, want: appErrors.LevelError},
+	}
+	for _, tt := range tests {
+		if got := appErrors.LevelOf(tt.err); got != tt.want {
+			t.Errorf("%s: LevelOf = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAppError(t *testing.T) {
+	cause := stderrors.New("connection refused")
+	err := appErrors.DatabaseError("failed to get todo", cause)
+	if err.Error() != "[DATABASE_ERROR] failed to get todo: connection refused" {
+		t.Fatalf("Error() = %q", err.Error())
+	}
+	if !stderrors.Is(err, cause) {
+		t.Fatal("the cause is not reachable through Unwrap")
+	}
+	if got := appErrors.NotFoundError("todo with id 1 not found", nil).Error(); got != "[NOT_FOUND] todo with id 1 not found" {
+		t.Fatalf("Error() = %q", got)
+	}
+	fields := map[string]string{"title": "This field is required"}
+	validation := appErrors.ValidationError("validation failed", nil, fields)
+	if validation.Code() != appErrors.CodeValidation || validation.GetValidationErrors() == nil {
+		t.Fatalf("validation error %v with fields %v", validation.Code(), validation.GetValidationErrors())
+	}
+}
@@ -5,7 +5,7 @@ import "fmt"
 // application error interface
 type AppError interface {
 	Error() string
-	Code() string
+	Code() Code
 	Message() string
 	Unwrap() error
 	GetValidationErrors() interface{}
@@ -13,7 +13,7 @@ type AppError interface {
 
 // basic error implementation
 type appError struct {
-	code    string
+	code    Code
 	message string
 	err     error
 
@@ -29,7 +29,7 @@ func (e *appError) Error() string {
 	return fmt.Sprintf("[%s] %s", e.code, e.message)
 }
 
-func (e *appError) Code() string {
+func (e *appError) Code() Code {
 	return e.code
 }
 
@@ -43,8 +43,9 @@ func (e *appError) GetValidationErrors() interface{} {
 	return e.validationErrors
 }
 
-// create a new generic application error
-func New(code, message string, err error) AppError {
+// create a new application error; code should be registered, or it is reported as an
+// unexpected 500
+func New(code Code, message string, err error) AppError {
 	return &appError{
 		code:    code,
 		message: message,
@@ -56,38 +57,43 @@ func New(code, message string, err error) AppError {
 
 // config issues
 func ConfigError(message string, err error) AppError {
-	return New("CONFIG_ERROR", message, err)
+	return New(CodeConfig, message, err)
 }
 
 // database issues
 func DatabaseError(message string, err error) AppError {
-	return New("DATABASE_ERROR", message, err)
+	return New(CodeDatabase, message, err)
 }
 
 // resource not found
 func NotFoundError(message string, err error) AppError {
-	return New("NOT_FOUND", message, err)
+	return New(CodeNotFound, message, err)
 }
 // conflict errors (e.g. duplicate entries)
 func ConflictError(message string, err error) AppError {
-	return New("CONFLICT_ERROR", message, err)
+	return New(CodeConflict, message, err)
 }
 
 // validation issues
 func ValidationError(message string, err error, fieldErrors interface{}) AppError {
 	return &appError{
-		code:             "VALIDATION_ERROR",
+		code:             CodeValidation,
 		message:          message,
 		err:              err,
 		validationErrors: fieldErrors,
 	}
 }
 
+// malformed request, such as a body that cannot be decoded
+func InvalidInputError(message string, err error) AppError {
+	return New(CodeInvalidInput, message, err)
+}
+
 // authentication issues
 func AuthError(message string, err error) AppError {
-	return New("AUTH_ERROR", message, err)
+	return New(CodeAuth, message, err)
 	// 	return &appError{
-	// 	code:    "AUTH_ERROR",
+	// 	code:    CodeAuth,
 	// 	message: message,
 	// 	err:     err,
 	// }
@@ -95,15 +101,40 @@ func AuthError(message string, err error) AppError {
 
 // authorization issues
 func AuthorizationError(message string, err error) AppError {
-	return New("AUTHORIZATION_ERROR", message, err)
+	return New(CodeAuthorization, message, err)
 }
 
 // internal server error
 func InternalServerError(message string, err error) AppError {
-	return New("INTERNAL_SERVER_ERROR", message, err)
+	return New(CodeInternal, message, err)
 }
 
 // external service error
 func ExternalServiceError(message string, err error) AppError {
-	return New("EXTERNAL_SERVICE_ERROR", message, err)
+	return New(CodeExternalService, message, err)
+}
+
+// conditional request whose precondition (If-Match) does not hold
+func PreconditionFailedError(message string, err error) AppError {
+	return New(CodePreconditionFailed, message, err)
+}
+
+// conditional request sent without its required precondition
+func PreconditionRequiredError(message string, err error) AppError {
+	return New(CodePreconditionRequired, message, err)
+}
+
+// request body over the allowed size
+func PayloadTooLargeError(message string, err error) AppError {
+	return New(CodePayloadTooLarge, message, err)
+}
+
+// response the client accepts in none of the formats the server writes
+func NotAcceptableError(message string, err error) AppError {
+	return New(CodeNotAcceptable, message, err)
+}
+
+// content type the endpoint does not accept
+func UnsupportedMediaTypeError(message string, err error) AppError {
+	return New(CodeUnsupportedMediaType, message, err)
 }
@@ -0,0 +1,160 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// events queued per subscriber; one that falls further behind is dropped and
+// has to reconnect, resuming from the buffer
+const subscriberQueue = 64
+
+// Event is one change pushed to subscribers. Only the users in Audience receive it.
+// ID is the value clients send back as Last-Event-ID.
+type Event struct {
+	ID       string
+	Seq      uint64
+	Type     string
+	Data     json.RawMessage
+	Audience []uint
+	Time     time.Time
+}
+
+// Hub fans events out to in-process subscribers and keeps the most recent
+// ones so that a reconnecting client can resume with Last-Event-ID.
+// Event IDs carry the hub's start time, so IDs from before a restart are
+// recognised as stale rather than resumed from the wrong place.
+type Hub struct {
+	mu     sync.Mutex
+	epoch  int64
+	seq    uint64
+	buffer []Event // ring of the last len(buffer) events
+	size   int
+	subs   map[*Subscription]struct{}
+}
+
+// Subscription receives the events addressed to one user until it is closed
+type Subscription struct {
+	UserID uint
+	ch     chan Event
+	hub    *Hub
+	closed bool
+}
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		epoch:  time.Now().UnixNano(),
+		buffer: make([]Event, bufferSize),
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish records an event and delivers it to the subscribed members of its audience
+func (h *Hub) Publish(eventType string, data interface{}, audience []uint) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("events: failed to encode %s event: %w", eventType, err)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	event := Event{ID: fmt.Sprintf("%d-%d", h.epoch, h.seq), Seq: h.seq, Type: eventType, Data: encoded, Audience: audience, Time: time.Now()}
+	if len(h.buffer) > 0 {
+		h.buffer[int(h.seq%uint64(len(h.buffer)))] = event
+		if h.size < len(h.buffer) {
+			h.size++
+		}
+	}
+	for sub := range h.subs {
+		if !event.For(sub.UserID) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			h.drop(sub)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers userID for new events. With a lastEventID it also
+// returns the buffered events after it; complete is false when that ID is
+// unknown or older than the buffer, meaning events may have been missed.
+func (h *Hub) Subscribe(userID uint, lastEventID string) (sub *Subscription, backlog []Event, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sub = &Subscription{UserID: userID, ch: make(chan Event, subscriberQueue), hub: h}
+	h.subs[sub] = struct{}{}
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+	after, ok := h.parseID(lastEventID)
+	oldest := h.seq - uint64(h.size) // the last sequence number no longer buffered
+	if !ok || after > h.seq || after < oldest {
+		return sub, nil, false
+	}
+	for seq := after + 1; seq <= h.seq; seq++ {
+		event := h.buffer[int(seq%uint64(len(h.buffer)))]
+		if event.For(userID) {
+			backlog = append(backlog, event)
+		}
+	}
+	return sub, backlog, true
+}
+
+func (h *Hub) parseID(id string) (uint64, bool) {
+	epoch, seq, found := strings.Cut(id, "-")
+	if !found || epoch != strconv.FormatInt(h.epoch, 10) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+// must be called with h.mu held
+func (h *Hub) drop(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(h.subs, sub)
+	close(sub.ch)
+}
+
+// Close ends every open subscription so that long-lived streams finish,
+// e.g. when the server shuts down
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		h.drop(sub)
+	}
+}
+
+// For reports whether userID is in the event's audience
+func (e Event) For(userID uint) bool {
+	for _, id := range e.Audience {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Events delivers the subscription's events; it is closed when the
+// subscription is closed or dropped for falling behind
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close stops delivery; it is safe to call more than once
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
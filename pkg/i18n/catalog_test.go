@@ -0,0 +1,136 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// the functions that show a success alert, by package, and the argument holding its message
+var alertFuncs = map[string]map[string]int{
+	"github.com/codetheuri/todolist/pkg/web": {
+		"RespondData":         3,
+		"RespondMessage":      2,
+		"WithSuccessMessage":  0,
+		"WithSuccessOverride": 0,
+	},
+	"github.com/codetheuri/todolist/pkg/tonic": {
+		"WithMessage": 0,
+		"WithToast":   0,
+	},
+}
+
+// an alert the app shows with a literal message
+type alert struct {
+	message string
+	pos     token.Position
+}
+
+// the literal alert messages in the module's sources
+func literalAlerts(t *testing.T) []alert {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	var alerts []alert
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || name == "smoke" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		// the functions above by the name this file calls them with
+		funcs := make(map[string]map[string]int)
+		tonic := false
+		for _, imp := range file.Imports {
+			importPath, _ := strconv.Unquote(imp.Path.Value)
+			tonic = tonic || importPath == "github.com/codetheuri/todolist/pkg/tonic"
+			if byName, ok := alertFuncs[importPath]; ok {
+				name := importPath[strings.LastIndex(importPath, "/")+1:]
+				if imp.Name != nil {
+					name = imp.Name.Name
+				}
+				funcs[name] = byName
+			}
+		}
+		if len(funcs) == 0 {
+			return nil
+		}
+		add := func(expr ast.Expr) {
+			lit, ok := expr.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return
+			}
+			if message, err := strconv.Unquote(lit.Value); err == nil && message != "" {
+				alerts = append(alerts, alert{message: message, pos: fset.Position(lit.Pos())})
+			}
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				sel, ok := n.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				pkg, ok := sel.X.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				if arg, ok := funcs[pkg.Name][sel.Sel.Name]; ok && arg < len(n.Args) {
+					add(n.Args[arg])
+				}
+			case *ast.AssignStmt:
+				// result.Message = "..." in a tonic handler replaces the route's message
+				if !tonic {
+					return true
+				}
+				for i, lhs := range n.Lhs {
+					if sel, ok := lhs.(*ast.SelectorExpr); ok && sel.Sel.Name == "Message" && i < len(n.Rhs) {
+						add(n.Rhs[i])
+					}
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) == 0 {
+		t.Fatal("found no alerts")
+	}
+	return alerts
+}
+
+// an alert without an entry is shown in English whatever the locale
+func TestAlertsAreTranslated(t *testing.T) {
+	for _, a := range literalAlerts(t) {
+		for _, locale := range Locales() {
+			if locale == DefaultLocale {
+				continue
+			}
+			if _, ok := Lookup(locale, a.message, nil); !ok {
+				t.Errorf("%s: %q has no %s translation", a.pos, a.message, locale)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package i18n
+
+// messages are written in English, so this catalog only holds what is built from keys
+var en = map[string]string{
+	"validation.required":           "This field is required",
+	"validation.required_with":      "This field is required when {param} is set",
+	"validation.timezone":           "Invalid timezone",
+	"validation.bcp47_language_tag": "Invalid language tag",
+	"validation.unique":             "This value must be unique",
+	"validation.exists":             "The selected value does not exist",
+	"validation.min":                "Minimum length is {param}",
+	"validation.max":                "Maximum length is {param}",
+	"validation.email":              "Invalid email format",
+	"validation.url":                "Invalid URL format",
+	"validation.len":                "Length must be {param}",
+	"validation.gt":                 "Must be greater than {param}",
+	"validation.gte":                "Must be greater than or equal to {param}",
+	"validation.lt":                 "Must be less than {param}",
+	"validation.lte":                "Must be less than or equal to {param}",
+	"validation.eqfield":            "Must be equal to {param}",
+	"validation.nefield":            "Must not be equal to {param}",
+	"validation.alpha":              "Must contain only alphabetic characters",
+	"validation.numeric":            "Must contain only numeric characters",
+	"validation.alphanum":           "Must contain only alphanumeric characters",
+	"validation.e164":               "Invalid phone number format (E.164)",
+	"validation.oneof":              "Must be one of {param}",
+	"validation.hexadecimal":        "Must be a hexadecimal number",
+	"validation.default":            "Invalid value for {tag}",
+
+	"error.unknown": "An unexpected application error occurred: {message}",
+}
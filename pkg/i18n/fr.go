@@ -0,0 +1,89 @@
+package i18n
+
+var fr = map[string]string{
+	"validation.required":           "Ce champ est obligatoire",
+	"validation.required_with":      "Ce champ est obligatoire lorsque {param} est renseigné",
+	"validation.timezone":           "Fuseau horaire invalide",
+	"validation.bcp47_language_tag": "Code de langue invalide",
+	"validation.unique":             "Cette valeur doit être unique",
+	"validation.exists":             "La valeur sélectionnée n'existe pas",
+	"validation.min":                "La longueur minimale est de {param}",
+	"validation.max":                "La longueur maximale est de {param}",
+	"validation.email":              "Format d'adresse e-mail invalide",
+	"validation.url":                "Format d'URL invalide",
+	"validation.len":                "La longueur doit être de {param}",
+	"validation.gt":                 "Doit être supérieur à {param}",
+	"validation.gte":                "Doit être supérieur ou égal à {param}",
+	"validation.lt":                 "Doit être inférieur à {param}",
+	"validation.lte":                "Doit être inférieur ou égal à {param}",
+	"validation.eqfield":            "Doit être égal à {param}",
+	"validation.nefield":            "Ne doit pas être égal à {param}",
+	"validation.alpha":              "Ne doit contenir que des lettres",
+	"validation.numeric":            "Ne doit contenir que des chiffres",
+	"validation.alphanum":           "Ne doit contenir que des lettres et des chiffres",
+	"validation.e164":               "Format de numéro de téléphone invalide (E.164)",
+	"validation.oneof":              "Doit être l'une des valeurs : {param}",
+	"validation.hexadecimal":        "Doit être un nombre hexadécimal",
+	"validation.default":            "Valeur invalide pour {tag}",
+
+	"error.unknown":                "Une erreur applicative inattendue s'est produite : {message}",
+	"error.NOT_FOUND":              "La ressource demandée est introuvable",
+	"error.CONFLICT_ERROR":         "Cette opération entre en conflit avec des données existantes",
+	"error.VALIDATION_ERROR":       "La validation a échoué",
+	"error.INVALID_INPUT":          "Le corps de la requête est invalide",
+	"error.AUTH_ERROR":             "Authentification requise",
+	"error.UNAUTHORIZED":           "Authentification requise",
+	"error.AUTHORIZATION_ERROR":    "Vous n'êtes pas autorisé à effectuer cette action",
+	"error.FORBIDDEN":              "Vous n'êtes pas autorisé à effectuer cette action",
+	"error.PRECONDITION_FAILED":    "La ressource a été modifiée entre-temps",
+	"error.PRECONDITION_REQUIRED":  "Cette requête doit être conditionnelle (If-Match)",
+	"error.PAYLOAD_TOO_LARGE":      "La requête est trop volumineuse",
+	"error.NOT_ACCEPTABLE":         "Aucun des formats de réponse demandés n'est disponible",
+	"error.UNSUPPORTED_MEDIA_TYPE": "Ce type de contenu n'est pas pris en charge",
+
+	"An unexpected error occurred":                    "Une erreur inattendue s'est produite",
+	"An unexpected server error occurred.":            "Une erreur inattendue s'est produite sur le serveur.",
+	"validation failed":                               "La validation a échoué",
+	"Invalid request body format":                     "Le format du corps de la requête est invalide",
+	"invalid request payload format":                  "Le format du corps de la requête est invalide",
+	"Authorization header is required":                "L'en-tête Authorization est obligatoire",
+	"Your request was made with invalid credentials.": "Votre requête a été faite avec des identifiants invalides.",
+
+	"Attachment deleted successfully":        "Pièce jointe supprimée",
+	"Attachment uploaded successfully":       "Pièce jointe envoyée",
+	"Avatar removed successfully":            "Avatar supprimé",
+	"Avatar updated successfully":            "Avatar mis à jour",
+	"Bulk operations applied successfully":   "Opérations groupées appliquées",
+	"Bulk operations rolled back":            "Opérations groupées annulées",
+	"Comment added successfully":             "Commentaire ajouté",
+	"Comment deleted successfully":           "Commentaire supprimé",
+	"Comment updated successfully":           "Commentaire mis à jour",
+	"Delivery queued":                        "Livraison mise en file d'attente",
+	"Import rejected, nothing was saved":     "Import refusé, rien n'a été enregistré",
+	"Import validated, nothing was saved":    "Import validé, rien n'a été enregistré",
+	"Invitation accepted successfully":       "Invitation acceptée",
+	"Invitation created successfully":        "Invitation créée",
+	"Logged out successfully":                "Déconnexion réussie",
+	"Password changed successfully":          "Mot de passe modifié",
+	"Profile retrieved successfully":         "Profil récupéré",
+	"Profile updated successfully":           "Profil mis à jour",
+	"Share revoked successfully":             "Partage révoqué",
+	"Subtask created successfully":           "Sous-tâche créée",
+	"Subtasks reordered successfully":        "Sous-tâches réordonnées",
+	"Todo restored successfully":             "Tâche restaurée",
+	"Todo soft-deleted successfully":         "Tâche placée dans la corbeille",
+	"Todo statistics retrieved successfully": "Statistiques des tâches récupérées",
+	"Todo updated successfully":              "Tâche mise à jour",
+	"Todos imported successfully":            "Tâches importées",
+	"Trash emptied successfully":             "Corbeille vidée",
+	"User profile retrieved successfully":    "Profil utilisateur récupéré",
+	"User registered successfully":           "Inscription réussie",
+	"User restored successfully":             "Utilisateur restauré",
+	"User retrieved successfully":            "Utilisateur récupéré",
+	"User soft-deleted successfully":         "Utilisateur désactivé",
+	"Webhook created successfully":           "Webhook créé",
+	"Webhook deleted successfully":           "Webhook supprimé",
+	"Webhook updated successfully":           "Webhook mis à jour",
+	"access granted":                         "accès autorisé",
+	"todo created succssfully":               "Tâche créée",
+}
@@ -0,0 +1,167 @@
+// Package i18n holds the message catalogs of the locales the app ships in and picks the
+// locale of each request.
+//
+// A catalog maps keys to messages. Validation messages are keyed validation.<tag> and the
+// generic message of an error code error.<CODE>; any other message is keyed by its English
+// text, so that code written in English needs no catalog entries for the default locale.
+// Messages may hold {name} placeholders, filled from Params.
+package i18n
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is the locale messages are written in, and the one used when a request asks
+// for none that has a catalog
+const DefaultLocale = "en"
+
+// Params are the values of a message's {name} placeholders
+type Params map[string]string
+
+var (
+	catalogsMu sync.RWMutex
+	catalogs   = make(map[string]map[string]string)
+)
+
+func init() {
+	Register("en", en)
+	Register("fr", fr)
+	Register("sw", sw)
+}
+
+// Register adds messages to the catalog of locale, replacing those with the same keys, for
+// modules that bring their own
+func Register(locale string, messages map[string]string) {
+	locale = normalize(locale)
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	catalog, ok := catalogs[locale]
+	if !ok {
+		catalog = make(map[string]string, len(messages))
+		catalogs[locale] = catalog
+	}
+	for key, message := range messages {
+		catalog[key] = message
+	}
+}
+
+// Locales lists the locales that have a catalog
+func Locales() []string {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supported returns the locale with a catalog that serves tag: tag itself, or its language
+// without the region ("fr" for "fr-CA")
+func Supported(tag string) (string, bool) {
+	tag = normalize(tag)
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+	if _, ok := catalogs[tag]; ok {
+		return tag, true
+	}
+	if language, _, found := strings.Cut(tag, "-"); found {
+		if _, ok := catalogs[language]; ok {
+			return language, true
+		}
+	}
+	return "", false
+}
+
+// Lookup returns the message for key in locale with params filled in. A key missing from the
+// locale's catalog is looked up in its language's and then in the default locale's.
+func Lookup(locale, key string, params Params) (string, bool) {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+	for _, candidate := range fallbacks(normalize(locale)) {
+		if message, ok := catalogs[candidate][key]; ok {
+			return interpolate(message, params), true
+		}
+	}
+	return "", false
+}
+
+// Text is the message for key in locale, or fallback with params filled in when no catalog
+// has one
+func Text(locale, key, fallback string, params Params) string {
+	if message, ok := Lookup(locale, key, params); ok {
+		return message
+	}
+	return interpolate(fallback, params)
+}
+
+// the catalogs to look in for locale, most specific first
+func fallbacks(locale string) []string {
+	candidates := []string{locale}
+	if language, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, language)
+	}
+	if locale != DefaultLocale {
+		candidates = append(candidates, DefaultLocale)
+	}
+	return candidates
+}
+
+// fill the {name} placeholders of message; unknown ones are left as they are
+func interpolate(message string, params Params) string {
+	if len(params) == 0 || !strings.Contains(message, "{") {
+		return message
+	}
+	pairs := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+// Match picks the locale an Accept-Language header ranks highest among those with a catalog,
+// or the default locale, and false, when it names none of them
+func Match(acceptLanguage string) (string, bool) {
+	type languageRange struct {
+		tag string
+		q   float64
+	}
+	var ranges []languageRange
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		// a language range is not a media type, but has the same ;q= parameter
+		tag, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, languageRange{tag, q})
+		}
+	}
+	// the first of equally ranked languages wins
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	for _, r := range ranges {
+		if r.tag == "*" {
+			return DefaultLocale, true
+		}
+		if locale, ok := Supported(r.tag); ok {
+			return locale, true
+		}
+	}
+	return DefaultLocale, false
+}
+
+// fr_CA and FR-ca are both fr-ca
+func normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
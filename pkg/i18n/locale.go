@@ -0,0 +1,82 @@
+package i18n
+
+import (
+	"context"
+	"sync"
+)
+
+// PreferenceFunc returns the locale a user chose, or "" when they chose none
+type PreferenceFunc func(ctx context.Context, userID uint) (string, error)
+
+// Selection picks the locale of one request: the signed-in user's preference when it has a
+// catalog, the Accept-Language header otherwise. It is resolved on first use, so requests
+// that translate nothing never look the preference up.
+type Selection struct {
+	ctx    context.Context
+	accept string
+	prefer PreferenceFunc
+
+	mu     sync.Mutex
+	userID uint
+	locale string
+}
+
+// NewSelection starts the selection of a request; prefer may be nil
+func NewSelection(ctx context.Context, acceptLanguage string, prefer PreferenceFunc) *Selection {
+	return &Selection{ctx: ctx, accept: acceptLanguage, prefer: prefer}
+}
+
+// SetUser records who made the request, once they are authenticated
+func (s *Selection) SetUser(userID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userID = userID
+	s.locale = ""
+}
+
+// Locale is the locale the request's messages are written in
+func (s *Selection) Locale() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locale != "" {
+		return s.locale
+	}
+	if s.userID != 0 && s.prefer != nil {
+		if preferred, err := s.prefer(s.ctx, s.userID); err == nil {
+			if locale, ok := Supported(preferred); ok {
+				s.locale = locale
+				return locale
+			}
+		}
+	}
+	s.locale, _ = Match(s.accept)
+	return s.locale
+}
+
+type contextKey struct{}
+
+// WithSelection attaches a request's selection to its context
+func WithSelection(ctx context.Context, s *Selection) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// SelectionFrom returns the selection attached to ctx, if any
+func SelectionFrom(ctx context.Context) (*Selection, bool) {
+	s, ok := ctx.Value(contextKey{}).(*Selection)
+	return s, ok
+}
+
+// Locale is the locale of the request ctx belongs to, the default one outside of a request
+func Locale(ctx context.Context) string {
+	if s, ok := SelectionFrom(ctx); ok {
+		return s.Locale()
+	}
+	return DefaultLocale
+}
+
+// SetUser records the signed-in user on the selection of the request ctx belongs to, if any
+func SetUser(ctx context.Context, userID uint) {
+	if s, ok := SelectionFrom(ctx); ok {
+		s.SetUser(userID)
+	}
+}
@@ -0,0 +1,89 @@
+package i18n
+
+var sw = map[string]string{
+	"validation.required":           "Sehemu hii inahitajika",
+	"validation.required_with":      "Sehemu hii inahitajika wakati {param} imejazwa",
+	"validation.timezone":           "Saa za eneo si sahihi",
+	"validation.bcp47_language_tag": "Msimbo wa lugha si sahihi",
+	"validation.unique":             "Thamani hii lazima iwe ya kipekee",
+	"validation.exists":             "Thamani iliyochaguliwa haipo",
+	"validation.min":                "Urefu wa chini ni {param}",
+	"validation.max":                "Urefu wa juu ni {param}",
+	"validation.email":              "Muundo wa barua pepe si sahihi",
+	"validation.url":                "Muundo wa URL si sahihi",
+	"validation.len":                "Urefu lazima uwe {param}",
+	"validation.gt":                 "Lazima iwe kubwa kuliko {param}",
+	"validation.gte":                "Lazima iwe kubwa kuliko au sawa na {param}",
+	"validation.lt":                 "Lazima iwe ndogo kuliko {param}",
+	"validation.lte":                "Lazima iwe ndogo kuliko au sawa na {param}",
+	"validation.eqfield":            "Lazima ilingane na {param}",
+	"validation.nefield":            "Haipaswi kulingana na {param}",
+	"validation.alpha":              "Lazima iwe na herufi pekee",
+	"validation.numeric":            "Lazima iwe na tarakimu pekee",
+	"validation.alphanum":           "Lazima iwe na herufi na tarakimu pekee",
+	"validation.e164":               "Muundo wa nambari ya simu si sahihi (E.164)",
+	"validation.oneof":              "Lazima iwe mojawapo ya: {param}",
+	"validation.hexadecimal":        "Lazima iwe nambari ya heksadesimali",
+	"validation.default":            "Thamani si sahihi kwa {tag}",
+
+	"error.unknown":                "Hitilafu isiyotarajiwa ya programu imetokea: {message}",
+	"error.NOT_FOUND":              "Rasilimali uliyoomba haikupatikana",
+	"error.CONFLICT_ERROR":         "Kitendo hiki kinakinzana na data iliyopo",
+	"error.VALIDATION_ERROR":       "Uthibitishaji umeshindwa",
+	"error.INVALID_INPUT":          "Maudhui ya ombi si sahihi",
+	"error.AUTH_ERROR":             "Unahitaji kuingia kwanza",
+	"error.UNAUTHORIZED":           "Unahitaji kuingia kwanza",
+	"error.AUTHORIZATION_ERROR":    "Huna ruhusa ya kufanya kitendo hiki",
+	"error.FORBIDDEN":              "Huna ruhusa ya kufanya kitendo hiki",
+	"error.PRECONDITION_FAILED":    "Rasilimali imebadilishwa tangu ulipoisoma",
+	"error.PRECONDITION_REQUIRED":  "Ombi hili lazima liwe na masharti (If-Match)",
+	"error.PAYLOAD_TOO_LARGE":      "Ombi ni kubwa mno",
+	"error.NOT_ACCEPTABLE":         "Hakuna muundo wa jibu ulioombwa unaopatikana",
+	"error.UNSUPPORTED_MEDIA_TYPE": "Aina hii ya maudhui haitumiki",
+
+	"An unexpected error occurred":                    "Hitilafu isiyotarajiwa imetokea",
+	"An unexpected server error occurred.":            "Hitilafu isiyotarajiwa imetokea kwenye seva.",
+	"validation failed":                               "Uthibitishaji umeshindwa",
+	"Invalid request body format":                     "Muundo wa maudhui ya ombi si sahihi",
+	"invalid request payload format":                  "Muundo wa maudhui ya ombi si sahihi",
+	"Authorization header is required":                "Kichwa cha Authorization kinahitajika",
+	"Your request was made with invalid credentials.": "Ombi lako limetumwa na vitambulisho batili.",
+
+	"Attachment deleted successfully":        "Kiambatisho kimefutwa",
+	"Attachment uploaded successfully":       "Kiambatisho kimepakiwa",
+	"Avatar removed successfully":            "Picha ya wasifu imeondolewa",
+	"Avatar updated successfully":            "Picha ya wasifu imesasishwa",
+	"Bulk operations applied successfully":   "Vitendo vya pamoja vimetekelezwa",
+	"Bulk operations rolled back":            "Vitendo vya pamoja vimerudishwa nyuma",
+	"Comment added successfully":             "Maoni yameongezwa",
+	"Comment deleted successfully":           "Maoni yamefutwa",
+	"Comment updated successfully":           "Maoni yamesasishwa",
+	"Delivery queued":                        "Uwasilishaji umewekwa kwenye foleni",
+	"Import rejected, nothing was saved":     "Uingizaji umekataliwa, hakuna kilichohifadhiwa",
+	"Import validated, nothing was saved":    "Uingizaji umethibitishwa, hakuna kilichohifadhiwa",
+	"Invitation accepted successfully":       "Mwaliko umekubaliwa",
+	"Invitation created successfully":        "Mwaliko umeundwa",
+	"Logged out successfully":                "Umetoka kikamilifu",
+	"Password changed successfully":          "Nenosiri limebadilishwa",
+	"Profile retrieved successfully":         "Wasifu umepatikana",
+	"Profile updated successfully":           "Wasifu umesasishwa",
+	"Share revoked successfully":             "Ushirikiano umebatilishwa",
+	"Subtask created successfully":           "Kazi ndogo imeundwa",
+	"Subtasks reordered successfully":        "Kazi ndogo zimepangwa upya",
+	"Todo restored successfully":             "Kazi imerejeshwa",
+	"Todo soft-deleted successfully":         "Kazi imehamishiwa kwenye tupio",
+	"Todo statistics retrieved successfully": "Takwimu za kazi zimepatikana",
+	"Todo updated successfully":              "Kazi imesasishwa",
+	"Todos imported successfully":            "Kazi zimeingizwa",
+	"Trash emptied successfully":             "Tupio limesafishwa",
+	"User profile retrieved successfully":    "Wasifu wa mtumiaji umepatikana",
+	"User registered successfully":           "Usajili umekamilika",
+	"User restored successfully":             "Mtumiaji amerejeshwa",
+	"User retrieved successfully":            "Mtumiaji amepatikana",
+	"User soft-deleted successfully":         "Mtumiaji amezimwa",
+	"Webhook created successfully":           "Webhook imeundwa",
+	"Webhook deleted successfully":           "Webhook imefutwa",
+	"Webhook updated successfully":           "Webhook imesasishwa",
+	"access granted":                         "ruhusa imetolewa",
+	"todo created succssfully":               "Kazi imeundwa",
+}
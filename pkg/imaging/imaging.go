@@ -0,0 +1,126 @@
+// Package imaging turns uploaded pictures into square, downscaled images such as
+// avatars and their thumbnails. It relies on the standard library codecs only, so
+// it reads PNG, JPEG and GIF (the first frame) and writes PNG or JPEG.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	// registers the GIF decoder with image.Decode
+	_ "image/gif"
+)
+
+// the largest width or height accepted, which bounds the memory a decode takes
+const MaxDimension = 4096
+
+var (
+	ErrUnsupportedFormat = errors.New("imaging: unsupported image format")
+	ErrTooLarge          = errors.New("imaging: image dimensions are too large")
+)
+
+// Decode reads an image and reports its format ("png", "jpeg" or "gif"). The
+// dimensions are checked from the header before any pixels are decoded.
+func Decode(data []byte) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, "", ErrUnsupportedFormat
+		}
+		return nil, "", fmt.Errorf("imaging: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+		return nil, "", ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("imaging: %w", err)
+	}
+	return img, format, nil
+}
+
+// Square crops the largest centred square out of img and scales it down to
+// size×size. Images smaller than size are cropped but never enlarged.
+func Square(img image.Image, size int) *image.RGBA {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	origin := image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2)
+	src := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(src, src.Bounds(), img, origin, draw.Src)
+	if size <= 0 || size >= side {
+		return src
+	}
+	return downscale(src, size)
+}
+
+// box filter: every destination pixel is the average of the source pixels it
+// covers, weighted by how much of each it covers
+func downscale(src *image.RGBA, size int) *image.RGBA {
+	side := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	scale := float64(side) / float64(size)
+	for y := 0; y < size; y++ {
+		y0, y1 := float64(y)*scale, float64(y+1)*scale
+		for x := 0; x < size; x++ {
+			x0, x1 := float64(x)*scale, float64(x+1)*scale
+			var sum [4]float64
+			var total float64
+			for sy := int(y0); float64(sy) < y1 && sy < side; sy++ {
+				wy := overlap(float64(sy), y0, y1)
+				row := src.Pix[sy*src.Stride:]
+				for sx := int(x0); float64(sx) < x1 && sx < side; sx++ {
+					w := wy * overlap(float64(sx), x0, x1)
+					p := row[sx*4 : sx*4+4]
+					sum[0] += w * float64(p[0])
+					sum[1] += w * float64(p[1])
+					sum[2] += w * float64(p[2])
+					sum[3] += w * float64(p[3])
+					total += w
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			for i := range sum {
+				d[i] = uint8(sum[i]/total + 0.5)
+			}
+		}
+	}
+	return dst
+}
+
+// how much of the unit pixel starting at p lies within [lo, hi)
+func overlap(p, lo, hi float64) float64 {
+	start, end := p, p+1
+	if lo > start {
+		start = lo
+	}
+	if hi < end {
+		end = hi
+	}
+	return end - start
+}
+
+// Encode writes img as a JPEG when format is "jpeg", and as a PNG otherwise so
+// that transparency survives
+func Encode(w io.Writer, img image.Image, format string) error {
+	if format == "jpeg" {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+	return png.Encode(w, img)
+}
+
+// Extension is the file extension, with its dot, of what Encode writes for format
+func Extension(format string) string {
+	if format == "jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
@@ -0,0 +1,384 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the two patch formats
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// ErrTestFailed is returned when a JSON Patch "test" operation does not match
+var ErrTestFailed = errors.New("jsonpatch: test operation failed")
+
+// MergePatch applies an RFC 7396 merge patch to a JSON document: objects are
+// merged key by key, null removes a key and any other value replaces the target.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: invalid document: %w", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: invalid merge patch: %w", err)
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = mergeValue(t[key], value)
+	}
+	return t
+}
+
+// Operation is one step of an RFC 6902 JSON Patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch is an RFC 6902 JSON Patch, applied in order and all or nothing
+type Patch []Operation
+
+// DecodePatch parses and checks the shape of a JSON Patch document
+func DecodePatch(data []byte) (Patch, error) {
+	var patch Patch
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patch); err != nil {
+		return nil, fmt.Errorf("jsonpatch: invalid patch: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("jsonpatch: invalid patch: unexpected data after the JSON value")
+	}
+	for i, op := range patch {
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("jsonpatch: operation %d (%s) is missing a value", i, op.Op)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(op.From); err != nil {
+				return nil, fmt.Errorf("jsonpatch: operation %d (%s): %w", i, op.Op, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("jsonpatch: operation %d has unknown op %q", i, op.Op)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, fmt.Errorf("jsonpatch: operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+	return patch, nil
+}
+
+// Apply runs every operation against doc and returns the patched document.
+// Nothing is returned if any operation fails.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	root, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: invalid document: %w", err)
+	}
+	for i, op := range p {
+		if root, err = applyOperation(root, op); err != nil {
+			if errors.Is(err, ErrTestFailed) {
+				return nil, fmt.Errorf("%w at operation %d (%s)", ErrTestFailed, i, op.Path)
+			}
+			return nil, fmt.Errorf("jsonpatch: operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+func applyOperation(root interface{}, op Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		switch op.Op {
+		case "add":
+			return add(root, path, value)
+		case "replace":
+			return replace(root, path, value)
+		}
+		current, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, ErrTestFailed
+		}
+		return root, nil
+	case "remove":
+		return remove(root, path)
+	}
+
+	from, err := parsePointer(op.From)
+	if err != nil {
+		return nil, err
+	}
+	value, err := get(root, from)
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	if op.Op == "copy" {
+		return add(root, path, deepCopy(value))
+	}
+	// a value cannot be moved into one of its own children
+	if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+		return nil, errors.New("cannot move a value into itself")
+	}
+	if root, err = remove(root, from); err != nil {
+		return nil, err
+	}
+	return add(root, path, value)
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(root interface{}, path []string) (interface{}, error) {
+	node := root
+	for _, token := range path {
+		child, err := childOf(node, token)
+		if err != nil {
+			return nil, err
+		}
+		node = child
+	}
+	return node, nil
+}
+
+func childOf(node interface{}, token string) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("member %q not found", token)
+		}
+		return child, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		return n[index], nil
+	}
+	return nil, fmt.Errorf("cannot reference %q in a scalar value", token)
+}
+
+// arrayIndex parses an array index token no greater than max
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index > max {
+		return 0, fmt.Errorf("array index %s out of range", token)
+	}
+	return index, nil
+}
+
+// run fn on the container holding the last token of path and store what it returns
+func update(root interface{}, path []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(root, path[0])
+	}
+	child, err := childOf(root, path[0])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = update(child, path[1:], fn); err != nil {
+		return nil, err
+	}
+	switch n := root.(type) {
+	case map[string]interface{}:
+		n[path[0]] = child
+	case []interface{}:
+		index, _ := strconv.Atoi(path[0])
+		n[index] = child
+	}
+	return root, nil
+}
+
+func add(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(root, path, func(container interface{}, token string) (interface{}, error) {
+		switch n := container.(type) {
+		case map[string]interface{}:
+			n[token] = value
+			return n, nil
+		case []interface{}:
+			if token == "-" {
+				return append(n, value), nil
+			}
+			index, err := arrayIndex(token, len(n))
+			if err != nil {
+				return nil, err
+			}
+			n = append(n, nil)
+			copy(n[index+1:], n[index:])
+			n[index] = value
+			return n, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to a scalar value", token)
+	})
+}
+
+func remove(root interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	return update(root, path, func(container interface{}, token string) (interface{}, error) {
+		switch n := container.(type) {
+		case map[string]interface{}:
+			if _, ok := n[token]; !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			delete(n, token)
+			return n, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(n[:index], n[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from a scalar value", token)
+	})
+}
+
+func replace(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(root, path, func(container interface{}, token string) (interface{}, error) {
+		if _, err := childOf(container, token); err != nil {
+			return nil, err
+		}
+		switch n := container.(type) {
+		case map[string]interface{}:
+			n[token] = value
+		case []interface{}:
+			index, _ := strconv.Atoi(token)
+			n[index] = value
+		}
+		return container, nil
+	})
+}
+
+// decode keeps numbers as json.Number so they round-trip unchanged
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// JSON equality: numbers compare by value and object member order is ignored
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			c[key] = deepCopy(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, child := range v {
+			c[i] = deepCopy(child)
+		}
+		return c
+	}
+	return value
+}
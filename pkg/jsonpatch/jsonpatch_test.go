@@ -0,0 +1,164 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func sameJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("expected %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+// the examples of RFC 6902 appendix A, and the cases around them it leaves to the reader
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string // "" when the patch fails
+		test  bool   // the failure is a test operation that did not match
+	}{
+		{name: "A.1 add an object member", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, want: `{"baz":"qux","foo":"bar"}`},
+		{name: "A.2 add an array element", doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, want: `{"foo":["bar","qux","baz"]}`},
+		{name: "A.3 remove an object member", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, want: `{"foo":"bar"}`},
+		{name: "A.4 remove an array element", doc: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, want: `{"foo":["bar","baz"]}`},
+		{name: "A.5 replace a value", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, want: `{"baz":"boo","foo":"bar"}`},
+		{
+			name:  "A.6 move a value",
+			doc:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{name: "A.7 move an array element", doc: `{"foo":["all","grass","cows","eat"]}`, patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, want: `{"foo":["all","cows","eat","grass"]}`},
+		{
+			name:  "A.8 test a value: success",
+			doc:   `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			want:  `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{name: "A.9 test a value: error", doc: `{"baz":"qux"}`, patch: `[{"op":"test","path":"/baz","value":"bar"}]`, test: true},
+		{name: "A.10 add a nested member object", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, want: `{"foo":"bar","child":{"grandchild":{}}}`},
+		{name: "A.12 add to a nonexistent target", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+		{name: "A.14 ~ escape ordering", doc: `{"/":9,"~1":10}`, patch: `[{"op":"test","path":"/~01","value":10}]`, want: `{"/":9,"~1":10}`},
+		{name: "A.14 / escape", doc: `{"/":9,"~1":10}`, patch: `[{"op":"replace","path":"/~1","value":8}]`, want: `{"/":8,"~1":10}`},
+		{name: "A.15 comparing strings and numbers", doc: `{"/":9,"~1":10}`, patch: `[{"op":"test","path":"/~01","value":"10"}]`, test: true},
+		{name: "A.16 add an array value", doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, want: `{"foo":["bar",["abc","def"]]}`},
+
+		{name: "add at the end index", doc: `{"foo":["a"]}`, patch: `[{"op":"add","path":"/foo/1","value":"b"}]`, want: `{"foo":["a","b"]}`},
+		{name: "add past the end", doc: `{"foo":["a"]}`, patch: `[{"op":"add","path":"/foo/2","value":"b"}]`},
+		{name: "add replaces a member", doc: `{"foo":"a"}`, patch: `[{"op":"add","path":"/foo","value":"b"}]`, want: `{"foo":"b"}`},
+		{name: "add the whole document", doc: `{"foo":"a"}`, patch: `[{"op":"add","path":"","value":[1]}]`, want: `[1]`},
+		{name: "leading zero index", doc: `{"foo":["a","b"]}`, patch: `[{"op":"remove","path":"/foo/01"}]`},
+		{name: "- only appends", doc: `{"foo":["a"]}`, patch: `[{"op":"remove","path":"/foo/-"}]`},
+		{name: "remove a missing member", doc: `{"foo":"a"}`, patch: `[{"op":"remove","path":"/bar"}]`},
+		{name: "remove the whole document", doc: `{"foo":"a"}`, patch: `[{"op":"remove","path":""}]`},
+		{name: "replace a missing member", doc: `{"foo":"a"}`, patch: `[{"op":"replace","path":"/bar","value":1}]`},
+		{name: "replace an array element", doc: `{"foo":["a","b"]}`, patch: `[{"op":"replace","path":"/foo/0","value":"z"}]`, want: `{"foo":["z","b"]}`},
+		{name: "copy is deep", doc: `{"a":{"b":1}}`, patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, want: `{"a":{"b":1},"c":{"b":2}}`},
+		{name: "copy from a missing member", doc: `{"a":1}`, patch: `[{"op":"copy","from":"/b","path":"/c"}]`},
+		{name: "move into itself", doc: `{"a":{"b":{}}}`, patch: `[{"op":"move","from":"/a","path":"/a/b/c"}]`},
+		{name: "move onto itself", doc: `{"a":1}`, patch: `[{"op":"move","from":"/a","path":"/a"}]`, want: `{"a":1}`},
+		{name: "move to a sibling with a longer name", doc: `{"a":1,"ab":{}}`, patch: `[{"op":"move","from":"/a","path":"/ab/x"}]`, want: `{"ab":{"x":1}}`},
+		{name: "test numbers by value", doc: `{"n":1}`, patch: `[{"op":"test","path":"/n","value":1.0},{"op":"test","path":"/n","value":1e0}]`, want: `{"n":1}`},
+		{name: "test objects ignore member order", doc: `{"o":{"a":1,"b":[true,null]}}`, patch: `[{"op":"test","path":"/o","value":{"b":[true,null],"a":1}}]`, want: `{"o":{"a":1,"b":[true,null]}}`},
+		{name: "test arrays in order", doc: `{"l":[1,2]}`, patch: `[{"op":"test","path":"/l","value":[2,1]}]`, test: true},
+		{name: "test null against a missing member", doc: `{}`, patch: `[{"op":"test","path":"/a","value":null}]`},
+		{name: "large numbers round-trip", doc: `{"n":12345678901234567890}`, patch: `[{"op":"add","path":"/m","value":1}]`, want: `{"n":12345678901234567890,"m":1}`},
+		{name: "all or nothing", doc: `{"a":1}`, patch: `[{"op":"add","path":"/b","value":2},{"op":"remove","path":"/c"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := DecodePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := patch.Apply([]byte(tt.doc))
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("applied as %s, want an error", got)
+				}
+				if errors.Is(err, ErrTestFailed) != tt.test {
+					t.Fatalf("error %v, want a failed test: %v", err, tt.test)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sameJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestDecodePatch(t *testing.T) {
+	tests := map[string]string{
+		"A.11 unknown op":      `[{"op":"launch","path":"/foo"}]`,
+		"A.13 not an array":    `{"op":"add","path":"/baz","value":"qux"}`,
+		"unknown field":        `[{"op":"add","path":"/baz","value":1,"values":2}]`,
+		"add without value":    `[{"op":"add","path":"/baz"}]`,
+		"test without value":   `[{"op":"test","path":"/baz"}]`,
+		"relative path":        `[{"op":"remove","path":"baz"}]`,
+		"move without from":    `[{"op":"move","path":"/baz","from":"baz"}]`,
+		"malformed":            `[{"op":"add"`,
+		"trailing garbage doc": `[] []`,
+	}
+	for name, patch := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodePatch([]byte(patch)); err == nil {
+				t.Fatal("decoded without an error")
+			}
+		})
+	}
+	// a null value is a value
+	if _, err := DecodePatch([]byte(`[{"op":"replace","path":"/a","value":null}]`)); err != nil {
+		t.Fatalf("null value: %v", err)
+	}
+}
+
+// the examples of RFC 7396 appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("%s + %s: %v", tt.doc, tt.patch, err)
+		}
+		sameJSON(t, got, tt.want)
+	}
+	if _, err := MergePatch([]byte(`{}`), []byte(`{"a":`)); err == nil {
+		t.Fatal("a malformed merge patch was applied")
+	}
+	if _, err := MergePatch([]byte(`{} {}`), []byte(`{}`)); err == nil {
+		t.Fatal("a malformed document was patched")
+	}
+}
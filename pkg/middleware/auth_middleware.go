@@ -8,6 +8,7 @@ import (
 
 	tokenPkg "github.com/codetheuri/todolist/pkg/auth/token"
 	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/i18n"
 	"github.com/codetheuri/todolist/pkg/logger"
 	"github.com/codetheuri/todolist/pkg/web"
 )
@@ -52,6 +53,10 @@ func Authenticator(tokenService tokenPkg.TokenService, log logger.Logger) func(n
 			ctx = context.WithValue(ctx, tokenPkg.ContextKeyJTI, claims.ID)
 			ctx = context.WithValue(ctx, tokenPkg.ContextKeyExpiresAt, claims.ExpiresAt.Time)
 			r = r.WithContext(ctx)
+			// messages follow the user's language preference from here on
+			if userID, ok := tokenPkg.GetUserIDFromContext(ctx); ok {
+				i18n.SetUser(ctx, userID)
+			}
 
 			next.ServeHTTP(w, r)
 
@@ -62,6 +67,39 @@ func Authenticator(tokenService tokenPkg.TokenService, log logger.Logger) func(n
 
 
 
+// StreamToken lets clients that cannot set headers (EventSource, browser
+// WebSockets) authenticate with ?param=<token>. It only applies to stream
+// requests that did not send an Authorization header, and must run before
+// Authenticator.
+//
+// A token in the URL ends up in the access logs of every proxy in front of the
+// application and in browser history, so clients should only use it where a
+// header is impossible. Within the application the parameter is redacted from
+// the request log and removed from the URL once it has been moved into the
+// Authorization header.
+func StreamToken(param string) func(next http.Handler) http.Handler {
+	redactParam(param)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			if token := query.Get(param); token != "" && r.Header.Get("Authorization") == "" && isStreamRequest(r) {
+				r.Header.Set("Authorization", "Bearer "+token)
+				query.Del(param)
+				r.URL.RawQuery = query.Encode()
+				r.RequestURI = r.URL.RequestURI()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isStreamRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") || strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
 //retrieve role from urequest context
 
 func GetRoleFromContext(ctx context.Context) (string, bool) {
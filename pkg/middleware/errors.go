@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/codetheuri/todolist/pkg/web"
+)
+
+// ErrorFormat makes web.RespondError write the errors of each request in format, or as
+// problem details when the client's Accept header prefers application/problem+json.
+// It needs the request ID, so it goes inside RequestID.
+func ErrorFormat(format web.ErrorFormat) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(web.NegotiateErrors(w, r, format, GetRequestID(r.Context())), r)
+		})
+	}
+}
+
+// ContentNegotiation makes the web Respond functions encode each response in the format the
+// client's Accept header prefers among the registered codecs. JSON is used unless the client
+// names another format and ranks it above everything else it asks for.
+func ContentNegotiation() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(web.NegotiateEncoding(w, r), r)
+		})
+	}
+}
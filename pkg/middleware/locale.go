@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/codetheuri/todolist/pkg/i18n"
+	"github.com/codetheuri/todolist/pkg/web"
+)
+
+// Locale picks the language of each request's messages: the signed-in user's preference, as
+// prefer returns it, or the Accept-Language header. Authenticator tells it who is signed in;
+// prefer may be nil.
+func Locale(prefer i18n.PreferenceFunc) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			selection := i18n.NewSelection(r.Context(), r.Header.Get("Accept-Language"), prefer)
+			w.Header().Add("Vary", "Accept-Language")
+			r = r.WithContext(i18n.WithSelection(r.Context(), selection))
+			next.ServeHTTP(web.Localize(w, selection.Locale), r)
+		})
+	}
+}
@@ -1,9 +1,16 @@
 package middleware
 
 import (
+	"bufio"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
 	"github.com/codetheuri/todolist/pkg/logger"
 )
 
@@ -20,17 +27,32 @@ func Logger(log logger.Logger) func(next http.Handler) http.Handler {
 
 			requestID := GetRequestID(r.Context())
 
-			log.Info("HTTP Request",
+			args := []any{
 				"request_id", requestID,
 				"method", r.Method,
 				"path", r.URL.Path,
-				"url", r.RequestURI,
+				"url", redactQuery(r.RequestURI),
 				"status", lrw.statusCode,
 				"duration", time.Since(start),
 				"remote_addr", r.RemoteAddr,
 
 				"user_agent", r.UserAgent(),
-			)
+			}
+			if lrw.err == nil {
+				log.Info("HTTP Request", args...)
+				return
+			}
+			// a failed request is logged at the level its error code is registered with
+			switch appErrors.LevelOf(lrw.err) {
+			case appErrors.LevelError:
+				log.Error("HTTP Request", lrw.err, args...)
+			case appErrors.LevelWarn:
+				log.Warn("HTTP Request", append(args, "error", lrw.err)...)
+			case appErrors.LevelDebug:
+				log.Debug("HTTP Request", append(args, "error", lrw.err)...)
+			default:
+				log.Info("HTTP Request", append(args, "error", lrw.err)...)
+			}
 		})
 	}
 }
@@ -38,13 +60,73 @@ func Logger(log logger.Logger) func(next http.Handler) http.Handler {
 type loggingResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	// the error web.RespondError reported, if any
+	err error
 }
 
 func newLoggingResponseWriter(w http.ResponseWriter) *loggingResponseWriter {
-	return &loggingResponseWriter{w, http.StatusOK}
+	return &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+}
+
+// RecordError implements web.ErrorRecorder
+func (lrw *loggingResponseWriter) RecordError(err error) {
+	lrw.err = err
 }
 
 func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.statusCode = code
 	lrw.ResponseWriter.WriteHeader(code)
 }
+
+// lets http.ResponseController reach the underlying writer (flushing, write deadlines)
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
+
+// WebSocket upgrades take over the connection
+func (lrw *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(lrw.ResponseWriter).Hijack()
+	if err == nil {
+		lrw.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// query parameters that carry credentials and must not reach the logs; StreamToken
+// adds the one it reads
+var (
+	redactedMu     sync.RWMutex
+	redactedParams = []string{"access_token"}
+)
+
+func redactParam(param string) {
+	redactedMu.Lock()
+	defer redactedMu.Unlock()
+	if !slices.Contains(redactedParams, param) {
+		redactedParams = append(redactedParams, param)
+	}
+}
+
+func redactQuery(requestURI string) string {
+	path, rawQuery, found := strings.Cut(requestURI, "?")
+	if !found {
+		return requestURI
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return path
+	}
+	redacted := false
+	redactedMu.RLock()
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	redactedMu.RUnlock()
+	if !redacted {
+		return requestURI
+	}
+	return path + "?" + query.Encode()
+}
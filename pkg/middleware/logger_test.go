@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingLogger keeps every line it is asked to log
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) record(level, msg string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, level+" "+msg+" "+fmt.Sprint(args...))
+}
+
+func (l *recordingLogger) Debug(msg string, args ...any) { l.record("DEBUG", msg, args...) }
+func (l *recordingLogger) Info(msg string, args ...any)  { l.record("INFO", msg, args...) }
+func (l *recordingLogger) Warn(msg string, args ...any)  { l.record("WARN", msg, args...) }
+func (l *recordingLogger) Error(msg string, err error, args ...any) {
+	l.record("ERROR", msg, append(args, err)...)
+}
+func (l *recordingLogger) Fatal(msg string, err error, args ...any) {
+	l.record("FATAL", msg, append(args, err)...)
+}
+
+func TestStreamTokenIsKeptOutOfTheLog(t *testing.T) {
+	const token = "eyJhbGciOiJIUzI1NiJ9.secret.signature"
+	tests := []struct {
+		name   string
+		param  string
+		accept string
+	}{
+		{name: "event stream", param: "access_token", accept: "text/event-stream"},
+		{name: "custom parameter", param: "stream_token", accept: "text/event-stream"},
+		{name: "not a stream request", param: "access_token", accept: "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &recordingLogger{}
+			var seenURL, seenAuth string
+			// like the request ID middleware, pass a copy of the request on, so the
+			// logger keeps the URL as it arrived
+			withContext := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r.WithContext(r.Context()))
+				})
+			}
+			handler := Logger(log)(withContext(StreamToken(tt.param)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seenURL, seenAuth = r.URL.String(), r.Header.Get("Authorization")
+			}))))
+			req := httptest.NewRequest(http.MethodGet, "/api/todos/stream?"+tt.param+"="+token+"&since=5", nil)
+			req.Header.Set("Accept", tt.accept)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(log.lines) != 1 {
+				t.Fatalf("logged %d lines, want 1", len(log.lines))
+			}
+			if strings.Contains(log.lines[0], token) {
+				t.Fatalf("the token reached the log: %s", log.lines[0])
+			}
+			if !strings.Contains(log.lines[0], tt.param+"=REDACTED") || !strings.Contains(log.lines[0], "since=5") {
+				t.Fatalf("the logged URL lost its other parameters: %s", log.lines[0])
+			}
+			if tt.accept != "text/event-stream" {
+				if seenAuth != "" {
+					t.Fatalf("a plain request was authenticated from the URL")
+				}
+				return
+			}
+			if seenAuth != "Bearer "+token {
+				t.Fatalf("Authorization = %q", seenAuth)
+			}
+			if strings.Contains(seenURL, token) || !strings.Contains(seenURL, "since=5") {
+				t.Fatalf("the handler saw %s", seenURL)
+			}
+		})
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := map[string]string{
+		"/api/todos":                            "/api/todos",
+		"/api/todos?page=2":                     "/api/todos?page=2",
+		"/api/todos/stream?access_token=abc":    "/api/todos/stream?access_token=REDACTED",
+		"/api/todos/stream?access%5Ftoken=abc":  "/api/todos/stream?access_token=REDACTED",
+		"/api/todos/stream?access_token=a;b=%%": "/api/todos/stream",
+	}
+	for uri, want := range tests {
+		if got := redactQuery(uri); got != want {
+			t.Errorf("redactQuery(%q) = %q, want %q", uri, got, want)
+		}
+	}
+}
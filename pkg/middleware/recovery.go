@@ -24,7 +24,7 @@ func Recovery(log logger.Logger) func(next http.Handler) http.Handler {
 						actualErr = fmt.Errorf("%v", rcvErr)
 					}
 					log.Error("PANIC_RECOVERED", actualErr, "stack_trace", string(debug.Stack()),)
-					web.RespondError(w, appErrors.New("INTERNAL_SERVER_ERROR", "An unexpected error occurred", nil), http.StatusInternalServerError)
+					web.RespondError(w, appErrors.InternalServerError("An unexpected error occurred", nil), http.StatusInternalServerError)
 				}
 			}()
 			next.ServeHTTP(w, r)
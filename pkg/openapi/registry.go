@@ -0,0 +1,281 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/codetheuri/todolist/pkg/pagination"
+	"github.com/codetheuri/todolist/pkg/web"
+)
+
+// Route describes what a handler takes and returns; the router adds the method and path
+type Route struct {
+	OperationID string
+	Summary     string
+	// the request struct, bound from parameters and the JSON body; nil for none
+	Request reflect.Type
+	// the response data; nil, or a *pagination.PaginationResponse listing Item, for a list
+	Response reflect.Type
+	List     bool
+	Item     reflect.Type
+	Status   int
+	Security []string
+	// the content types of a success response streamed in place of the JSON envelope
+	Streams []string
+	// the content types of a request body read raw rather than decoded
+	Consumes []string
+}
+
+// Describer is a handler that documents itself
+type Describer interface {
+	Describe() Route
+}
+
+type registeredRoute struct {
+	module string
+	method string
+	path   string
+	route  Route
+}
+
+// Registry collects the documented routes of every module and builds their OpenAPI documents
+type Registry struct {
+	title   string
+	version string
+	mu      sync.RWMutex
+	routes  []registeredRoute
+}
+
+func NewRegistry(title, version string) *Registry {
+	return &Registry{title: title, version: version}
+}
+
+// chi parameters may carry a regular expression: {id:[0-9]+}
+var paramPattern = regexp.MustCompile(`\{([^}:]+):[^}]*\}`)
+
+// Add records a route of module served at method and the full chi pattern path
+func (r *Registry) Add(module, method, path string, route Route) {
+	path = paramPattern.ReplaceAllString(path, "{$1}")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, registeredRoute{module: module, method: method, path: path, route: route})
+}
+
+// Modules lists the modules with documented routes
+func (r *Registry) Modules() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := make(map[string]bool)
+	var modules []string
+	for _, route := range r.routes {
+		if !seen[route.module] {
+			seen[route.module] = true
+			modules = append(modules, route.module)
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// Document builds the OpenAPI document of one module, or of the whole API for module "".
+// It reports false for a module without documented routes.
+func (r *Registry) Document(module string) (*Document, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: r.title, Version: r.version},
+		Paths:   make(map[string]*PathItem),
+	}
+	if module != "" {
+		doc.Info.Title = r.title + ": " + strings.ToUpper(module[:1]) + module[1:] + " module"
+	}
+	g := newGenerator()
+	secured := false
+	found := false
+	for _, registered := range r.routes {
+		if module != "" && registered.module != module {
+			continue
+		}
+		found = true
+		item, ok := doc.Paths[registered.path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[registered.path] = item
+		}
+		op := g.operation(registered)
+		(*item)[strings.ToLower(registered.method)] = op
+		secured = secured || len(op.Security) > 0
+	}
+	if !found {
+		return nil, false
+	}
+	doc.Components.Schemas = g.schemas
+	if secured {
+		doc.Components.SecuritySchemes = map[string]*SecurityScheme{
+			BearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "The access token returned on login or registration."},
+		}
+	}
+	return doc, true
+}
+
+func (g *generator) operation(registered registeredRoute) *Operation {
+	route := registered.route
+	op := &Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Responses:   make(map[string]*Response),
+	}
+	if registered.module != "" {
+		op.Tags = []string{registered.module}
+	}
+	validated := false
+	if route.Request != nil && route.Request.Kind() == reflect.Struct {
+		op.Parameters = g.parameters(route.Request)
+		hasBody := registered.method != http.MethodGet && registered.method != http.MethodHead
+		if form := g.form(route.Request); hasBody && len(form.Properties) > 0 {
+			op.RequestBody = &RequestBody{
+				Required: len(form.Required) > 0,
+				Content:  map[string]MediaType{formMediaType(form): {Schema: form}},
+			}
+		} else if body := g.object(route.Request); hasBody && len(body.Properties) > 0 {
+			op.RequestBody = &RequestBody{
+				Required: len(body.Required) > 0,
+				Content:  encoded(g.ref(route.Request)),
+			}
+		}
+		if hasBody && len(route.Consumes) > 0 {
+			if op.RequestBody == nil {
+				op.RequestBody = &RequestBody{Required: true, Content: make(map[string]MediaType)}
+			}
+			for _, contentType := range route.Consumes {
+				op.RequestBody.Content[contentType] = MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+			}
+		}
+		validated = len(op.Parameters) > 0 || op.RequestBody != nil
+	}
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	op.Responses[strconv.Itoa(status)] = g.success(route, status)
+	if validated {
+		op.Responses[strconv.Itoa(http.StatusUnprocessableEntity)] = g.failure(http.StatusText(http.StatusUnprocessableEntity))
+	}
+	for _, scheme := range route.Security {
+		op.Security = append(op.Security, map[string][]string{scheme: {}})
+	}
+	if len(op.Security) > 0 {
+		op.Responses[strconv.Itoa(http.StatusUnauthorized)] = g.failure(http.StatusText(http.StatusUnauthorized))
+	}
+	op.Responses["default"] = g.failure("Error")
+	return op
+}
+
+// forms with files are sent as multipart/form-data
+func formMediaType(form *Schema) string {
+	for _, property := range form.Properties {
+		if property.Format == "binary" || (property.Items != nil && property.Items.Format == "binary") {
+			return "multipart/form-data"
+		}
+	}
+	return "application/x-www-form-urlencoded"
+}
+
+// an error written by web.RespondError, in the envelope or as problem details
+func (g *generator) failure(description string) *Response {
+	content := encoded(g.schema(reflect.TypeOf(web.APIErrorResponse{})))
+	content[web.ProblemContentType] = MediaType{Schema: g.schema(reflect.TypeOf(web.ProblemDetails{}))}
+	return &Response{Description: description, Content: content}
+}
+
+// the same schema in each format the web codecs read and write
+func encoded(schema *Schema) map[string]MediaType {
+	content := make(map[string]MediaType)
+	for _, mediaType := range web.MediaTypes() {
+		content[mediaType] = MediaType{Schema: schema}
+	}
+	return content
+}
+
+// the parameters of a request struct, from its path, query, header and cookie tags
+func (g *generator) parameters(t reflect.Type) []*Parameter {
+	var params []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		source, name := paramTag(f.Tag)
+		if source == "" {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				params = append(params, g.parameters(f.Type)...)
+			}
+			continue
+		}
+		s := g.field(f)
+		if def, ok := f.Tag.Lookup("default"); ok {
+			s.Default = typedValue(s, def)
+		}
+		params = append(params, &Parameter{
+			Name:     name,
+			In:       source,
+			Required: source == "path" || isRequired(f.Tag),
+			Schema:   s,
+		})
+	}
+	return params
+}
+
+// the success envelope written by web.RespondData or, for lists, web.RespondListData
+func (g *generator) success(route Route, status int) *Response {
+	response := &Response{Description: http.StatusText(status)}
+	if status == http.StatusNoContent || status == http.StatusNotModified || (status >= 300 && status < 400) {
+		return response
+	}
+	if len(route.Streams) > 0 {
+		response.Content = make(map[string]MediaType)
+		for _, contentType := range route.Streams {
+			response.Content[contentType] = MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+		}
+		return response
+	}
+	var envelope *Schema
+	if route.List {
+		items := &Schema{}
+		if route.Item != nil {
+			items = g.schema(route.Item)
+		}
+		envelope = &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"listdatapayload": {
+					Type: "object",
+					Properties: map[string]*Schema{
+						"data":       {Type: "array", Items: items},
+						"pagination": g.schema(reflect.TypeOf(pagination.Metadata{})),
+					},
+				},
+			},
+		}
+	} else {
+		data := &Schema{}
+		if route.Response != nil {
+			data = g.schema(route.Response)
+		}
+		envelope = &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"datapayload": {Type: "object", Properties: map[string]*Schema{"data": data}},
+				"alertify":    g.schema(reflect.TypeOf(web.AlertifyPayload{})),
+			},
+		}
+	}
+	response.Content = encoded(envelope)
+	return response
+}
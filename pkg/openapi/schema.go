@@ -0,0 +1,380 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// request parameter tags; fields carrying one are not part of the JSON body
+var paramSources = []string{"path", "query", "header", "cookie"}
+
+// SchemaProvider is a type that gives its own schema, for values such as uploads whose
+// encoding reflection cannot see
+type SchemaProvider interface {
+	OpenAPISchema() *Schema
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	providerType      = reflect.TypeOf((*SchemaProvider)(nil)).Elem()
+	unsafeNameChars   = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// generator turns Go types into schemas, collecting named structs as components
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// schema of t as encoding/json writes it
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t.Implements(providerType) {
+		if t.Kind() == reflect.Ptr {
+			return reflect.New(t.Elem()).Interface().(SchemaProvider).OpenAPISchema()
+		}
+		return reflect.Zero(t).Interface().(SchemaProvider).OpenAPISchema()
+	}
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+	s := g.valueSchema(t)
+	if nullable {
+		if name, ok := s.Type.(string); ok {
+			s.Type = []string{name, "null"}
+		}
+	}
+	return s
+}
+
+func (g *generator) valueSchema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		// its own encoding, whose shape reflection cannot see
+		return &Schema{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.ref(t)
+	}
+	// interfaces and anything else encoding/json cannot describe up front
+	return &Schema{}
+}
+
+// a reference to the component of a named struct, generating it on first use
+func (g *generator) ref(t reflect.Type) *Schema {
+	if name, ok := g.names[t]; ok {
+		return refTo(name)
+	}
+	name := g.componentName(t)
+	g.names[t] = name
+	// reserved before generating so that recursive types end in a reference
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.object(t)
+	return refTo(name)
+}
+
+// the type name, qualified by its package when two packages use the same one
+func (g *generator) componentName(t reflect.Type) string {
+	name := unsafeNameChars.ReplaceAllString(t.Name(), "_")
+	if _, taken := g.schemas[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	qualified := unsafeNameChars.ReplaceAllString(pkg[strings.LastIndex(pkg, "/")+1:], "_") + "." + name
+	for i := 2; ; i++ {
+		if _, taken := g.schemas[qualified]; !taken {
+			return qualified
+		}
+		qualified = name + strconv.Itoa(i)
+	}
+}
+
+// the JSON object a struct encodes to, with embedded structs flattened into it
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isParam(f.Tag) || isFormField(f.Tag) {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.field(f)
+		if isRequired(f.Tag) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// the form a struct is bound from: its fields tagged form, including those of embedded structs
+func (g *generator) form(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFormFields(s, t)
+	return s
+}
+
+func (g *generator) addFormFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !isFormField(f.Tag) || isParam(f.Tag) {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct && !isParam(f.Tag) {
+				g.addFormFields(s, f.Type)
+			}
+			continue
+		}
+		name := f.Tag.Get("form")
+		s.Properties[name] = g.field(f)
+		if isRequired(f.Tag) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// the schema of a struct field, narrowed by its validate, format, maxsize and accept tags
+func (g *generator) field(f reflect.StructField) *Schema {
+	s := g.schema(f.Type)
+	if layout := f.Tag.Get("format"); layout == time.DateOnly && s.Format == "date-time" {
+		s.Format = "date"
+	}
+	applyRules(s, f.Tag.Get("validate"))
+	target := s
+	if s.Items != nil && schemaType(s) == "array" {
+		target = s.Items
+	}
+	var notes []string
+	if accept := f.Tag.Get("accept"); accept != "" {
+		notes = append(notes, "Accepted types: "+strings.ReplaceAll(accept, ",", ", ")+".")
+	}
+	if size := f.Tag.Get("maxsize"); size != "" {
+		notes = append(notes, "At most "+size+".")
+	}
+	if len(notes) > 0 {
+		target.Description = strings.Join(notes, " ")
+	}
+	return s
+}
+
+// the rules of a validate tag that JSON Schema can express; those after dive apply to the items
+func applyRules(s *Schema, tag string) {
+	if tag == "" || s.Ref != "" {
+		return
+	}
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		if rule == "dive" {
+			if s.Items != nil {
+				applyRules(s.Items, strings.Join(rules[i+1:], ","))
+			}
+			return
+		}
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "min", "gte":
+			setBound(s, param, true, false)
+		case "max", "lte":
+			setBound(s, param, false, false)
+		case "gt":
+			setBound(s, param, true, true)
+		case "lt":
+			setBound(s, param, false, true)
+		case "len":
+			setBound(s, param, true, false)
+			setBound(s, param, false, false)
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				s.Enum = append(s.Enum, typedValue(s, value))
+			}
+		case "email":
+			s.Format = "email"
+		case "url", "uri", "http_url":
+			s.Format = "uri"
+		case "uuid", "uuid4":
+			s.Format = "uuid"
+		case "hostname":
+			s.Format = "hostname"
+		case "ip", "ipv4":
+			s.Format = "ipv4"
+		case "ipv6":
+			s.Format = "ipv6"
+		}
+	}
+}
+
+// a length for strings and arrays, a value for numbers
+func setBound(s *Schema, param string, lower, exclusive bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch schemaType(s) {
+	case "string":
+		if lower {
+			s.MinLength = count(n, exclusive, 1)
+		} else {
+			s.MaxLength = count(n, exclusive, -1)
+		}
+	case "array":
+		if lower {
+			s.MinItems = count(n, exclusive, 1)
+		} else {
+			s.MaxItems = count(n, exclusive, -1)
+		}
+	case "integer", "number":
+		switch {
+		case lower && exclusive:
+			s.ExclusiveMinimum = float(n)
+		case lower:
+			s.Minimum = float(n)
+		case exclusive:
+			s.ExclusiveMaximum = float(n)
+		default:
+			s.Maximum = float(n)
+		}
+	}
+}
+
+// the first of a schema's types
+func schemaType(s *Schema) string {
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []string:
+		return t[0]
+	}
+	return ""
+}
+
+// parse a default or enum value as the schema's type
+func typedValue(s *Schema, value string) interface{} {
+	switch schemaType(s) {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+func isFormField(tag reflect.StructTag) bool {
+	name := tag.Get("form")
+	return name != "" && name != "-"
+}
+
+func isParam(tag reflect.StructTag) bool {
+	_, name := paramTag(tag)
+	return name != ""
+}
+
+// the source and name of a field bound from a request parameter
+func paramTag(tag reflect.StructTag) (string, string) {
+	for _, source := range paramSources {
+		if name := tag.Get(source); name != "" && name != "-" {
+			return source, name
+		}
+	}
+	return "", ""
+}
+
+func isRequired(tag reflect.StructTag) bool {
+	for _, rule := range strings.Split(tag.Get("validate"), ",") {
+		switch rule {
+		case "required":
+			return true
+		case "dive":
+			return false
+		}
+	}
+	return false
+}
+
+func count(n float64, exclusive bool, step int) *int {
+	c := int(n)
+	if exclusive {
+		c += step
+	}
+	return &c
+}
+
+func float(n float64) *float64 {
+	return &n
+}
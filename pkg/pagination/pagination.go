@@ -10,9 +10,10 @@ const (
 	MaxLimit     = 100
 )
 
+// Params are the page and limit query parameters of a list request
 type Params struct {
-	Page  int `json:"page"`
-	Limit int `json:"limit"`
+	Page  int `json:"page" query:"page" default:"1"`
+	Limit int `json:"limit" query:"limit" default:"10"`
 }
 
 func NewPaginationParams(page, limit int) *Params {
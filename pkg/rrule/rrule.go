@@ -0,0 +1,385 @@
+package rrule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule is the subset of an iCalendar (RFC 5545) RRULE that Tusk understands:
+// FREQ=DAILY|WEEKLY|MONTHLY with INTERVAL, BYDAY, BYMONTHDAY, COUNT, UNTIL and WKST.
+// Occurrences are computed in wall-clock time of the series' location so that
+// a 09:00 chore stays at 09:00 across daylight saving changes.
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// maxPeriods bounds iteration for rules that rarely or never match
+const maxPeriods = 10000
+
+// Weekday is a BYDAY entry; N is the ordinal within the month (1 = first, -1 = last, 0 = every)
+type Weekday struct {
+	N   int
+	Day time.Weekday
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+	// UntilLocal marks an UNTIL written without a Z: Until then holds a wall-clock
+	// time, read in the series' location rather than in UTC
+	UntilLocal bool
+	// WeekStart is the first day of a week for WEEKLY rules; Parse defaults it to Monday
+	WeekStart time.Weekday
+
+	// UNTIL was a date only, kept to render it back the same way
+	untilDate bool
+}
+
+var dayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Parse reads an RRULE value, with or without the leading "RRULE:"
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, fmt.Errorf("rrule is empty")
+	}
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("malformed rrule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch Frequency(strings.ToUpper(val)) {
+			case Daily, Weekly, Monthly:
+				rule.Freq = Frequency(strings.ToUpper(val))
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q (DAILY, WEEKLY or MONTHLY)", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("INTERVAL must be a positive integer")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive integer")
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, local, date, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until, rule.UntilLocal, rule.untilDate = &until, local, date
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				wd, err := parseWeekday(code)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", d)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "WKST":
+			day, ok := dayCodes[strings.ToUpper(val)]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", val)
+			}
+			rule.WeekStart = day
+		default:
+			return nil, fmt.Errorf("unsupported rrule part %q", key)
+		}
+	}
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot both be set")
+	}
+	if rule.Freq != Monthly {
+		for _, wd := range rule.ByDay {
+			if wd.N != 0 {
+				return nil, fmt.Errorf("ordinal BYDAY values are only supported with FREQ=MONTHLY")
+			}
+		}
+		if len(rule.ByMonthDay) > 0 {
+			return nil, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+		}
+	}
+	return rule, nil
+}
+
+func parseWeekday(code string) (Weekday, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) < 2 {
+		return Weekday{}, fmt.Errorf("invalid BYDAY %q", code)
+	}
+	day, ok := dayCodes[code[len(code)-2:]]
+	if !ok {
+		return Weekday{}, fmt.Errorf("invalid BYDAY %q", code)
+	}
+	wd := Weekday{Day: day}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return Weekday{}, fmt.Errorf("invalid BYDAY ordinal %q", code)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+// an UNTIL without a Z is local to the series; a date-only UNTIL includes the whole day
+func parseUntil(val string) (until time.Time, local, date bool, err error) {
+	if t, err := time.Parse("20060102T150405Z", val); err == nil {
+		return t, false, false, nil
+	}
+	if t, err := time.Parse("20060102T150405", val); err == nil {
+		return t, true, false, nil
+	}
+	if t, err := time.Parse("20060102", val); err == nil {
+		return t.Add(24*time.Hour - time.Second), true, true, nil
+	}
+	return time.Time{}, false, false, fmt.Errorf("invalid UNTIL %q", val)
+}
+
+// String renders the rule back into RRULE syntax
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			code := strings.ToUpper(wd.Day.String()[:2])
+			if wd.N != 0 {
+				code = strconv.Itoa(wd.N) + code
+			}
+			codes[i] = code
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.Until != nil {
+		switch {
+		case r.untilDate:
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		case r.UntilLocal:
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+		default:
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	if r.Freq == Weekly && r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+strings.ToUpper(r.WeekStart.String()[:2]))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Iterator walks the occurrences of a rule starting at dtstart, which is always the first occurrence
+type Iterator struct {
+	rule    *Rule
+	dtstart time.Time
+	until   *time.Time
+	period  int
+	pending []time.Time
+	emitted int
+	done    bool
+}
+
+// Iterator starts a walk over the series beginning at dtstart, evaluated in loc
+func (r *Rule) Iterator(dtstart time.Time, loc *time.Location) *Iterator {
+	if loc == nil {
+		loc = time.UTC
+	}
+	it := &Iterator{rule: r, dtstart: dtstart.In(loc)}
+	if r.Until != nil {
+		until := *r.Until
+		if r.UntilLocal {
+			until = time.Date(until.Year(), until.Month(), until.Day(), until.Hour(), until.Minute(), until.Second(), 0, loc)
+		}
+		it.until = &until
+	}
+	return it
+}
+
+// Next returns the next occurrence, or false once COUNT/UNTIL is exhausted
+func (it *Iterator) Next() (time.Time, bool) {
+	for !it.done && len(it.pending) == 0 {
+		if it.emitted == 0 && it.period == 0 {
+			it.pending = []time.Time{it.dtstart}
+		}
+		for _, candidate := range it.rule.expand(it.dtstart, it.period) {
+			if candidate.After(it.dtstart) {
+				it.pending = append(it.pending, candidate)
+			}
+		}
+		it.period++
+		if it.period > maxPeriods {
+			it.done = true
+		}
+	}
+	if len(it.pending) == 0 {
+		return time.Time{}, false
+	}
+	next := it.pending[0]
+	it.pending = it.pending[1:]
+	if it.until != nil && next.After(*it.until) {
+		it.done, it.pending = true, nil
+		return time.Time{}, false
+	}
+	if it.rule.Count > 0 && it.emitted >= it.rule.Count {
+		it.done, it.pending = true, nil
+		return time.Time{}, false
+	}
+	it.emitted++
+	return next, true
+}
+
+// After returns up to n occurrences strictly after t
+func (r *Rule) After(dtstart time.Time, loc *time.Location, t time.Time, n int) []time.Time {
+	var occurrences []time.Time
+	it := r.Iterator(dtstart, loc)
+	for len(occurrences) < n {
+		next, ok := it.Next()
+		if !ok {
+			break
+		}
+		if next.After(t) {
+			occurrences = append(occurrences, next)
+		}
+	}
+	return occurrences
+}
+
+// expand lists the sorted candidates in the given period (0 = the period containing dtstart)
+func (r *Rule) expand(dtstart time.Time, period int) []time.Time {
+	loc := dtstart.Location()
+	hour, min, sec := dtstart.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hour, min, sec, 0, loc)
+	}
+	var candidates []time.Time
+	switch r.Freq {
+	case Daily:
+		day := at(dtstart.Year(), dtstart.Month(), dtstart.Day()+period*r.Interval)
+		if len(r.ByDay) == 0 || r.matchesDay(day.Weekday()) {
+			candidates = append(candidates, day)
+		}
+	case Weekly:
+		// days since the start of the week
+		sinceStart := func(day time.Weekday) int { return (int(day) - int(r.WeekStart) + 7) % 7 }
+		offset := sinceStart(dtstart.Weekday())
+		week := at(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+period*r.Interval*7)
+		if len(r.ByDay) == 0 {
+			candidates = append(candidates, at(week.Year(), week.Month(), week.Day()+offset))
+		}
+		for _, wd := range r.ByDay {
+			candidates = append(candidates, at(week.Year(), week.Month(), week.Day()+sinceStart(wd.Day)))
+		}
+	case Monthly:
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(period*r.Interval), 1, 0, 0, 0, 0, loc)
+		year, month := first.Year(), first.Month()
+		daysIn := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			// months without the start day (e.g. the 31st) are skipped, as RFC 5545 requires
+			if dtstart.Day() <= daysIn {
+				candidates = append(candidates, at(year, month, dtstart.Day()))
+			}
+		}
+		monthDays := make(map[int]bool)
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = daysIn + d + 1
+			}
+			if d >= 1 && d <= daysIn {
+				monthDays[d] = true
+			}
+		}
+		weekDays := make(map[int]bool)
+		for _, wd := range r.ByDay {
+			var days []int
+			for d := 1; d <= daysIn; d++ {
+				if time.Date(year, month, d, 0, 0, 0, 0, loc).Weekday() == wd.Day {
+					days = append(days, d)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				for _, d := range days {
+					weekDays[d] = true
+				}
+			case wd.N > 0 && wd.N <= len(days):
+				weekDays[days[wd.N-1]] = true
+			case wd.N < 0 && -wd.N <= len(days):
+				weekDays[days[len(days)+wd.N]] = true
+			}
+		}
+		// BYMONTHDAY limits BYDAY when both are set (RFC 5545 3.3.10)
+		for d := 1; d <= daysIn; d++ {
+			inMonthDays, inWeekDays := monthDays[d], weekDays[d]
+			if len(r.ByMonthDay) == 0 {
+				inMonthDays = inWeekDays
+			}
+			if len(r.ByDay) == 0 {
+				inWeekDays = inMonthDays
+			}
+			if inMonthDays && inWeekDays {
+				candidates = append(candidates, at(year, month, d))
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	unique := candidates[:0]
+	for i, c := range candidates {
+		if i == 0 || !c.Equal(candidates[i-1]) {
+			unique = append(unique, c)
+		}
+	}
+	return unique
+}
+
+func (r *Rule) matchesDay(day time.Weekday) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package rrule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "daily", value: "FREQ=DAILY"},
+		{name: "prefix and lower case", value: "RRULE:freq=weekly;byday=mo,we"},
+		{name: "monthly ordinal", value: "FREQ=MONTHLY;BYDAY=-1FR,2MO"},
+		{name: "negative month day", value: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{name: "week start", value: "FREQ=WEEKLY;WKST=SU"},
+		{name: "empty", value: " ", wantErr: "empty"},
+		{name: "no freq", value: "INTERVAL=2", wantErr: "FREQ is required"},
+		{name: "yearly", value: "FREQ=YEARLY", wantErr: "unsupported FREQ"},
+		{name: "zero interval", value: "FREQ=DAILY;INTERVAL=0", wantErr: "INTERVAL"},
+		{name: "zero count", value: "FREQ=DAILY;COUNT=0", wantErr: "COUNT"},
+		{name: "count and until", value: "FREQ=DAILY;COUNT=2;UNTIL=20260101", wantErr: "cannot both be set"},
+		{name: "bad until", value: "FREQ=DAILY;UNTIL=2026-01-01", wantErr: "invalid UNTIL"},
+		{name: "bad day", value: "FREQ=WEEKLY;BYDAY=XX", wantErr: "invalid BYDAY"},
+		{name: "bad ordinal", value: "FREQ=MONTHLY;BYDAY=6MO", wantErr: "invalid BYDAY ordinal"},
+		{name: "weekly ordinal", value: "FREQ=WEEKLY;BYDAY=1MO", wantErr: "only supported with FREQ=MONTHLY"},
+		{name: "weekly month day", value: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: "only supported with FREQ=MONTHLY"},
+		{name: "month day out of range", value: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: "invalid BYMONTHDAY"},
+		{name: "zero month day", value: "FREQ=MONTHLY;BYMONTHDAY=0", wantErr: "invalid BYMONTHDAY"},
+		{name: "bad week start", value: "FREQ=WEEKLY;WKST=XX", wantErr: "invalid WKST"},
+		{name: "unknown part", value: "FREQ=DAILY;BYHOUR=9", wantErr: "unsupported rrule part"},
+		{name: "malformed part", value: "FREQ=DAILY;COUNT", wantErr: "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.value)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Parse(%q) = %v", tt.value, err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("Parse(%q) succeeded, want an error containing %q", tt.value, tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("Parse(%q) = %v, want an error containing %q", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"RRULE:freq=daily;interval=1", "FREQ=DAILY"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"},
+		{"FREQ=WEEKLY;BYDAY=TU;WKST=SU", "FREQ=WEEKLY;BYDAY=TU;WKST=SU"},
+		{"FREQ=WEEKLY;WKST=MO", "FREQ=WEEKLY"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3"},
+		{"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1,15", "FREQ=MONTHLY;BYMONTHDAY=-1,15"},
+		{"FREQ=DAILY;UNTIL=20260131T090000Z", "FREQ=DAILY;UNTIL=20260131T090000Z"},
+		{"FREQ=DAILY;UNTIL=20260131T090000", "FREQ=DAILY;UNTIL=20260131T090000"},
+		{"FREQ=DAILY;UNTIL=20260131", "FREQ=DAILY;UNTIL=20260131"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			rule, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse(%q) = %v", tt.value, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Fatalf("String() = %q, want %q", got, tt.want)
+			}
+			again, err := Parse(rule.String())
+			if err != nil || again.String() != tt.want {
+				t.Fatalf("String() does not round-trip: %q, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestIterator(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tz database: %v", err)
+	}
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart string
+		loc     *time.Location
+		limit   int
+		want    []string
+	}{
+		{
+			name:    "daily count",
+			rule:    "FREQ=DAILY;INTERVAL=2;COUNT=3",
+			dtstart: "2026-01-01T09:00:00Z",
+			want:    []string{"2026-01-01T09:00:00Z", "2026-01-03T09:00:00Z", "2026-01-05T09:00:00Z"},
+		},
+		{
+			name:    "daily keeps wall-clock time across daylight saving",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: "2026-03-07T09:00:00-05:00",
+			loc:     newYork,
+			want:    []string{"2026-03-07T09:00:00-05:00", "2026-03-08T09:00:00-04:00", "2026-03-09T09:00:00-04:00"},
+		},
+		{
+			name:    "utc until",
+			rule:    "FREQ=DAILY;UNTIL=20260103T100000Z",
+			dtstart: "2026-01-01T09:00:00-05:00",
+			loc:     newYork,
+			want:    []string{"2026-01-01T09:00:00-05:00", "2026-01-02T09:00:00-05:00"},
+		},
+		{
+			name:    "local until is read in the series' location",
+			rule:    "FREQ=DAILY;UNTIL=20260103T100000",
+			dtstart: "2026-01-01T09:00:00-05:00",
+			loc:     newYork,
+			want:    []string{"2026-01-01T09:00:00-05:00", "2026-01-02T09:00:00-05:00", "2026-01-03T09:00:00-05:00"},
+		},
+		{
+			name:    "date until includes the whole day",
+			rule:    "FREQ=DAILY;UNTIL=20260102",
+			dtstart: "2026-01-01T23:00:00Z",
+			want:    []string{"2026-01-01T23:00:00Z", "2026-01-02T23:00:00Z"},
+		},
+		{
+			name:    "weekly by day",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=4",
+			dtstart: "2026-01-05T09:00:00Z",
+			want:    []string{"2026-01-05T09:00:00Z", "2026-01-09T09:00:00Z", "2026-01-12T09:00:00Z", "2026-01-16T09:00:00Z"},
+		},
+		{
+			// RFC 5545 3.8.5.3, "an example where the days generated makes a difference because of WKST"
+			name:    "week starting on monday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			dtstart: "1997-08-05T09:00:00Z",
+			want:    []string{"1997-08-05T09:00:00Z", "1997-08-10T09:00:00Z", "1997-08-19T09:00:00Z", "1997-08-24T09:00:00Z"},
+		},
+		{
+			name:    "week starting on sunday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			dtstart: "1997-08-05T09:00:00Z",
+			want:    []string{"1997-08-05T09:00:00Z", "1997-08-17T09:00:00Z", "1997-08-19T09:00:00Z", "1997-08-31T09:00:00Z"},
+		},
+		{
+			name:    "monthly skips months without the start day",
+			rule:    "FREQ=MONTHLY;COUNT=4",
+			dtstart: "2026-01-31T09:00:00Z",
+			want:    []string{"2026-01-31T09:00:00Z", "2026-03-31T09:00:00Z", "2026-05-31T09:00:00Z", "2026-07-31T09:00:00Z"},
+		},
+		{
+			name:    "last day of the month",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=4",
+			dtstart: "2026-01-31T09:00:00Z",
+			want:    []string{"2026-01-31T09:00:00Z", "2026-02-28T09:00:00Z", "2026-03-31T09:00:00Z", "2026-04-30T09:00:00Z"},
+		},
+		{
+			name:    "second to last day in a leap year",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-2;COUNT=3",
+			dtstart: "2028-01-30T09:00:00Z",
+			want:    []string{"2028-01-30T09:00:00Z", "2028-02-28T09:00:00Z", "2028-03-30T09:00:00Z"},
+		},
+		{
+			name:    "31st only in long months",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=3",
+			dtstart: "2026-03-31T09:00:00Z",
+			want:    []string{"2026-03-31T09:00:00Z", "2026-05-31T09:00:00Z", "2026-07-31T09:00:00Z"},
+		},
+		{
+			name:    "last friday",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			dtstart: "2026-01-30T09:00:00Z",
+			want:    []string{"2026-01-30T09:00:00Z", "2026-02-27T09:00:00Z", "2026-03-27T09:00:00Z"},
+		},
+		{
+			name:    "month day limits week day",
+			rule:    "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13;COUNT=4",
+			dtstart: "2026-02-13T09:00:00Z",
+			want:    []string{"2026-02-13T09:00:00Z", "2026-03-13T09:00:00Z", "2026-11-13T09:00:00Z", "2027-08-13T09:00:00Z"},
+		},
+		{
+			name:    "several month days",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=4",
+			dtstart: "2026-02-01T09:00:00Z",
+			want:    []string{"2026-02-01T09:00:00Z", "2026-02-28T09:00:00Z", "2026-03-01T09:00:00Z", "2026-03-31T09:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) = %v", tt.rule, err)
+			}
+			dtstart, err := time.Parse(time.RFC3339, tt.dtstart)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loc := tt.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+			it := rule.Iterator(dtstart, loc)
+			var got []string
+			for len(got) < len(tt.want)+1 {
+				next, ok := it.Next()
+				if !ok {
+					break
+				}
+				got = append(got, next.Format(time.RFC3339))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("occurrences:\n got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAfter(t *testing.T) {
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=MO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	got := rule.After(dtstart, time.UTC, time.Date(2026, 1, 12, 9, 0, 0, 0, time.UTC), 2)
+	want := []time.Time{time.Date(2026, 1, 19, 9, 0, 0, 0, time.UTC), time.Date(2026, 1, 26, 9, 0, 0, 0, time.UTC)}
+	if len(got) != len(want) || !got[0].Equal(want[0]) || !got[1].Equal(want[1]) {
+		t.Fatalf("After() = %v, want %v", got, want)
+	}
+}
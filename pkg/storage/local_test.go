@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalPutOpenDelete(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://files.test/api/files", []byte("signing-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "todos/3/notes.txt"
+	if err := l.Put(ctx, key, strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	reader, err := l.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	body, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(body) != "hello" {
+		t.Fatalf("Open read %q", body)
+	}
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := l.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open after Delete = %v, want ErrNotFound", err)
+	}
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete of a missing object = %v, want nil", err)
+	}
+	if err := l.Put(ctx, "../escape", strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Put outside the root = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestLocalSignedURL(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://files.test/api/files", []byte("signing-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "todos/3/report.pdf"
+	if err := l.Put(ctx, key, strings.NewReader("pdf"), 3, "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	serve := func(link string) *httptest.ResponseRecorder {
+		u, err := url.Parse(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		l.ServeSigned(rec, httptest.NewRequest(http.MethodGet, link, nil), strings.TrimPrefix(u.Path, "/api/files/"))
+		return rec
+	}
+
+	link, err := l.SignedURL(ctx, key, "report.pdf", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := serve(link)
+	if rec.Code != http.StatusOK || rec.Body.String() != "pdf" {
+		t.Fatalf("signed URL = %d %q", rec.Code, rec.Body)
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename=report.pdf` {
+		t.Fatalf("Content-Disposition = %q", disposition)
+	}
+
+	if rec := serve(strings.Replace(link, "name=report.pdf", "name=other.pdf", 1)); rec.Code != http.StatusForbidden {
+		t.Fatalf("tampered signed URL = %d, want 403", rec.Code)
+	}
+	expired, err := l.SignedURL(ctx, key, "report.pdf", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve(expired); rec.Code != http.StatusForbidden {
+		t.Fatalf("expired signed URL = %d, want 403", rec.Code)
+	}
+}
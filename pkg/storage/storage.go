@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/codetheuri/todolist/config"
+)
+
+// Storage keeps uploaded files as objects addressed by slash-separated keys
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a time-limited download link; downloadName is offered as the file name
+	SignedURL(ctx context.Context, key, downloadName string, ttl time.Duration) (string, error)
+}
+
+// Server is implemented by backends whose signed URLs point back at this application
+// rather than at an external service (the local filesystem backend)
+type Server interface {
+	ServeSigned(w http.ResponseWriter, r *http.Request, key string)
+}
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid object key")
+)
+
+// New builds the backend selected by STORAGE_DRIVER
+func New(cfg *config.Config) (Storage, error) {
+	switch cfg.StorageDriver {
+	case "", "local":
+		return NewLocal(cfg.StorageLocalPath, strings.TrimRight(cfg.AppURL, "/")+"/api/files", []byte(cfg.StorageSigningKey))
+	case "s3":
+		return NewS3(S3Options{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       cfg.S3Region,
+			Bucket:       cfg.S3Bucket,
+			AccessKey:    cfg.S3AccessKey,
+			SecretKey:    cfg.S3SecretKey,
+			UsePathStyle: cfg.S3UsePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported STORAGE_DRIVER %q (local or s3)", cfg.StorageDriver)
+	}
+}
+
+// keys are relative, slash-separated and may not climb out of their root
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || strings.HasPrefix(cleaned, "../") || cleaned == ".." {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,266 @@
+package tonic
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// request parameter sources, in the order a field's tags are looked up
+var bindSources = []string{"path", "query", "header", "cookie"}
+
+// a struct field bound from a request parameter, a form field or the raw body
+type paramField struct {
+	index      []int
+	source     string
+	name       string
+	def        string
+	hasDefault bool
+	layout     string
+	// a *File or []*File form field, with its maxsize and accept tags
+	file    bool
+	maxSize int64
+	accept  []string
+}
+
+// binding plans by request type
+var paramPlans sync.Map
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// bind fills the fields of dst tagged path, query, header or cookie from r. A field whose
+// parameter is absent takes its default tag, if any, and is otherwise left as decoded from
+// the body. Values that cannot be converted are reported by parameter name.
+func bind(r *http.Request, dst interface{}) map[string]string {
+	v := reflect.ValueOf(dst).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var query map[string][]string
+	var fieldErrors map[string]string
+	for _, field := range plan(v.Type()) {
+		var values []string
+		switch field.source {
+		case "form", "body":
+			// bound from the body by decodeBody
+			continue
+		case "path":
+			if value := chi.URLParamFromCtx(r.Context(), field.name); value != "" {
+				values = []string{value}
+			}
+		case "query":
+			if query == nil {
+				query = r.URL.Query()
+			}
+			values = query[field.name]
+		case "header":
+			values = r.Header.Values(field.name)
+		case "cookie":
+			if cookie, err := r.Cookie(field.name); err == nil {
+				values = []string{cookie.Value}
+			}
+		}
+		if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+			if !field.hasDefault {
+				continue
+			}
+			values = []string{field.def}
+		}
+		if err := setValue(v.FieldByIndex(field.index), values, field.layout); err != nil {
+			if fieldErrors == nil {
+				fieldErrors = make(map[string]string)
+			}
+			fieldErrors[field.name] = err.Error()
+		}
+	}
+	return fieldErrors
+}
+
+// the bound fields of t, including those of embedded structs
+func plan(t reflect.Type) []paramField {
+	if cached, ok := paramPlans.Load(t); ok {
+		return cached.([]paramField)
+	}
+	fields := collectFields(t, nil)
+	paramPlans.Store(t, fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, parent []int) []paramField {
+	var fields []paramField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int(nil), parent...), i)
+		source, name := paramTag(sf.Tag)
+		if form := sf.Tag.Get("form"); source == "" && form != "" && form != "-" {
+			source, name = "form", form
+		}
+		if source == "" && sf.Type == bodyType {
+			source = "body"
+		}
+		if source == "" {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				fields = append(fields, collectFields(sf.Type, index)...)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		def, hasDefault := sf.Tag.Lookup("default")
+		field := paramField{
+			index:      index,
+			source:     source,
+			name:       name,
+			def:        def,
+			hasDefault: hasDefault,
+			layout:     sf.Tag.Get("format"),
+		}
+		if source == "form" && (sf.Type == fileType || sf.Type == reflect.SliceOf(fileType)) {
+			field.file = true
+			field.maxSize = defaultMaxFileSize
+			if tag := sf.Tag.Get("maxsize"); tag != "" {
+				size, err := parseSize(tag)
+				if err != nil {
+					panic(fmt.Sprintf("tonic: maxsize of %s.%s: %v", t.Name(), sf.Name, err))
+				}
+				field.maxSize = size
+			}
+			for _, accepted := range strings.Split(sf.Tag.Get("accept"), ",") {
+				if accepted = strings.TrimSpace(accepted); accepted != "" {
+					field.accept = append(field.accept, accepted)
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// the first parameter tag of a field and the name it gives
+func paramTag(tag reflect.StructTag) (string, string) {
+	for _, source := range bindSources {
+		if name, ok := tag.Lookup(source); ok && name != "" && name != "-" {
+			return source, name
+		}
+	}
+	return "", ""
+}
+
+// convert values into v; slices take every value, split on commas, other kinds the first
+func setValue(v reflect.Value, values []string, layout string) error {
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), values, layout); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		var items []string
+		for _, value := range values {
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setScalar(slice.Index(i), item, layout); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setScalar(v, values[0], layout)
+}
+
+func setScalar(v reflect.Value, s string, layout string) error {
+	switch {
+	case v.Type() == timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return timeError(layout)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.New("Must be a duration such as 90s or 1h30m")
+		}
+		v.SetInt(int64(d))
+		return nil
+	case reflect.PointerTo(v.Type()).Implements(textUnmarshalerType):
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return errors.New("Invalid value")
+		}
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("Must be true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return numberError(err, "Must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return numberError(err, "Must be a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return numberError(err, "Must be a number")
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("Parameters of type %s are not supported", v.Type())
+	}
+	return nil
+}
+
+func numberError(err error, message string) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return errors.New("Value is out of range")
+	}
+	return errors.New(message)
+}
+
+func timeError(layout string) error {
+	switch layout {
+	case time.RFC3339:
+		return errors.New("Must be a date-time in RFC 3339 format")
+	case time.DateOnly:
+		return errors.New("Must be a date in YYYY-MM-DD format")
+	}
+	return fmt.Errorf("Must be a time in the format %s", layout)
+}
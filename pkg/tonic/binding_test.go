@@ -0,0 +1,165 @@
+package tonic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// a TextUnmarshaler parameter
+type priority int
+
+func (p *priority) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*p = 1
+	case "high":
+		*p = 2
+	default:
+		return errors.New("unknown priority")
+	}
+	return nil
+}
+
+type searchParams struct {
+	ID       uint          `json:"-" path:"id"`
+	Query    string        `json:"-" query:"q"`
+	Limit    int           `json:"-" query:"limit" default:"20"`
+	Small    int8          `json:"-" query:"small"`
+	Ratio    float64       `json:"-" query:"ratio"`
+	Done     *bool         `json:"-" query:"done"`
+	Tags     []string      `json:"-" query:"tag"`
+	IDs      []uint        `json:"-" query:"ids"`
+	Since    time.Time     `json:"-" query:"since"`
+	On       time.Time     `json:"-" query:"on" format:"2006-01-02"`
+	Every    time.Duration `json:"-" query:"every" default:"1h"`
+	Priority priority      `json:"-" query:"priority"`
+	Locale   string        `json:"-" header:"Accept-Language" default:"en"`
+	Session  string        `json:"-" cookie:"session"`
+}
+
+// the parameters bound for target, served under a chi route, or the response when binding failed
+func search(t *testing.T, target string, prepare func(r *http.Request)) (*searchParams, *httptest.ResponseRecorder) {
+	t.Helper()
+	var bound *searchParams
+	router := chi.NewRouter()
+	router.Method(http.MethodGet, "/things/{id}", Handle(func(ctx context.Context, req *searchParams) (*greeting, error) {
+		bound = req
+		return &greeting{}, nil
+	}))
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if prepare != nil {
+		prepare(req)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return bound, rec
+}
+
+func TestBindParameters(t *testing.T) {
+	done := false
+	since := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	on := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		target  string
+		prepare func(r *http.Request)
+		want    searchParams
+	}{
+		{
+			name:   "defaults",
+			target: "/things/7",
+			want:   searchParams{ID: 7, Limit: 20, Every: time.Hour, Locale: "en"},
+		},
+		{
+			name:   "empty values take the default",
+			target: "/things/7?limit=&every=",
+			want:   searchParams{ID: 7, Limit: 20, Every: time.Hour, Locale: "en"},
+		},
+		{
+			name:   "query",
+			target: "/things/7?q=milk&limit=5&small=-8&ratio=0.5&done=false&every=90s&priority=high",
+			want:   searchParams{ID: 7, Query: "milk", Limit: 5, Small: -8, Ratio: 0.5, Done: &done, Every: 90 * time.Second, Priority: 2, Locale: "en"},
+		},
+		{
+			name:   "slices split on commas and repeated",
+			target: "/things/7?tag=a,b&tag=c&tag=&ids=1,%202",
+			want:   searchParams{ID: 7, Limit: 20, Tags: []string{"a", "b", "c"}, IDs: []uint{1, 2}, Every: time.Hour, Locale: "en"},
+		},
+		{
+			name:   "times in RFC 3339 and a format layout",
+			target: "/things/7?since=2026-03-01T09:30:00Z&on=2026-03-02",
+			want:   searchParams{ID: 7, Limit: 20, Since: since, On: on, Every: time.Hour, Locale: "en"},
+		},
+		{
+			name:   "header and cookie",
+			target: "/things/7",
+			prepare: func(r *http.Request) {
+				r.Header.Set("Accept-Language", "sw")
+				r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+			},
+			want: searchParams{ID: 7, Limit: 20, Every: time.Hour, Locale: "sw", Session: "abc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rec := search(t, tt.target, tt.prepare)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Fatalf("bound %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		errors map[string]string
+	}{
+		{name: "path", target: "/things/abc", errors: map[string]string{"id": "Must be a non-negative integer"}},
+		{name: "negative uint", target: "/things/-1", errors: map[string]string{"id": "Must be a non-negative integer"}},
+		{name: "bool", target: "/things/7?done=maybe", errors: map[string]string{"done": "Must be true or false"}},
+		{name: "int", target: "/things/7?limit=ten", errors: map[string]string{"limit": "Must be an integer"}},
+		{name: "out of range", target: "/things/7?small=300", errors: map[string]string{"small": "Value is out of range"}},
+		{name: "float", target: "/things/7?ratio=half", errors: map[string]string{"ratio": "Must be a number"}},
+		{name: "slice item", target: "/things/7?ids=1,x", errors: map[string]string{"ids": "Must be a non-negative integer"}},
+		{name: "RFC 3339", target: "/things/7?since=yesterday", errors: map[string]string{"since": "Must be a date-time in RFC 3339 format"}},
+		{name: "date layout", target: "/things/7?on=02/03/2026", errors: map[string]string{"on": "Must be a date in YYYY-MM-DD format"}},
+		{name: "duration", target: "/things/7?every=often", errors: map[string]string{"every": "Must be a duration such as 90s or 1h30m"}},
+		{name: "text unmarshaler", target: "/things/7?priority=urgent", errors: map[string]string{"priority": "Invalid value"}},
+		{name: "every failure at once", target: "/things/x?limit=ten&ratio=half", errors: map[string]string{
+			"id":    "Must be a non-negative integer",
+			"limit": "Must be an integer",
+			"ratio": "Must be a number",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rec := search(t, tt.target, nil)
+			if got != nil {
+				t.Fatalf("the handler ran with %+v", *got)
+			}
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+			}
+			var env envelope
+			if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil || env.ErrorPayload == nil {
+				t.Fatalf("response %q: %v", rec.Body.String(), err)
+			}
+			if env.ErrorPayload.Code != "VALIDATION_ERROR" || !reflect.DeepEqual(env.ErrorPayload.Errors, tt.errors) {
+				t.Fatalf("error %s %v, want %v", env.ErrorPayload.Code, env.ErrorPayload.Errors, tt.errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package tonic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/openapi"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	// the size of an upload when neither its maxsize tag nor WithFileLimit sets one
+	defaultMaxFileSize = 10 << 20
+	// uploads held in memory per request before they spill to temporary files
+	defaultFormMemory = 8 << 20
+	// the size of a form value that is not a file
+	maxFormValueSize = 1 << 20
+	// the bytes read to detect a file's content type
+	sniffLength = 3072
+)
+
+var (
+	fileType = reflect.TypeOf((*File)(nil))
+	bodyType = reflect.TypeOf((*Body)(nil))
+)
+
+// Body is a request body handed to the handler unread, bound to a *Body field, for content
+// the web codecs do not decode such as a patch document or a file sent as the whole body.
+// A multipart or url-encoded form still fills the fields tagged form of a request that has
+// them, leaving its *Body nil.
+type Body struct {
+	// the media type of the Content-Type header, without its parameters
+	MediaType string
+	io.Reader
+}
+
+// File is an upload bound from a multipart/form-data field tagged form. Its content is held
+// in memory or, past the route's memory limit, in a temporary file, and is removed once the
+// handler returns.
+type File struct {
+	Filename string
+	// detected from the content; the type the client declared is in Header
+	ContentType string
+	Size        int64
+	Header      textproto.MIMEHeader
+	detected    *mimetype.MIME
+	data        []byte
+	path        string
+}
+
+// Open reads the file from the start; close it when done
+func (f *File) Open() (io.ReadSeekCloser, error) {
+	if f.path == "" {
+		return nopCloser{bytes.NewReader(f.data)}, nil
+	}
+	return os.Open(f.path)
+}
+
+// OpenAPISchema documents a File as a binary form field
+func (f *File) OpenAPISchema() *openapi.Schema {
+	return &openapi.Schema{Type: "string", Format: "binary"}
+}
+
+func (f *File) remove() {
+	if f.path != "" {
+		os.Remove(f.path)
+	}
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
+
+// the uploads of a request, removed after its handler
+type uploads []*File
+
+func (u uploads) remove() {
+	for _, f := range u {
+		f.remove()
+	}
+}
+
+// decodeBody fills dst from the request body: url-encoded and multipart forms into its fields
+// tagged form when it has any, anything else into its *Body field when it has one, or with the
+// web codec of its Content-Type. Form values that cannot be converted are reported by field name.
+func decodeBody(r *http.Request, dst interface{}, cfg config) (uploads, map[string]string, appErrors.AppError) {
+	v := reflect.ValueOf(dst).Elem()
+	var fields []paramField
+	var body *paramField
+	hasFiles := false
+	if v.Kind() == reflect.Struct {
+		for _, field := range plan(v.Type()) {
+			switch field.source {
+			case "form":
+				fields = append(fields, field)
+				hasFiles = hasFiles || field.file
+			case "body":
+				body = &field
+			}
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if len(fields) > 0 {
+		switch {
+		case mediaType == "multipart/form-data":
+			return decodeMultipart(r, v, fields, cfg)
+		case mediaType == "application/x-www-form-urlencoded" && !hasFiles:
+			fieldErrors, err := decodeURLEncoded(r, v, fields)
+			return nil, fieldErrors, err
+		case hasFiles && body == nil && r.ContentLength != 0:
+			return nil, nil, appErrors.UnsupportedMediaTypeError("uploads must be sent as multipart/form-data", nil)
+		}
+	}
+	if body != nil {
+		v.FieldByIndex(body.index).Set(reflect.ValueOf(&Body{MediaType: mediaType, Reader: r.Body}))
+		return nil, nil, nil
+	}
+	return nil, nil, decodeEncoded(r, dst)
+}
+
+func decodeURLEncoded(r *http.Request, v reflect.Value, fields []paramField) (map[string]string, appErrors.AppError) {
+	if err := r.ParseForm(); err != nil {
+		return nil, tooLargeOr(err, appErrors.InvalidInputError("Invalid form body", err))
+	}
+	values := make(map[string][]string)
+	for _, field := range fields {
+		if posted := r.PostForm[field.name]; len(posted) > 0 {
+			values[field.name] = posted
+		}
+	}
+	return setFormValues(v, fields, values), nil
+}
+
+// decodeMultipart streams the parts of a multipart body into the form fields of v. Parts
+// without a field are discarded unread; a part over its field's limit ends the request.
+func decodeMultipart(r *http.Request, v reflect.Value, fields []paramField, cfg config) (uploads, map[string]string, appErrors.AppError) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, appErrors.InvalidInputError("Invalid multipart body", err)
+	}
+	byName := make(map[string]paramField, len(fields))
+	for _, field := range fields {
+		byName[field.name] = field
+	}
+	memory := cfg.formMemory
+	values := make(map[string][]string)
+	var files uploads
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return files, nil, tooLargeOr(err, appErrors.InvalidInputError("Invalid multipart body", err))
+		}
+		field, ok := byName[part.FormName()]
+		if !ok {
+			part.Close()
+			continue
+		}
+		if !field.file {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize+1))
+			part.Close()
+			if err != nil {
+				return files, nil, tooLargeOr(err, appErrors.InvalidInputError("Invalid multipart body", err))
+			}
+			if len(value) > maxFormValueSize {
+				return files, nil, appErrors.PayloadTooLargeError(fmt.Sprintf("%s may be at most %d bytes", field.name, maxFormValueSize), nil)
+			}
+			values[field.name] = append(values[field.name], string(value))
+			continue
+		}
+		// an empty file input is sent as a part without a file name
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		limit := field.maxSize
+		if override, ok := cfg.fileLimits[field.name]; ok {
+			limit = override
+		}
+		f, err := readFile(part, limit, &memory)
+		part.Close()
+		if f != nil {
+			files = append(files, f)
+		}
+		if err != nil {
+			if errors.Is(err, errFileTooLarge) {
+				return files, nil, appErrors.PayloadTooLargeError(fmt.Sprintf("%s may be at most %d bytes", field.name, limit), nil)
+			}
+			return files, nil, tooLargeOr(err, appErrors.InvalidInputError("Invalid multipart body", err))
+		}
+		if !accepts(field.accept, f.detected) {
+			return files, nil, appErrors.UnsupportedMediaTypeError(fmt.Sprintf("%s must be of type %s, not %s", field.name, strings.Join(field.accept, ", "), f.ContentType), nil)
+		}
+		fv := v.FieldByIndex(field.index)
+		if fv.Kind() == reflect.Slice {
+			fv.Set(reflect.Append(fv, reflect.ValueOf(f)))
+		} else {
+			fv.Set(reflect.ValueOf(f))
+		}
+	}
+	return files, setFormValues(v, fields, values), nil
+}
+
+var errFileTooLarge = errors.New("file too large")
+
+// readFile copies a part into memory while the request's memory budget lasts and into a
+// temporary file past it, failing once it reaches more than limit bytes
+func readFile(part *multipart.Part, limit int64, memory *int64) (*File, error) {
+	f := &File{Filename: part.FileName(), Header: part.Header}
+	var buf bytes.Buffer
+	// enough to detect the content type even when the budget is spent
+	inMemory := max(*memory, sniffLength)
+	n, err := io.CopyN(&buf, part, min(inMemory, limit)+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if n > limit {
+		return nil, errFileTooLarge
+	}
+	f.detected = mimetype.Detect(buf.Bytes()[:min(buf.Len(), sniffLength)])
+	f.ContentType = f.detected.String()
+	if n <= inMemory {
+		f.data = buf.Bytes()
+		f.Size = n
+		*memory -= min(n, *memory)
+		return f, nil
+	}
+	tmp, err := os.CreateTemp("", "tonic-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	f.path = tmp.Name()
+	written, err := io.Copy(tmp, io.MultiReader(&buf, io.LimitReader(part, limit-n+1)))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written > limit {
+		err = errFileTooLarge
+	}
+	f.Size = written
+	return f, err
+}
+
+// a body cut off by http.MaxBytesReader is reported as too large rather than malformed
+func tooLargeOr(err error, otherwise appErrors.AppError) appErrors.AppError {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return appErrors.PayloadTooLargeError(fmt.Sprintf("the request body may be at most %d bytes", maxBytesErr.Limit), err)
+	}
+	return otherwise
+}
+
+// whether a detected content type, or one it derives from, is among the accepted types;
+// "image/*" accepts any image
+func accepts(accepted []string, detected *mimetype.MIME) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	for _, a := range accepted {
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok {
+			if strings.HasPrefix(detected.String(), prefix+"/") {
+				return true
+			}
+			continue
+		}
+		for m := detected; m != nil; m = m.Parent() {
+			if m.Is(a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// convert the values posted for each non-file form field, falling back to its default tag
+func setFormValues(v reflect.Value, fields []paramField, values map[string][]string) map[string]string {
+	var fieldErrors map[string]string
+	for _, field := range fields {
+		if field.file {
+			continue
+		}
+		posted := values[field.name]
+		if len(posted) == 0 || (len(posted) == 1 && posted[0] == "") {
+			if !field.hasDefault {
+				continue
+			}
+			posted = []string{field.def}
+		}
+		if err := setValue(v.FieldByIndex(field.index), posted, field.layout); err != nil {
+			if fieldErrors == nil {
+				fieldErrors = make(map[string]string)
+			}
+			fieldErrors[field.name] = err.Error()
+		}
+	}
+	return fieldErrors
+}
+
+// parse a maxsize tag: bytes, or a number of KB, MB or GB (powers of 1024)
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if number, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, multiplier = strings.TrimSpace(number), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
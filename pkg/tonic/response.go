@@ -0,0 +1,205 @@
+package tonic
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/codetheuri/todolist/pkg/pagination"
+	"github.com/codetheuri/todolist/pkg/web"
+)
+
+// Response carries what a handler returns besides its data: a status, headers and cookies,
+// a message, a redirect or a streamed body. Return a *Response as the result, with Data as the response
+// data, or embed a Response in the result type to add them to its data.
+type Response struct {
+	// overrides the route's status
+	Status  int            `json:"-"`
+	Header  http.Header    `json:"-"`
+	Cookies []*http.Cookie `json:"-"`
+	// written in the usual envelope when the Response is the result itself
+	Data interface{} `json:"-"`
+	// overrides the route's success message, for results that succeed in more than one way
+	Message string `json:"-"`
+	// Location redirects the client, with 302 Found unless the status is another redirect
+	Location string `json:"-"`
+	// Body is copied to the client as ContentType in place of the envelope, and closed
+	// afterwards if it is an io.Closer
+	Body        io.Reader `json:"-"`
+	ContentType string    `json:"-"`
+}
+
+// Redirect sends the client to location with status, such as http.StatusSeeOther
+func Redirect(location string, status int) *Response {
+	return &Response{Location: location, Status: status}
+}
+
+// Stream sends body as it is read, as contentType
+func Stream(contentType string, body io.Reader) *Response {
+	return &Response{Body: body, ContentType: contentType}
+}
+
+// NoContent sends 204 No Content with an empty body
+func NoContent() *Response {
+	return &Response{Status: http.StatusNoContent}
+}
+
+// NotModified sends 304 Not Modified with an empty body, for a conditional GET that matched
+func NotModified() *Response {
+	return &Response{Status: http.StatusNotModified}
+}
+
+// SetHeader sets a response header, replacing any value it had
+func (r *Response) SetHeader(key, value string) *Response {
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	r.Header.Set(key, value)
+	return r
+}
+
+// SetCookie adds a Set-Cookie header
+func (r *Response) SetCookie(cookie *http.Cookie) *Response {
+	r.Cookies = append(r.Cookies, cookie)
+	return r
+}
+
+// Attachment asks the client to save the body as filename
+func (r *Response) Attachment(filename string) *Response {
+	return r.SetHeader("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
+
+func (r *Response) response() *Response {
+	return r
+}
+
+// a result that is, or embeds, a Response
+type responder interface {
+	response() *Response
+}
+
+// bodyless reports the statuses that must not have a body
+func bodyless(status int) bool {
+	return status < 200 || status == http.StatusNoContent || status == http.StatusNotModified
+}
+
+// the bytes of a streamed body read at a time
+const streamChunk = 32 << 10
+
+// writeResult writes the result of a handler: res as the data, with the status, headers,
+// redirect or body of the Response it is or embeds
+func writeResult(w http.ResponseWriter, r *http.Request, cfg config, res interface{}) {
+	var extra *Response
+	data := res
+	if rr, ok := res.(responder); ok {
+		extra = rr.response()
+		if _, self := res.(*Response); self {
+			data = extra.Data
+		}
+	}
+	if extra == nil {
+		extra = &Response{}
+	}
+	status := cfg.status
+	switch {
+	case extra.Status != 0:
+		status = extra.Status
+	case extra.Location != "" && (status < 300 || status > 399):
+		status = http.StatusFound
+	}
+
+	if extra.Body != nil && !bodyless(status) && extra.Location == "" {
+		stream(w, cfg, extra, status)
+		return
+	}
+	if c, ok := extra.Body.(io.Closer); ok {
+		c.Close()
+	}
+	writeHeaders(w, extra)
+	switch {
+	case extra.Location != "":
+		http.Redirect(w, r, extra.Location, status)
+	case bodyless(status):
+		w.WriteHeader(status)
+	default:
+		if page, ok := data.(*pagination.PaginationResponse); ok {
+			web.RespondListData(w, status, page.Data, page.Metadata)
+			return
+		}
+		message := cfg.message
+		if extra.Message != "" {
+			message = extra.Message
+		}
+		if message == "" {
+			web.RespondData(w, status, data, "", web.WithoutSuccess())
+			return
+		}
+		web.RespondData(w, status, data, message, web.WithSuccessType(alertType(cfg)))
+	}
+}
+
+// the alertify type of the route's success message
+func alertType(cfg config) string {
+	if cfg.toast {
+		return "toast"
+	}
+	return "alert"
+}
+
+func writeHeaders(w http.ResponseWriter, extra *Response) {
+	for key, values := range extra.Header {
+		w.Header()[key] = values
+	}
+	for _, cookie := range extra.Cookies {
+		http.SetCookie(w, cookie)
+	}
+}
+
+// stream copies a Response's body to the client. The first read happens before anything is
+// written, so that a body failing at once is still reported as an error response; a failure
+// after that can only cut the response short.
+func stream(w http.ResponseWriter, cfg config, extra *Response, status int) {
+	if c, ok := extra.Body.(io.Closer); ok {
+		defer c.Close()
+	}
+	length := -1
+	if sized, ok := extra.Body.(interface{ Len() int }); ok {
+		length = sized.Len()
+	}
+	buf := make([]byte, streamChunk)
+	n, err := readSome(extra.Body, buf)
+	if err != nil && !errors.Is(err, io.EOF) {
+		web.RespondError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if cfg.noWriteDeadline {
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	}
+	writeHeaders(w, extra)
+	contentType := extra.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if length >= 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(length))
+	}
+	w.WriteHeader(status)
+	if _, werr := w.Write(buf[:n]); werr != nil || err != nil {
+		return
+	}
+	io.CopyBuffer(w, extra.Body, buf)
+}
+
+// read until some of r arrives or it fails
+func readSome(r io.Reader, buf []byte) (int, error) {
+	for {
+		n, err := r.Read(buf)
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
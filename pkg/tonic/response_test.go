@@ -0,0 +1,273 @@
+package tonic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type noRequest struct{}
+
+// the response recorded for fn, served with opts
+func record(t *testing.T, fn HandlerFunc[noRequest, Response], opts ...Option) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handle(fn, opts...).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/thing", nil))
+	return rec
+}
+
+func respond(res *Response, err error) HandlerFunc[noRequest, Response] {
+	return func(ctx context.Context, req *noRequest) (*Response, error) {
+		return res, err
+	}
+}
+
+// the alert of a success response
+func alertOf(t *testing.T, rec *httptest.ResponseRecorder) (message string, typ string) {
+	t.Helper()
+	var body struct {
+		Alertify *struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"alertify"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response %q: %v", rec.Body.String(), err)
+	}
+	if body.Alertify == nil {
+		return "", ""
+	}
+	return body.Alertify.Message, body.Alertify.Type
+}
+
+func TestWriteResultRedirects(t *testing.T) {
+	tests := []struct {
+		name   string
+		res    *Response
+		opts   []Option
+		status int
+	}{
+		{name: "found by default", res: &Response{Location: "/elsewhere"}, status: http.StatusFound},
+		{name: "status of the response", res: Redirect("/elsewhere", http.StatusSeeOther), status: http.StatusSeeOther},
+		{name: "redirect status of the route", res: &Response{Location: "/elsewhere"}, opts: []Option{WithStatus(http.StatusMovedPermanently)}, status: http.StatusMovedPermanently},
+		{name: "found over a success status", res: &Response{Location: "/elsewhere"}, opts: []Option{WithStatus(http.StatusCreated)}, status: http.StatusFound},
+		{name: "body is not sent", res: &Response{Location: "/elsewhere", Body: strings.NewReader("ignored")}, status: http.StatusFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := record(t, respond(tt.res, nil), tt.opts...)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Location"); got != "/elsewhere" {
+				t.Fatalf("Location %q", got)
+			}
+			if strings.Contains(rec.Body.String(), "ignored") {
+				t.Fatalf("the body was sent with the redirect: %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestWriteResultHeadersAndCookies(t *testing.T) {
+	res := &Response{Data: map[string]string{"a": "b"}}
+	res.SetHeader("ETag", `"1"`).SetHeader("ETag", `"2"`).SetCookie(&http.Cookie{Name: "session", Value: "abc", HttpOnly: true})
+	rec := record(t, respond(res, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if got := rec.Header().Values("ETag"); len(got) != 1 || got[0] != `"2"` {
+		t.Fatalf("ETag %q, want the value set last", got)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "abc" || !cookies[0].HttpOnly {
+		t.Fatalf("cookies %v", cookies)
+	}
+	var body struct {
+		Datapayload struct {
+			Data map[string]string `json:"data"`
+		} `json:"datapayload"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Datapayload.Data["a"] != "b" {
+		t.Fatalf("body %q: %v", rec.Body.String(), err)
+	}
+}
+
+// a result embedding a Response is its data, with the Response's headers
+type etagged struct {
+	Response
+	Name string `json:"name"`
+}
+
+func TestWriteResultEmbeddedResponse(t *testing.T) {
+	fn := func(ctx context.Context, req *noRequest) (*etagged, error) {
+		res := &etagged{Name: "ada"}
+		res.SetHeader("ETag", `"7"`)
+		res.Status = http.StatusAccepted
+		return res, nil
+	}
+	rec := httptest.NewRecorder()
+	Handle(fn).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/thing", nil))
+	if rec.Code != http.StatusAccepted || rec.Header().Get("ETag") != `"7"` {
+		t.Fatalf("status %d, ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if !strings.Contains(rec.Body.String(), `"name":"ada"`) {
+		t.Fatalf("body %q", rec.Body.String())
+	}
+}
+
+func TestWriteResultBodyless(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     HandlerFunc[noRequest, Response]
+		opts   []Option
+		status int
+	}{
+		{name: "no content", fn: respond(NoContent().SetHeader("X-Done", "yes"), nil), status: http.StatusNoContent},
+		{name: "not modified", fn: respond(NotModified().SetHeader("X-Done", "yes"), nil), status: http.StatusNotModified},
+		{name: "data is dropped", fn: respond(&Response{Status: http.StatusNoContent, Data: "dropped", Header: http.Header{"X-Done": {"yes"}}}, nil), status: http.StatusNoContent},
+		{name: "nil result on a 204 route", fn: respond(nil, nil), opts: []Option{WithStatus(http.StatusNoContent), WithMessage("dropped")}, status: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := record(t, tt.fn, tt.opts...)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			if rec.Body.Len() != 0 {
+				t.Fatalf("body %q, want none", rec.Body.String())
+			}
+			if tt.opts == nil && rec.Header().Get("X-Done") != "yes" {
+				t.Fatalf("headers %v", rec.Header())
+			}
+		})
+	}
+	// a nil result is a mistake where a body is expected
+	if rec := record(t, respond(nil, nil)); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("nil result answered %d", rec.Code)
+	}
+}
+
+func TestWriteResultMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      HandlerFunc[noRequest, Response]
+		opts    []Option
+		status  int
+		message string
+		typ     string
+	}{
+		{name: "route message", fn: respond(&Response{Data: 1}, nil), opts: []Option{WithMessage("Saved")}, status: http.StatusOK, message: "Saved", typ: "alert"},
+		{name: "toast", fn: respond(&Response{Data: 1}, nil), opts: []Option{WithToast("Deleted")}, status: http.StatusOK, message: "Deleted", typ: "toast"},
+		{name: "result message", fn: respond(&Response{Data: 1, Status: http.StatusUnprocessableEntity, Message: "Rolled back"}, nil), opts: []Option{WithMessage("Saved")}, status: http.StatusUnprocessableEntity, message: "Rolled back", typ: "alert"},
+		{name: "result message without a route message", fn: respond(&Response{Data: 1, Message: "Checked"}, nil), status: http.StatusOK, message: "Checked", typ: "alert"},
+		{name: "message alone", fn: respond(nil, nil), opts: []Option{WithToast("Deleted")}, status: http.StatusOK, message: "Deleted", typ: "toast"},
+		{name: "no message", fn: respond(&Response{Data: 1}, nil), status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := record(t, tt.fn, tt.opts...)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			message, typ := alertOf(t, rec)
+			if message != tt.message || typ != tt.typ {
+				t.Fatalf("alert %q of type %q, want %q of type %q", message, typ, tt.message, tt.typ)
+			}
+		})
+	}
+}
+
+// a reader that fails after reading n bytes
+type failingReader struct {
+	n      int
+	closed bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, errors.New("disk on fire")
+	}
+	n := copy(p, strings.Repeat("x", r.n))
+	r.n -= n
+	return n, nil
+}
+
+func (r *failingReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestStream(t *testing.T) {
+	t.Run("body", func(t *testing.T) {
+		res := Stream("text/csv", strings.NewReader("a,b\n1,2\n")).Attachment("todos.csv")
+		rec := record(t, respond(res, nil), WithStatus(http.StatusCreated), WithMessage("not in a stream"))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status %d", rec.Code)
+		}
+		if rec.Body.String() != "a,b\n1,2\n" {
+			t.Fatalf("body %q", rec.Body.String())
+		}
+		if got := rec.Header().Get("Content-Type"); got != "text/csv" {
+			t.Fatalf("Content-Type %q", got)
+		}
+		if got := rec.Header().Get("Content-Length"); got != "8" {
+			t.Fatalf("Content-Length %q, want the length of the reader", got)
+		}
+		if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=todos.csv` {
+			t.Fatalf("Content-Disposition %q", got)
+		}
+	})
+	t.Run("unknown length and type", func(t *testing.T) {
+		rec := record(t, respond(&Response{Body: io.MultiReader(strings.NewReader("raw"))}, nil))
+		if rec.Body.String() != "raw" || rec.Header().Get("Content-Type") != "application/octet-stream" || rec.Header().Get("Content-Length") != "" {
+			t.Fatalf("body %q with headers %v", rec.Body.String(), rec.Header())
+		}
+	})
+	t.Run("fails on the first read", func(t *testing.T) {
+		body := &failingReader{}
+		rec := record(t, respond(Stream("text/csv", body).Attachment("todos.csv"), nil))
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("status %d, want an error response", rec.Code)
+		}
+		if rec.Header().Get("Content-Disposition") != "" || rec.Header().Get("Content-Type") == "text/csv" {
+			t.Fatalf("the stream's headers were sent with the error: %v", rec.Header())
+		}
+		var env envelope
+		if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil || env.ErrorPayload == nil || env.ErrorPayload.Code != "INTERNAL_SERVER_ERROR" {
+			t.Fatalf("body %q: %v", rec.Body.String(), err)
+		}
+		if !body.closed {
+			t.Fatal("the body was not closed")
+		}
+	})
+	t.Run("fails later", func(t *testing.T) {
+		body := &failingReader{n: 4}
+		rec := record(t, respond(Stream("text/csv", body), nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != "xxxx" {
+			t.Fatalf("status %d with body %q, want what was read before the failure", rec.Code, rec.Body.String())
+		}
+		if !body.closed {
+			t.Fatal("the body was not closed")
+		}
+	})
+	t.Run("bodyless status closes the body", func(t *testing.T) {
+		body := &failingReader{n: 4}
+		rec := record(t, respond(&Response{Status: http.StatusNoContent, Body: body}, nil))
+		if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 || !body.closed {
+			t.Fatalf("status %d, body %q, closed %v", rec.Code, rec.Body.String(), body.closed)
+		}
+	})
+}
+
+func TestHandleError(t *testing.T) {
+	rec := record(t, respond(&Response{Data: 1, Header: http.Header{"X-Lost": {"yes"}}}, errors.New("boom")))
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("X-Lost") != "" {
+		t.Fatalf("status %d with headers %v", rec.Code, rec.Header())
+	}
+}
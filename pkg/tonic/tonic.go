@@ -1,83 +1,349 @@
 package tonic
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"reflect"
+	"runtime"
+	"strings"
+	"unicode"
 
 	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/openapi"
+	"github.com/codetheuri/todolist/pkg/pagination"
 	"github.com/codetheuri/todolist/pkg/validators"
 	"github.com/codetheuri/todolist/pkg/web"
 )
 
-//Tonic uses reflection to pull docs straight from your routes and struct binding tags — both request and responses.
-//It also provides a clean separation between your HTTP layer and business logic by using pure functions as handlers.
-//The Adapter function converts these pure functions into standard http.HandlerFunc, handling JSON decoding, validation, and error responses automatically.
+//Tonic provides a clean separation between your HTTP layer and business logic by using pure functions as handlers.
+//Handle converts a typed function into a standard http.Handler, handling body decoding, parameter binding, validation, and error responses automatically.
+//The request and response types are checked at compile time, so no reflection or type assertions are needed in the handlers.
+//The same types document the route: an Endpoint describes itself to the OpenAPI registry when it is mounted.
 
-type Response struct {
-	Data   interface{}
-	Status int
+// HandlerFunc is a pure handler: it receives the decoded and validated request and returns the response data
+type HandlerFunc[Req, Resp any] func(ctx context.Context, req *Req) (*Resp, error)
+
+// used when a route is registered without WithValidator
+var defaultValidator = validators.NewValidator()
+
+type config struct {
+	validator *validators.Validator
+	status    int
+	message   string
+	toast     bool // the message is shown as a toast rather than an alert
+	header    http.Header
+	summary   string
+	security  []string
+	item      reflect.Type
+	// upload limits by form field name, overriding maxsize tags
+	fileLimits map[string]int64
+	formMemory int64
+	// the size of a request body, 0 for no limit; and the raw content types it documents
+	bodyLimit int64
+	bodyTypes []string
+	// content types of a streamed success response, for the documentation
+	streams         []string
+	noWriteDeadline bool
+}
+
+// Option configures a route built by Handle
+type Option func(*config)
+
+// Options combines options, for those shared by the routes of a module
+func Options(opts ...Option) Option {
+	return func(c *config) {
+		for _, opt := range opts {
+			opt(c)
+		}
+	}
+}
+
+// WithValidator validates requests with v instead of a default validator
+func WithValidator(v *validators.Validator) Option {
+	return func(c *config) {
+		c.validator = v
+	}
+}
+
+// WithStatus sets the status of a successful response, 200 by default
+func WithStatus(status int) Option {
+	return func(c *config) {
+		c.status = status
+	}
+}
+
+// WithMessage adds a success alert with message to the response
+func WithMessage(message string) Option {
+	return func(c *config) {
+		c.message = message
+		c.toast = false
+	}
+}
+
+// WithToast adds a success toast with message to the response, the passing notice the client
+// shows for routes such as deletions
+func WithToast(message string) Option {
+	return func(c *config) {
+		c.message = message
+		c.toast = true
+	}
+}
+
+// WithHeader sets a header on every response of the route, errors included, such as the
+// Accept-Patch a PATCH route advertises when it refuses a patch format
+func WithHeader(key, value string) Option {
+	return func(c *config) {
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+		c.header.Set(key, value)
+	}
+}
+
+// WithSummary sets the summary of the documented operation, which defaults to the handler's name
+func WithSummary(summary string) Option {
+	return func(c *config) {
+		c.summary = summary
+	}
+}
+
+// WithSecurity documents that the route needs the given security schemes, such as openapi.BearerAuth
+func WithSecurity(schemes ...string) Option {
+	return func(c *config) {
+		c.security = append(c.security, schemes...)
+	}
+}
+
+// WithFileLimit limits the upload in the form field name to size bytes, for limits that
+// come from configuration rather than a maxsize tag
+func WithFileLimit(name string, size int64) Option {
+	return func(c *config) {
+		if c.fileLimits == nil {
+			c.fileLimits = make(map[string]int64)
+		}
+		c.fileLimits[name] = size
+	}
 }
 
-func NewResponse(data interface{}) *Response {
-	return &Response{
-		Data:   data,
-		Status: http.StatusOK,
+// WithFormMemory sets how many bytes of a request's uploads are held in memory before
+// they spill to temporary files, 8 MiB by default
+func WithFormMemory(size int64) Option {
+	return func(c *config) {
+		c.formMemory = size
 	}
 }
-func NewCreatedResponse(data interface{}) *Response {
-	return &Response{Data: data, Status: http.StatusCreated}
+
+// WithBodyLimit limits a request body to size bytes; a larger one is answered with 413
+// Payload Too Large, including when the handler reads past the limit of a *Body
+func WithBodyLimit(size int64) Option {
+	return func(c *config) {
+		c.bodyLimit = size
+	}
 }
 
-func NewNoContentResponse() *Response {
-	return &Response{
-		Data:   nil,
-		Status: http.StatusNoContent,
+// WithBodyTypes documents the content types the route reads through a *Body field
+func WithBodyTypes(contentTypes ...string) Option {
+	return func(c *config) {
+		c.bodyTypes = append(c.bodyTypes, contentTypes...)
 	}
 }
 
-type HandlerFunc func(ctx context.Context, input interface{}) (*Response, error)
+// WithStream documents that the route streams its success response as one of contentTypes
+func WithStream(contentTypes ...string) Option {
+	return func(c *config) {
+		c.streams = append(c.streams, contentTypes...)
+	}
+}
 
-func Adapter(pureFunc HandlerFunc, inputType interface{}, validator *validators.Validator) http.HandlerFunc {
-	inputTypeReflect := reflect.TypeOf(inputType)
-	var inputTypeElem reflect.Type
-	if inputTypeReflect.Kind() == reflect.Ptr {
-		inputTypeElem = inputTypeReflect.Elem()
-	} else {
-		inputTypeElem = inputTypeReflect
+// WithoutWriteDeadline lifts the server's write timeout while a body is streamed, for
+// downloads that may take longer
+func WithoutWriteDeadline() Option {
+	return func(c *config) {
+		c.noWriteDeadline = true
 	}
+}
+
+// ListOf documents the items of a *pagination.PaginationResponse
+func ListOf[T any]() Option {
+	return func(c *config) {
+		c.item = reflect.TypeOf((*T)(nil)).Elem()
+	}
+}
+
+// Endpoint is a route built by Handle; it serves requests and describes itself to an openapi.Registry
+type Endpoint struct {
+	handler http.HandlerFunc
+	route   openapi.Route
+}
+
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.handler(w, r)
+}
+
+func (e *Endpoint) Describe() openapi.Route {
+	return e.route
+}
+
+// Handle adapts fn to an http.Handler. The body, if any, is decoded into a new Req (a form
+// into its fields tagged form, anything else by its Content-Type: JSON, MessagePack, XML,
+// CBOR or another web codec, or handed to fn unread through a *Body field), its path, query,
+// header and cookie fields are bound, and it is validated before fn is called. Uploads bound
+// to *File fields are removed once fn returns.
+//
+// fn's result is written as the response data (a *pagination.PaginationResponse as a list) and
+// its error through web.RespondError. A result that is or embeds a Response can also set the
+// status, headers, cookies and message, redirect, or stream a body. Responses with a 204 or
+// 304 status have no body, and a nil result is allowed for them; on a route with a message, a
+// nil result answers with the message alone.
+//
+// Handle panics when Req has a unique or exists rule its validator cannot run, so that the
+// mistake stops the server at start-up.
+func Handle[Req, Resp any](fn HandlerFunc[Req, Resp], opts ...Option) *Endpoint {
+	cfg := config{validator: defaultValidator, status: http.StatusOK, formMemory: defaultFormMemory}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := cfg.validator.CheckRules(new(Req)); err != nil {
+		panic(fmt.Sprintf("tonic: %v", err))
+	}
+	return &Endpoint{handler: handler(fn, cfg), route: describe(fn, cfg)}
+}
+
+func handler[Req, Resp any](fn HandlerFunc[Req, Resp], cfg config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		input := reflect.New(inputTypeElem).Interface()
-		if r.ContentLength > 0 {
-			if err := json.NewDecoder(r.Body).Decode(input); err != nil {
-				web.RespondError(w, appErrors.ValidationError("invalid request payload format", err, nil), http.StatusBadRequest)
-				return
+		for key, values := range cfg.header {
+			w.Header()[key] = values
+		}
+		req := new(Req)
+		if cfg.bodyLimit > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, cfg.bodyLimit)
+		}
+		files, formErrors, bodyErr := decodeBody(r, req, cfg)
+		defer files.remove()
+		if bodyErr != nil {
+			web.RespondError(w, bodyErr, http.StatusBadRequest)
+			return
+		}
+		bindErrors := bind(r, req)
+		for name, message := range formErrors {
+			if bindErrors == nil {
+				bindErrors = make(map[string]string)
 			}
+			bindErrors[name] = message
 		}
-
-		if validationErrors := validator.Struct(input); validationErrors != nil {
+		validationErrors, err := cfg.validator.Validate(r.Context(), req)
+		if err != nil {
+			web.RespondError(w, err, http.StatusInternalServerError)
+			return
+		}
+		for name, message := range bindErrors {
+			if validationErrors == nil {
+				validationErrors = make(map[string]string)
+			}
+			// a value that could not be converted explains more than the check of its zero value
+			validationErrors[name] = message
+		}
+		if validationErrors != nil {
 			web.RespondError(w, appErrors.ValidationError("validation failed", nil, validationErrors), http.StatusUnprocessableEntity)
 			return
 		}
 
-		tonicResponse, err := pureFunc(r.Context(), input)
-
+		res, err := fn(r.Context(), req)
 		if err != nil {
+			// fn read a *Body past the limit
+			if tooLarge := tooLargeOr(err, nil); tooLarge != nil {
+				err = tooLarge
+			}
 			web.RespondError(w, err, http.StatusInternalServerError)
 			return
 		}
-		if tonicResponse == nil {
-			web.RespondError(w, appErrors.InternalServerError("handler returned nil response struct", nil), http.StatusInternalServerError)
+		if res == nil {
+			if bodyless(cfg.status) {
+				w.WriteHeader(cfg.status)
+				return
+			}
+			if cfg.message != "" {
+				web.RespondMessage(w, cfg.status, cfg.message, "success", alertType(cfg))
+				return
+			}
+			web.RespondError(w, appErrors.InternalServerError("handler returned no response", nil), http.StatusInternalServerError)
 			return
 		}
-		statusCode := tonicResponse.Status
-		if statusCode == http.StatusNoContent {
+		writeResult(w, r, cfg, res)
+	}
+}
 
-			web.RespondMessage(w, http.StatusNoContent, "Operation successful", "success", "toast")
-			return
+// the documentation of a route; its operation ID is the name of the handler method
+func describe[Req, Resp any](fn HandlerFunc[Req, Resp], cfg config) openapi.Route {
+	route := openapi.Route{
+		Request:  reflect.TypeOf((*Req)(nil)).Elem(),
+		Response: reflect.TypeOf((*Resp)(nil)).Elem(),
+		Item:     cfg.item,
+		Status:   cfg.status,
+		Security: cfg.security,
+		Summary:  cfg.summary,
+		Streams:  cfg.streams,
+		Consumes: cfg.bodyTypes,
+	}
+	route.List = route.Response == reflect.TypeOf(pagination.PaginationResponse{})
+	if route.Response == reflect.TypeOf(Response{}) {
+		// its data is untyped
+		route.Response = nil
+	}
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		name := strings.TrimSuffix(f.Name(), "-fm")
+		name = name[strings.LastIndex(name, ".")+1:]
+		// function literals are named func1, func2...
+		if !strings.HasPrefix(name, "func") {
+			route.OperationID = name
 		}
+	}
+	if route.Summary == "" {
+		route.Summary = sentence(route.OperationID)
+	}
+	return route
+}
+
+// GetWebhookDeliveries reads as "Get webhook deliveries", GetTodoByID as "Get todo by id"
+func sentence(name string) string {
+	var b strings.Builder
+	prev := ' '
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			if unicode.IsLower(prev) {
+				b.WriteByte(' ')
+			}
+			prev, r = r, unicode.ToLower(r)
+		} else {
+			prev = r
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
 
-		web.RespondData(w, statusCode, tonicResponse.Data, "", web.WithoutSuccess())
+// decodeEncoded decodes the body with the codec of its Content-Type, JSON when it has none;
+// an absent or empty body leaves dst at its zero value
+func decodeEncoded(r *http.Request, dst interface{}) appErrors.AppError {
+	if r.Body == nil || r.ContentLength == 0 {
+		return nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return tooLargeOr(err, appErrors.ValidationError("invalid request payload format", err, nil))
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	codec, ok := web.CodecFor(r.Header.Get("Content-Type"))
+	if !ok {
+		return appErrors.UnsupportedMediaTypeError("the request body must be sent as one of "+strings.Join(web.MediaTypes(), ", "), nil)
+	}
+	if err := codec.Unmarshal(body, dst); err != nil {
+		return appErrors.ValidationError("invalid request payload format", err, nil)
 	}
+	return nil
 }
@@ -0,0 +1,187 @@
+package tonic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type greetRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type greeting struct {
+	Text string `json:"text"`
+}
+
+func greet(ctx context.Context, req *greetRequest) (*greeting, error) {
+	return &greeting{Text: "hello " + req.Name}, nil
+}
+
+// the decoded envelope of a response, success or error
+type envelope struct {
+	Datapayload *struct {
+		Data greeting `json:"data"`
+	} `json:"datapayload"`
+	Alertify *struct {
+		Message string `json:"message"`
+	} `json:"alertify"`
+	ErrorPayload *struct {
+		Code   string            `json:"code"`
+		Errors map[string]string `json:"errors"`
+	} `json:"errorpayload"`
+}
+
+func serve(t *testing.T, h http.Handler, contentType, body string) (int, envelope) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var env envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Fatalf("response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, env
+}
+
+func TestHandleDecodesBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		code        string
+	}{
+		{name: "json", contentType: "application/json", body: `{"name":"ada"}`, status: http.StatusOK},
+		{name: "no content type", body: `{"name":"ada"}`, status: http.StatusOK},
+		{name: "malformed json", contentType: "application/json", body: `{"name":`, status: http.StatusUnprocessableEntity, code: "VALIDATION_ERROR"},
+		{name: "wrong type", contentType: "application/json", body: `{"name":7}`, status: http.StatusUnprocessableEntity, code: "VALIDATION_ERROR"},
+		{name: "malformed xml", contentType: "application/xml", body: `<greetRequest><name>ada`, status: http.StatusUnprocessableEntity, code: "VALIDATION_ERROR"},
+		{name: "missing field", contentType: "application/json", body: `{}`, status: http.StatusUnprocessableEntity, code: "VALIDATION_ERROR"},
+		{name: "unsupported type", contentType: "text/csv", body: `name\nada`, status: http.StatusUnsupportedMediaType, code: "UNSUPPORTED_MEDIA_TYPE"},
+	}
+	h := Handle(greet)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, env := serve(t, h, tt.contentType, tt.body)
+			if status != tt.status {
+				t.Fatalf("status %d, want %d", status, tt.status)
+			}
+			if tt.code == "" {
+				if env.Datapayload == nil || env.Datapayload.Data.Text != "hello ada" {
+					t.Fatalf("unexpected data %+v", env.Datapayload)
+				}
+				return
+			}
+			if env.ErrorPayload == nil || env.ErrorPayload.Code != tt.code {
+				t.Fatalf("error %+v, want code %s", env.ErrorPayload, tt.code)
+			}
+		})
+	}
+}
+
+func TestHandleSuccessAlert(t *testing.T) {
+	_, env := serve(t, Handle(greet), "application/json", `{"name":"ada"}`)
+	if env.Alertify != nil {
+		t.Fatalf("a route without a message answered with an alert %+v", env.Alertify)
+	}
+	_, env = serve(t, Handle(greet, WithMessage("Greeted")), "application/json", `{"name":"ada"}`)
+	if env.Alertify == nil || env.Alertify.Message != "Greeted" {
+		t.Fatalf("alert %+v, want the route's message", env.Alertify)
+	}
+}
+
+type inviteRequest struct {
+	Email string `json:"email" validate:"required,unique=users.email"`
+}
+
+func invite(ctx context.Context, req *inviteRequest) (*greeting, error) {
+	return &greeting{Text: "invited " + req.Email}, nil
+}
+
+// a rule the validator cannot run is found when the route is built, not when it is requested
+func TestHandleRefusesRulesItCannotRun(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(fmt.Sprint(r), "needs a database") {
+			t.Fatalf("Handle recovered %v, want a panic about the unique rule", r)
+		}
+	}()
+	Handle(invite)
+}
+
+type rawRequest struct {
+	Name string `json:"-" form:"name"`
+	Body *Body  `json:"-"`
+	File *File  `json:"-" form:"file"`
+}
+
+type rawResult struct {
+	MediaType string `json:"media_type"`
+	Content   string `json:"content"`
+	Name      string `json:"name"`
+}
+
+func readRaw(ctx context.Context, req *rawRequest) (*rawResult, error) {
+	res := &rawResult{Name: req.Name}
+	if req.Body == nil {
+		return res, nil
+	}
+	content, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	res.MediaType, res.Content = req.Body.MediaType, string(content)
+	return res, nil
+}
+
+// a *Body field takes what is not a form, unread; a limit the handler reads past is a 413
+func TestHandleRawBody(t *testing.T) {
+	h := Handle(readRaw, WithBodyLimit(128), WithHeader("Accept-Patch", "text/csv"))
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		want        rawResult
+	}{
+		{name: "raw", contentType: "text/csv; charset=utf-8", body: "a,b", status: http.StatusOK, want: rawResult{MediaType: "text/csv", Content: "a,b"}},
+		{name: "json is not decoded", contentType: "application/json", body: `{"a":1}`, status: http.StatusOK, want: rawResult{MediaType: "application/json", Content: `{"a":1}`}},
+		{name: "multipart form", contentType: "multipart/form-data; boundary=b", body: "--b\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\nada\r\n--b--\r\n", status: http.StatusOK, want: rawResult{Name: "ada"}},
+		{name: "over the limit", contentType: "text/csv", body: strings.Repeat("a,", 65), status: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/raw", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if rec.Header().Get("Accept-Patch") != "text/csv" {
+				t.Fatalf("the route's header is missing: %v", rec.Header())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var env struct {
+				Datapayload struct {
+					Data rawResult `json:"data"`
+				} `json:"datapayload"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+				t.Fatal(err)
+			}
+			if env.Datapayload.Data != tt.want {
+				t.Fatalf("read %+v, want %+v", env.Datapayload.Data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package validators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	gv "github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The unique and exists rules check a value against a column:
+//
+//	Email string `validate:"required,email,unique=users.email"`
+//	TodoID uint `validate:"exists=todos.id"`
+//
+// Rows whose deleted_at is set do not count on tables declared with SoftDelete, unless the
+// rule adds the trashed option, for columns whose unique index also covers deleted rows:
+//
+//	Email string `validate:"unique=users.email trashed"`
+//
+// An update skips its own row with self=<Field>, naming the field that holds the row's ID;
+// a zero ID skips nothing:
+//
+//	Email string `validate:"unique=users.email self=ID"`
+//
+// The rules may only name the tables and columns given to UseDatabase, which are quoted
+// before they reach the query. CheckRules finds rules that break this, so that a route can
+// refuse to start with them; a query that fails is reported by Validate as an error, never
+// as a field that failed its rule.
+
+// Table is a table the unique and exists rules may read
+type Table struct {
+	// the columns the rules may name
+	Columns []string
+	// the table's primary key for self=, "id" when empty
+	Key string
+	// the table is soft-deleted through a deleted_at column
+	SoftDelete bool
+}
+
+// Tables are the tables the unique and exists rules may read, by name
+type Tables map[string]Table
+
+// UseDatabase backs the unique and exists rules with db, reading only the given tables
+func (v *Validator) UseDatabase(db *gorm.DB, tables Tables) {
+	v.db = db
+	v.tables = tables
+}
+
+// CheckRules reports the unique and exists rules of s's type, and of the structs it holds,
+// that could not run: those naming a table or column UseDatabase did not allow, those with
+// an unknown option, and any rule at all when there is no database.
+func (v *Validator) CheckRules(s interface{}) error {
+	var problems []error
+	v.checkRules(reflect.TypeOf(s), make(map[reflect.Type]bool), &problems)
+	return errors.Join(problems...)
+}
+
+func (v *Validator) checkRules(t reflect.Type, seen map[reflect.Type]bool, problems *[]error) {
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			for _, alternative := range strings.Split(rule, "|") {
+				tag, param, _ := strings.Cut(alternative, "=")
+				if !columnTag(tag, param) {
+					continue
+				}
+				if _, err := v.parseColumnRule(tag, param); err != nil {
+					*problems = append(*problems, fmt.Errorf("%s.%s: %w", t, field.Name, err))
+				}
+			}
+		}
+		v.checkRules(field.Type, seen, problems)
+	}
+}
+
+// whether a rule reads the database; unique without a table.column is the built-in rule
+func columnTag(tag, param string) bool {
+	switch tag {
+	case "exists":
+		return true
+	case "unique":
+		fields := strings.Fields(param)
+		return len(fields) > 0 && strings.Contains(fields[0], ".")
+	}
+	return false
+}
+
+// a parsed unique= or exists= parameter
+type columnRule struct {
+	table   string
+	column  string
+	key     string
+	self    string
+	trashed bool
+	soft    bool
+}
+
+func (v *Validator) parseColumnRule(tag, param string) (columnRule, error) {
+	if v.db == nil {
+		return columnRule{}, fmt.Errorf("validators: the %s rule needs a database, see UseDatabase", tag)
+	}
+	fields := strings.Fields(param)
+	if len(fields) == 0 {
+		return columnRule{}, fmt.Errorf("validators: %s needs a table.column", tag)
+	}
+	table, column, _ := strings.Cut(fields[0], ".")
+	allowed, ok := v.tables[table]
+	if !ok || !slices.Contains(allowed.Columns, column) {
+		return columnRule{}, fmt.Errorf("validators: %s may not read %s", tag, fields[0])
+	}
+	rule := columnRule{table: table, column: column, key: allowed.Key, soft: allowed.SoftDelete}
+	if rule.key == "" {
+		rule.key = "id"
+	}
+	for _, option := range fields[1:] {
+		switch name, value, _ := strings.Cut(option, "="); name {
+		case "self":
+			rule.self = value
+		case "trashed":
+			rule.trashed = true
+		default:
+			return columnRule{}, fmt.Errorf("validators: unknown %s option %q", tag, option)
+		}
+	}
+	return rule, nil
+}
+
+// the number of rows holding the field's value, at most one. A rule that cannot run, or
+// a query that fails, is recorded for Validate to report and counts as the given value,
+// so that the field is not reported as failing its rule.
+func (v *Validator) countMatching(ctx context.Context, fl gv.FieldLevel, tag string, failed int64) int64 {
+	rule, err := v.parseColumnRule(tag, fl.Param())
+	if err != nil {
+		recordRuleError(ctx, appErrors.ConfigError("invalid "+tag+" rule", err))
+		return failed
+	}
+	count, err := v.countRows(ctx, fl, rule)
+	if err != nil {
+		recordRuleError(ctx, appErrors.DatabaseError("failed to check "+rule.table+"."+rule.column, err))
+		return failed
+	}
+	return count
+}
+
+// the number of rows holding the field's value, at most one
+func (v *Validator) countRows(ctx context.Context, fl gv.FieldLevel, rule columnRule) (int64, error) {
+	query := v.db.WithContext(ctx).
+		Table(rule.table).
+		Where(clause.Eq{Column: clause.Column{Name: rule.column}, Value: fl.Field().Interface()})
+	if rule.soft && !rule.trashed {
+		query = query.Where(clause.Eq{Column: clause.Column{Name: "deleted_at"}, Value: nil})
+	}
+	if rule.self != "" {
+		if self := selfID(fl, rule.self); self.IsValid() && !self.IsZero() {
+			query = query.Where(clause.Neq{Column: clause.Column{Name: rule.key}, Value: self.Interface()})
+		}
+	}
+	var count int64
+	err := query.Limit(1).Count(&count).Error
+	return count, err
+}
+
+// the field of the validated struct that holds its row's ID
+func selfID(fl gv.FieldLevel, name string) reflect.Value {
+	parent := fl.Parent()
+	for parent.Kind() == reflect.Pointer {
+		if parent.IsNil() {
+			return reflect.Value{}
+		}
+		parent = parent.Elem()
+	}
+	if parent.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	field := parent.FieldByName(name)
+	for field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return reflect.Value{}
+		}
+		field = field.Elem()
+	}
+	return field
+}
+
+// a value no row holds yet. Without a table.column it is the built-in rule it replaces, for
+// distinct elements.
+func (v *Validator) unique(ctx context.Context, fl gv.FieldLevel) bool {
+	if !columnTag("unique", fl.Param()) {
+		return distinct(fl)
+	}
+	return v.countMatching(ctx, fl, "unique", 0) == 0
+}
+
+// a value some row holds
+func (v *Validator) exists(ctx context.Context, fl gv.FieldLevel) bool {
+	return v.countMatching(ctx, fl, "exists", 1) > 0
+}
+
+// the elements of a slice or array, or the values of a map, are distinct; unique=Field
+// compares a field of each element
+func distinct(fl gv.FieldLevel) bool {
+	field := fl.Field()
+	seen := make(map[interface{}]struct{})
+	add := func(value reflect.Value) bool {
+		value = reflect.Indirect(value)
+		if name := fl.Param(); name != "" && value.Kind() == reflect.Struct {
+			value = reflect.Indirect(value.FieldByName(name))
+		}
+		if !value.IsValid() || !value.Comparable() {
+			return true
+		}
+		key := value.Interface()
+		if _, dup := seen[key]; dup {
+			return false
+		}
+		seen[key] = struct{}{}
+		return true
+	}
+	switch field.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			if !add(field.Index(i)) {
+				return false
+			}
+		}
+	case reflect.Map:
+		iter := field.MapRange()
+		for iter.Next() {
+			if !add(iter.Value()) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,225 @@
+package validators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+type account struct {
+	ID        uint `gorm:"primarykey"`
+	Email     string
+	DeletedAt gorm.DeletedAt
+}
+
+// a validator backed by a private in-memory database holding ada (live) and bob (deleted)
+func newDatabaseValidator(t *testing.T) (*Validator, *gorm.DB) {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: gormLogger.Default.LogMode(gormLogger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&account{}); err != nil {
+		t.Fatal(err)
+	}
+	deletedAt := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	if err := db.Create([]account{{Email: "ada@example.com"}, {Email: "bob@example.com", DeletedAt: deletedAt}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	v := NewValidator()
+	v.UseDatabase(db, Tables{"accounts": {Columns: []string{"email", "id"}, SoftDelete: true}})
+	return v, db
+}
+
+type signUp struct {
+	Email string `json:"email" validate:"required,unique=accounts.email"`
+}
+
+type signUpTrashed struct {
+	Email string `json:"email" validate:"required,unique=accounts.email trashed"`
+}
+
+type changeEmail struct {
+	ID    uint   `json:"-"`
+	Email string `json:"email" validate:"required,unique=accounts.email self=ID"`
+}
+
+type invite struct {
+	AccountID uint `json:"account_id" validate:"required,exists=accounts.id"`
+}
+
+func TestDatabaseRules(t *testing.T) {
+	v, _ := newDatabaseValidator(t)
+	tests := []struct {
+		name  string
+		value interface{}
+		field string // the field that fails, "" when valid
+	}{
+		{name: "unique free", value: &signUp{Email: "new@example.com"}},
+		{name: "unique taken", value: &signUp{Email: "ada@example.com"}, field: "email"},
+		{name: "unique ignores deleted rows", value: &signUp{Email: "bob@example.com"}},
+		{name: "unique trashed counts deleted rows", value: &signUpTrashed{Email: "bob@example.com"}, field: "email"},
+		{name: "unique self skips the row", value: &changeEmail{ID: 1, Email: "ada@example.com"}},
+		{name: "unique self sees other rows", value: &changeEmail{ID: 2, Email: "ada@example.com"}, field: "email"},
+		{name: "unique zero self skips nothing", value: &changeEmail{Email: "ada@example.com"}, field: "email"},
+		{name: "exists", value: &invite{AccountID: 1}},
+		{name: "exists missing", value: &invite{AccountID: 99}, field: "account_id"},
+		{name: "exists deleted", value: &invite{AccountID: 2}, field: "account_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fieldErrors, err := v.Validate(context.Background(), tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.field == "" && fieldErrors != nil {
+				t.Fatalf("unexpected field errors %v", fieldErrors)
+			}
+			if tt.field != "" && fieldErrors[tt.field] == "" {
+				t.Fatalf("field errors %v, want one for %s", fieldErrors, tt.field)
+			}
+		})
+	}
+}
+
+// a query that fails is an error of its own, not a value that failed its rule
+func TestDatabaseRuleQueryFails(t *testing.T) {
+	v, db := newDatabaseValidator(t)
+	if err := db.Migrator().DropTable(&account{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []interface{}{&signUp{Email: "new@example.com"}, &invite{AccountID: 1}} {
+		fieldErrors, err := v.Validate(context.Background(), value)
+		var appErr appErrors.AppError
+		if !errors.As(err, &appErr) || appErr.Code() != appErrors.CodeDatabase {
+			t.Fatalf("%T: error %v, want a database error", value, err)
+		}
+		if fieldErrors != nil {
+			t.Fatalf("%T: the failed query was reported as field errors %v", value, fieldErrors)
+		}
+		if fieldErrors := v.StructCtx(context.Background(), value); fieldErrors != nil {
+			t.Fatalf("%T: StructCtx reported the failed query as field errors %v", value, fieldErrors)
+		}
+	}
+}
+
+type nestedRules struct {
+	Owner   *signUp      `json:"owner"`
+	Members []invite     `json:"members"`
+	Tags    []string     `json:"tags" validate:"unique"`
+	Self    *nestedRules `json:"self"`
+}
+
+type badTable struct {
+	Email string `validate:"unique=users.email"`
+}
+
+type badColumn struct {
+	Email string `validate:"required,unique=accounts.password"`
+}
+
+type badOption struct {
+	Email string `validate:"unique=accounts.email softly"`
+}
+
+type badAlternative struct {
+	ID uint `validate:"omitempty|exists=accounts.secret"`
+}
+
+type nestedBad struct {
+	Rows []struct {
+		ID uint `validate:"exists=todos.id"`
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	v, _ := newDatabaseValidator(t)
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string // part of the error, "" when the rules can run
+	}{
+		{name: "valid", value: &signUp{}},
+		{name: "options", value: changeEmail{}},
+		{name: "nested", value: &nestedRules{}},
+		{name: "no rules", value: &struct{ Name string }{}},
+		{name: "not a struct", value: "text"},
+		{name: "table not allowed", value: &badTable{}, want: "unique may not read users.email"},
+		{name: "column not allowed", value: &badColumn{}, want: "unique may not read accounts.password"},
+		{name: "unknown option", value: &badOption{}, want: `unknown unique option "softly"`},
+		{name: "alternative", value: &badAlternative{}, want: "exists may not read accounts.secret"},
+		{name: "nested in a slice", value: &nestedBad{}, want: "exists may not read todos.id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.CheckRules(tt.value)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("CheckRules = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if err := NewValidator().CheckRules(&signUp{}); err == nil || !strings.Contains(err.Error(), "needs a database") {
+		t.Fatalf("without a database: %v", err)
+	}
+	if err := NewValidator().CheckRules(&nestedRules{Tags: []string{"a"}}); !strings.Contains(fmt.Sprint(err), "needs a database") {
+		t.Fatalf("without a database, nested: %v", err)
+	}
+}
+
+// a rule CheckRules would have refused fails the validation with an error instead of a panic
+func TestUncheckedRuleDoesNotPanic(t *testing.T) {
+	v, _ := newDatabaseValidator(t)
+	fieldErrors, err := v.Validate(context.Background(), &badTable{Email: "ada@example.com"})
+	var appErr appErrors.AppError
+	if !errors.As(err, &appErr) || appErr.Code() != appErrors.CodeConfig {
+		t.Fatalf("error %v, want a config error", err)
+	}
+	if fieldErrors != nil {
+		t.Fatalf("field errors %v", fieldErrors)
+	}
+}
+
+func TestBuiltInUnique(t *testing.T) {
+	v := NewValidator()
+	type list struct {
+		Tags  []string `json:"tags" validate:"unique"`
+		Items []struct {
+			Name string
+		} `json:"items" validate:"unique=Name"`
+	}
+	if fieldErrors := v.Struct(&list{Tags: []string{"a", "b"}}); fieldErrors != nil {
+		t.Fatalf("distinct tags: %v", fieldErrors)
+	}
+	if fieldErrors := v.Struct(&list{Tags: []string{"a", "a"}}); fieldErrors["tags"] == "" {
+		t.Fatalf("repeated tags: %v", fieldErrors)
+	}
+	value := list{}
+	value.Items = append(value.Items, struct{ Name string }{"x"}, struct{ Name string }{"x"})
+	if fieldErrors := v.Struct(&value); fieldErrors["items"] == "" {
+		t.Fatalf("repeated item names: %v", fieldErrors)
+	}
+}
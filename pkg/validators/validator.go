@@ -1,15 +1,21 @@
 package validators
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
 
+	"github.com/codetheuri/todolist/pkg/i18n"
 	gv "github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
 )
 
 type Validator struct {
 	validate *gv.Validate
+	// back the unique and exists rules
+	db     *gorm.DB
+	tables Tables
 }
 
 func NewValidator() *Validator {
@@ -17,13 +23,22 @@ func NewValidator() *Validator {
 	v.RegisterTagNameFunc(func(fld reflect.StructField) string{
 		name := strings.SplitN(fld.Tag.Get("json"), ",",2)[0]
 		if name == "-" || name == ""{
+			// fields bound from request parameters and forms are reported by parameter name
+			for _, source := range []string{"path", "query", "header", "cookie", "form"} {
+				if param := fld.Tag.Get(source); param != "" && param != "-" {
+					return param
+				}
+			}
 			return fld.Name
 		}
 		return name
 	})
-	return &Validator{
+	validator := &Validator{
 		validate: v,
 	}
+	v.RegisterValidationCtx("unique", validator.unique)
+	v.RegisterValidationCtx("exists", validator.exists)
+	return validator
 }
 
 // type FieldError struct {
@@ -31,57 +46,65 @@ func NewValidator() *Validator {
 // 	Message string `json:"error"`
 // }
 
+// Validate validates s like StructCtx. A unique or exists rule that could not be checked,
+// because its query failed, is returned as the error instead of failing its field.
+func (v *Validator) Validate(ctx context.Context, s interface{}) (map[string]string, error) {
+	failure := &ruleFailure{}
+	fieldErrors := v.StructCtx(context.WithValue(ctx, ruleFailureKey{}, failure), s)
+	if failure.err != nil {
+		return nil, failure.err
+	}
+	return fieldErrors, nil
+}
+
+// where Validate collects the first rule that could not be checked
+type ruleFailureKey struct{}
+
+type ruleFailure struct {
+	err error
+}
+
+func recordRuleError(ctx context.Context, err error) {
+	if failure, ok := ctx.Value(ruleFailureKey{}).(*ruleFailure); ok && failure.err == nil {
+		failure.err = err
+	}
+}
+
+// Struct validates s and returns its field errors in English, or nil when it is valid
 func (v *Validator) Struct(s interface{}) map[string]string {
-	err := v.validate.Struct(s)
+	return v.StructCtx(context.Background(), s)
+}
+
+// StructCtx validates s and returns its field errors in the locale of the request ctx
+// belongs to, or nil when it is valid. A unique or exists rule that could not be checked
+// passes; Validate reports it.
+func (v *Validator) StructCtx(ctx context.Context, s interface{}) map[string]string {
+	err := v.validate.StructCtx(ctx, s)
 	if err == nil {
 		return nil
 	}
+	locale := i18n.Locale(ctx)
 	validationErrors := make(map[string]string)
 	for _, err := range err.(gv.ValidationErrors) {
-		validationErrors[err.Field()] = parseTag(err)
+		validationErrors[err.Field()] = parseTag(err, locale)
 	}
 	return validationErrors
 }
-	
-
 
-func parseTag(fe gv.FieldError) string {
+// the message of a failed rule, from the validation.<tag> message of the locale's catalog
+func parseTag(fe gv.FieldError, locale string) string {
+	param := fe.Param()
 	switch fe.Tag() {
-	case "required":
-		return "This field is required"
-	case "unique":
-		return "This value must be unique"	
-	case "min":
-		return fmt.Sprintf("Minimum length is %s", fe.Param())
-	case "max":
-		return fmt.Sprintf("Maximum length is %s", fe.Param())
-	case "email":
-		return "Invalid email format"
-	case "url":
-		return "Invalid URL format"
-	case "len":
-		return fmt.Sprintf("Length must be %s", fe.Param())
-	case "gt":
-		return fmt.Sprintf("Must be greater than %s", fe.Param())
-	case "gte":
-		return fmt.Sprintf("Must be greater than or equal to %s", fe.Param())
-	case "lt":
-		return fmt.Sprintf("Must be less than %s", fe.Param())
-	case "lte":
-		return fmt.Sprintf("Must be less than or equal to %s", fe.Param())
-	case "eqfield":
-		return fmt.Sprintf("Must be equal to %s", strings.ToLower(fe.Param())) // e.g., password_confirmation
-	case "nefield":
-		return fmt.Sprintf("Must not be equal to %s", strings.ToLower(fe.Param()))
-	case "alpha":
-		return "Must contain only alphabetic characters"
-	case "numeric":
-		return "Must contain only numeric characters"
-	case "alphanum":
-		return "Must contain only alphanumeric characters"
-	case "e164": // For phone numbers
-		return "Invalid phone number format (E.164)"
-	default:
-		return fmt.Sprintf("Invalid value for %s", fe.Tag())
+	case "required_with", "eqfield", "nefield":
+		// the param names a field, e.g. password_confirmation
+		param = strings.ToLower(param)
+	case "unique", "exists":
+		// the column, without the options
+		param, _, _ = strings.Cut(param, " ")
+	}
+	params := i18n.Params{"field": fe.Field(), "param": param, "tag": fe.Tag()}
+	if message, ok := i18n.Lookup(locale, "validation."+fe.Tag(), params); ok {
+		return message
 	}
+	return i18n.Text(locale, "validation.default", fmt.Sprintf("Invalid value for %s", fe.Tag()), params)
 }
@@ -0,0 +1,354 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec writes response bodies in one media type and reads request bodies sent in it.
+// Every codec but JSON encodes the same value the JSON codec would, so that field names,
+// omitted fields and custom JSON marshalers shape all formats alike.
+type Codec struct {
+	MediaType string
+	// other names clients use for the media type, such as application/x-msgpack
+	Aliases   []string
+	Marshal   func(v interface{}) ([]byte, error)
+	Unmarshal func(data []byte, v interface{}) error
+}
+
+const JSONContentType = "application/json"
+
+var (
+	jsonCodec = Codec{
+		MediaType: JSONContentType,
+		Marshal: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			err := json.NewEncoder(&buf).Encode(v)
+			return buf.Bytes(), err
+		},
+		// like the decoding tonic did before codecs, trailing data after the value is ignored
+		Unmarshal: func(data []byte, v interface{}) error {
+			return json.NewDecoder(bytes.NewReader(data)).Decode(v)
+		},
+	}
+	// responses are first encoded as JSON, so numbers arrive as float64 or int64
+	cborDecoding, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}(nil))}.DecMode()
+	// guards codecs against RegisterCodec
+	codecsMu sync.RWMutex
+	codecs   = []Codec{
+		jsonCodec,
+		{
+			MediaType: "application/msgpack",
+			Aliases:   []string{"application/x-msgpack", "application/vnd.msgpack"},
+			Marshal: func(v interface{}) ([]byte, error) {
+				plain, err := plainValue(v)
+				if err != nil {
+					return nil, err
+				}
+				var buf bytes.Buffer
+				enc := msgpack.NewEncoder(&buf)
+				enc.UseCompactInts(true)
+				enc.UseCompactFloats(true)
+				err = enc.Encode(plain)
+				return buf.Bytes(), err
+			},
+			Unmarshal: func(data []byte, v interface{}) error {
+				var plain interface{}
+				if err := msgpack.Unmarshal(data, &plain); err != nil {
+					return err
+				}
+				return fromPlain(plain, v)
+			},
+		},
+		{
+			MediaType: "application/xml",
+			Aliases:   []string{"text/xml"},
+			Marshal:   marshalXML,
+			Unmarshal: unmarshalXML,
+		},
+		{
+			MediaType: "application/cbor",
+			Marshal: func(v interface{}) ([]byte, error) {
+				plain, err := plainValue(v)
+				if err != nil {
+					return nil, err
+				}
+				return cbor.Marshal(plain)
+			},
+			Unmarshal: func(data []byte, v interface{}) error {
+				var plain interface{}
+				if err := cborDecoding.Unmarshal(data, &plain); err != nil {
+					return err
+				}
+				return fromPlain(plain, v)
+			},
+		},
+	}
+)
+
+// RegisterCodec adds a codec, or replaces the one registered for its media type. It is
+// meant for start-up, but is safe to call while requests are served.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	// requests may be reading the current slice, so the change is made to a copy
+	updated := append([]Codec(nil), codecs...)
+	for i, existing := range updated {
+		if existing.MediaType == c.MediaType {
+			updated[i] = c
+			codecs = updated
+			return
+		}
+	}
+	codecs = append(updated, c)
+}
+
+// the registered codecs, JSON first; callers must not modify the slice
+func registeredCodecs() []Codec {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	return codecs
+}
+
+// MediaTypes lists the media types of the registered codecs, JSON first
+func MediaTypes() []string {
+	registered := registeredCodecs()
+	types := make([]string, len(registered))
+	for i, c := range registered {
+		types[i] = c.MediaType
+	}
+	return types
+}
+
+// CodecFor returns the codec of a Content-Type header; a request without one is JSON
+func CodecFor(contentType string) (Codec, bool) {
+	if strings.TrimSpace(contentType) == "" {
+		return jsonCodec, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return Codec{}, false
+	}
+	for _, c := range registeredCodecs() {
+		if c.names(mediaType) {
+			return c, true
+		}
+	}
+	return Codec{}, false
+}
+
+func (c Codec) names(mediaType string) bool {
+	if mediaType == c.MediaType {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if mediaType == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// how well a media range from an Accept header matches a codec: 3 for its own type, 2 for
+// its type/*, 1 for */*, 0 for no match
+func (c Codec) specificity(mediaRange string) int {
+	switch {
+	case c.names(mediaRange):
+		return 3
+	case mediaRange == "*/*":
+		return 1
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(c.MediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 2
+	}
+	return 0
+}
+
+// negotiate picks the codec for an Accept header. JSON is the default: it is used when there
+// is no header, and for any wildcard the header accepts. Another codec is only chosen when the
+// client names its media type and ranks it above JSON and above everything else it asks for,
+// so that a browser's "text/html,...,application/xml;q=0.9,*/*;q=0.8" still gets JSON. When
+// JSON is ruled out, the codec ranked highest is used, the earliest registered on a tie; no
+// codec is acceptable when all are ruled out.
+func negotiate(accept string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return jsonCodec, true
+	}
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	top := 0.0
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType, q})
+		top = max(top, q)
+	}
+	// the quality of the most specific range that matches c, and whether c was named
+	quality := func(c Codec) (float64, bool) {
+		q, specificity := 0.0, 0
+		for _, r := range ranges {
+			if s := c.specificity(r.mediaType); s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		return q, specificity == 3
+	}
+	registered := registeredCodecs()
+	jsonQ, _ := quality(jsonCodec)
+	if jsonQ > 0 {
+		for _, c := range registered {
+			if q, named := quality(c); named && q > jsonQ && q == top {
+				return c, true
+			}
+		}
+		return jsonCodec, true
+	}
+	var best Codec
+	bestQ := 0.0
+	for _, c := range registered {
+		if q, _ := quality(c); q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// encodingWriter carries, for the Respond functions, the codec negotiated for one request
+type encodingWriter struct {
+	http.ResponseWriter
+	codec      Codec
+	acceptable bool
+}
+
+// lets http.ResponseController and the Respond functions reach the underlying writer
+func (ew *encodingWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// NegotiateEncoding returns w set up so that the Respond functions encode the response to r
+// in the format its Accept header prefers. When r accepts none of the registered formats,
+// data is refused with 406 Not Acceptable and errors are sent as JSON.
+func NegotiateEncoding(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	w.Header().Add("Vary", "Accept")
+	codec, ok := negotiate(r.Header.Get("Accept"))
+	return &encodingWriter{ResponseWriter: w, codec: codec, acceptable: ok}
+}
+
+// the encodingWriter w is or wraps, if any
+func encodingWriterOf(w http.ResponseWriter) *encodingWriter {
+	for {
+		if ew, ok := w.(*encodingWriter); ok {
+			return ew
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// send writes data with statusCode in the negotiated format
+func send(w http.ResponseWriter, statusCode int, data interface{}) {
+	ew := encodingWriterOf(w)
+	if ew == nil {
+		SendJSON(w, statusCode, data)
+		return
+	}
+	// a response without a body has nothing to negotiate
+	if !ew.acceptable && statusCode != http.StatusNoContent && statusCode != http.StatusNotModified {
+		RespondError(w, appErrors.NotAcceptableError("the response can only be sent as "+strings.Join(MediaTypes(), ", "), nil), http.StatusNotAcceptable)
+		return
+	}
+	sendWith(w, ew.codec, statusCode, data)
+}
+
+// the codec an error is sent with: the negotiated one, or JSON when nothing was acceptable
+func errorCodec(w http.ResponseWriter) Codec {
+	if ew := encodingWriterOf(w); ew != nil && ew.acceptable {
+		return ew.codec
+	}
+	return jsonCodec
+}
+
+func sendWith(w http.ResponseWriter, codec Codec, statusCode int, data interface{}) {
+	// these statuses must not have a body
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		w.WriteHeader(statusCode)
+		return
+	}
+	body, err := codec.Marshal(data)
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", codec.MediaType)
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// plainValue turns v into the maps, slices, strings, numbers, booleans and nils of its JSON
+// encoding, with whole numbers as int64
+func plainValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var plain interface{}
+	if err := dec.Decode(&plain); err != nil {
+		return nil, err
+	}
+	return numbers(plain), nil
+}
+
+func numbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			val[key] = numbers(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = numbers(item)
+		}
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n
+		}
+		f, _ := val.Float64()
+		return f
+	}
+	return v
+}
+
+// fromPlain decodes a plain value into v through JSON, so that v's json tags and custom
+// unmarshalers apply to every format
+func fromPlain(plain interface{}, v interface{}) error {
+	data, err := json.Marshal(plain)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
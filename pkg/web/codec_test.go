@@ -0,0 +1,269 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string // "" when nothing is acceptable
+	}{
+		{accept: "", want: JSONContentType},
+		{accept: "application/json", want: JSONContentType},
+		{accept: "*/*", want: JSONContentType},
+		{accept: "application/*", want: JSONContentType},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: JSONContentType},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8", want: JSONContentType},
+		{accept: "application/xml, application/json", want: JSONContentType},
+		{accept: "application/xml, */*", want: JSONContentType},
+		{accept: "application/xml", want: "application/xml"},
+		{accept: "text/xml", want: "application/xml"},
+		{accept: "application/xml, */*;q=0.1", want: "application/xml"},
+		{accept: "application/json;q=0.5, application/xml", want: "application/xml"},
+		{accept: "text/html, application/xml;q=0.9", want: "application/xml"},
+		{accept: "application/msgpack", want: "application/msgpack"},
+		{accept: "application/x-msgpack", want: "application/msgpack"},
+		{accept: "application/vnd.msgpack;q=1.0, application/json;q=0.9", want: "application/msgpack"},
+		{accept: "application/cbor", want: "application/cbor"},
+		{accept: "application/cbor;q=0.9, application/msgpack;q=0.8", want: "application/cbor"},
+		{accept: "application/json;q=0, */*", want: "application/msgpack"},
+		{accept: "application/json;q=0, application/cbor", want: "application/cbor"},
+		{accept: "image/png", want: ""},
+		{accept: "text/*", want: ""},
+		{accept: "application/json;q=0", want: ""},
+		{accept: "*/*;q=0", want: ""},
+		{accept: "not a media type", want: ""},
+	}
+	for _, tt := range tests {
+		codec, ok := negotiate(tt.accept)
+		got := ""
+		if ok {
+			got = codec.MediaType
+		}
+		if got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      string
+		status      int
+		contentType string
+	}{
+		{name: "json", accept: "application/json", status: http.StatusOK, contentType: JSONContentType},
+		{name: "browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", status: http.StatusOK, contentType: JSONContentType},
+		{name: "msgpack", accept: "application/msgpack", status: http.StatusOK, contentType: "application/msgpack"},
+		{name: "cbor", accept: "application/cbor", status: http.StatusOK, contentType: "application/cbor"},
+		{name: "xml", accept: "application/xml", status: http.StatusOK, contentType: "application/xml"},
+		// the refusal itself is sent as JSON
+		{name: "not acceptable", accept: "image/png", status: http.StatusNotAcceptable, contentType: JSONContentType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/todos/1", nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			RespondData(NegotiateEncoding(rec, req), http.StatusOK, map[string]string{"title": "buy milk"}, "")
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Fatalf("Content-Type %q, want %q", got, tt.contentType)
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Fatalf("Vary %q, want Accept", rec.Header().Get("Vary"))
+			}
+			if tt.status == http.StatusNotAcceptable {
+				var resp APIErrorResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.ErrorPayload == nil || resp.ErrorPayload.Code != "NOT_ACCEPTABLE" {
+					t.Fatalf("error %+v, want NOT_ACCEPTABLE", resp.ErrorPayload)
+				}
+			}
+		})
+	}
+}
+
+func TestNotAcceptableAllowsBodylessResponses(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/todos/1", nil)
+	req.Header.Set("Accept", "image/png")
+	rec := httptest.NewRecorder()
+	RespondData(NegotiateEncoding(rec, req), http.StatusNoContent, nil, "")
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Fatalf("status %d with %d bytes, want an empty 204", rec.Code, rec.Body.Len())
+	}
+}
+
+type sample struct {
+	ID       uint              `json:"id"`
+	Title    string            `json:"title"`
+	Done     bool              `json:"done"`
+	Score    float64           `json:"score"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	DueAt    *time.Time        `json:"due_at"`
+	Secret   string            `json:"-"`
+	Nested   *sample           `json:"nested,omitempty"`
+	Duration json.Number       `json:"duration"`
+}
+
+func sampleValue() sample {
+	due := time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+	return sample{
+		ID: 42, Title: "write <codec> tests & more", Done: true, Score: 2.5,
+		Tags:     []string{"go", "web"},
+		Labels:   map[string]string{"priority": "high", "1st": "odd key"},
+		DueAt:    &due,
+		Secret:   "not sent",
+		Nested:   &sample{ID: 7, Title: "child", Tags: []string{}, Duration: "3"},
+		Duration: "90",
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, mediaType := range []string{JSONContentType, "application/msgpack", "application/cbor", "application/xml"} {
+		t.Run(mediaType, func(t *testing.T) {
+			codec, ok := CodecFor(mediaType)
+			if !ok {
+				t.Fatalf("no codec for %s", mediaType)
+			}
+			in := sampleValue()
+			data, err := codec.Marshal(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out sample
+			if err := codec.Unmarshal(data, &out); err != nil {
+				t.Fatalf("%v in %q", err, data)
+			}
+			in.Secret = ""
+			if !reflect.DeepEqual(out, in) {
+				t.Fatalf("round trip\n got %+v\nwant %+v", out, in)
+			}
+		})
+	}
+}
+
+// the binary formats carry the JSON form of a value: its field names, with whole numbers as integers
+func TestBinaryCodecsUseJSONNames(t *testing.T) {
+	value := map[string]interface{}{"id": int64(42), "title": "write <codec> tests & more", "done": true, "score": 2.5}
+	decoders := map[string]func([]byte, interface{}) error{
+		"application/msgpack": msgpack.Unmarshal,
+		"application/cbor":    cbor.Unmarshal,
+	}
+	for mediaType, decode := range decoders {
+		t.Run(mediaType, func(t *testing.T) {
+			codec, _ := CodecFor(mediaType)
+			data, err := codec.Marshal(sampleValue())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded map[string]interface{}
+			if err := decode(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := decoded["Secret"]; ok {
+				t.Fatal("a field tagged json:\"-\" was encoded")
+			}
+			for key, want := range value {
+				if got := decoded[key]; fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("%s = %v (%T), want %v", key, got, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCodecFor(t *testing.T) {
+	tests := map[string]string{
+		"":                                JSONContentType,
+		"application/json":                JSONContentType,
+		"application/json; charset=utf-8": JSONContentType,
+		"application/x-msgpack":           "application/msgpack",
+		"text/xml; charset=utf-8":         "application/xml",
+		"application/cbor":                "application/cbor",
+		"text/csv":                        "",
+		"application/json; charset":       "",
+	}
+	for contentType, want := range tests {
+		codec, ok := CodecFor(contentType)
+		got := ""
+		if ok {
+			got = codec.MediaType
+		}
+		if got != want {
+			t.Errorf("CodecFor(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	saved := registeredCodecs()
+	t.Cleanup(func() {
+		codecsMu.Lock()
+		codecs = saved
+		codecsMu.Unlock()
+	})
+	csv := Codec{
+		MediaType: "text/csv",
+		Marshal:   func(v interface{}) ([]byte, error) { return []byte("id\n1\n"), nil },
+		Unmarshal: func(data []byte, v interface{}) error { return nil },
+	}
+
+	// registering while requests negotiate is safe; run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterCodec(csv)
+		}()
+		go func() {
+			defer wg.Done()
+			negotiate("text/csv, application/json;q=0.5")
+			CodecFor("text/csv")
+			MediaTypes()
+		}()
+	}
+	wg.Wait()
+
+	if types := MediaTypes(); len(types) != len(saved)+1 || types[0] != JSONContentType || types[len(types)-1] != "text/csv" {
+		t.Fatalf("media types %v", types)
+	}
+	if codec, _ := negotiate("text/csv, application/json;q=0.5"); codec.MediaType != "text/csv" {
+		t.Fatalf("negotiated %s, want the registered codec", codec.MediaType)
+	}
+
+	// registering a media type again replaces its codec
+	replaced := csv
+	replaced.Marshal = func(v interface{}) ([]byte, error) { return []byte("replaced"), nil }
+	RegisterCodec(replaced)
+	codec, ok := CodecFor("text/csv")
+	if !ok {
+		t.Fatal("the codec is gone")
+	}
+	if data, _ := codec.Marshal(nil); !bytes.Equal(data, []byte("replaced")) {
+		t.Fatalf("the codec was not replaced: %q", data)
+	}
+	if len(MediaTypes()) != len(saved)+1 {
+		t.Fatalf("replacing a codec added one: %v", MediaTypes())
+	}
+}
@@ -0,0 +1,31 @@
+package web
+
+import "strings"
+
+// MatchETag reports whether an If-Match or If-None-Match header value lists etag
+// (or is "*"). If-Match uses strong comparison, where weak tags never match;
+// If-None-Match uses weak comparison, which ignores the W/ prefix.
+func MatchETag(header, etag string, weak bool) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"net/http"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/i18n"
+)
+
+// localeWriter carries, for the Respond functions, the locale of one request's messages
+type localeWriter struct {
+	http.ResponseWriter
+	locale func() string
+}
+
+// lets http.ResponseController and the Respond functions reach the underlying writer
+func (lw *localeWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
+// Localize returns w set up so that the Respond functions translate their messages into the
+// locale that locale returns; it is called only once a message is written
+func Localize(w http.ResponseWriter, locale func() string) http.ResponseWriter {
+	return &localeWriter{ResponseWriter: w, locale: locale}
+}
+
+// the locale of the messages written to w, which it records in Content-Language
+func localeOf(w http.ResponseWriter) string {
+	for {
+		if lw, ok := w.(*localeWriter); ok {
+			locale := lw.locale()
+			w.Header().Set("Content-Language", locale)
+			return locale
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return i18n.DefaultLocale
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// translate a message written in English, which is its own catalog key
+func translate(w http.ResponseWriter, message string) string {
+	if message == "" {
+		return message
+	}
+	return i18n.Text(localeOf(w), message, message, nil)
+}
+
+// the message of an application error in the request's locale: its own translation, or,
+// outside of English, the generic message of its code
+func errorMessage(w http.ResponseWriter, code appErrors.Code, message string) string {
+	locale := localeOf(w)
+	if translated, ok := i18n.Lookup(locale, message, nil); ok {
+		return translated
+	}
+	if translated, ok := i18n.Lookup(locale, "error."+string(code), nil); ok && locale != i18n.DefaultLocale {
+		return translated
+	}
+	return message
+}
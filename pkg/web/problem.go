@@ -0,0 +1,114 @@
+package web
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrorFormat is how RespondError writes errors
+type ErrorFormat string
+
+const (
+	// the errorpayload and alertify envelope
+	ErrorFormatEnvelope ErrorFormat = "envelope"
+	// RFC 9457 problem details
+	ErrorFormatProblem ErrorFormat = "problem"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails is an error as RFC 9457 describes it, with the error code, the field
+// violations of a validation error and the request ID as extension members
+type ProblemDetails struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      string      `json:"code,omitempty"`
+	Errors    interface{} `json:"errors,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// errorWriter carries, for RespondError, how the errors of one request are written
+type errorWriter struct {
+	http.ResponseWriter
+	problem   bool
+	instance  string
+	requestID string
+}
+
+// lets http.ResponseController and RespondError reach the underlying writer
+func (ew *errorWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// NegotiateErrors returns w set up so that RespondError writes the errors of r in format, or
+// as problem details whenever r's Accept header prefers application/problem+json
+func NegotiateErrors(w http.ResponseWriter, r *http.Request, format ErrorFormat, requestID string) http.ResponseWriter {
+	return &errorWriter{
+		ResponseWriter: w,
+		problem:        format == ErrorFormatProblem || prefersProblem(r.Header.Get("Accept")),
+		instance:       r.URL.Path,
+		requestID:      requestID,
+	}
+}
+
+// the errorWriter w is or wraps, if any
+func errorWriterOf(w http.ResponseWriter) *errorWriter {
+	for {
+		if ew, ok := w.(*errorWriter); ok {
+			return ew
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// whether an Accept header ranks application/problem+json at least as high as plain JSON
+func prefersProblem(accept string) bool {
+	problem, json := -1.0, -1.0
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		switch mediaType {
+		case ProblemContentType:
+			problem = max(problem, q)
+		case "application/json":
+			json = max(json, q)
+		}
+	}
+	return problem > 0 && problem >= json
+}
+
+// write payload as problem details with status
+func sendProblem(w http.ResponseWriter, ew *errorWriter, statusCode int, payload *ErrorPayload) {
+	problem := ProblemDetails{
+		// the code says what went wrong; the type adds nothing beyond the status
+		Type:      "about:blank",
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Detail:    payload.Message,
+		Instance:  ew.instance,
+		Code:      payload.Code,
+		Errors:    payload.Errors,
+		RequestID: ew.requestID,
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(problem)
+}
@@ -3,15 +3,20 @@ package web
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
 
 	appErrors "github.com/codetheuri/todolist/pkg/errors"
+	"github.com/codetheuri/todolist/pkg/i18n"
 	"github.com/codetheuri/todolist/pkg/pagination"
 )
 
 // SendJSON writes the given status code and data as JSON to the http.ResponseWriter.
 func SendJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	// these statuses must not have a body
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		w.WriteHeader(statusCode)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if data != nil {
@@ -19,6 +24,11 @@ func SendJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 		}
 	}
 }
+
+// RespondError writes err with the status its code is registered with in pkg/errors: in the
+// errorpayload envelope, in the format NegotiateEncoding picked, or as problem details for
+// requests that NegotiateErrors set up for them. Messages of codes not registered as safe are
+// replaced with a generic one, and messages are translated for requests that Localize set up.
 func RespondError(w http.ResponseWriter, err error, defaultStatus int, opts ...AlertifyOption) {
 	apiErrResp := APIErrorResponse{
 		ErrorPayload: &ErrorPayload{
@@ -29,68 +39,74 @@ func RespondError(w http.ResponseWriter, err error, defaultStatus int, opts ...A
 	statusCode := defaultStatus
 	var appErr appErrors.AppError
 	if errors.As(err, &appErr) {
-		apiErrResp.ErrorPayload.Code = appErr.Code()
+		apiErrResp.ErrorPayload.Code = string(appErr.Code())
 		apiErrResp.ErrorPayload.Message = appErr.Message()
 		// apiErrResp.ErrorPayload.Details = appErr.Details()
 
-		//determone status code based on error code
-		isValidationError := false
-		switch appErr.Code() {
-		case "AUTH_ERROR":
-			statusCode = http.StatusUnauthorized
-		case "NOT_FOUND":
-			statusCode = http.StatusNotFound
-		case "INVALID_INPUT":
-			statusCode = http.StatusBadRequest
-		case "FORBIDDEN":
-			statusCode = http.StatusForbidden
-		case "CONFLICT_ERROR":
-			statusCode = http.StatusConflict
-		case "CONFIG_ERROR", "DATABASE_ERROR":
-			statusCode = http.StatusInternalServerError
-		case "UNAUTHORIZED":
-			statusCode = http.StatusUnauthorized
-		case "VALIDATION_ERROR":
-			isValidationError = true
-
-			statusCode = http.StatusUnprocessableEntity
-
-			if valErrors := appErr.GetValidationErrors(); valErrors != nil {
-
-				if fieldErrorsMap, ok := valErrors.(map[string]string); ok {
-					apiErrResp.ErrorPayload.Errors = fieldErrorsMap
-				} else {
-
-					apiErrResp.ErrorPayload.Errors = valErrors
-				}
-			}
-
-		case "INTERNAL_SERVER_ERROR":
+		// the status and whether the message may be shown come from the code's registration
+		info, known := appErrors.Lookup(appErr.Code())
+		switch {
+		case !known:
 			statusCode = http.StatusInternalServerError
+			apiErrResp.ErrorPayload.Message = i18n.Text(localeOf(w), "error.unknown", "An unexpected application error occurred: {message}", i18n.Params{"message": appErr.Message()})
+		case !info.Safe:
+			statusCode = info.Status
+			apiErrResp.ErrorPayload.Message = translate(w, "An unexpected error occurred")
 		default:
-			statusCode = http.StatusInternalServerError
-			apiErrResp.ErrorPayload.Message = fmt.Sprintf("An unexpected application error occurred: %s", appErr.Message())
+			statusCode = info.Status
+			apiErrResp.ErrorPayload.Message = errorMessage(w, appErr.Code(), appErr.Message())
+		}
+		if valErrors := appErr.GetValidationErrors(); valErrors != nil {
+			apiErrResp.ErrorPayload.Errors = valErrors
 		}
-		if !isValidationError {
+		// field errors explain a validation failure better than an alert
+		if appErr.Code() != appErrors.CodeValidation {
 			apiErrResp.AlertifyPayload = &AlertifyPayload{
-				Message: appErr.Message(),
+				Message: apiErrResp.ErrorPayload.Message,
 				Theme:   "danger",
 				Type:    "alert",
 			}
 		}
 	} else {
 		statusCode = http.StatusInternalServerError
-		apiErrResp.ErrorPayload.Message = "An unexpected server error occurred."
+		apiErrResp.ErrorPayload.Message = translate(w, "An unexpected server error occurred.")
 		apiErrResp.AlertifyPayload = &AlertifyPayload{
-			Message: "An unexpected server error occurred.",
+			Message: apiErrResp.ErrorPayload.Message,
 			Theme:   "danger",
 			Type:    "alert",
 		}
 	}
+	recordError(w, err)
+	if ew := errorWriterOf(w); ew != nil && ew.problem {
+		// alert options only shape the envelope
+		sendProblem(w, ew, statusCode, apiErrResp.ErrorPayload)
+		return
+	}
 	for _, opt := range opts {
 		opt(&apiErrResp)
 	}
-	SendJSON(w, statusCode, apiErrResp)
+	sendWith(w, errorCodec(w), statusCode, apiErrResp)
+}
+
+// ErrorRecorder is implemented by response writers, such as the request logger's, that want
+// to know the error a response reports
+type ErrorRecorder interface {
+	RecordError(err error)
+}
+
+// tell the first ErrorRecorder w is or wraps about err
+func recordError(w http.ResponseWriter, err error) {
+	for {
+		if recorder, ok := w.(ErrorRecorder); ok {
+			recorder.RecordError(err)
+			return
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = unwrapper.Unwrap()
+	}
 }
 
 func RespondData(w http.ResponseWriter, statusCode int, data interface{}, message string, opts ...SuccessOption) {
@@ -101,7 +117,7 @@ func RespondData(w http.ResponseWriter, statusCode int, data interface{}, messag
 	}
 	if message != "" {
 		resp.AlertifyPayload = &AlertifyPayload{
-			Message: message,
+			Message: translate(w, message),
 			Theme:   "success",
 			Type:    "alert",
 		}
@@ -109,7 +125,7 @@ func RespondData(w http.ResponseWriter, statusCode int, data interface{}, messag
 	for _, opt := range opts {
 		opt(&resp)
 	}
-	SendJSON(w, statusCode, resp)
+	send(w, statusCode, resp)
 
 }
 func RespondListData(w http.ResponseWriter, statusCode int, data interface{}, p *pagination.Metadata) {
@@ -120,17 +136,17 @@ func RespondListData(w http.ResponseWriter, statusCode int, data interface{}, p
 		},
 	}
 
-	SendJSON(w, statusCode, resp)
+	send(w, statusCode, resp)
 }
 func RespondMessage(w http.ResponseWriter, statusCode int, message string, theme string, typ interface{}) {
 	resp := SuccessResponse{
 		AlertifyPayload: &AlertifyPayload{
-			Message: message,
+			Message: translate(w, message),
 			Theme:   theme,
 			Type:    typ,
 		},
 	}
-	SendJSON(w, statusCode, resp)
+	send(w, statusCode, resp)
 }
 
 // ---success response options
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appErrors "github.com/codetheuri/todolist/pkg/errors"
+)
+
+// the status and message clients see come from the error code's registration
+func TestRespondErrorMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+		alert   bool
+	}{
+		{
+			name:   "safe code",
+			err:    appErrors.NotFoundError("todo with id 7 not found", nil),
+			status: http.StatusNotFound, code: "NOT_FOUND", message: "todo with id 7 not found", alert: true,
+		},
+		{
+			name:   "wrapped",
+			err:    fmt.Errorf("loading todo: %w", appErrors.ConflictError("title already taken", nil)),
+			status: http.StatusConflict, code: "CONFLICT_ERROR", message: "title already taken", alert: true,
+		},
+		{
+			name:   "unsafe code hides its message",
+			err:    appErrors.DatabaseError("failed to query todos: dial tcp 10.0.0.5:3306", errors.New("connection refused")),
+			status: http.StatusInternalServerError, code: "DATABASE_ERROR", message: "An unexpected error occurred", alert: true,
+		},
+		{
+			name:   "unregistered code",
+			err:    appErrors.New("NEVER_REGISTERED", "something odd", nil),
+			status: http.StatusInternalServerError, code: "NEVER_REGISTERED", message: "An unexpected application error occurred: something odd", alert: true,
+		},
+		{
+			name:   "plain error",
+			err:    errors.New("pq: password authentication failed"),
+			status: http.StatusInternalServerError, code: "INTERNAL_SERVER_ERROR", message: "An unexpected server error occurred.", alert: true,
+		},
+		{
+			name:   "validation errors carry fields instead of an alert",
+			err:    appErrors.ValidationError("validation failed", nil, map[string]string{"title": "This field is required"}),
+			status: http.StatusUnprocessableEntity, code: "VALIDATION_ERROR", message: "validation failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			// the default status only applies to errors without a registration
+			RespondError(rec, tt.err, http.StatusTeapot)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			var resp APIErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.ErrorPayload.Code != tt.code || resp.ErrorPayload.Message != tt.message {
+				t.Fatalf("error %s %q, want %s %q", resp.ErrorPayload.Code, resp.ErrorPayload.Message, tt.code, tt.message)
+			}
+			if (resp.AlertifyPayload != nil) != tt.alert {
+				t.Fatalf("alert %+v, want one: %v", resp.AlertifyPayload, tt.alert)
+			}
+			if tt.alert && resp.AlertifyPayload.Message != tt.message {
+				t.Fatalf("alert message %q, want %q", resp.AlertifyPayload.Message, tt.message)
+			}
+		})
+	}
+}
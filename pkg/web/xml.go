@@ -0,0 +1,271 @@
+package web
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// XML has no maps or untyped values, so the XML codec writes the JSON form of a value as
+// elements: an object's members as children named by their keys, an array's items as <item>
+// children and null as an empty element, all inside <response>. A key that is not an XML name
+// becomes <entry key="...">. Request bodies are read the same way, converting the text of each
+// element to the type of the field it fills; an empty element leaves a pointer field nil.
+
+const (
+	xmlRoot  = "response"
+	xmlItem  = "item"
+	xmlEntry = "entry"
+)
+
+func marshalXML(v interface{}) ([]byte, error) {
+	plain, err := plainValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err := writeXML(enc, xmlRoot, plain); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func writeXML(enc *xml.Encoder, name string, v interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !xmlName(name) {
+		start = xml.StartElement{Name: xml.Name{Local: xmlEntry}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}}}
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := writeXML(enc, key, val[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if err := writeXML(enc, xmlItem, item); err != nil {
+				return err
+			}
+		}
+	case float64:
+		if err := enc.EncodeToken(xml.CharData(strconv.FormatFloat(val, 'f', -1, 64))); err != nil {
+			return err
+		}
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(val))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// whether s can name an element: a letter or underscore, then letters, digits, '_', '-' and
+// '.', and not starting with "xml"
+func xmlName(s string) bool {
+	if s == "" || strings.HasPrefix(strings.ToLower(s), "xml") {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// an element read from a request body
+type xmlNode struct {
+	name     string
+	text     strings.Builder
+	children []*xmlNode
+}
+
+func unmarshalXML(data []byte, v interface{}) error {
+	root, err := parseXML(data)
+	if err != nil {
+		return err
+	}
+	return fromPlain(root.value(reflect.TypeOf(v).Elem()), v)
+}
+
+func parseXML(data []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local}
+			for _, attr := range t.Attr {
+				if t.Name.Local == xmlEntry && attr.Name.Local == "key" {
+					node.name = attr.Value
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("xml: no root element")
+	}
+	return root, nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// value is the JSON form of the node as a value of type t
+func (n *xmlNode) value(t reflect.Type) interface{} {
+	text := strings.TrimSpace(n.text.String())
+	// null is written as an empty element; for a pointer that is what it reads back as
+	if t.Kind() == reflect.Pointer && text == "" && len(n.children) == 0 {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return text
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		object := make(map[string]interface{}, len(n.children))
+		for _, child := range n.children {
+			if ft, ok := fields[child.name]; ok {
+				object[child.name] = child.value(ft)
+			}
+		}
+		return object
+	case reflect.Map:
+		object := make(map[string]interface{}, len(n.children))
+		for _, child := range n.children {
+			object[child.name] = child.value(t.Elem())
+		}
+		return object
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// base64, as JSON carries bytes
+			return text
+		}
+		items := make([]interface{}, 0, len(n.children))
+		for _, child := range n.children {
+			items = append(items, child.value(t.Elem()))
+		}
+		return items
+	case reflect.Interface:
+		return n.untyped()
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		var number float64
+		if text != "null" && json.Unmarshal([]byte(text), &number) == nil {
+			return rawNumber(text)
+		}
+	}
+	// a string, or text that does not fit its field and fails as JSON would
+	return text
+}
+
+// an element filling an interface{}: an object when it has children, its text otherwise
+func (n *xmlNode) untyped() interface{} {
+	if len(n.children) == 0 {
+		return strings.TrimSpace(n.text.String())
+	}
+	object := make(map[string]interface{}, len(n.children))
+	for _, child := range n.children {
+		object[child.name] = child.untyped()
+	}
+	return object
+}
+
+// a number written into JSON as it was sent
+type rawNumber string
+
+func (r rawNumber) MarshalJSON() ([]byte, error) {
+	return []byte(r), nil
+}
+
+// the types of a struct's fields by JSON name, with those of embedded structs promoted
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for promoted, pt := range jsonFields(ft) {
+					if _, ok := fields[promoted]; !ok {
+						fields[promoted] = pt
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = ft
+	}
+	return fields
+}
@@ -0,0 +1,157 @@
+package web
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalXML(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "null", value: nil, want: `<response></response>`},
+		{name: "string", value: "a < b", want: `<response>a &lt; b</response>`},
+		{name: "numbers", value: []interface{}{1, 2.5, 1e21, -3}, want: `<response><item>1</item><item>2.5</item><item>1000000000000000000000</item><item>-3</item></response>`},
+		{
+			name:  "object in key order",
+			value: map[string]interface{}{"title": "buy milk", "done": false, "due_at": nil},
+			want:  `<response><done>false</done><due_at></due_at><title>buy milk</title></response>`,
+		},
+		{
+			name:  "keys that are not names",
+			value: map[string]interface{}{"1st": 1, "with space": 2, "xmlns": 3, "ok-name.v2": 4},
+			want:  `<response><entry key="1st">1</entry><ok-name.v2>4</ok-name.v2><entry key="with space">2</entry><entry key="xmlns">3</entry></response>`,
+		},
+		{
+			name: "struct through its JSON form",
+			value: struct {
+				ID     uint     `json:"id"`
+				Tags   []string `json:"tags"`
+				Hidden string   `json:"-"`
+				Empty  string   `json:"empty,omitempty"`
+			}{ID: 1, Tags: []string{"a"}, Hidden: "x"},
+			want: `<response><id>1</id><tags><item>a</item></tags></response>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := marshalXML(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.TrimSuffix(strings.TrimPrefix(string(data), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"), "\n")
+			if got != tt.want {
+				t.Fatalf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+type xmlBase struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type xmlRequest struct {
+	xmlBase
+	Title    string                 `json:"title"`
+	Priority int                    `json:"priority"`
+	Weight   float64                `json:"weight"`
+	Done     bool                   `json:"done"`
+	DueAt    *time.Time             `json:"due_at"`
+	Parent   *uint                  `json:"parent_id"`
+	Tags     []string               `json:"tags"`
+	IDs      []uint                 `json:"ids"`
+	Blob     []byte                 `json:"blob"`
+	Labels   map[string]int         `json:"labels"`
+	Extra    map[string]interface{} `json:"extra"`
+	Ignored  string                 `json:"-"`
+	Untagged string
+}
+
+func TestUnmarshalXML(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<request>
+	<created_at>2026-10-19T09:00:00Z</created_at>
+	<title> buy &amp; sell </title>
+	<priority>3</priority>
+	<weight>0.25</weight>
+	<done>true</done>
+	<due_at></due_at>
+	<parent_id>7</parent_id>
+	<tags><item>home</item><item>errands</item></tags>
+	<ids><item>1</item><item>2</item></ids>
+	<blob>aGk=</blob>
+	<labels><entry key="1st">1</entry><urgent>2</urgent></labels>
+	<extra><note>hello</note><nested><deep>x</deep></nested></extra>
+	<Ignored>dropped</Ignored>
+	<Untagged>kept</Untagged>
+	<unknown>dropped</unknown>
+</request>`
+	var got xmlRequest
+	if err := unmarshalXML([]byte(body), &got); err != nil {
+		t.Fatal(err)
+	}
+	parent := uint(7)
+	want := xmlRequest{
+		xmlBase:  xmlBase{CreatedAt: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		Title:    "buy & sell",
+		Priority: 3,
+		Weight:   0.25,
+		Done:     true,
+		Parent:   &parent,
+		Tags:     []string{"home", "errands"},
+		IDs:      []uint{1, 2},
+		Blob:     []byte("hi"),
+		Labels:   map[string]int{"1st": 1, "urgent": 2},
+		Extra:    map[string]interface{}{"note": "hello", "nested": map[string]interface{}{"deep": "x"}},
+		Untagged: "kept",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestUnmarshalXMLErrors(t *testing.T) {
+	tests := map[string]string{
+		"malformed":       `<request><title>unclosed</request>`,
+		"no root":         `<?xml version="1.0"?>`,
+		"empty":           ``,
+		"text for number": `<request><priority>high</priority></request>`,
+		"text for bool":   `<request><done>maybe</done></request>`,
+		"bad time":        `<request><created_at>yesterday</created_at></request>`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got xmlRequest
+			if err := unmarshalXML([]byte(body), &got); err == nil {
+				t.Fatalf("decoded %+v without an error", got)
+			}
+		})
+	}
+}
+
+func TestXMLName(t *testing.T) {
+	tests := map[string]bool{
+		"title":     true,
+		"_private":  true,
+		"due_at":    true,
+		"api-v2.1":  true,
+		"título":    true,
+		"":          false,
+		"1st":       false,
+		"-dash":     false,
+		"has space": false,
+		"xml":       false,
+		"XMLthing":  false,
+		"a:b":       false,
+	}
+	for name, want := range tests {
+		if got := xmlName(name); got != want {
+			t.Errorf("xmlName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
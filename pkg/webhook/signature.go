@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// headers sent with every delivery
+const (
+	SignatureHeader = "X-Tusk-Signature"
+	EventHeader     = "X-Tusk-Event"
+	DeliveryHeader  = "X-Tusk-Delivery"
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	ErrSignatureExpired = errors.New("webhook: signature timestamp outside tolerance")
+)
+
+// Sign returns the X-Tusk-Signature value for a body sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">".
+// Binding the timestamp into the MAC lets receivers reject replays.
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, mac(secret, timestamp, body))
+}
+
+// Verify checks a signature header against the body, and that it was made
+// within tolerance of now (0 skips the age check)
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		if age := now.Sub(time.Unix(sent, 0)); age > tolerance || age < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+	expected := mac(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func mac(secret, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
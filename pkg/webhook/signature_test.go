@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "0123456789abcdef0123456789abcdef"
+	body := []byte(`{"id":"6a1f","type":"todo.created"}`)
+	sentAt := time.Unix(1_790_000_000, 0)
+	header := Sign(secret, sentAt, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		now       time.Time
+		want      error
+	}{
+		{name: "valid", secret: secret, header: header, body: body, tolerance: 5 * time.Minute, now: sentAt.Add(time.Minute)},
+		{name: "no age check", secret: secret, header: header, body: body, now: sentAt.Add(24 * time.Hour)},
+		{name: "wrong secret", secret: "another secret entirely", header: header, body: body, now: sentAt, want: ErrInvalidSignature},
+		{name: "tampered body", secret: secret, header: header, body: []byte(`{"id":"6a1f","type":"todo.deleted"}`), now: sentAt, want: ErrInvalidSignature},
+		{name: "tampered timestamp", secret: secret, header: strings.Replace(header, "t=1790000000", "t=1790000060", 1), body: body, now: sentAt, want: ErrInvalidSignature},
+		{name: "too old", secret: secret, header: header, body: body, tolerance: 5 * time.Minute, now: sentAt.Add(6 * time.Minute), want: ErrSignatureExpired},
+		{name: "from the future", secret: secret, header: header, body: body, tolerance: 5 * time.Minute, now: sentAt.Add(-6 * time.Minute), want: ErrSignatureExpired},
+		{name: "rotated secret", secret: secret, header: Sign("the old secret", sentAt, body) + ",v1=" + mac(secret, "1790000000", body), body: body, now: sentAt},
+		{name: "missing timestamp", secret: secret, header: "v1=" + mac(secret, "1790000000", body), body: body, now: sentAt, want: ErrInvalidSignature},
+		{name: "missing signature", secret: secret, header: "t=1790000000", body: body, now: sentAt, want: ErrInvalidSignature},
+		{name: "empty", secret: secret, body: body, now: sentAt, want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.header, tt.body, tt.tolerance, tt.now); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRequestIsVerifiable(t *testing.T) {
+	const secret = "0123456789abcdef0123456789abcdef"
+	body := []byte(`{"id":"6a1f","type":"todo.updated"}`)
+	now := time.Now()
+	var verifyErr error
+	var event, delivery string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		verifyErr = Verify(secret, r.Header.Get(SignatureHeader), received, 5*time.Minute, time.Now())
+		event, delivery = r.Header.Get(EventHeader), r.Header.Get(DeliveryHeader)
+	}))
+	defer receiver.Close()
+
+	req, err := NewRequest(t.Context(), receiver.URL, secret, "todo.updated", "6a1f", body, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := NewClient(time.Second, true).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if verifyErr != nil {
+		t.Fatalf("the receiver could not verify the delivery: %v", verifyErr)
+	}
+	if event != "todo.updated" || delivery != "6a1f" {
+		t.Fatalf("event %q, delivery %q", event, delivery)
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	_, err := NewClient(time.Second, false).Get(receiver.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("loopback delivery: %v, want %v", err, ErrForbiddenAddress)
+	}
+	resp, err := NewClient(time.Second, true).Get(receiver.URL)
+	if err != nil {
+		t.Fatalf("loopback delivery with private addresses allowed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	var followed bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/elsewhere" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/elsewhere", http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	resp, err := NewClient(time.Second, true).Get(receiver.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if followed || resp.StatusCode != http.StatusTemporaryRedirect {
+		t.Fatalf("status %d, followed %v", resp.StatusCode, followed)
+	}
+}